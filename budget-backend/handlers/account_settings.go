@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+)
+
+// accountSettingsDBFactory allows swapping the DB in tests.
+var accountSettingsDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+var accountScopes = []string{"inherit", "personal", "shared"}
+
+// budgetAccountFilter drops transactions posted to accounts the user has
+// excluded from budgets and insights. Bank rows synced before their account
+// was known are matched on the Plaid account id instead. Expects
+// transactions aliased as t.
+const budgetAccountFilter = `
+	AND NOT EXISTS (
+		SELECT 1 FROM account_balances ab
+		WHERE ab.include_in_budgets = false
+		  AND (ab.id = t.account_balance_id
+		       OR (t.account_balance_id IS NULL AND ab.plaid_account_id = t.plaid_account_id AND ab.user_id = t.user_id))
+	)`
+
+// plaidAccountSettings is the subset of account_balances that sync needs.
+type plaidAccountSettings struct {
+	AccountBalanceID string
+	SyncEnabled      bool
+	Scope            string
+}
+
+// loadAccountSettings returns settings for every known account of a linked
+// item, keyed by Plaid account_id. Accounts not in the map have never been
+// seen and use the defaults (synced, inherit scope).
+func loadAccountSettings(client db.DBTX, linkedAccountID string) map[string]plaidAccountSettings {
+	settings := map[string]plaidAccountSettings{}
+	rows, err := client.Query(`
+		SELECT id, plaid_account_id, sync_enabled, scope
+		FROM account_balances
+		WHERE linked_account_id = $1
+	`, linkedAccountID)
+	if err != nil {
+		log.Printf("loadAccountSettings %s error: %v", linkedAccountID, err)
+		return settings
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s plaidAccountSettings
+		var plaidAccountID string
+		if err := rows.Scan(&s.AccountBalanceID, &plaidAccountID, &s.SyncEnabled, &s.Scope); err != nil {
+			log.Printf("loadAccountSettings scan error: %v", err)
+			continue
+		}
+		settings[plaidAccountID] = s
+	}
+	return settings
+}
+
+// scopedHouseholdID picks the household an account's data belongs to.
+// "inherit" keeps the existing behaviour: the item's household, falling back
+// to the user's current household.
+func scopedHouseholdID(scope string, itemHH *string, userHH string) *string {
+	switch scope {
+	case "personal":
+		return nil
+	case "shared":
+		if userHH != "" {
+			return &userHH
+		}
+		return itemHH
+	}
+	if itemHH != nil {
+		return itemHH
+	}
+	if userHH != "" {
+		return &userHH
+	}
+	return nil
+}
+
+// UpdateAccountSettings changes sync, budget visibility, sharing scope and the
+// low-balance warning threshold for a single account. Changing scope also moves the account's existing
+// transactions in or out of the household.
+// PUT /auth/accounts/{id}/settings
+func UpdateAccountSettings(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var req models.AccountSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = r.URL.Query().Get("user_id")
+	}
+	if req.UserID == "" {
+		http.Error(w, "Missing user_id", http.StatusBadRequest)
+		return
+	}
+	if req.Scope != nil {
+		if e := validateEnum(*req.Scope, "scope", accountScopes); e != nil {
+			respondValidationError(w, []ValidationError{*e})
+			return
+		}
+	}
+	if req.LowBalanceThreshold != nil && *req.LowBalanceThreshold < 0 {
+		respondValidationError(w, []ValidationError{{Field: "low_balance_threshold", Message: "low_balance_threshold cannot be negative"}})
+		return
+	}
+
+	client, err := accountSettingsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), "account_balances", id, req.UserID) {
+		return
+	}
+	// Household members may hide a shared account from their budgets, but
+	// only the owner can make it personal or shared.
+	if req.Scope != nil {
+		var ownerID string
+		if err := client.QueryRow(`SELECT user_id FROM account_balances WHERE id = $1`, id).Scan(&ownerID); err != nil {
+			http.Error(w, "DB error", http.StatusInternalServerError)
+			return
+		}
+		if ownerID != req.UserID {
+			http.Error(w, "Only the account owner can change its scope", http.StatusForbidden)
+			return
+		}
+	}
+
+	tx, err := client.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	account, err := scanAccountBalance(tx.QueryRow(`
+		UPDATE account_balances
+		SET sync_enabled = COALESCE($1, sync_enabled),
+		    include_in_budgets = COALESCE($2, include_in_budgets),
+		    scope = COALESCE($3, scope),
+		    low_balance_threshold = COALESCE($4, low_balance_threshold),
+		    updated_at = NOW()
+		WHERE id = $5
+		RETURNING `+accountBalanceColumns,
+		req.SyncEnabled, req.IncludeInBudgets, req.Scope, req.LowBalanceThreshold, id,
+	))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("UpdateAccountSettings error: %v", err)
+		http.Error(w, "Update error", http.StatusInternalServerError)
+		return
+	}
+
+	if req.Scope != nil {
+		var itemHH *string
+		if account.LinkedAccountID != nil {
+			if err := tx.QueryRow(`SELECT household_id FROM linked_accounts WHERE id = $1`,
+				*account.LinkedAccountID).Scan(&itemHH); err != nil && err != sql.ErrNoRows {
+				log.Printf("UpdateAccountSettings item lookup error: %v", err)
+			}
+		}
+		hh := scopedHouseholdID(account.Scope, itemHH, db.ResolveHouseholdID(client.Raw(), req.UserID))
+		if _, err := tx.Exec(`UPDATE account_balances SET household_id = $1 WHERE id = $2`, hh, id); err != nil {
+			log.Printf("UpdateAccountSettings account scope error: %v", err)
+			http.Error(w, "Update error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.Exec(`
+			UPDATE transactions SET household_id = $1, updated_at = NOW()
+			WHERE account_balance_id = $2
+		`, hh, id); err != nil {
+			log.Printf("UpdateAccountSettings transaction scope error: %v", err)
+			http.Error(w, "Update error", http.StatusInternalServerError)
+			return
+		}
+		account.HouseholdID = hh
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Commit error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/gorilla/mux"
+)
+
+func withAccountSettingsMockDB(t *testing.T, setup func(sqlmock.Sqlmock)) {
+	t.Helper()
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockSQL.Close() })
+
+	oldFactory := accountSettingsDBFactory
+	accountSettingsDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	t.Cleanup(func() { accountSettingsDBFactory = oldFactory })
+
+	setup(mock)
+}
+
+func TestScopedHouseholdID(t *testing.T) {
+	itemHH := "hh-item"
+	cases := []struct {
+		scope  string
+		itemHH *string
+		userHH string
+		want   string
+	}{
+		{"inherit", &itemHH, "hh-user", "hh-item"},
+		{"inherit", nil, "hh-user", "hh-user"},
+		{"inherit", nil, "", ""},
+		{"shared", &itemHH, "hh-user", "hh-user"},
+		{"shared", &itemHH, "", "hh-item"},
+		{"personal", &itemHH, "hh-user", ""},
+	}
+	for _, tc := range cases {
+		got := ""
+		if hh := scopedHouseholdID(tc.scope, tc.itemHH, tc.userHH); hh != nil {
+			got = *hh
+		}
+		if got != tc.want {
+			t.Errorf("scope=%s itemHH=%v userHH=%q: expected %q, got %q", tc.scope, tc.itemHH, tc.userHH, tc.want, got)
+		}
+	}
+}
+
+func TestUpdateAccountSettings_InvalidScope(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/auth/accounts/a1/settings",
+		strings.NewReader(`{"user_id":"u1","scope":"everyone"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "a1"})
+	rr := httptest.NewRecorder()
+
+	UpdateAccountSettings(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestUpdateAccountSettings_ScopeRequiresOwner(t *testing.T) {
+	owner := "11111111-1111-1111-1111-111111111111"
+	partner := "22222222-2222-2222-2222-222222222222"
+
+	withAccountSettingsMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT user_id, household_id FROM account_balances`).
+			WithArgs("a1").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id"}).AddRow(owner, "hh1"))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM household_members`).
+			WithArgs("hh1", partner).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`SELECT user_id FROM account_balances`).
+			WithArgs("a1").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(owner))
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/auth/accounts/a1/settings",
+		strings.NewReader(`{"user_id":"`+partner+`","scope":"personal"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "a1"})
+	rr := httptest.NewRecorder()
+
+	UpdateAccountSettings(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
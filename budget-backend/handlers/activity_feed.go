@@ -212,3 +212,17 @@ func RecordActivity(client db.DBTX, householdID, userID, eventType, entityID, en
 
 	return nil
 }
+
+// recordActivityMetadata records an activity event with a JSON metadata
+// payload. It takes an execer so callers can record inside their own DB
+// transaction.
+func recordActivityMetadata(client execer, householdID, userID, eventType, entityType, description, metadata string) error {
+	_, err := client.Exec(`
+		INSERT INTO activity_events (id, household_id, user_id, event_type, entity_type, description, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, uuid.Must(uuid.NewV4()), householdID, userID, eventType, entityType, description, metadata)
+	if err != nil {
+		log.Printf("recordActivityMetadata insert error: %v", err)
+	}
+	return err
+}
@@ -357,7 +357,7 @@ func SendAIMessage(w http.ResponseWriter, r *http.Request) {
 			(SELECT COUNT(*) FROM debt_accounts WHERE user_id = $1),
 			COALESCE((SELECT SUM(current_amount) FROM savings_goals WHERE user_id = $1), 0),
 			(SELECT COUNT(*) FROM savings_goals WHERE user_id = $1),
-			COALESCE((SELECT SUM(current_balance) FROM account_balances WHERE user_id = $1), 0),
+			COALESCE((SELECT SUM(current_balance) FROM account_balances WHERE user_id = $1 AND sync_enabled), 0),
 			(SELECT COUNT(*) FROM budgets WHERE user_id = $1)
 	`, userID).Scan(
 		&ctxData.BudgetedIncome,
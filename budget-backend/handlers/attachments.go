@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/attachments"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// attachmentsDBFactory allows swapping the DB in tests.
+var attachmentsDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+// attachmentStoreFactory returns the blob store; tests swap it for a temp dir.
+var attachmentStoreFactory = sync.OnceValues(attachments.NewStore)
+
+// attachmentParent is a table attachments can hang off.
+type attachmentParent struct {
+	table  string // trusted constant, passed to ownershipCheck
+	column string // foreign key column on attachments
+}
+
+var (
+	transactionAttachments = attachmentParent{table: "transactions", column: "transaction_id"}
+	billPaymentAttachments = attachmentParent{table: "bill_payments", column: "bill_payment_id"}
+)
+
+const attachmentColumns = `id, user_id, household_id, transaction_id, bill_payment_id, filename, content_type,
+	size_bytes, storage_key, thumbnail_key, created_at`
+
+func scanAttachment(row interface{ Scan(...any) error }) (models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(&a.ID, &a.UserID, &a.HouseholdID, &a.TransactionID, &a.BillPaymentID, &a.Filename,
+		&a.ContentType, &a.SizeBytes, &a.StorageKey, &a.ThumbnailKey, &a.CreatedAt)
+	a.HasThumbnail = a.ThumbnailKey != nil
+	return a, err
+}
+
+// cleanFilename keeps the base name of an uploaded file for display and
+// Content-Disposition, falling back to a generic name.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	if len(name) > 200 {
+		name = name[len(name)-200:]
+	}
+	return name
+}
+
+// uploadAttachment handles a multipart upload (field "file") for a parent
+// row the caller can access.
+func uploadAttachment(w http.ResponseWriter, r *http.Request, parent attachmentParent) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	parentID := mux.Vars(r)["id"]
+
+	// Leave room for the multipart envelope around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, attachments.MaxSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("File is larger than %d MB", attachments.MaxSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, attachments.MaxSize+1))
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+	contentType, err := attachments.Validate(data)
+	if err != nil {
+		status := http.StatusBadRequest
+		if len(data) > attachments.MaxSize {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	client, err := attachmentsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), parent.table, parentID, userID) {
+		return
+	}
+	var householdID sql.NullString
+	if err := client.QueryRow(`SELECT household_id FROM `+parent.table+` WHERE id = $1`, parentID).Scan(&householdID); err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	store, err := attachmentStoreFactory()
+	if err != nil {
+		log.Printf("uploadAttachment store error: %v", err)
+		http.Error(w, "Attachment storage unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	id := uuid.New().String()
+	key := fmt.Sprintf("%s/%s/%s", parent.table, parentID, id)
+	if err := store.Put(r.Context(), key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		log.Printf("uploadAttachment put error: %v", err)
+		http.Error(w, "Failed to store file", http.StatusInternalServerError)
+		return
+	}
+
+	// A thumbnail is a nicety; the upload succeeds without one.
+	var thumbKey *string
+	if attachments.CanThumbnail(contentType) {
+		thumb, err := attachments.Thumbnail(bytes.NewReader(data), attachments.ThumbnailSize)
+		if err == nil {
+			k := key + "_thumb.jpg"
+			err = store.Put(r.Context(), k, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg")
+			if err == nil {
+				thumbKey = &k
+			}
+		}
+		if err != nil {
+			log.Printf("uploadAttachment thumbnail error: %v", err)
+		}
+	}
+
+	a, err := scanAttachment(client.QueryRow(`
+		INSERT INTO attachments (id, user_id, household_id, `+parent.column+`, filename, content_type, size_bytes, storage_key, thumbnail_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+attachmentColumns,
+		id, userID, householdID, parentID, cleanFilename(header.Filename), contentType, len(data), key, thumbKey))
+	if err != nil {
+		log.Printf("uploadAttachment insert error: %v", err)
+		store.Delete(r.Context(), key)
+		if thumbKey != nil {
+			store.Delete(r.Context(), *thumbKey)
+		}
+		http.Error(w, "Failed to save attachment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(a)
+}
+
+// listAttachments returns a parent row's attachments, oldest first.
+func listAttachments(w http.ResponseWriter, r *http.Request, parent attachmentParent) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	parentID := mux.Vars(r)["id"]
+
+	client, err := attachmentsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), parent.table, parentID, userID) {
+		return
+	}
+
+	rows, err := client.Query(`SELECT `+attachmentColumns+` FROM attachments WHERE `+parent.column+` = $1 ORDER BY created_at`, parentID)
+	if err != nil {
+		log.Printf("listAttachments query error: %v", err)
+		http.Error(w, "Failed to fetch attachments", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			log.Printf("listAttachments scan error: %v", err)
+			continue
+		}
+		list = append(list, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// loadAccessibleAttachment fetches an attachment the caller can access,
+// writing an error response and returning false otherwise.
+func loadAccessibleAttachment(w http.ResponseWriter, r *http.Request, client db.DBTX) (models.Attachment, bool) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return models.Attachment{}, false
+	}
+	id := mux.Vars(r)["id"]
+	if !ownershipCheck(w, client.Raw(), "attachments", id, userID) {
+		return models.Attachment{}, false
+	}
+	a, err := scanAttachment(client.QueryRow(`SELECT `+attachmentColumns+` FROM attachments WHERE id = $1`, id))
+	if err != nil {
+		log.Printf("loadAccessibleAttachment error: %v", err)
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return a, false
+	}
+	return a, true
+}
+
+// serveAttachmentBlob streams a blob with download headers.
+func serveAttachmentBlob(w http.ResponseWriter, r *http.Request, key, contentType, filename string) {
+	store, err := attachmentStoreFactory()
+	if err != nil {
+		http.Error(w, "Attachment storage unavailable", http.StatusInternalServerError)
+		return
+	}
+	blob, err := store.Get(r.Context(), key)
+	if err == attachments.ErrNotFound {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("serveAttachmentBlob get error: %v", err)
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("serveAttachmentBlob copy error: %v", err)
+	}
+}
+
+// UploadTransactionAttachment attaches a receipt to a transaction.
+// POST /auth/transactions/{id}/attachments (multipart, field "file")
+func UploadTransactionAttachment(w http.ResponseWriter, r *http.Request) {
+	uploadAttachment(w, r, transactionAttachments)
+}
+
+// ListTransactionAttachments lists a transaction's attachments.
+// GET /auth/transactions/{id}/attachments
+func ListTransactionAttachments(w http.ResponseWriter, r *http.Request) {
+	listAttachments(w, r, transactionAttachments)
+}
+
+// UploadBillPaymentAttachment attaches a receipt to a bill payment.
+// POST /auth/bill-payments/{id}/attachments (multipart, field "file")
+func UploadBillPaymentAttachment(w http.ResponseWriter, r *http.Request) {
+	uploadAttachment(w, r, billPaymentAttachments)
+}
+
+// ListBillPaymentAttachments lists a bill payment's attachments.
+// GET /auth/bill-payments/{id}/attachments
+func ListBillPaymentAttachments(w http.ResponseWriter, r *http.Request) {
+	listAttachments(w, r, billPaymentAttachments)
+}
+
+// DownloadAttachment streams the original file.
+// GET /auth/attachments/{id}
+func DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	client, err := attachmentsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	a, ok := loadAccessibleAttachment(w, r, client)
+	if !ok {
+		return
+	}
+	serveAttachmentBlob(w, r, a.StorageKey, a.ContentType, a.Filename)
+}
+
+// DownloadAttachmentThumbnail streams the JPEG thumbnail of an image
+// attachment.
+// GET /auth/attachments/{id}/thumbnail
+func DownloadAttachmentThumbnail(w http.ResponseWriter, r *http.Request) {
+	client, err := attachmentsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	a, ok := loadAccessibleAttachment(w, r, client)
+	if !ok {
+		return
+	}
+	if a.ThumbnailKey == nil {
+		http.Error(w, "No thumbnail for this attachment", http.StatusNotFound)
+		return
+	}
+	name := strings.TrimSuffix(a.Filename, filepath.Ext(a.Filename)) + "_thumb.jpg"
+	serveAttachmentBlob(w, r, *a.ThumbnailKey, "image/jpeg", name)
+}
+
+// DeleteAttachment removes an attachment and its stored files.
+// DELETE /auth/attachments/{id}
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	client, err := attachmentsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	a, ok := loadAccessibleAttachment(w, r, client)
+	if !ok {
+		return
+	}
+	if _, err := client.Exec(`DELETE FROM attachments WHERE id = $1`, a.ID); err != nil {
+		log.Printf("DeleteAttachment error: %v", err)
+		http.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+		return
+	}
+
+	keys := []string{a.StorageKey}
+	if a.ThumbnailKey != nil {
+		keys = append(keys, *a.ThumbnailKey)
+	}
+	removeAttachmentBlobs(r.Context(), keys)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// attachmentQuerier is the DB or transaction a parent row is deleted on.
+type attachmentQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// detachAttachments deletes the attachments matching where and returns the
+// keys of their stored files. Call it in the same transaction as the parent
+// delete: ON DELETE CASCADE removes the rows but not the files.
+func detachAttachments(q attachmentQuerier, where string, args ...any) ([]string, error) {
+	rows, err := q.Query(`DELETE FROM attachments WHERE `+where+` RETURNING storage_key, thumbnail_key`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		var thumb sql.NullString
+		if err := rows.Scan(&key, &thumb); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		if thumb.Valid {
+			keys = append(keys, thumb.String)
+		}
+	}
+	return keys, rows.Err()
+}
+
+// removeAttachmentBlobs deletes stored files once their rows are committed
+// as gone. A failed delete only leaves an orphan file, so it is logged.
+func removeAttachmentBlobs(ctx context.Context, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	store, err := attachmentStoreFactory()
+	if err != nil {
+		log.Printf("removeAttachmentBlobs store error: %v", err)
+		return
+	}
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			log.Printf("removeAttachmentBlobs %s error: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/aboogie/budget-backend/internal/recurring"
+	"github.com/aboogie/budget-backend/models"
+)
+
+// defaultForecastAlertPct is used when a variable bill has no threshold of
+// its own.
+const defaultForecastAlertPct = 20.0
+
+// variableBillMatchTolerance is how far from its forecast a bank charge may
+// be and still be auto-detected as a variable bill's payment.
+const variableBillMatchTolerance = 0.5
+
+// billForecasts forecasts variable bills for the month containing on, keyed
+// by bill id. amounts holds each bill's amount_due, the fallback when it has
+// no payment history.
+func billForecasts(conn *sql.DB, amounts map[string]float64, on time.Time) map[string]recurring.Forecast {
+	forecasts := map[string]recurring.Forecast{}
+	if len(amounts) == 0 {
+		return forecasts
+	}
+	ids := make([]string, 0, len(amounts))
+	for id := range amounts {
+		ids = append(ids, id)
+	}
+	payments, err := recurring.LoadPayments(conn, ids, on.AddDate(-3, -1, 0))
+	if err != nil {
+		log.Printf("billForecasts payments error: %v", err)
+		payments = nil
+	}
+	for id, amountDue := range amounts {
+		forecasts[id] = recurring.ForecastAmount(amountDue, payments[id], on)
+	}
+	return forecasts
+}
+
+// setBillForecast fills in a bill's expected amount for the month containing on.
+func setBillForecast(b *models.Bill, f recurring.Forecast) {
+	amount := f.Amount
+	b.ExpectedAmount = &amount
+	b.ForecastMethod = f.Method
+}
+
+// alertForecastDeviation tells the bill's owner when a variable bill's charge
+// strays from its forecast by more than the bill's threshold.
+func alertForecastDeviation(b models.Bill, actual float64, f recurring.Forecast) {
+	if !b.IsVariable {
+		return
+	}
+	threshold := defaultForecastAlertPct
+	if b.ForecastAlertPct != nil {
+		threshold = *b.ForecastAlertPct
+	}
+	dev := recurring.Deviation(actual, f.Amount)
+	if math.Abs(dev) <= threshold {
+		return
+	}
+	direction := "above"
+	if dev < 0 {
+		direction = "below"
+	}
+	SendPushNotification(b.UserID,
+		fmt.Sprintf("%s came in %s forecast", b.Name, direction),
+		fmt.Sprintf("$%.2f is %.0f%% %s the $%.2f expected.", actual, math.Abs(dev), direction, f.Amount),
+		map[string]string{"screen": "/bills", "bill_id": b.ID},
+	)
+}
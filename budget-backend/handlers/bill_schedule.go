@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/recurring"
+)
+
+// scheduledBill is a bill with what's needed to lay out its upcoming due
+// dates. Anchor is its last payment (or creation) date, which fixes the
+// months quarterly and yearly bills fall in.
+type scheduledBill struct {
+	ID, Name, Frequency   string
+	AmountDue             float64
+	DueDay                int
+	IsAutopay, IsVariable bool
+	Anchor                time.Time
+	Payments              []recurring.Charge
+}
+
+// loadScheduledBills returns the user's bills and, when householdID is set,
+// the household's shared bills, each with its payments. Variable bills need
+// payments going back three years to forecast, so since is pushed back that
+// far.
+func loadScheduledBills(conn db.DBTX, userID, householdID string, since time.Time) ([]scheduledBill, error) {
+	rows, err := conn.Query(`
+		SELECT b.id, b.name, b.amount_due, b.due_day, b.frequency, b.is_autopay, b.is_variable,
+		       COALESCE((SELECT MAX(bp.paid_date) FROM bill_payments bp WHERE bp.bill_id = b.id),
+		                b.created_at::date, CURRENT_DATE)
+		FROM bills b
+		WHERE b.user_id = $1 OR ($2 <> '' AND b.household_id::text = $2 AND b.is_shared)
+	`, userID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bills []scheduledBill
+	var ids []string
+	for rows.Next() {
+		var b scheduledBill
+		if err := rows.Scan(&b.ID, &b.Name, &b.AmountDue, &b.DueDay, &b.Frequency,
+			&b.IsAutopay, &b.IsVariable, &b.Anchor); err != nil {
+			return nil, err
+		}
+		ids = append(ids, b.ID)
+		bills = append(bills, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(bills) == 0 {
+		return bills, nil
+	}
+
+	payments, err := recurring.LoadPayments(conn.Raw(), ids, since.AddDate(-3, -1, 0))
+	if err != nil {
+		return nil, err
+	}
+	for i := range bills {
+		bills[i].Payments = payments[bills[i].ID]
+	}
+	return bills, nil
+}
+
+// amountOn is what the bill is expected to cost when due on the given date:
+// its forecast for variable bills, otherwise amount_due.
+func (b scheduledBill) amountOn(due time.Time) float64 {
+	if !b.IsVariable {
+		return b.AmountDue
+	}
+	return recurring.ForecastAmount(b.AmountDue, b.Payments, due).Amount
+}
+
+// paidFor reports whether the billing period containing due already has a
+// payment, e.g. a bill paid early.
+func (b scheduledBill) paidFor(due time.Time) bool {
+	start, end := computeBillingPeriod(b.DueDay, b.Frequency, due)
+	for _, p := range b.Payments {
+		if !p.Date.Before(start) && !p.Date.After(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// billDueDates lists a bill's due dates between from and to, inclusive,
+// period by period as computeBillingPeriod defines them. Quarterly and yearly
+// bills fall in the months that line up with anchor, their last payment.
+func billDueDates(dueDay int, frequency string, anchor, from, to time.Time) []time.Time {
+	var dates []time.Time
+	start, end := computeBillingPeriod(dueDay, frequency, from)
+	for !start.After(to) {
+		for _, d := range periodDueDates(dueDay, frequency, start, anchor) {
+			if !d.Before(from) && !d.After(to) {
+				dates = append(dates, d)
+			}
+		}
+		switch frequency {
+		case "weekly":
+			start, end = start.AddDate(0, 0, 7), end.AddDate(0, 0, 7)
+		case "biweekly":
+			start, end = start.AddDate(0, 0, 14), end.AddDate(0, 0, 14)
+		default:
+			start, end = computeBillingPeriod(dueDay, frequency, end.AddDate(0, 0, 1))
+		}
+	}
+	return dates
+}
+
+// periodDueDates returns the due dates within one billing period, mirroring
+// isDueDatePassed.
+func periodDueDates(dueDay int, frequency string, start, anchor time.Time) []time.Time {
+	switch frequency {
+	case "weekly":
+		if dueDay < 1 || dueDay > 7 {
+			dueDay = 1
+		}
+		return []time.Time{start.AddDate(0, 0, dueDay-1)}
+	case "biweekly":
+		return []time.Time{start}
+	case "1st-15th":
+		return []time.Time{dayInMonth(start, dueDay), dayInMonth(start, 15+dueDay)}
+	case "quarterly":
+		months := (start.Year()-anchor.Year())*12 + int(start.Month()) - int(anchor.Month())
+		if months%3 != 0 {
+			return nil
+		}
+	case "yearly":
+		if start.Month() != anchor.Month() {
+			return nil
+		}
+	}
+	return []time.Time{dayInMonth(start, dueDay)}
+}
+
+// dayInMonth returns the given day of monthStart's month, clamped to the
+// month's length.
+func dayInMonth(monthStart time.Time, day int) time.Time {
+	last := monthStart.AddDate(0, 1, -monthStart.Day()).Day()
+	if day > last {
+		day = last
+	}
+	if day < 1 {
+		day = 1
+	}
+	return time.Date(monthStart.Year(), monthStart.Month(), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/merchants"
+	"github.com/aboogie/budget-backend/internal/recurring"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// billDiscoveryMonths is how much history is scanned; long enough to see a
+// yearly charge twice.
+const billDiscoveryMonths = 18
+
+var billFrequencies = []string{"weekly", "biweekly", "1st-15th", "monthly", "quarterly", "yearly"}
+
+const billSuggestionSelect = `
+	SELECT id, user_id, merchant_id, category_id, name, frequency, due_day,
+	       typical_amount, min_amount, max_amount, amount_variance, confidence, occurrences,
+	       to_char(last_charged, 'YYYY-MM-DD'), to_char(next_expected, 'YYYY-MM-DD'),
+	       status, bill_id, created_at
+	FROM bill_suggestions`
+
+func scanBillSuggestion(row interface{ Scan(...any) error }) (models.BillSuggestion, error) {
+	var s models.BillSuggestion
+	var merchantID, categoryID, billID sql.NullString
+	err := row.Scan(&s.ID, &s.UserID, &merchantID, &categoryID, &s.Name, &s.Frequency, &s.DueDay,
+		&s.TypicalAmount, &s.MinAmount, &s.MaxAmount, &s.AmountVariance, &s.Confidence, &s.Occurrences,
+		&s.LastCharged, &s.NextExpected, &s.Status, &billID, &s.CreatedAt)
+	if merchantID.Valid {
+		s.MerchantID = &merchantID.String
+	}
+	if categoryID.Valid {
+		s.CategoryID = &categoryID.String
+	}
+	if billID.Valid {
+		s.BillID = &billID.String
+	}
+	return s, err
+}
+
+// merchantHistory is one merchant's expenses, oldest first.
+type merchantHistory struct {
+	key        string
+	name       string
+	merchantID *string
+	categoryID *string
+	charges    []recurring.Charge
+}
+
+// loadMerchantHistory groups the user's recent expenses by merchant. Bill
+// payments recorded by hand are skipped since they already belong to a bill.
+func loadMerchantHistory(conn db.DBTX, userID string, since time.Time) ([]*merchantHistory, error) {
+	rows, err := conn.Query(`
+		SELECT t.merchant_id, COALESCE(m.normalized_key, ''), COALESCE(m.name, ''), COALESCE(t.note, ''),
+		       t.category_id, t.amount, t.date
+		FROM transactions t
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE t.user_id = $1 AND t.type = 'expense' AND COALESCE(t.source, '') <> 'bill' AND t.date >= $2
+		ORDER BY t.date
+	`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byKey := map[string]*merchantHistory{}
+	var order []*merchantHistory
+	for rows.Next() {
+		var merchantID, categoryID sql.NullString
+		var key, name, note string
+		var ch recurring.Charge
+		if err := rows.Scan(&merchantID, &key, &name, &note, &categoryID, &ch.Amount, &ch.Date); err != nil {
+			return nil, err
+		}
+		if key == "" {
+			key = merchants.Key(note)
+			name = merchants.Normalize(note)
+		}
+		if key == "" || ch.Amount <= 0 {
+			continue
+		}
+		h, ok := byKey[key]
+		if !ok {
+			h = &merchantHistory{key: key, name: name}
+			byKey[key] = h
+			order = append(order, h)
+		}
+		if merchantID.Valid {
+			h.merchantID = &merchantID.String
+		}
+		if categoryID.Valid {
+			h.categoryID = &categoryID.String
+		}
+		h.charges = append(h.charges, ch)
+	}
+	return order, rows.Err()
+}
+
+// billedMerchants returns the merchant ids and keys already covered by the
+// user's bills, including shared household bills.
+func billedMerchants(conn db.DBTX, userID, householdID string) (map[string]bool, error) {
+	rows, err := conn.Query(`
+		SELECT COALESCE(merchant_id::text, ''), name, COALESCE(payee, '')
+		FROM bills
+		WHERE user_id = $1 OR ($2 <> '' AND household_id::text = $2 AND is_shared)
+	`, userID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	covered := map[string]bool{}
+	for rows.Next() {
+		var merchantID, name, payee string
+		if err := rows.Scan(&merchantID, &name, &payee); err != nil {
+			return nil, err
+		}
+		for _, k := range []string{merchantID, merchants.Key(name), merchants.Key(payee)} {
+			if k != "" {
+				covered[k] = true
+			}
+		}
+	}
+	return covered, rows.Err()
+}
+
+// refreshBillSuggestions re-runs discovery over the user's history. New
+// patterns are stored as suggestions and open suggestions that no longer
+// recur are dropped. Accepted and ignored rows are never touched, so an
+// ignored merchant doesn't come back.
+func refreshBillSuggestions(conn db.DBTX, userID, householdID string, now time.Time) error {
+	history, err := loadMerchantHistory(conn, userID, now.AddDate(0, -billDiscoveryMonths, 0))
+	if err != nil {
+		return err
+	}
+	covered, err := billedMerchants(conn, userID, householdID)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{}
+	for _, h := range history {
+		if covered[h.key] || (h.merchantID != nil && covered[*h.merchantID]) {
+			continue
+		}
+		p, ok := recurring.Find(h.charges, now)
+		if !ok {
+			continue
+		}
+		keys = append(keys, h.key)
+		if _, err := conn.Exec(`
+			INSERT INTO bill_suggestions (user_id, merchant_key, merchant_id, category_id, name, frequency, due_day,
+			                              typical_amount, min_amount, max_amount, amount_variance, confidence, occurrences,
+			                              last_charged, next_expected)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			ON CONFLICT (user_id, merchant_key) DO UPDATE SET
+				merchant_id = EXCLUDED.merchant_id, category_id = EXCLUDED.category_id, name = EXCLUDED.name,
+				frequency = EXCLUDED.frequency, due_day = EXCLUDED.due_day, typical_amount = EXCLUDED.typical_amount,
+				min_amount = EXCLUDED.min_amount, max_amount = EXCLUDED.max_amount,
+				amount_variance = EXCLUDED.amount_variance, confidence = EXCLUDED.confidence,
+				occurrences = EXCLUDED.occurrences, last_charged = EXCLUDED.last_charged,
+				next_expected = EXCLUDED.next_expected, updated_at = NOW()
+			WHERE bill_suggestions.status = 'suggested'
+		`, userID, h.key, h.merchantID, h.categoryID, h.name, p.Frequency, p.DueDay,
+			p.TypicalAmount, p.MinAmount, p.MaxAmount, p.AmountVariance, p.Confidence, p.Occurrences,
+			p.LastDate.Format("2006-01-02"), p.NextDate.Format("2006-01-02")); err != nil {
+			return err
+		}
+	}
+
+	_, err = conn.Exec(`
+		DELETE FROM bill_suggestions
+		WHERE user_id = $1 AND status = 'suggested' AND NOT (merchant_key = ANY($2))
+	`, userID, pq.Array(keys))
+	return err
+}
+
+// ListBillSuggestions scans transaction history for recurring charges that
+// aren't tracked as bills yet (GET /auth/bills/suggestions).
+func ListBillSuggestions(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := billsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	hh := db.ResolveHouseholdID(conn.Raw(), userID)
+	if err := refreshBillSuggestions(conn, userID, hh, time.Now().UTC()); err != nil {
+		log.Printf("ListBillSuggestions refresh error: %v", err)
+		http.Error(w, "Failed to detect recurring charges", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := conn.Query(billSuggestionSelect+`
+		WHERE user_id = $1 AND status = 'suggested'
+		ORDER BY confidence DESC, typical_amount DESC
+	`, userID)
+	if err != nil {
+		log.Printf("ListBillSuggestions query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	suggestions := []models.BillSuggestion{}
+	for rows.Next() {
+		s, err := scanBillSuggestion(rows)
+		if err != nil {
+			log.Printf("ListBillSuggestions scan error: %v", err)
+			continue
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// AcceptBillSuggestion creates a bill from a suggestion, applying any
+// overrides in the body (POST /auth/bills/suggestions/{id}/accept).
+func AcceptBillSuggestion(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	suggestionID := mux.Vars(r)["id"]
+
+	var req models.AcceptBillSuggestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := billsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	s, err := scanBillSuggestion(conn.QueryRow(billSuggestionSelect+` WHERE id = $1 AND user_id = $2`, suggestionID, userID))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Suggestion not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("AcceptBillSuggestion load error: %v", err)
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if s.Status != "suggested" {
+		http.Error(w, "Suggestion was already "+s.Status, http.StatusConflict)
+		return
+	}
+
+	b := models.Bill{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		Name:           s.Name,
+		AmountDue:      s.TypicalAmount,
+		DueDay:         s.DueDay,
+		Frequency:      s.Frequency,
+		CategoryID:     s.CategoryID,
+		MerchantID:     s.MerchantID,
+		IsAutopay:      req.IsAutopay,
+		IsShared:       req.IsShared,
+		IsSubscription: req.IsSubscription,
+	}
+	if req.Name != nil {
+		b.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.AmountDue != nil {
+		b.AmountDue = *req.AmountDue
+	}
+	if req.DueDay != nil {
+		b.DueDay = *req.DueDay
+	}
+	if req.Frequency != nil {
+		b.Frequency = *req.Frequency
+	}
+	if req.CategoryID != nil && *req.CategoryID != "" {
+		b.CategoryID = req.CategoryID
+	}
+
+	var errs []ValidationError
+	if b.Name == "" {
+		errs = append(errs, ValidationError{Field: "name", Message: "name is required"})
+	}
+	if b.AmountDue <= 0 {
+		errs = append(errs, ValidationError{Field: "amount_due", Message: "amount_due must be greater than zero"})
+	}
+	if b.DueDay < 1 || b.DueDay > 31 {
+		errs = append(errs, ValidationError{Field: "due_day", Message: "due_day must be between 1 and 31"})
+	}
+	if ve := validateEnum(b.Frequency, "frequency", billFrequencies); ve != nil {
+		errs = append(errs, *ve)
+	}
+	if len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+
+	b.HouseholdID = db.ResolveHouseholdID(conn.Raw(), userID)
+	if b.IsShared && b.HouseholdID == "" {
+		validationError(w, "Join or create a household before creating shared items")
+		return
+	}
+
+	tx, err := conn.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := insertBill(tx, b); err != nil {
+		log.Printf("AcceptBillSuggestion insert error: %v", err)
+		http.Error(w, "Insert error", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(`
+		UPDATE bill_suggestions SET status = 'accepted', bill_id = $1, updated_at = NOW() WHERE id = $2
+	`, b.ID, s.ID); err != nil {
+		log.Printf("AcceptBillSuggestion update error: %v", err)
+		http.Error(w, "Update error", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(b)
+}
+
+// IgnoreBillSuggestion dismisses a suggestion for good
+// (POST /auth/bills/suggestions/{id}/ignore).
+func IgnoreBillSuggestion(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	suggestionID := mux.Vars(r)["id"]
+
+	conn, err := billsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	res, err := conn.Exec(`
+		UPDATE bill_suggestions SET status = 'ignored', updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = 'suggested'
+	`, suggestionID, userID)
+	if err != nil {
+		log.Printf("IgnoreBillSuggestion error: %v", err)
+		http.Error(w, "Update error", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Suggestion not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
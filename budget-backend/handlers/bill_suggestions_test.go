@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+)
+
+func TestRefreshBillSuggestions_SkipsBilledMerchants(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	now := time.Date(2026, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	withBillsMockDB(t, func(mock sqlmock.Sqlmock) {
+		history := sqlmock.NewRows([]string{"merchant_id", "key", "name", "note", "category_id", "amount", "date"})
+		for m := time.January; m <= time.June; m++ {
+			history.AddRow("m-netflix", "netflix", "Netflix", "NETFLIX.COM", "cat-ent", 15.49, time.Date(2026, m, 3, 0, 0, 0, 0, time.UTC))
+			history.AddRow(nil, "", "", "SPOTIFY USA 877-778-1161", "cat-ent", 11.99, time.Date(2026, m, 12, 0, 0, 0, 0, time.UTC))
+		}
+		history.AddRow(nil, "", "", "CORNER CAFE", nil, 6.50, time.Date(2026, 4, 9, 0, 0, 0, 0, time.UTC))
+		mock.ExpectQuery(`FROM transactions t`).
+			WithArgs(userID, sqlmock.AnyArg()).
+			WillReturnRows(history)
+		mock.ExpectQuery(`FROM bills`).
+			WithArgs(userID, "").
+			WillReturnRows(sqlmock.NewRows([]string{"merchant_id", "name", "payee"}).AddRow("m-netflix", "Netflix", ""))
+		mock.ExpectExec(`INSERT INTO bill_suggestions`).
+			WithArgs(userID, "spotify", nil, "cat-ent", "Spotify", "monthly", 12,
+				11.99, 11.99, 11.99, 0.0, 1.0, 6, "2026-06-12", "2026-07-12").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM bill_suggestions`).
+			WithArgs(userID, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		conn, _ := billsDBFactory()
+		if err := refreshBillSuggestions(conn, userID, "", now); err != nil {
+			t.Fatalf("refresh failed: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	})
+}
+
+func TestAcceptBillSuggestion_AlreadyIgnored(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+
+	withBillsMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`FROM bill_suggestions WHERE id = \$1 AND user_id = \$2`).
+			WithArgs("s1", userID).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "user_id", "merchant_id", "category_id", "name", "frequency", "due_day",
+				"typical_amount", "min_amount", "max_amount", "amount_variance", "confidence", "occurrences",
+				"last_charged", "next_expected", "status", "bill_id", "created_at",
+			}).AddRow("s1", userID, nil, nil, "Spotify", "monthly", 12,
+				11.99, 11.99, 11.99, 0.0, 1.0, 6, "2026-06-12", "2026-07-12", "ignored", nil, time.Now()))
+
+		req := httptest.NewRequest(http.MethodPost, "/auth/bills/suggestions/s1/accept", strings.NewReader(""))
+		req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+		req = mux.SetURLVars(req, map[string]string{"id": "s1"})
+		rr := httptest.NewRecorder()
+
+		AcceptBillSuggestion(rr, req)
+
+		if rr.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	})
+}
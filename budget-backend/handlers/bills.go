@@ -11,6 +11,8 @@ import (
 
 	"github.com/aboogie/budget-backend/db"
 	"github.com/aboogie/budget-backend/internal/categories"
+	"github.com/aboogie/budget-backend/internal/merchants"
+	"github.com/aboogie/budget-backend/internal/recurring"
 	"github.com/aboogie/budget-backend/models"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -101,7 +103,8 @@ func ListBills(w http.ResponseWriter, r *http.Request) {
 	query := `
 		SELECT b.id, b.user_id, COALESCE(b.household_id::text, ''), b.name, b.amount_due,
 		       b.due_day, b.frequency, COALESCE(b.payee, ''), b.category_id, b.debt_account_id,
-		       b.is_autopay, b.is_shared,
+		       b.is_autopay, b.is_shared, b.is_subscription, to_char(b.trial_ends_at, 'YYYY-MM-DD'),
+		       b.is_variable, b.forecast_alert_pct,
 		       COALESCE(c.name, ''), COALESCE(d.name, '')
 		FROM bills b
 		LEFT JOIN categories c ON b.category_id = c.id
@@ -136,11 +139,13 @@ func ListBills(w http.ResponseWriter, r *http.Request) {
 	var bills []models.Bill
 	for rows.Next() {
 		var b models.Bill
-		var catID, debtID sql.NullString
+		var catID, debtID, trialEndsAt sql.NullString
+		var alertPct sql.NullFloat64
 		var catName, debtName, payee, hhID string
 		if err := rows.Scan(&b.ID, &b.UserID, &hhID, &b.Name, &b.AmountDue,
 			&b.DueDay, &b.Frequency, &payee, &catID, &debtID,
-			&b.IsAutopay, &b.IsShared,
+			&b.IsAutopay, &b.IsShared, &b.IsSubscription, &trialEndsAt,
+			&b.IsVariable, &alertPct,
 			&catName, &debtName); err != nil {
 			log.Printf("ListBills scan error: %v", err)
 			continue
@@ -155,6 +160,12 @@ func ListBills(w http.ResponseWriter, r *http.Request) {
 		if debtID.Valid {
 			b.DebtAccountID = &debtID.String
 		}
+		if trialEndsAt.Valid {
+			b.TrialEndsAt = &trialEndsAt.String
+		}
+		if alertPct.Valid {
+			b.ForecastAlertPct = &alertPct.Float64
+		}
 		if catName != "" {
 			b.CategoryName = &catName
 		}
@@ -181,6 +192,20 @@ func ListBills(w http.ResponseWriter, r *http.Request) {
 		bills = append(bills, b)
 	}
 
+	// Variable bills show their forecast as the expected amount.
+	variable := map[string]float64{}
+	for _, b := range bills {
+		if b.IsVariable {
+			variable[b.ID] = b.AmountDue
+		}
+	}
+	forecasts := billForecasts(client.Raw(), variable, now)
+	for i := range bills {
+		if f, ok := forecasts[bills[i].ID]; ok {
+			setBillForecast(&bills[i], f)
+		}
+	}
+
 	if bills == nil {
 		bills = []models.Bill{}
 	}
@@ -188,6 +213,54 @@ func ListBills(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(bills)
 }
 
+// billMerchantID links a bill to an existing merchant by payee, falling back
+// to the bill name. Bills never create merchants; they only pick up ones
+// already seen on transactions.
+func billMerchantID(conn *sql.DB, b models.Bill) *string {
+	candidates := []string{b.Name}
+	if b.Payee != nil && *b.Payee != "" {
+		candidates = []string{*b.Payee, b.Name}
+	}
+	for _, raw := range candidates {
+		m, err := merchants.Lookup(conn, raw)
+		if err != nil {
+			log.Printf("billMerchantID lookup error: %v", err)
+			return nil
+		}
+		if m != nil {
+			return &m.ID
+		}
+	}
+	return nil
+}
+
+// validTrialEndDate checks the optional trial end date, clearing it when
+// blank.
+func validTrialEndDate(b *models.Bill) bool {
+	if b.TrialEndsAt == nil || *b.TrialEndsAt == "" {
+		b.TrialEndsAt = nil
+		return true
+	}
+	_, err := time.Parse("2006-01-02", *b.TrialEndsAt)
+	return err == nil
+}
+
+// insertBill stores a validated bill with its category and merchant resolved.
+func insertBill(client execer, b models.Bill) error {
+	var hhVal any
+	if b.HouseholdID == "" {
+		hhVal = nil
+	} else {
+		hhVal = b.HouseholdID
+	}
+
+	_, err := client.Exec(`
+		INSERT INTO bills (id, user_id, household_id, name, amount_due, due_day, frequency, payee, category_id, debt_account_id, is_autopay, is_shared, merchant_id, is_subscription, trial_ends_at, is_variable, forecast_alert_pct)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17)
+	`, b.ID, b.UserID, hhVal, b.Name, b.AmountDue, b.DueDay, b.Frequency, b.Payee, b.CategoryID, b.DebtAccountID, b.IsAutopay, b.IsShared, b.MerchantID, b.IsSubscription, b.TrialEndsAt, b.IsVariable, b.ForecastAlertPct)
+	return err
+}
+
 func CreateBill(w http.ResponseWriter, r *http.Request) {
 	var b models.Bill
 	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
@@ -223,6 +296,14 @@ func CreateBill(w http.ResponseWriter, r *http.Request) {
 	if b.Frequency == "" {
 		b.Frequency = "monthly"
 	}
+	if !validTrialEndDate(&b) {
+		http.Error(w, "Trial end date must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if b.ForecastAlertPct != nil && (*b.ForecastAlertPct <= 0 || *b.ForecastAlertPct > 999) {
+		http.Error(w, "Forecast alert percentage must be between 0 and 999", http.StatusBadRequest)
+		return
+	}
 
 	client, err := billsDBFactory()
 	if err != nil {
@@ -249,18 +330,11 @@ func CreateBill(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var hhVal any
-	if b.HouseholdID == "" {
-		hhVal = nil
-	} else {
-		hhVal = b.HouseholdID
+	if b.MerchantID == nil || *b.MerchantID == "" {
+		b.MerchantID = billMerchantID(client.Raw(), b)
 	}
 
-	_, err = client.Exec(`
-		INSERT INTO bills (id, user_id, household_id, name, amount_due, due_day, frequency, payee, category_id, debt_account_id, is_autopay, is_shared)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
-	`, b.ID, b.UserID, hhVal, b.Name, b.AmountDue, b.DueDay, b.Frequency, b.Payee, b.CategoryID, b.DebtAccountID, b.IsAutopay, b.IsShared)
-	if err != nil {
+	if err := insertBill(client, b); err != nil {
 		log.Printf("CreateBill insert error: %v", err)
 		http.Error(w, "Insert error", http.StatusInternalServerError)
 		return
@@ -303,12 +377,25 @@ func UpdateBill(w http.ResponseWriter, r *http.Request) {
 	if !ownershipCheck(w, client.Raw(), "bills", billID, userID) {
 		return
 	}
+	if !validTrialEndDate(&b) {
+		http.Error(w, "Trial end date must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if b.ForecastAlertPct != nil && (*b.ForecastAlertPct <= 0 || *b.ForecastAlertPct > 999) {
+		http.Error(w, "Forecast alert percentage must be between 0 and 999", http.StatusBadRequest)
+		return
+	}
+
+	if b.MerchantID == nil || *b.MerchantID == "" {
+		b.MerchantID = billMerchantID(client.Raw(), b)
+	}
 
 	res, err := client.Exec(`
 		UPDATE bills
-		SET name=$1, amount_due=$2, due_day=$3, frequency=$4, payee=$5, category_id=$6, debt_account_id=$7, is_autopay=$8, is_shared=$9, updated_at=NOW()
+		SET name=$1, amount_due=$2, due_day=$3, frequency=$4, payee=$5, category_id=$6, debt_account_id=$7, is_autopay=$8, is_shared=$9, merchant_id=$11,
+		    is_subscription=$12, trial_ends_at=$13, is_variable=$14, forecast_alert_pct=$15, updated_at=NOW()
 		WHERE id=$10
-	`, b.Name, b.AmountDue, b.DueDay, b.Frequency, b.Payee, b.CategoryID, b.DebtAccountID, b.IsAutopay, b.IsShared, billID)
+	`, b.Name, b.AmountDue, b.DueDay, b.Frequency, b.Payee, b.CategoryID, b.DebtAccountID, b.IsAutopay, b.IsShared, billID, b.MerchantID, b.IsSubscription, b.TrialEndsAt, b.IsVariable, b.ForecastAlertPct)
 	if err != nil {
 		http.Error(w, "Update error", http.StatusInternalServerError)
 		return
@@ -347,7 +434,20 @@ func DeleteBill(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := client.Exec(`DELETE FROM bills WHERE id=$1`, billID)
+	tx, err := client.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	// Receipts on the bill's payments go with them.
+	blobKeys, err := detachAttachments(tx, "bill_payment_id IN (SELECT id FROM bill_payments WHERE bill_id = $1)", billID)
+	if err != nil {
+		log.Printf("DeleteBill attachments error: %v", err)
+		http.Error(w, "Delete error", http.StatusInternalServerError)
+		return
+	}
+	res, err := tx.Exec(`DELETE FROM bills WHERE id=$1`, billID)
 	if err != nil {
 		http.Error(w, "Delete error", http.StatusInternalServerError)
 		return
@@ -357,6 +457,11 @@ func DeleteBill(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Bill not found", http.StatusNotFound)
 		return
 	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Commit error", http.StatusInternalServerError)
+		return
+	}
+	removeAttachmentBlobs(r.Context(), blobKeys)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -391,12 +496,13 @@ func MarkBillPaid(w http.ResponseWriter, r *http.Request) {
 	row := client.QueryRow(`
 		SELECT b.id, b.user_id, COALESCE(b.household_id::text, ''), b.name, b.amount_due, b.due_day, b.frequency,
 		       COALESCE(b.payee, ''), b.category_id, b.debt_account_id, b.is_autopay, b.is_shared,
-		       COALESCE(c.name, '')
+		       b.is_variable, b.forecast_alert_pct, COALESCE(c.name, '')
 		FROM bills b
 		LEFT JOIN categories c ON b.category_id = c.id
 		WHERE b.id = $1
 	`, billID)
-	if err := row.Scan(&bill.ID, &bill.UserID, &bill.HouseholdID, &bill.Name, &bill.AmountDue, &bill.DueDay, &bill.Frequency, &payee, &catID, &debtID, &bill.IsAutopay, &bill.IsShared, &catName); err != nil {
+	var alertPct sql.NullFloat64
+	if err := row.Scan(&bill.ID, &bill.UserID, &bill.HouseholdID, &bill.Name, &bill.AmountDue, &bill.DueDay, &bill.Frequency, &payee, &catID, &debtID, &bill.IsAutopay, &bill.IsShared, &bill.IsVariable, &alertPct, &catName); err != nil {
 		http.Error(w, "Bill not found", http.StatusNotFound)
 		return
 	}
@@ -409,11 +515,10 @@ func MarkBillPaid(w http.ResponseWriter, r *http.Request) {
 	if debtID.Valid {
 		bill.DebtAccountID = &debtID.String
 	}
-
-	amount := body.Amount
-	if amount <= 0 {
-		amount = bill.AmountDue
+	if alertPct.Valid {
+		bill.ForecastAlertPct = &alertPct.Float64
 	}
+
 	paidDate := time.Now().UTC()
 	if body.PaidDate != "" {
 		if parsed, err := time.Parse(time.RFC3339, body.PaidDate); err == nil {
@@ -435,6 +540,24 @@ func MarkBillPaid(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Forecast before recording the payment so it isn't part of its own history.
+	var forecast recurring.Forecast
+	if bill.IsVariable {
+		forecast = billForecasts(client.Raw(), map[string]float64{bill.ID: bill.AmountDue}, paidDate)[bill.ID]
+		setBillForecast(&bill, forecast)
+	}
+
+	// Without an amount, a variable bill is recorded at its forecast. That is
+	// an estimate, so it isn't used as history for later forecasts.
+	amount := body.Amount
+	source := "manual"
+	if amount <= 0 {
+		amount = bill.AmountDue
+		if bill.IsVariable {
+			amount, source = forecast.Amount, "estimated"
+		}
+	}
+
 	paymentID := uuid.New().String()
 	txID := uuid.New().String()
 	var hhVal any
@@ -458,8 +581,8 @@ func MarkBillPaid(w http.ResponseWriter, r *http.Request) {
 
 	_, err = client.Exec(`
 		INSERT INTO bill_payments (id, bill_id, user_id, household_id, amount_paid, paid_date, transaction_id, source, period_start, period_end)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,'manual',$8,$9)
-	`, paymentID, billID, bill.UserID, hhVal, amount, paidDate, txID,
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+	`, paymentID, billID, bill.UserID, hhVal, amount, paidDate, txID, source,
 		periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02"))
 	if err != nil {
 		log.Printf("MarkBillPaid insert error: %v", err)
@@ -467,13 +590,15 @@ func MarkBillPaid(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If linked to a debt account, decrease the balance
+	// If linked to a debt account, record the payment in its ledger
 	if bill.DebtAccountID != nil && *bill.DebtAccountID != "" {
-		_, err = client.Exec(`
-			UPDATE debt_accounts SET balance = GREATEST(balance - $1, 0) WHERE id = $2
-		`, amount, *bill.DebtAccountID)
+		payment := models.DebtPayment{Amount: math.Round(amount*100) / 100, TransactionID: &txID,
+			BillPaymentID: &paymentID, Source: "bill", Note: bill.Name}
+		err := inDebtLedgerTx(client.Raw(), func(q ledgerExecer) error {
+			return recordDebtPayment(q, *bill.DebtAccountID, &payment, paidDate)
+		})
 		if err != nil {
-			log.Printf("MarkBillPaid debt update error: %v", err)
+			log.Printf("MarkBillPaid debt payment error: %v", err)
 		}
 	}
 
@@ -494,6 +619,10 @@ func MarkBillPaid(w http.ResponseWriter, r *http.Request) {
 		)
 	}
 
+	if source != "estimated" {
+		alertForecastDeviation(bill, amount, forecast)
+	}
+
 	bill.Status = "paid"
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(bill)
@@ -564,7 +693,8 @@ func AutoDetectBillPayments(w http.ResponseWriter, r *http.Request) {
 
 	// Get all bills for user
 	billRows, err := client.Query(`
-		SELECT id, user_id, COALESCE(household_id::text, ''), name, amount_due, due_day, frequency, category_id, debt_account_id
+		SELECT id, user_id, COALESCE(household_id::text, ''), name, amount_due, due_day, frequency, category_id, debt_account_id,
+		       COALESCE(merchant_id::text, ''), is_variable, forecast_alert_pct
 		FROM bills WHERE user_id = $1
 	`, userID)
 	if err != nil {
@@ -574,24 +704,31 @@ func AutoDetectBillPayments(w http.ResponseWriter, r *http.Request) {
 	defer billRows.Close()
 
 	type billInfo struct {
-		ID            string
-		UserID        string
-		HouseholdID   string
-		AmountDue     float64
-		DueDay        int
-		Frequency     string
-		CategoryID    *string
-		DebtAccountID *string
+		ID               string
+		UserID           string
+		HouseholdID      string
+		Name             string
+		AmountDue        float64
+		DueDay           int
+		Frequency        string
+		CategoryID       *string
+		DebtAccountID    *string
+		MerchantID       string
+		IsVariable       bool
+		ForecastAlertPct *float64
 	}
 
 	var bills []billInfo
 	for billRows.Next() {
 		var b billInfo
 		var catID, debtID sql.NullString
-		var name string
-		if err := billRows.Scan(&b.ID, &b.UserID, &b.HouseholdID, &name, &b.AmountDue, &b.DueDay, &b.Frequency, &catID, &debtID); err != nil {
+		var alertPct sql.NullFloat64
+		if err := billRows.Scan(&b.ID, &b.UserID, &b.HouseholdID, &b.Name, &b.AmountDue, &b.DueDay, &b.Frequency, &catID, &debtID, &b.MerchantID, &b.IsVariable, &alertPct); err != nil {
 			continue
 		}
+		if alertPct.Valid {
+			b.ForecastAlertPct = &alertPct.Float64
+		}
 		if catID.Valid {
 			b.CategoryID = &catID.String
 		}
@@ -617,9 +754,17 @@ func AutoDetectBillPayments(w http.ResponseWriter, r *http.Request) {
 
 		// Try to match a bank-synced transaction
 		// Match criteria: source='bank', amount within 5%, date in period, same category (if set)
+		expected := bill.AmountDue
 		tolerance := bill.AmountDue * 0.05
-		lowerBound := bill.AmountDue - tolerance
-		upperBound := bill.AmountDue + tolerance
+		var forecast recurring.Forecast
+		if bill.IsVariable {
+			// Variable bills swing, so match loosely around the forecast.
+			forecast = billForecasts(client.Raw(), map[string]float64{bill.ID: bill.AmountDue}, now)[bill.ID]
+			expected = forecast.Amount
+			tolerance = expected * variableBillMatchTolerance
+		}
+		lowerBound := expected - tolerance
+		upperBound := expected + tolerance
 
 		var matchQuery string
 		var matchArgs []any
@@ -651,7 +796,25 @@ func AutoDetectBillPayments(w http.ResponseWriter, r *http.Request) {
 
 		var txID string
 		var txAmount float64
-		err := client.QueryRow(matchQuery, matchArgs...).Scan(&txID, &txAmount)
+		// A transaction from the bill's own merchant is the strongest match;
+		// fall back to amount and category when there isn't one.
+		err := sql.ErrNoRows
+		if bill.MerchantID != "" {
+			err = client.QueryRow(`
+				SELECT id, amount FROM transactions
+				WHERE user_id = $1
+				  AND source = 'bank'
+				  AND amount >= $2 AND amount <= $3
+				  AND date >= $4 AND date <= $5
+				  AND merchant_id = $6
+				LIMIT 1
+			`, bill.UserID, lowerBound, upperBound,
+				periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02"),
+				bill.MerchantID).Scan(&txID, &txAmount)
+		}
+		if err != nil {
+			err = client.QueryRow(matchQuery, matchArgs...).Scan(&txID, &txAmount)
+		}
 		if err != nil {
 			continue // no match
 		}
@@ -675,13 +838,23 @@ func AutoDetectBillPayments(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		// If linked to debt, decrease balance
+		// If linked to debt, record the payment in its ledger
 		if bill.DebtAccountID != nil && *bill.DebtAccountID != "" {
-			_, _ = client.Exec(`
-				UPDATE debt_accounts SET balance = GREATEST(balance - $1, 0) WHERE id = $2
-			`, txAmount, *bill.DebtAccountID)
+			payment := models.DebtPayment{Amount: math.Round(txAmount*100) / 100, TransactionID: &txID,
+				BillPaymentID: &paymentID, Source: "bill", Note: bill.Name}
+			err := inDebtLedgerTx(client.Raw(), func(q ledgerExecer) error {
+				return recordDebtPayment(q, *bill.DebtAccountID, &payment, time.Now().UTC())
+			})
+			if err != nil {
+				log.Printf("AutoDetect debt payment error for bill %s: %v", bill.ID, err)
+			}
 		}
 
+		alertForecastDeviation(models.Bill{
+			ID: bill.ID, UserID: bill.UserID, Name: bill.Name,
+			IsVariable: bill.IsVariable, ForecastAlertPct: bill.ForecastAlertPct,
+		}, txAmount, forecast)
+
 		detected = append(detected, map[string]any{
 			"bill_id":        bill.ID,
 			"payment_id":     paymentID,
@@ -2,15 +2,20 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/attachments"
 	"github.com/gorilla/mux"
 )
 
@@ -29,6 +34,31 @@ func withBillsMockDB(t *testing.T, setup func(sqlmock.Sqlmock)) {
 	setup(mock)
 }
 
+// stubPush stands in for the push notification database so a handler's
+// background push can't reach for the real pool. Each push attempt is
+// signalled on the returned channel; tests that trigger one should wait for
+// it before returning.
+func stubPush(t *testing.T) <-chan struct{} {
+	t.Helper()
+	pushed := make(chan struct{}, 8)
+	orig := notifyDBFactory
+	notifyDBFactory = func() (db.DBTX, error) {
+		pushed <- struct{}{}
+		return nil, errors.New("push disabled in tests")
+	}
+	t.Cleanup(func() { notifyDBFactory = orig })
+	return pushed
+}
+
+func waitForPush(t *testing.T, pushed <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-pushed:
+	case <-time.After(time.Second):
+		t.Error("expected a push notification")
+	}
+}
+
 func TestListBills_PersonalOnly(t *testing.T) {
 	userID := "11111111-1111-1111-1111-111111111111"
 
@@ -42,12 +72,14 @@ func TestListBills_PersonalOnly(t *testing.T) {
 		rows := sqlmock.NewRows([]string{
 			"id", "user_id", "household_id", "name", "amount_due",
 			"due_day", "frequency", "payee", "category_id", "debt_account_id",
-			"is_autopay", "is_shared",
+			"is_autopay", "is_shared", "is_subscription", "trial_ends_at",
+			"is_variable", "forecast_alert_pct",
 			"cat_name", "debt_name",
 		}).AddRow(
 			"b1", userID, "", "Netflix", 15.99,
 			1, "monthly", "Netflix Inc", nil, nil,
-			true, false,
+			true, false, true, nil,
+			false, nil,
 			"Entertainment", "",
 		)
 		mock.ExpectQuery(`FROM bills`).
@@ -220,11 +252,11 @@ func TestMarkBillPaid_Basic(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{
 				"id", "user_id", "household_id", "name", "amount_due",
 				"due_day", "frequency", "payee", "category_id", "debt_account_id",
-				"is_autopay", "is_shared", "cat_name",
+				"is_autopay", "is_shared", "is_variable", "forecast_alert_pct", "cat_name",
 			}).AddRow(
 				billID, userID, "", "Rent", 1500.00,
 				1, "monthly", "", nil, nil,
-				false, false, "",
+				false, false, false, nil, "",
 			))
 
 		// Insert transaction (bill creates expense transaction for budget)
@@ -262,18 +294,20 @@ func TestMarkBillPaid_WithDebtDecrease(t *testing.T) {
 	userID := "11111111-1111-1111-1111-111111111111"
 	debtID := "dddddddd-dddd-dddd-dddd-dddddddddddd"
 
-	withBillsMockDB(t, func(mock sqlmock.Sqlmock) {
+	var mock sqlmock.Sqlmock
+	withBillsMockDB(t, func(m sqlmock.Sqlmock) {
+		mock = m
 		// Fetch the bill (with debt_account_id and category join)
 		mock.ExpectQuery(`FROM bills b`).
 			WithArgs(billID).
 			WillReturnRows(sqlmock.NewRows([]string{
 				"id", "user_id", "household_id", "name", "amount_due",
 				"due_day", "frequency", "payee", "category_id", "debt_account_id",
-				"is_autopay", "is_shared", "cat_name",
+				"is_autopay", "is_shared", "is_variable", "forecast_alert_pct", "cat_name",
 			}).AddRow(
 				billID, userID, "", "Car Payment", 450.00,
 				15, "monthly", "", nil, debtID,
-				false, false, "",
+				false, false, false, nil, "",
 			))
 
 		// Insert transaction (bill creates expense transaction for budget)
@@ -284,9 +318,22 @@ func TestMarkBillPaid_WithDebtDecrease(t *testing.T) {
 		mock.ExpectExec(`INSERT INTO bill_payments`).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		// Debt balance decrease
-		mock.ExpectExec(`UPDATE debt_accounts SET balance`).
+		// Payment recorded in the debt's ledger, in its own transaction
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM debt_accounts d`).
+			WithArgs(debtID).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"user_id", "plaid_account_id", "apr", "balance", "unpaid_interest",
+				"interest_accrued_through", "entries", "ledger_balance", "opened_on",
+			}).AddRow(userID, "", 0.0, 5000.0, 0.0, nil, 1, 5000.0, time.Now().AddDate(0, -1, 0)))
+		mock.ExpectExec(`INSERT INTO debt_payments`).
+			WithArgs(sqlmock.AnyArg(), debtID, userID, "payment", 450.0, 450.0, 0.0, 4550.0,
+				sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "bill", "Car Payment").
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE debt_accounts`).
+			WithArgs(4550.0, 0.0, sqlmock.AnyArg(), debtID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 	})
 
 	body := map[string]any{"amount": 450.00}
@@ -308,6 +355,9 @@ func TestMarkBillPaid_WithDebtDecrease(t *testing.T) {
 	if result["status"] != "paid" {
 		t.Fatalf("expected status=paid, got %v", result["status"])
 	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("debt ledger not updated: %v", err)
+	}
 }
 
 func TestDeleteBill(t *testing.T) {
@@ -321,11 +371,27 @@ func TestDeleteBill(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id"}).
 				AddRow(userID, nil))
 
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM attachments WHERE bill_payment_id IN \(SELECT id FROM bill_payments WHERE bill_id = \$1\)`).
+			WithArgs(billID).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key", "thumbnail_key"}).
+				AddRow("bill_payments/p1/a1", nil))
 		mock.ExpectExec(`DELETE FROM bills`).
 			WithArgs(billID).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 	})
 
+	// The receipt's stored file is removed along with the bill.
+	store, err := attachments.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Put(context.Background(), "bill_payments/p1/a1", strings.NewReader("receipt"), 7, "image/png")
+	oldStore := attachmentStoreFactory
+	attachmentStoreFactory = func() (attachments.BlobStore, error) { return store, nil }
+	t.Cleanup(func() { attachmentStoreFactory = oldStore })
+
 	req := httptest.NewRequest(http.MethodDelete, "/auth/bills/"+billID+"?user_id="+userID, nil)
 	req = mux.SetURLVars(req, map[string]string{"id": billID})
 	rr := httptest.NewRecorder()
@@ -335,6 +401,9 @@ func TestDeleteBill(t *testing.T) {
 	if rr.Code != http.StatusNoContent {
 		t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
 	}
+	if _, err := store.Get(context.Background(), "bill_payments/p1/a1"); err != attachments.ErrNotFound {
+		t.Errorf("expected the receipt file to be deleted, got %v", err)
+	}
 }
 
 func TestDeleteBill_NotFound(t *testing.T) {
@@ -358,3 +427,110 @@ func TestDeleteBill_NotFound(t *testing.T) {
 		t.Fatalf("expected 404, got %d: %s", rr.Code, rr.Body.String())
 	}
 }
+
+func TestMarkBillPaid_VariableBillReturnsForecast(t *testing.T) {
+	billID := "b1111111-1111-1111-1111-111111111111"
+	userID := "11111111-1111-1111-1111-111111111111"
+	lastMonth := time.Now().UTC().AddDate(0, -1, 0)
+	pushed := stubPush(t)
+
+	withBillsMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`FROM bills b`).
+			WithArgs(billID).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "user_id", "household_id", "name", "amount_due",
+				"due_day", "frequency", "payee", "category_id", "debt_account_id",
+				"is_autopay", "is_shared", "is_variable", "forecast_alert_pct", "cat_name",
+			}).AddRow(
+				billID, userID, "", "Electric", 100.00,
+				20, "monthly", "", nil, nil,
+				false, false, true, 15.0, "",
+			))
+
+		// Payment history for the forecast
+		mock.ExpectQuery(`FROM bill_payments\s+WHERE bill_id = ANY`).
+			WillReturnRows(sqlmock.NewRows([]string{"bill_id", "amount_paid", "paid_date"}).
+				AddRow(billID, 120.0, lastMonth.AddDate(0, -1, 0)).
+				AddRow(billID, 140.0, lastMonth))
+
+		mock.ExpectExec(`INSERT INTO transactions`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO bill_payments`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/bills/"+billID+"/pay", bytes.NewReader([]byte(`{"amount": 180}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req = mux.SetURLVars(req, map[string]string{"id": billID})
+	rr := httptest.NewRecorder()
+
+	MarkBillPaid(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var result map[string]any
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if result["expected_amount"] != 130.0 || result["forecast_method"] != "trailing_average" {
+		t.Fatalf("expected a 130 trailing-average forecast, got %v (%v)", result["expected_amount"], result["forecast_method"])
+	}
+	// $180 is 38% over the forecast, past the bill's 15% threshold.
+	waitForPush(t, pushed)
+}
+
+func TestMarkBillPaid_VariableBillWithoutAmountUsesForecast(t *testing.T) {
+	billID := "b1111111-1111-1111-1111-111111111111"
+	userID := "11111111-1111-1111-1111-111111111111"
+	lastMonth := time.Now().UTC().AddDate(0, -1, 0)
+	pushed := stubPush(t)
+
+	var mock sqlmock.Sqlmock
+	withBillsMockDB(t, func(m sqlmock.Sqlmock) {
+		mock = m
+		mock.ExpectQuery(`FROM bills b`).
+			WithArgs(billID).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "user_id", "household_id", "name", "amount_due",
+				"due_day", "frequency", "payee", "category_id", "debt_account_id",
+				"is_autopay", "is_shared", "is_variable", "forecast_alert_pct", "cat_name",
+			}).AddRow(
+				billID, userID, "", "Electric", 100.00,
+				20, "monthly", "", nil, nil,
+				false, false, true, 15.0, "",
+			))
+		mock.ExpectQuery(`FROM bill_payments\s+WHERE bill_id = ANY`).
+			WillReturnRows(sqlmock.NewRows([]string{"bill_id", "amount_paid", "paid_date"}).
+				AddRow(billID, 120.0, lastMonth.AddDate(0, -1, 0)).
+				AddRow(billID, 140.0, lastMonth))
+
+		// Recorded at the forecast, not the bill's fixed amount, and marked
+		// as an estimate so it stays out of the forecast history.
+		mock.ExpectExec(`INSERT INTO transactions`).
+			WithArgs(sqlmock.AnyArg(), userID, nil, nil, 130.0, "", "Electric payment",
+				sqlmock.AnyArg(), "monthly", 20).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO bill_payments`).
+			WithArgs(sqlmock.AnyArg(), billID, userID, nil, 130.0, sqlmock.AnyArg(), sqlmock.AnyArg(),
+				"estimated", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/bills/"+billID+"/pay", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req = mux.SetURLVars(req, map[string]string{"id": billID})
+	rr := httptest.NewRecorder()
+
+	MarkBillPaid(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	select {
+	case <-pushed:
+		t.Error("expected no deviation alert for an estimated amount")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -448,17 +448,18 @@ func GetBudgetSummary(w http.ResponseWriter, r *http.Request) {
 	// JOIN categories to get parent_id so subcategory spending rolls up to parent budgets.
 	// Non-split transactions use their own category_id; split transactions contribute
 	// via transaction_splits rows so each split's category is counted individually.
+	// Reimbursable expenses count net of what is (or is expected to be) paid back.
 	txQueryNonSplit := `
-		SELECT COALESCE(t.category_id::text, ''), COALESCE(c.parent_id::text, ''), t.amount
+		SELECT COALESCE(t.category_id::text, ''), COALESCE(c.parent_id::text, ''), ` + netExpenseAmountSQL + `
 		FROM transactions t
 		LEFT JOIN categories c ON t.category_id = c.id
 		WHERE COALESCE(t.is_split, false) = false
 		  AND t.type = 'expense'
 		  AND t.date >= $1 AND t.date < $2
 		  AND COALESCE(t.source, '') != 'bill'
-	`
+	` + budgetAccountFilter
 	txQuerySplit := `
-		SELECT ts.category_id::text, COALESCE(c.parent_id::text, ''), ts.amount
+		SELECT ts.category_id::text, COALESCE(c.parent_id::text, ''), ` + netSplitAmountSQL + `
 		FROM transaction_splits ts
 		JOIN transactions t ON ts.transaction_id = t.id
 		LEFT JOIN categories c ON ts.category_id = c.id
@@ -466,7 +467,7 @@ func GetBudgetSummary(w http.ResponseWriter, r *http.Request) {
 		  AND t.type = 'expense'
 		  AND t.date >= $1 AND t.date < $2
 		  AND COALESCE(t.source, '') != 'bill'
-	`
+	` + budgetAccountFilter
 	var txRows *sql.Rows
 	if hhID == "" {
 		txRows, err = dbClient.Query(
@@ -518,6 +519,7 @@ func GetBudgetSummary(w http.ResponseWriter, r *http.Request) {
 	// 3b. Fetch income transactions in this month (for income budget tracking).
 	// JOIN categories to get parent_id for subcategory rollup, same as expenses.
 	// Handle splits the same way as expenses.
+	// Reimbursement payments are left out; they were netted from the expense.
 	earnedByCategory := map[string]float64{}
 	incNonSplit := `
 		SELECT COALESCE(t.category_id::text, ''), COALESCE(c.parent_id::text, ''), t.amount
@@ -526,7 +528,7 @@ func GetBudgetSummary(w http.ResponseWriter, r *http.Request) {
 		WHERE COALESCE(t.is_split, false) = false
 		  AND t.type = 'income'
 		  AND t.date >= $1 AND t.date < $2
-	`
+	` + budgetAccountFilter + reimbursementPaymentFilter
 	incSplit := `
 		SELECT ts.category_id::text, COALESCE(c.parent_id::text, ''), ts.amount
 		FROM transaction_splits ts
@@ -535,7 +537,7 @@ func GetBudgetSummary(w http.ResponseWriter, r *http.Request) {
 		WHERE t.is_split = true
 		  AND t.type = 'income'
 		  AND t.date >= $1 AND t.date < $2
-	`
+	` + budgetAccountFilter + reimbursementPaymentFilter
 	var incTxRows *sql.Rows
 	if hhID == "" {
 		incTxRows, err = dbClient.Query(
@@ -569,7 +571,7 @@ func GetBudgetSummary(w http.ResponseWriter, r *http.Request) {
 	// 4. Fetch bills to include as budgeted expenses.
 	billQuery := `
 		SELECT b.id, b.name, b.amount_due, b.frequency,
-		       b.category_id, COALESCE(c.name, ''), COALESCE(b.household_id::text, '')
+		       b.category_id, COALESCE(c.name, ''), COALESCE(b.household_id::text, ''), b.is_variable
 		FROM bills b
 		LEFT JOIN categories c ON b.category_id = c.id
 	`
@@ -602,6 +604,7 @@ func GetBudgetSummary(w http.ResponseWriter, r *http.Request) {
 		CategoryID  *string
 		CatName     string
 		HouseholdID string
+		IsVariable  bool
 	}
 	var billList []billEntry
 	if billRows != nil {
@@ -609,7 +612,7 @@ func GetBudgetSummary(w http.ResponseWriter, r *http.Request) {
 		for billRows.Next() {
 			var be billEntry
 			var catID sql.NullString
-			if err := billRows.Scan(&be.ID, &be.Name, &be.AmountDue, &be.Frequency, &catID, &be.CatName, &be.HouseholdID); err != nil {
+			if err := billRows.Scan(&be.ID, &be.Name, &be.AmountDue, &be.Frequency, &catID, &be.CatName, &be.HouseholdID, &be.IsVariable); err != nil {
 				log.Printf("budget summary: scan bill: %v", err)
 				continue
 			}
@@ -620,6 +623,20 @@ func GetBudgetSummary(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Variable bills are budgeted at their forecast for the month.
+	variableBills := map[string]float64{}
+	for _, be := range billList {
+		if be.IsVariable {
+			variableBills[be.ID] = be.AmountDue
+		}
+	}
+	forecasts := billForecasts(dbClient.Raw(), variableBills, monthStart)
+	for i := range billList {
+		if f, ok := forecasts[billList[i].ID]; ok {
+			billList[i].AmountDue = f.Amount
+		}
+	}
+
 	// Look up how much was paid for each bill this month.
 	billPaid := map[string]float64{}
 	for _, be := range billList {
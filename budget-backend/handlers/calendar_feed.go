@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/ical"
+	"github.com/gorilla/mux"
+)
+
+// calendarFeedDBFactory allows swapping the DB in tests.
+var calendarFeedDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+// calendarFeedLookbackDays keeps recently passed events on the calendar.
+const calendarFeedLookbackDays = 31
+
+// calendarFeedMonths is how far ahead recurring events are published.
+const calendarFeedMonths = 6
+
+// calendarUIDDomain qualifies event UIDs so they can't collide with events
+// from other calendars.
+const calendarUIDDomain = "coupleflow"
+
+type calendarFeedResponse struct {
+	URL            string     `json:"url"`
+	WebcalURL      string     `json:"webcal_url"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+}
+
+// newCalendarFeedToken returns a random 192-bit token, hex encoded.
+func newCalendarFeedToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// calendarFeedPath is where a feed is served, relative to the API root.
+func calendarFeedPath(token string) string {
+	return "/calendar/" + token + ".ics"
+}
+
+func newCalendarFeedResponse(r *http.Request, token string, createdAt time.Time, lastAccessed sql.NullTime) calendarFeedResponse {
+	scheme := "https"
+	if p := r.Header.Get("X-Forwarded-Proto"); p != "" {
+		scheme = p
+	} else if r.TLS == nil {
+		scheme = "http"
+	}
+	resp := calendarFeedResponse{
+		URL:       scheme + "://" + r.Host + calendarFeedPath(token),
+		WebcalURL: "webcal://" + r.Host + calendarFeedPath(token),
+		CreatedAt: createdAt,
+	}
+	if lastAccessed.Valid {
+		resp.LastAccessedAt = &lastAccessed.Time
+	}
+	return resp
+}
+
+// GetCalendarFeed returns the user's calendar feed URL.
+// GET /auth/calendar-feed
+func GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := calendarFeedDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	var token string
+	var createdAt time.Time
+	var lastAccessed sql.NullTime
+	err = conn.QueryRow(`
+		SELECT token, created_at, last_accessed_at FROM calendar_feeds WHERE user_id = $1
+	`, userID).Scan(&token, &createdAt, &lastAccessed)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Calendar feed not enabled", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("GetCalendarFeed query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newCalendarFeedResponse(r, token, createdAt, lastAccessed))
+}
+
+// CreateCalendarFeed enables the user's calendar feed, or rotates its token
+// if it already exists so the old URL stops working.
+// POST /auth/calendar-feed
+func CreateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	token, err := newCalendarFeedToken()
+	if err != nil {
+		log.Printf("CreateCalendarFeed token error: %v", err)
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+	conn, err := calendarFeedDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	var createdAt time.Time
+	err = conn.QueryRow(`
+		INSERT INTO calendar_feeds (user_id, token)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			token = EXCLUDED.token, created_at = NOW(), last_accessed_at = NULL
+		RETURNING created_at
+	`, userID, token).Scan(&createdAt)
+	if err != nil {
+		log.Printf("CreateCalendarFeed insert error: %v", err)
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newCalendarFeedResponse(r, token, createdAt, sql.NullTime{}))
+}
+
+// RevokeCalendarFeed disables the user's calendar feed.
+// DELETE /auth/calendar-feed
+func RevokeCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := calendarFeedDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec(`DELETE FROM calendar_feeds WHERE user_id = $1`, userID); err != nil {
+		log.Printf("RevokeCalendarFeed delete error: %v", err)
+		http.Error(w, "Failed to revoke calendar feed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeCalendarFeed publishes a user's bills, recurring income, debt payments
+// and plan milestones as an iCalendar feed. Public: the token in the URL is
+// the credential.
+// GET /calendar/{token}.ics
+func ServeCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	conn, err := calendarFeedDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	var userID string
+	err = conn.QueryRow(`
+		UPDATE calendar_feeds SET last_accessed_at = NOW()
+		WHERE token = $1
+		RETURNING user_id
+	`, token).Scan(&userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("ServeCalendarFeed lookup error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now().UTC()
+	events, err := calendarEvents(conn, userID, now)
+	if err != nil {
+		log.Printf("ServeCalendarFeed events error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	if err := ical.Write(w, ical.Calendar{Name: "CoupleFlow Bills", Events: events}, now); err != nil {
+		log.Printf("ServeCalendarFeed write error: %v", err)
+	}
+}
+
+// calendarEvents gathers every event in the user's feed.
+func calendarEvents(conn db.DBTX, userID string, now time.Time) ([]ical.Event, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, 0, -calendarFeedLookbackDays)
+	to := today.AddDate(0, calendarFeedMonths, 0)
+	hh := db.ResolveHouseholdID(conn.Raw(), userID)
+
+	var events []ical.Event
+	for _, load := range []func() ([]ical.Event, error){
+		func() ([]ical.Event, error) { return billCalendarEvents(conn, userID, hh, from, to) },
+		func() ([]ical.Event, error) { return incomeCalendarEvents(conn, userID, from, to) },
+		func() ([]ical.Event, error) { return debtCalendarEvents(conn, userID, from, to) },
+		func() ([]ical.Event, error) { return milestoneCalendarEvents(conn, userID, hh) },
+	} {
+		evs, err := load()
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, evs...)
+	}
+	return events, nil
+}
+
+// calendarUID is an event's stable identifier: the same source row and date
+// always produce the same UID.
+func calendarUID(kind, id string, date time.Time) string {
+	if date.IsZero() {
+		return fmt.Sprintf("%s-%s@%s", kind, id, calendarUIDDomain)
+	}
+	return fmt.Sprintf("%s-%s-%s@%s", kind, id, date.Format("20060102"), calendarUIDDomain)
+}
+
+// billCalendarEvents lists the due dates of the user's bills and the
+// household's shared bills. Variable bills show their forecast amount.
+func billCalendarEvents(conn db.DBTX, userID, householdID string, from, to time.Time) ([]ical.Event, error) {
+	bills, err := loadScheduledBills(conn, userID, householdID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ical.Event
+	for _, b := range bills {
+		for _, due := range billDueDates(b.DueDay, b.Frequency, b.Anchor, from, to) {
+			amount, label := b.amountOn(due), ""
+			if b.IsVariable {
+				label = " (est.)"
+			}
+			desc := fmt.Sprintf("$%.2f%s due, %s.", amount, label, b.Frequency)
+			if b.IsAutopay {
+				desc += " Paid automatically."
+			}
+			events = append(events, ical.Event{
+				UID:         calendarUID("bill", b.ID, due),
+				Date:        due,
+				Summary:     fmt.Sprintf("%s due $%.2f%s", b.Name, amount, label),
+				Description: desc,
+				Category:    "Bill",
+			})
+		}
+	}
+	return events, nil
+}
+
+// incomeCalendarEvents lists upcoming paydays from the user's recurring
+// income templates, stepping forward from each template's date the same way
+// RunRecurringSync does.
+func incomeCalendarEvents(conn db.DBTX, userID string, from, to time.Time) ([]ical.Event, error) {
+	rows, err := conn.Query(`
+		SELECT id, COALESCE(NULLIF(note, ''), NULLIF(category_name, ''), 'Income'), amount, date, frequency, due_day
+		FROM transactions
+		WHERE user_id = $1 AND type = 'income'
+		  AND frequency IS NOT NULL
+		  AND frequency NOT IN ('', 'one-time')
+		  AND COALESCE(source, '') != 'recurring'
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []ical.Event
+	for rows.Next() {
+		var id, name, frequency string
+		var amount float64
+		var date time.Time
+		var dueDay sql.NullInt64
+		if err := rows.Scan(&id, &name, &amount, &date, &frequency, &dueDay); err != nil {
+			return nil, err
+		}
+		var day *int
+		if dueDay.Valid {
+			d := int(dueDay.Int64)
+			day = &d
+		}
+		for _, next := range templateOccurrences(date, frequency, day, from, to) {
+			events = append(events, ical.Event{
+				UID:         calendarUID("income", id, next),
+				Date:        next,
+				Summary:     fmt.Sprintf("%s +$%.2f", name, amount),
+				Description: fmt.Sprintf("Expected %s income of $%.2f.", frequency, amount),
+				Category:    "Income",
+			})
+		}
+	}
+	return events, rows.Err()
+}
+
+// debtCalendarEvents lists monthly payment due dates for the user's debts.
+func debtCalendarEvents(conn db.DBTX, userID string, from, to time.Time) ([]ical.Event, error) {
+	rows, err := conn.Query(`
+		SELECT id, name, min_payment, due_day
+		FROM debt_accounts
+		WHERE user_id = $1 AND due_day IS NOT NULL AND balance > 0
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []ical.Event
+	for rows.Next() {
+		var id, name string
+		var minPayment float64
+		var dueDay int
+		if err := rows.Scan(&id, &name, &minPayment, &dueDay); err != nil {
+			return nil, err
+		}
+		for _, due := range billDueDates(dueDay, "monthly", from, from, to) {
+			events = append(events, ical.Event{
+				UID:         calendarUID("debt", id, due),
+				Date:        due,
+				Summary:     fmt.Sprintf("%s payment due", name),
+				Description: fmt.Sprintf("Minimum payment $%.2f.", minPayment),
+				Category:    "Debt",
+			})
+		}
+	}
+	return events, rows.Err()
+}
+
+// milestoneCalendarEvents lists pending milestone target dates from plans the
+// user created or shares through their household.
+func milestoneCalendarEvents(conn db.DBTX, userID, householdID string) ([]ical.Event, error) {
+	rows, err := conn.Query(`
+		SELECT m.id, m.title, COALESCE(m.target_amount, 0), m.target_date, p.name
+		FROM plan_milestones m
+		JOIN financial_plans p ON p.id = m.plan_id
+		WHERE m.target_date IS NOT NULL AND m.status = 'pending'
+		  AND (p.created_by = $1 OR ($2 <> '' AND p.household_id::text = $2))
+	`, userID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []ical.Event
+	for rows.Next() {
+		var id, title, planName string
+		var target float64
+		var date time.Time
+		if err := rows.Scan(&id, &title, &target, &date, &planName); err != nil {
+			return nil, err
+		}
+		desc := planName
+		if target > 0 {
+			desc = fmt.Sprintf("%s: target $%.2f.", planName, target)
+		}
+		// One event per milestone; moving the target date moves the event.
+		events = append(events, ical.Event{
+			UID:         calendarUID("milestone", id, time.Time{}),
+			Date:        time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+			Summary:     "Milestone: " + title,
+			Description: desc,
+			Category:    "Milestone",
+		})
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/gorilla/mux"
+)
+
+func withCalendarFeedMockDB(t *testing.T, setup func(sqlmock.Sqlmock)) {
+	t.Helper()
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockSQL.Close() })
+
+	oldFactory := calendarFeedDBFactory
+	calendarFeedDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	t.Cleanup(func() { calendarFeedDBFactory = oldFactory })
+
+	setup(mock)
+}
+
+func calDay(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func formatDates(dates []time.Time) []string {
+	out := make([]string, len(dates))
+	for i, d := range dates {
+		out[i] = d.Format("2006-01-02")
+	}
+	return out
+}
+
+func TestBillDueDates(t *testing.T) {
+	cases := []struct {
+		name      string
+		dueDay    int
+		frequency string
+		anchor    time.Time
+		from, to  time.Time
+		want      string
+	}{
+		{"monthly clamps to month end", 31, "monthly", time.Time{},
+			calDay(2026, 2, 1), calDay(2026, 4, 30), "2026-02-28 2026-03-31 2026-04-30"},
+		{"quarterly follows last payment", 10, "quarterly", calDay(2025, 11, 10),
+			calDay(2026, 1, 1), calDay(2026, 9, 30), "2026-02-10 2026-05-10 2026-08-10"},
+		{"yearly", 1, "yearly", calDay(2025, 7, 1),
+			calDay(2026, 1, 1), calDay(2026, 12, 31), "2026-07-01"},
+		{"weekly on Friday", 5, "weekly", time.Time{},
+			calDay(2026, 5, 4), calDay(2026, 5, 17), "2026-05-08 2026-05-15"},
+		{"twice monthly", 1, "1st-15th", time.Time{},
+			calDay(2026, 5, 1), calDay(2026, 5, 31), "2026-05-01 2026-05-16"},
+	}
+	for _, tc := range cases {
+		got := strings.Join(formatDates(billDueDates(tc.dueDay, tc.frequency, tc.anchor, tc.from, tc.to)), " ")
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestServeCalendarFeed_UnknownToken(t *testing.T) {
+	withCalendarFeedMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`UPDATE calendar_feeds`).
+			WithArgs("abc123").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/abc123.ics", nil)
+	req = mux.SetURLVars(req, map[string]string{"token": "abc123"})
+	rr := httptest.NewRecorder()
+
+	ServeCalendarFeed(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestServeCalendarFeed_PublishesEvents(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	milestoneDate := monthStart.AddDate(0, 2, 0)
+
+	withCalendarFeedMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`UPDATE calendar_feeds`).
+			WithArgs("abc123").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(userID))
+		mock.ExpectQuery(`FROM household_members`).
+			WillReturnRows(sqlmock.NewRows([]string{"household_id"}))
+		mock.ExpectQuery(`FROM bills b`).
+			WithArgs(userID, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "amount_due", "due_day", "frequency", "is_autopay", "is_variable", "anchor"}).
+				AddRow("b1", "Rent", 1800.0, 15, "monthly", false, false, monthStart))
+		mock.ExpectQuery(`FROM bill_payments`).
+			WillReturnRows(sqlmock.NewRows([]string{"bill_id", "amount_paid", "paid_date"}))
+		mock.ExpectQuery(`FROM transactions`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "amount", "date", "frequency", "due_day"}).
+				AddRow("t1", "Paycheck", 2500.0, monthStart.AddDate(-1, 0, 0), "biweekly", nil))
+		mock.ExpectQuery(`FROM debt_accounts`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "min_payment", "due_day"}).
+				AddRow("d1", "Visa", 35.0, 20))
+		mock.ExpectQuery(`FROM plan_milestones`).
+			WithArgs(userID, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "target_amount", "target_date", "name"}).
+				AddRow("m1", "Emergency fund", 5000.0, milestoneDate, "Safety net"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/abc123.ics", nil)
+	req = mux.SetURLVars(req, map[string]string{"token": "abc123"})
+	rr := httptest.NewRecorder()
+
+	ServeCalendarFeed(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"UID:bill-b1-" + monthStart.AddDate(0, 0, 14).Format("20060102") + "@coupleflow",
+		"SUMMARY:Rent due $1800.00",
+		"UID:debt-d1-" + monthStart.AddDate(0, 0, 19).Format("20060102") + "@coupleflow",
+		"SUMMARY:Paycheck +$2500.00",
+		"UID:milestone-m1@coupleflow",
+		"DTSTART;VALUE=DATE:" + milestoneDate.Format("20060102"),
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("feed missing %q", want)
+		}
+	}
+}
+
+func TestCreateCalendarFeed_ReturnsURL(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	withCalendarFeedMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`INSERT INTO calendar_feeds`).
+			WithArgs(userID, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "http://api.example.com/auth/calendar-feed", nil)
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rr := httptest.NewRecorder()
+
+	CreateCalendarFeed(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp calendarFeedResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("bad response: %v", err)
+	}
+	if !strings.HasPrefix(resp.URL, "https://api.example.com/calendar/") || !strings.HasSuffix(resp.URL, ".ics") {
+		t.Errorf("unexpected url %q", resp.URL)
+	}
+	if !strings.HasPrefix(resp.WebcalURL, "webcal://api.example.com/calendar/") {
+		t.Errorf("unexpected webcal url %q", resp.WebcalURL)
+	}
+}
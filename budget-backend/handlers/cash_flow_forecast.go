@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/ai"
+	"github.com/aboogie/budget-backend/internal/cashflow"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/lib/pq"
+)
+
+// cashFlowDBFactory allows swapping the DB in tests.
+var cashFlowDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+var cashFlowHorizons = []string{"30", "60", "90"}
+var cashFlowScopes = []string{"personal", "household"}
+
+// cashFlowWarningDays is how far ahead the daily run looks for low balances.
+const cashFlowWarningDays = 14
+
+// GetCashFlowForecast projects the user's (or household's) cash account
+// balances day by day from scheduled bills, recurring income and expenses,
+// and average discretionary spending.
+// GET /auth/cash-flow/forecast?days=30|60|90&scope=personal|household
+func GetCashFlowForecast(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	days := r.URL.Query().Get("days")
+	if days == "" {
+		days = "30"
+	}
+	scope := r.URL.Query().Get("scope")
+	if scope == "" {
+		scope = "personal"
+	}
+	var errs []ValidationError
+	if e := validateEnum(days, "days", cashFlowHorizons); e != nil {
+		errs = append(errs, *e)
+	}
+	if e := validateEnum(scope, "scope", cashFlowScopes); e != nil {
+		errs = append(errs, *e)
+	}
+	if len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+
+	conn, err := cashFlowDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	householdID := ""
+	if scope == "household" {
+		householdID = db.ResolveHouseholdID(conn.Raw(), userID)
+		if householdID == "" {
+			respondValidationError(w, []ValidationError{{Field: "scope", Message: "you are not in a household"}})
+			return
+		}
+	}
+
+	n, _ := strconv.Atoi(days)
+	forecast, err := forecastCashFlow(conn, userID, householdID, time.Now().UTC(), n)
+	if err != nil {
+		log.Printf("GetCashFlowForecast error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	forecast.Scope = scope
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecast)
+}
+
+// forecastCashFlow projects balances for the user, or for the whole household
+// when householdID is set.
+func forecastCashFlow(conn db.DBTX, userID, householdID string, now time.Time, days int) (models.CashFlowForecast, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from, to := today.AddDate(0, 0, 1), today.AddDate(0, 0, days)
+
+	accounts, err := loadCashAccounts(conn, userID, householdID)
+	if err != nil {
+		return models.CashFlowForecast{}, fmt.Errorf("accounts: %w", err)
+	}
+	flows, err := billCashFlows(conn, userID, householdID, from, to)
+	if err != nil {
+		return models.CashFlowForecast{}, fmt.Errorf("bills: %w", err)
+	}
+	templates, err := templateCashFlows(conn, userID, householdID, from, to)
+	if err != nil {
+		return models.CashFlowForecast{}, fmt.Errorf("recurring templates: %w", err)
+	}
+	flows = append(flows, templates...)
+	spend, err := dailyDiscretionarySpend(conn, userID, householdID)
+	if err != nil {
+		return models.CashFlowForecast{}, fmt.Errorf("discretionary spend: %w", err)
+	}
+	return cashflow.Project(accounts, flows, spend, today, days), nil
+}
+
+// loadCashAccounts returns the cash accounts the forecast runs over. Accounts
+// with no subtype are treated as checking so manual accounts get warnings too.
+func loadCashAccounts(conn db.DBTX, userID, householdID string) ([]cashflow.Account, error) {
+	rows, err := conn.Query(`
+		SELECT id, name, type, COALESCE(subtype, ''),
+		       COALESCE(available_balance, current_balance, 0), low_balance_threshold
+		FROM account_balances
+		WHERE (user_id = $1 OR ($2 <> '' AND household_id::text = $2))
+		  AND type IN ('depository', 'cash') AND NOT is_liability AND sync_enabled
+		ORDER BY name
+	`, userID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []cashflow.Account
+	for rows.Next() {
+		var a cashflow.Account
+		var accountType, subtype string
+		var threshold sql.NullFloat64
+		if err := rows.Scan(&a.ID, &a.Name, &accountType, &subtype, &a.Balance, &threshold); err != nil {
+			return nil, err
+		}
+		a.Checking = subtype == "checking" || (subtype == "" && accountType == "depository")
+		a.Threshold = threshold.Float64
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// billCashFlows lays out upcoming bill payments. Variable bills use their
+// forecast and periods that are already paid are skipped.
+func billCashFlows(conn db.DBTX, userID, householdID string, from, to time.Time) ([]cashflow.Flow, error) {
+	bills, err := loadScheduledBills(conn, userID, householdID, from)
+	if err != nil {
+		return nil, err
+	}
+	var flows []cashflow.Flow
+	for _, b := range bills {
+		for _, due := range billDueDates(b.DueDay, b.Frequency, b.Anchor, from, to) {
+			if b.paidFor(due) {
+				continue
+			}
+			flows = append(flows, cashflow.Flow{
+				Date:   due,
+				Name:   b.Name,
+				Source: "bill",
+				Amount: -b.amountOn(due),
+			})
+		}
+	}
+	return flows, nil
+}
+
+// templateCashFlows lays out upcoming recurring income and expense templates.
+// Bill payment transactions are left out since bills are scheduled on their
+// own.
+func templateCashFlows(conn db.DBTX, userID, householdID string, from, to time.Time) ([]cashflow.Flow, error) {
+	rows, err := conn.Query(`
+		SELECT type, COALESCE(NULLIF(note, ''), NULLIF(category_name, ''), INITCAP(type)),
+		       amount, date, frequency, due_day, COALESCE(account_balance_id::text, '')
+		FROM transactions
+		WHERE (user_id = $1 OR ($2 <> '' AND household_id::text = $2))
+		  AND type IN ('income', 'expense')
+		  AND frequency IS NOT NULL
+		  AND frequency NOT IN ('', 'one-time')
+		  AND COALESCE(source, '') NOT IN ('recurring', 'bill')
+	`, userID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flows []cashflow.Flow
+	for rows.Next() {
+		var txType, name, frequency, accountID string
+		var amount float64
+		var date time.Time
+		var dueDay sql.NullInt64
+		if err := rows.Scan(&txType, &name, &amount, &date, &frequency, &dueDay, &accountID); err != nil {
+			return nil, err
+		}
+		var day *int
+		if dueDay.Valid {
+			d := int(dueDay.Int64)
+			day = &d
+		}
+		if txType == "expense" {
+			amount = -amount
+		}
+		for _, on := range templateOccurrences(date, frequency, day, from, to) {
+			flows = append(flows, cashflow.Flow{
+				Date:      on,
+				AccountID: accountID,
+				Name:      name,
+				Source:    txType,
+				Amount:    amount,
+			})
+		}
+	}
+	return flows, rows.Err()
+}
+
+// dailyDiscretionarySpend is average monthly spending from AnalyzeCashFlow
+// less what went to bills and recurring expenses, which the forecast already
+// schedules, spread over the days of the year.
+func dailyDiscretionarySpend(conn db.DBTX, userID, householdID string) (float64, error) {
+	members := []string{userID}
+	if householdID != "" {
+		rows, err := conn.Query(`SELECT user_id FROM household_members WHERE household_id::text = $1`, householdID)
+		if err != nil {
+			return 0, err
+		}
+		members = nil
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			members = append(members, id)
+		}
+		rows.Close()
+	}
+
+	var monthly float64
+	for _, id := range members {
+		analysis, err := ai.AnalyzeCashFlow(conn.Raw(), id)
+		if err != nil {
+			return 0, err
+		}
+		monthly += analysis.AvgMonthlyExpenses
+	}
+
+	var scheduled float64
+	err := conn.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE user_id = ANY($1) AND type = 'expense'
+		  AND date >= NOW() - INTERVAL '3 months'
+		  AND (COALESCE(source, '') IN ('bill', 'recurring')
+		       OR COALESCE(frequency, '') NOT IN ('', 'one-time'))
+	`, pq.Array(members)).Scan(&scheduled)
+	if err != nil {
+		return 0, err
+	}
+
+	monthly = math.Max(monthly-scheduled/3, 0)
+	return monthly * 12 / 365, nil
+}
+
+// RunCashFlowWarnings pushes a warning when a user's checking account is
+// forecast to drop below its low-balance threshold in the next two weeks.
+// An account is warned once per dip, not once per predicted date.
+func RunCashFlowWarnings() {
+	client, err := db.New()
+	if err != nil {
+		log.Printf("cash flow warnings: db error: %v", err)
+		return
+	}
+	defer client.Close()
+
+	rows, err := client.Query(`SELECT DISTINCT user_id FROM push_tokens WHERE enabled = true`)
+	if err != nil {
+		log.Printf("cash flow warnings: user query error: %v", err)
+		return
+	}
+	var users []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			users = append(users, id)
+		}
+	}
+	rows.Close()
+
+	now := time.Now().UTC()
+	sent := 0
+	for _, userID := range users {
+		forecast, err := forecastCashFlow(client, userID, "", now, cashFlowWarningDays)
+		if err != nil {
+			log.Printf("cash flow warnings: forecast error for user %s: %v", userID, err)
+			continue
+		}
+		for _, a := range forecast.Accounts {
+			if warnLowBalance(client, userID, a) {
+				sent++
+			}
+		}
+	}
+	log.Printf("cash flow warnings: sent %d notifications", sent)
+}
+
+// warnLowBalance pushes a low-balance warning when an account's forecast
+// first dips under its threshold. The warning stays open, and no other is
+// sent, until a forecast shows the account back above the threshold.
+func warnLowBalance(client execer, userID string, a models.AccountCashFlowForecast) bool {
+	if a.BelowThresholdOn == nil {
+		_, err := client.Exec(`
+			UPDATE cash_flow_alerts SET cleared_at = NOW()
+			WHERE account_balance_id = $1 AND cleared_at IS NULL
+		`, a.AccountID)
+		if err != nil {
+			log.Printf("cash flow warnings: clear error for account %s: %v", a.AccountID, err)
+		}
+		return false
+	}
+	res, err := client.Exec(`
+		INSERT INTO cash_flow_alerts (account_balance_id, predicted_date, projected_balance)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (account_balance_id) WHERE cleared_at IS NULL DO NOTHING
+	`, a.AccountID, *a.BelowThresholdOn, *a.BelowThresholdBalance)
+	if err != nil {
+		log.Printf("cash flow warnings: record error for account %s: %v", a.AccountID, err)
+		return false
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false
+	}
+
+	date, _ := time.Parse("2006-01-02", *a.BelowThresholdOn)
+	body := fmt.Sprintf("%s may drop to $%.2f on %s, below your $%.2f threshold.",
+		a.Name, *a.BelowThresholdBalance, date.Format("Mon, Jan 2"), a.Threshold)
+	if a.Threshold == 0 {
+		body = fmt.Sprintf("%s may be overdrawn by $%.2f on %s.",
+			a.Name, -*a.BelowThresholdBalance, date.Format("Mon, Jan 2"))
+	}
+	SendPushNotification(userID, "Low balance ahead", body,
+		map[string]string{"screen": "/cash-flow", "account_id": a.AccountID},
+	)
+	return true
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+)
+
+func withCashFlowMockDB(t *testing.T, setup func(sqlmock.Sqlmock)) *sql.DB {
+	t.Helper()
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockSQL.Close() })
+
+	oldFactory := cashFlowDBFactory
+	cashFlowDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	t.Cleanup(func() { cashFlowDBFactory = oldFactory })
+
+	setup(mock)
+	return mockSQL
+}
+
+func TestGetCashFlowForecast_InvalidHorizon(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/auth/cash-flow/forecast?days=45", nil)
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, "11111111-1111-1111-1111-111111111111"))
+	rr := httptest.NewRecorder()
+
+	GetCashFlowForecast(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestGetCashFlowForecast_FlagsLowChecking(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	withCashFlowMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`FROM account_balances`).
+			WithArgs(userID, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "subtype", "balance", "low_balance_threshold"}).
+				AddRow("chk", "Checking", "depository", "checking", 500.0, 100.0))
+		mock.ExpectQuery(`FROM bills b`).
+			WithArgs(userID, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "amount_due", "due_day", "frequency", "is_autopay", "is_variable", "anchor"}).
+				AddRow("b1", "Rent", 450.0, tomorrow.Day(), "monthly", false, false, today))
+		mock.ExpectQuery(`FROM bill_payments`).
+			WillReturnRows(sqlmock.NewRows([]string{"bill_id", "amount_paid", "paid_date"}))
+		mock.ExpectQuery(`FROM transactions\s+WHERE \(user_id`).
+			WithArgs(userID, "").
+			WillReturnRows(sqlmock.NewRows([]string{"type", "name", "amount", "date", "frequency", "due_day", "account_balance_id"}).
+				AddRow("income", "Paycheck", 1000.0, today.AddDate(0, 0, 5), "biweekly", nil, ""))
+		// AnalyzeCashFlow: $900/month spent over the last three months.
+		mock.ExpectQuery(`type = 'income'`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(9000.0))
+		mock.ExpectQuery(`type = 'expense'`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(2700.0))
+		mock.ExpectQuery(`GROUP BY category`).
+			WillReturnRows(sqlmock.NewRows([]string{"category", "total"}))
+		// $1,800 of that was rent.
+		mock.ExpectQuery(`source, ''\) IN \('bill', 'recurring'\)`).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1800.0))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/cash-flow/forecast?days=30", nil)
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+	rr := httptest.NewRecorder()
+
+	GetCashFlowForecast(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var f models.CashFlowForecast
+	if err := json.Unmarshal(rr.Body.Bytes(), &f); err != nil {
+		t.Fatalf("bad response: %v", err)
+	}
+	// 900 - 1800/3 = $300/month discretionary.
+	if f.DailyDiscretionary != 9.86 {
+		t.Errorf("daily discretionary = %v", f.DailyDiscretionary)
+	}
+	if len(f.Days) != 30 || len(f.Accounts) != 1 {
+		t.Fatalf("expected 30 days for 1 account, got %d / %d", len(f.Days), len(f.Accounts))
+	}
+	chk := f.Accounts[0]
+	if chk.BelowThresholdOn == nil || *chk.BelowThresholdOn != tomorrow.Format("2006-01-02") {
+		t.Fatalf("expected checking flagged tomorrow, got %+v", chk.BelowThresholdOn)
+	}
+	if *chk.BelowThresholdBalance != 40.14 {
+		t.Errorf("balance when flagged = %v", *chk.BelowThresholdBalance)
+	}
+	var paychecks int
+	for _, e := range f.Events {
+		if e.Source == "income" {
+			paychecks++
+		}
+	}
+	if paychecks != 2 {
+		t.Errorf("expected 2 paychecks in 30 days, got %d", paychecks)
+	}
+}
+
+func TestWarnLowBalance_OncePerDip(t *testing.T) {
+	pushed := stubPush(t)
+	mockSQL := withCashFlowMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec(`INSERT INTO cash_flow_alerts .* ON CONFLICT \(account_balance_id\) WHERE cleared_at IS NULL DO NOTHING`).
+			WithArgs("chk", "2026-05-03", 40.14).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		// The low day moves but the warning is still open.
+		mock.ExpectExec(`INSERT INTO cash_flow_alerts`).
+			WithArgs("chk", "2026-05-04", 40.14).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`UPDATE cash_flow_alerts SET cleared_at = NOW\(\)`).
+			WithArgs("chk").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO cash_flow_alerts`).
+			WithArgs("chk", "2026-05-20", 40.14).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	})
+	warn := func(date string) bool {
+		bal := 40.14
+		return warnLowBalance(mockSQL, "u1", models.AccountCashFlowForecast{AccountID: "chk", Name: "Checking",
+			IsChecking: true, Threshold: 100, BelowThresholdOn: &date, BelowThresholdBalance: &bal})
+	}
+
+	if !warn("2026-05-03") {
+		t.Fatal("expected the first warning to send")
+	}
+	waitForPush(t, pushed)
+	if warn("2026-05-04") {
+		t.Error("expected the warning to be skipped while the dip continues")
+	}
+	// The forecast recovers, then dips again.
+	if warnLowBalance(mockSQL, "u1", models.AccountCashFlowForecast{AccountID: "chk", IsChecking: true, Threshold: 100}) {
+		t.Error("expected no warning above the threshold")
+	}
+	if !warn("2026-05-20") {
+		t.Error("expected a new dip to warn again")
+	}
+	waitForPush(t, pushed)
+}
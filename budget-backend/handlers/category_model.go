@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/categories"
+)
+
+// learnCategoryAsync feeds a transaction's current category to the learned
+// categorization model. Fire-and-forget, like rule usage counts.
+func learnCategoryAsync(conn *sql.DB, txID string) {
+	go func() {
+		if err := categories.LearnTransaction(conn, txID); err != nil {
+			log.Printf("LearnTransaction %s error: %v", txID, err)
+		}
+	}()
+}
+
+// RetrainCategoryModel rebuilds the caller's household model (or personal
+// model without a household) from all verified transactions. Day to day the
+// model updates itself as categories are corrected; this is for the first
+// run and for recovering from drift.
+// POST /auth/category-model/retrain
+func RetrainCategoryModel(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := db.New()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		log.Printf("RetrainCategoryModel DB error: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	scopeKey := db.ResolveHouseholdID(conn.Conn, userID)
+	if scopeKey == "" {
+		scopeKey = userID
+	}
+
+	trained, err := categories.Retrain(conn.Conn, scopeKey)
+	if err != nil {
+		http.Error(w, "Failed to retrain model", http.StatusInternalServerError)
+		log.Printf("RetrainCategoryModel error: %v", err)
+		return
+	}
+
+	log.Printf("Category model retrained on %d transactions (scope %s)", trained, scopeKey)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"trained": trained})
+}
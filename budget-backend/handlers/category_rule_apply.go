@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/categories"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+)
+
+// ruleApplyDBFactory allows swapping the DB in tests.
+var ruleApplyDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+// ruleCandidate is an existing transaction a rule may recategorize.
+type ruleCandidate struct {
+	change   models.RuleChange
+	verified bool
+	input    categories.Transaction
+}
+
+// loadEditableRule returns a rule owned by the user or their household.
+// System rules can't be applied retroactively.
+func loadEditableRule(client db.DBTX, ruleID, userID, householdID string) (models.CategoryMappingRule, error) {
+	return scanCategoryRule(client.QueryRow(`
+		SELECT `+categoryRuleColumns+`
+		FROM category_mapping_rules r
+		WHERE r.id = $1 AND (r.user_id = $2 OR r.household_id::text = $3)
+	`, ruleID, userID, householdID))
+}
+
+// loadRuleCandidates returns the user's and household's unsplit income and
+// expense transactions, newest first. As in BackfillTransactionCategories the
+// note stands in for the merchant and category_name for the Plaid category.
+func loadRuleCandidates(client db.DBTX, userID, householdID string) ([]ruleCandidate, error) {
+	rows, err := client.Query(`
+		SELECT t.id, t.type, t.amount, t.date, COALESCE(t.note, ''), COALESCE(t.category_name, ''),
+		       COALESCE(t.category_id::text, ''), COALESCE(c.name, ''),
+		       COALESCE(t.account_balance_id::text, ''), COALESCE(t.merchant_id::text, ''),
+		       COALESCE(t.user_verified, false)
+		FROM transactions t
+		LEFT JOIN categories c ON c.id = t.category_id
+		WHERE (t.user_id = $1 OR t.household_id::text = $2)
+		  AND NOT COALESCE(t.is_split, false)
+		  AND t.type IN ('income', 'expense')
+		ORDER BY t.date DESC
+	`, userID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ruleCandidate
+	for rows.Next() {
+		var c ruleCandidate
+		var date time.Time
+		var catName string
+		if err := rows.Scan(&c.change.TransactionID, &c.change.Type, &c.change.Amount, &date,
+			&c.change.Description, &catName, &c.change.Before.ID, &c.change.Before.Name,
+			&c.input.AccountBalanceID, &c.input.MerchantID, &c.verified); err != nil {
+			return nil, err
+		}
+		c.change.Date = date.Format("2006-01-02")
+		if c.change.Before.Name == "" {
+			c.change.Before.Name = catName
+		}
+		c.input.Merchant = c.change.Description
+		c.input.PlaidCategories = []string{catName}
+		c.input.Amount = c.change.Amount
+		c.input.Type = c.change.Type
+		c.input.Date = date
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// previewRule works out which candidates a rule would recategorize.
+func previewRule(rule models.CategoryMappingRule, after models.RuleCategoryRef, candidates []ruleCandidate) models.RulePreview {
+	preview := models.RulePreview{RuleID: rule.ID, Changes: []models.RuleChange{}}
+	for _, c := range candidates {
+		if !categories.MatchRule(rule, c.input) {
+			continue
+		}
+		preview.Matched++
+		switch {
+		case c.verified:
+			preview.SkippedVerified++
+		case c.change.Before.ID == after.ID:
+			preview.Unchanged++
+		default:
+			change := c.change
+			change.After = after
+			preview.Changes = append(preview.Changes, change)
+		}
+	}
+	return preview
+}
+
+// buildRulePreview loads the rule and candidates for the preview and apply
+// endpoints, writing an error response and returning false on failure.
+func buildRulePreview(w http.ResponseWriter, client db.DBTX, ruleID, userID string) (models.CategoryMappingRule, models.RulePreview, bool) {
+	householdID := db.ResolveHouseholdID(client.Raw(), userID)
+
+	rule, err := loadEditableRule(client, ruleID, userID, householdID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Rule not found", http.StatusNotFound)
+		return rule, models.RulePreview{}, false
+	}
+	if err != nil {
+		log.Printf("buildRulePreview rule lookup error: %v", err)
+		http.Error(w, "Failed to load rule", http.StatusInternalServerError)
+		return rule, models.RulePreview{}, false
+	}
+	if rule.CategoryID == "" {
+		http.Error(w, "Rule does not set a category", http.StatusBadRequest)
+		return rule, models.RulePreview{}, false
+	}
+
+	after := models.RuleCategoryRef{ID: rule.CategoryID}
+	if err := client.QueryRow(`SELECT name FROM categories WHERE id = $1`, rule.CategoryID).Scan(&after.Name); err != nil {
+		log.Printf("buildRulePreview category lookup error: %v", err)
+	}
+
+	candidates, err := loadRuleCandidates(client, userID, householdID)
+	if err != nil {
+		log.Printf("buildRulePreview candidates error: %v", err)
+		http.Error(w, "Failed to load transactions", http.StatusInternalServerError)
+		return rule, models.RulePreview{}, false
+	}
+	return rule, previewRule(rule, after, candidates), true
+}
+
+// PreviewCategoryRule is a dry run of applying a rule to existing
+// transactions, listing each recategorization with its before and after
+// category. User-verified transactions are never changed.
+// POST /auth/category-rules/{id}/preview
+func PreviewCategoryRule(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	client, err := ruleApplyDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	_, preview, ok := buildRulePreview(w, client, mux.Vars(r)["id"], userID)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+// ApplyCategoryRule recategorizes the transactions a preview would change,
+// optionally limited to transaction_ids, and records each change in
+// category_rule_applications.
+// POST /auth/category-rules/{id}/apply
+func ApplyCategoryRule(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ApplyRuleRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	client, err := ruleApplyDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	rule, preview, ok := buildRulePreview(w, client, mux.Vars(r)["id"], userID)
+	if !ok {
+		return
+	}
+
+	selected := map[string]bool{}
+	for _, id := range req.TransactionIDs {
+		selected[id] = true
+	}
+
+	tx, err := client.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result := models.ApplyRuleResult{RuleID: rule.ID, Changes: []models.RuleChange{}}
+	for _, change := range preview.Changes {
+		if len(selected) > 0 && !selected[change.TransactionID] {
+			continue
+		}
+		// Re-check verification inside the transaction in case the user
+		// edited the row since the preview was built.
+		res, err := tx.Exec(`
+			UPDATE transactions
+			SET category_id = $1, match_confidence = 'exact', matched_rule_id = $2, updated_at = NOW()
+			WHERE id = $3 AND NOT COALESCE(user_verified, false) AND NOT COALESCE(is_split, false)
+		`, rule.CategoryID, rule.ID, change.TransactionID)
+		if err != nil {
+			log.Printf("ApplyCategoryRule update %s error: %v", change.TransactionID, err)
+			http.Error(w, "Failed to apply rule", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO category_rule_applications (rule_id, transaction_id, previous_category_id, new_category_id, applied_by)
+			VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5)
+		`, rule.ID, change.TransactionID, change.Before.ID, rule.CategoryID, userID); err != nil {
+			log.Printf("ApplyCategoryRule audit %s error: %v", change.TransactionID, err)
+			http.Error(w, "Failed to apply rule", http.StatusInternalServerError)
+			return
+		}
+		if rule.RuleType == "advanced" {
+			if err := applyRuleActions(tx, change.TransactionID, change.Amount, &rule.RuleActions); err != nil {
+				log.Printf("ApplyCategoryRule actions %s error: %v", change.TransactionID, err)
+				http.Error(w, "Failed to apply rule", http.StatusInternalServerError)
+				return
+			}
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	result.Applied = len(result.Changes)
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Commit error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Category rule %s applied to %d transactions (user %s)", rule.ID, result.Applied, userID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListRuleApplications returns the changes a rule has made when applied
+// retroactively, newest first.
+// GET /auth/category-rules/{id}/applications
+func ListRuleApplications(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	client, err := ruleApplyDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	ruleID := mux.Vars(r)["id"]
+	householdID := db.ResolveHouseholdID(client.Raw(), userID)
+	if _, err := loadEditableRule(client, ruleID, userID, householdID); err == sql.ErrNoRows {
+		http.Error(w, "Rule not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to load rule", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := client.Query(`
+		SELECT id, rule_id, transaction_id, previous_category_id, new_category_id,
+		       COALESCE(applied_by::text, ''), applied_at
+		FROM category_rule_applications
+		WHERE rule_id = $1
+		ORDER BY applied_at DESC
+	`, ruleID)
+	if err != nil {
+		log.Printf("ListRuleApplications query error: %v", err)
+		http.Error(w, "Failed to fetch applications", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	apps := []models.RuleApplication{}
+	for rows.Next() {
+		var a models.RuleApplication
+		if err := rows.Scan(&a.ID, &a.RuleID, &a.TransactionID, &a.PreviousCategoryID, &a.NewCategoryID,
+			&a.AppliedBy, &a.AppliedAt); err != nil {
+			log.Printf("ListRuleApplications scan error: %v", err)
+			continue
+		}
+		apps = append(apps, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apps)
+}
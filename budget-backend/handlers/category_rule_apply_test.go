@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/aboogie/budget-backend/internal/categories"
+	"github.com/aboogie/budget-backend/models"
+)
+
+func TestPreviewRule(t *testing.T) {
+	rule := models.CategoryMappingRule{ID: "r1", RuleType: "keyword", MatchValue: "uber", CategoryID: "rides"}
+	after := models.RuleCategoryRef{ID: "rides", Name: "Rideshare"}
+
+	candidate := func(id, merchant, catID string, verified bool) ruleCandidate {
+		return ruleCandidate{
+			change:   models.RuleChange{TransactionID: id, Description: merchant, Before: models.RuleCategoryRef{ID: catID}},
+			verified: verified,
+			input:    categories.Transaction{Merchant: merchant},
+		}
+	}
+	candidates := []ruleCandidate{
+		candidate("t1", "UBER *TRIP", "travel", false),
+		candidate("t2", "Uber Eats", "dining", true),
+		candidate("t3", "uber trip", "rides", false),
+		candidate("t4", "Lyft", "travel", false),
+	}
+
+	preview := previewRule(rule, after, candidates)
+
+	if preview.Matched != 3 || preview.SkippedVerified != 1 || preview.Unchanged != 1 {
+		t.Fatalf("unexpected counts: %+v", preview)
+	}
+	if len(preview.Changes) != 1 || preview.Changes[0].TransactionID != "t1" {
+		t.Fatalf("expected only t1 to change, got %+v", preview.Changes)
+	}
+	if preview.Changes[0].Before.ID != "travel" || preview.Changes[0].After.Name != "Rideshare" {
+		t.Errorf("unexpected before/after: %+v", preview.Changes[0])
+	}
+}
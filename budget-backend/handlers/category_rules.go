@@ -3,15 +3,125 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"math"
 	"net/http"
 	"strings"
 
 	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/categories"
+	"github.com/aboogie/budget-backend/internal/merchants"
 	"github.com/aboogie/budget-backend/models"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 )
 
+var categoryRuleTypes = map[string]bool{"merchant": true, "plaid_category": true, "keyword": true, "advanced": true}
+
+// categoryRuleColumns are the columns scanCategoryRule expects, with the rules
+// table aliased as r.
+const categoryRuleColumns = `r.id, r.user_id, r.household_id, r.rule_type, r.name, r.match_value, r.merchant_id, r.conditions,
+	COALESCE(r.category_id::text, ''), r.budget_id, r.split_template, r.tag, r.mark_transfer,
+	r.priority, r.auto_created, r.usage_count, r.created_at`
+
+// scanCategoryRule scans categoryRuleColumns followed by any extra columns.
+func scanCategoryRule(row interface{ Scan(...any) error }, extra ...any) (models.CategoryMappingRule, error) {
+	var rule models.CategoryMappingRule
+	var conds, split []byte
+	dest := []any{
+		&rule.ID, &rule.UserID, &rule.HouseholdID, &rule.RuleType, &rule.Name, &rule.MatchValue, &rule.MerchantID, &conds,
+		&rule.CategoryID, &rule.BudgetID, &split, &rule.Tag, &rule.MarkTransfer,
+		&rule.Priority, &rule.AutoCreated, &rule.UsageCount, &rule.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return rule, err
+	}
+	if len(conds) > 0 {
+		rule.Conditions = &models.RuleConditions{}
+		if err := json.Unmarshal(conds, rule.Conditions); err != nil {
+			return rule, err
+		}
+	}
+	if len(split) > 0 {
+		if err := json.Unmarshal(split, &rule.SplitTemplate); err != nil {
+			return rule, err
+		}
+	}
+	return rule, nil
+}
+
+// validateRuleRequest checks a create (partial=false) or update payload and
+// returns a message for the client, or "" if the request is valid.
+func validateRuleRequest(req *models.CreateRuleRequest, partial bool) string {
+	if req.RuleType != "" && !categoryRuleTypes[req.RuleType] {
+		return "rule_type must be merchant, plaid_category, keyword, or advanced"
+	}
+	hasActions := req.BudgetID != nil || len(req.SplitTemplate) > 0 || req.Tag != nil || req.MarkTransfer
+	if req.RuleType != "advanced" {
+		if !partial && (req.RuleType == "" || req.MatchValue == "" || req.CategoryID == "") {
+			return "rule_type, match_value, and category_id are required"
+		}
+		if req.RuleType != "" && (req.Conditions != nil || hasActions) {
+			return "conditions and actions are only supported on advanced rules"
+		}
+	} else if !partial {
+		if req.Conditions == nil {
+			return "conditions are required for advanced rules"
+		}
+		if req.CategoryID == "" && !hasActions {
+			return "advanced rules need a category_id or at least one action"
+		}
+	}
+	if req.Conditions != nil {
+		if err := categories.ValidateConditions(*req.Conditions); err != nil {
+			return err.Error()
+		}
+	}
+	if err := categories.ValidateSplitTemplate(req.SplitTemplate); err != nil {
+		return err.Error()
+	}
+	if req.Tag != nil && cleanTagName(*req.Tag) == "" {
+		return fmt.Sprintf("tag must be non-empty and at most %d characters", maxTagNameLength)
+	}
+	return ""
+}
+
+// ruleJSONColumns marshals the JSONB columns of a rule request. Values are
+// passed as strings (lib/pq sends []byte as bytea); unset values are NULL.
+func ruleJSONColumns(req *models.CreateRuleRequest) (conds, split any, err error) {
+	if req.Conditions != nil {
+		b, err := json.Marshal(req.Conditions)
+		if err != nil {
+			return nil, nil, err
+		}
+		conds = string(b)
+	}
+	if len(req.SplitTemplate) > 0 {
+		b, err := json.Marshal(req.SplitTemplate)
+		if err != nil {
+			return nil, nil, err
+		}
+		split = string(b)
+	}
+	return conds, split, nil
+}
+
+// ruleMerchantID returns the merchant entity a merchant rule should key on,
+// creating it if needed. Lookup failures just leave the rule name-only.
+func ruleMerchantID(conn *sql.DB, merchantName string) *string {
+	m, err := merchants.Resolve(conn, merchantName, "")
+	if err != nil {
+		log.Printf("ruleMerchantID resolve error: %v", err)
+		return nil
+	}
+	if m == nil {
+		return nil
+	}
+	return &m.ID
+}
+
 // ListCategoryRules returns all mapping rules visible to the authenticated user,
 // including system rules (user_id IS NULL AND household_id IS NULL), user-owned
 // rules, and household rules.
@@ -35,9 +145,7 @@ func ListCategoryRules(w http.ResponseWriter, r *http.Request) {
 	var rows *sql.Rows
 	if householdID != "" {
 		rows, err = conn.Query(`
-			SELECT r.id, r.user_id, r.household_id, r.rule_type, r.match_value,
-			       r.category_id, COALESCE(c.name, '') AS category_name,
-			       r.priority, r.auto_created, r.usage_count, r.created_at
+			SELECT `+categoryRuleColumns+`, COALESCE(c.name, '') AS category_name
 			FROM category_mapping_rules r
 			LEFT JOIN categories c ON c.id = r.category_id
 			WHERE r.user_id = $1
@@ -47,9 +155,7 @@ func ListCategoryRules(w http.ResponseWriter, r *http.Request) {
 		`, userID, householdID)
 	} else {
 		rows, err = conn.Query(`
-			SELECT r.id, r.user_id, r.household_id, r.rule_type, r.match_value,
-			       r.category_id, COALESCE(c.name, '') AS category_name,
-			       r.priority, r.auto_created, r.usage_count, r.created_at
+			SELECT `+categoryRuleColumns+`, COALESCE(c.name, '') AS category_name
 			FROM category_mapping_rules r
 			LEFT JOIN categories c ON c.id = r.category_id
 			WHERE r.user_id = $1
@@ -66,22 +172,13 @@ func ListCategoryRules(w http.ResponseWriter, r *http.Request) {
 
 	rules := make([]models.CategoryMappingRule, 0)
 	for rows.Next() {
-		var rule models.CategoryMappingRule
-		var uid, hid sql.NullString
-		if err := rows.Scan(
-			&rule.ID, &uid, &hid, &rule.RuleType, &rule.MatchValue,
-			&rule.CategoryID, &rule.CategoryName,
-			&rule.Priority, &rule.AutoCreated, &rule.UsageCount, &rule.CreatedAt,
-		); err != nil {
+		var categoryName string
+		rule, err := scanCategoryRule(rows, &categoryName)
+		if err != nil {
 			log.Printf("ListCategoryRules scan error: %v", err)
 			continue
 		}
-		if uid.Valid {
-			rule.UserID = &uid.String
-		}
-		if hid.Valid {
-			rule.HouseholdID = &hid.String
-		}
+		rule.CategoryName = categoryName
 		rules = append(rules, rule)
 	}
 
@@ -89,7 +186,8 @@ func ListCategoryRules(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(rules)
 }
 
-// CreateCategoryRule creates a new user-scoped mapping rule.
+// CreateCategoryRule creates a new user-scoped mapping rule. Advanced rules
+// match on conditions instead of match_value and may carry actions.
 func CreateCategoryRule(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserIDFromRequest(r)
 	if err != nil {
@@ -104,12 +202,13 @@ func CreateCategoryRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.RuleType == "" || req.MatchValue == "" || req.CategoryID == "" {
-		http.Error(w, "rule_type, match_value, and category_id are required", http.StatusBadRequest)
+	if msg := validateRuleRequest(&req, false); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
 		return
 	}
-	if req.RuleType != "merchant" && req.RuleType != "plaid_category" && req.RuleType != "keyword" {
-		http.Error(w, "rule_type must be merchant, plaid_category, or keyword", http.StatusBadRequest)
+	conds, split, err := ruleJSONColumns(&req)
+	if err != nil {
+		http.Error(w, "Invalid rule", http.StatusBadRequest)
 		return
 	}
 
@@ -127,28 +226,34 @@ func CreateCategoryRule(w http.ResponseWriter, r *http.Request) {
 		householdID = &hh
 	}
 
-	var rule models.CategoryMappingRule
-	err = conn.QueryRow(`
-		INSERT INTO category_mapping_rules (user_id, household_id, rule_type, match_value, category_id, priority)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, user_id, household_id, rule_type, match_value, category_id, priority, auto_created, usage_count, created_at
-	`, userID, householdID, req.RuleType, req.MatchValue, req.CategoryID, req.Priority).Scan(
-		&rule.ID, &rule.UserID, &rule.HouseholdID, &rule.RuleType, &rule.MatchValue,
-		&rule.CategoryID, &rule.Priority, &rule.AutoCreated, &rule.UsageCount, &rule.CreatedAt,
-	)
+	var merchantID *string
+	if req.RuleType == "merchant" {
+		merchantID = ruleMerchantID(conn.Conn, req.MatchValue)
+	}
+
+	rule, err := scanCategoryRule(conn.QueryRow(`
+		INSERT INTO category_mapping_rules AS r (user_id, household_id, rule_type, name, match_value, merchant_id, conditions,
+			category_id, budget_id, split_template, tag, mark_transfer, priority)
+		VALUES ($1, $2, $3, $4, $5, $13, $6, NULLIF($7, '')::uuid, $8, $9, $10, $11, $12)
+		RETURNING `+categoryRuleColumns,
+		userID, householdID, req.RuleType, req.Name, req.MatchValue, conds,
+		req.CategoryID, req.BudgetID, split, req.Tag, req.MarkTransfer, req.Priority, merchantID,
+	))
 	if err != nil {
 		http.Error(w, "Failed to create rule", http.StatusInternalServerError)
 		log.Printf("CreateCategoryRule insert error: %v", err)
 		return
 	}
 
-	log.Printf("Category rule created: %s -> %s (user %s)", rule.MatchValue, rule.CategoryID, userID)
+	log.Printf("Category rule created: %s %s -> %s (user %s)", rule.RuleType, rule.MatchValue, rule.CategoryID, userID)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(rule)
 }
 
-// UpdateCategoryRule updates an existing rule owned by the user.
+// UpdateCategoryRule updates an existing rule owned by the user. An advanced
+// rule keeps the actions the request leaves out and drops those it sends as
+// null or empty.
 func UpdateCategoryRule(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserIDFromRequest(r)
 	if err != nil {
@@ -162,15 +267,44 @@ func UpdateCategoryRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req models.CreateRuleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		models.CreateRuleRequest
+		// A pointer so an update that leaves it out keeps the rule's setting.
+		MarkTransfer *bool `json:"mark_transfer"`
+	}
+	// Which actions the request names at all: an action left out keeps its
+	// value, while one sent as null or empty is cleared.
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &body); err != nil || json.Unmarshal(raw, &present) != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		log.Printf("UpdateCategoryRule decode error: %v", err)
 		return
 	}
+	req := body.CreateRuleRequest
+	req.MarkTransfer = body.MarkTransfer != nil && *body.MarkTransfer
+	if req.BudgetID != nil && *req.BudgetID == "" {
+		req.BudgetID = nil
+	}
+	if req.Tag != nil && *req.Tag == "" {
+		req.Tag = nil
+	}
+	_, setBudget := present["budget_id"]
+	_, setSplit := present["split_template"]
+	_, setTag := present["tag"]
+	_, setTransfer := present["mark_transfer"]
 
-	if req.RuleType != "" && req.RuleType != "merchant" && req.RuleType != "plaid_category" && req.RuleType != "keyword" {
-		http.Error(w, "rule_type must be merchant, plaid_category, or keyword", http.StatusBadRequest)
+	if msg := validateRuleRequest(&req, true); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	conds, split, err := ruleJSONColumns(&req)
+	if err != nil {
+		http.Error(w, "Invalid rule", http.StatusBadRequest)
 		return
 	}
 
@@ -182,16 +316,44 @@ func UpdateCategoryRule(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
-	// Only allow updating rules owned by this user
+	var merchantID *string
+	if req.MatchValue != "" && (req.RuleType == "" || req.RuleType == "merchant") {
+		merchantID = ruleMerchantID(conn.Conn, req.MatchValue)
+	}
+
+	// Only allow updating rules owned by this user. Fields left out of the
+	// request keep their current values; actions only apply to a rule that is
+	// advanced once updated.
 	result, err := conn.Exec(`
 		UPDATE category_mapping_rules
-		SET rule_type   = COALESCE(NULLIF($1, ''), rule_type),
-		    match_value = COALESCE(NULLIF($2, ''), match_value),
-		    category_id = COALESCE(NULLIF($3, ''), category_id),
-		    priority    = $4,
-		    updated_at  = NOW()
+		SET rule_type      = COALESCE(NULLIF($1, ''), rule_type),
+		    match_value    = COALESCE(NULLIF($2, ''), match_value),
+		    merchant_id    = CASE WHEN $2 = '' THEN merchant_id
+		                          WHEN COALESCE(NULLIF($1, ''), rule_type) = 'merchant' THEN $13::uuid
+		                          ELSE NULL END,
+		    category_id    = COALESCE(NULLIF($3, '')::uuid, category_id),
+		    priority       = $4,
+		    name           = COALESCE($7, name),
+		    conditions     = COALESCE($8, conditions),
+		    budget_id      = CASE WHEN $14 AND COALESCE(NULLIF($1, ''), rule_type) = 'advanced' THEN $9::uuid ELSE budget_id END,
+		    split_template = CASE WHEN $15 AND COALESCE(NULLIF($1, ''), rule_type) = 'advanced' THEN $10::jsonb ELSE split_template END,
+		    tag            = CASE WHEN $16 AND COALESCE(NULLIF($1, ''), rule_type) = 'advanced' THEN $11 ELSE tag END,
+		    mark_transfer  = CASE WHEN $17 AND COALESCE(NULLIF($1, ''), rule_type) = 'advanced' THEN COALESCE($12::boolean, false) ELSE mark_transfer END,
+		    updated_at     = NOW()
 		WHERE id = $5 AND user_id = $6
-	`, req.RuleType, req.MatchValue, req.CategoryID, req.Priority, ruleID, userID)
+	`, req.RuleType, req.MatchValue, req.CategoryID, req.Priority, ruleID, userID,
+		req.Name, conds, req.BudgetID, split, req.Tag, body.MarkTransfer, merchantID,
+		setBudget, setSplit, setTag, setTransfer)
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Constraint {
+		case "category_mapping_rules_has_action":
+			respondValidationError(w, []ValidationError{{Field: "mark_transfer", Message: "a rule needs a category_id or at least one action"}})
+			return
+		case "category_mapping_rules_advanced_conditions":
+			respondValidationError(w, []ValidationError{{Field: "conditions", Message: "conditions are required for advanced rules"}})
+			return
+		}
+	}
 	if err != nil {
 		http.Error(w, "Failed to update rule", http.StatusInternalServerError)
 		log.Printf("UpdateCategoryRule exec error: %v", err)
@@ -285,16 +447,19 @@ func CreateRuleFromEdit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	lowerMerchant := strings.ToLower(strings.TrimSpace(req.MerchantName))
+	// Key the rule on the merchant entity too, so other spellings of the
+	// same merchant ("SQ *BLUE BOTTLE 1234") follow the edit.
+	merchantID := ruleMerchantID(conn.Conn, req.MerchantName)
 
 	// Upsert: if user already has a merchant rule for this value, update category
 	var rule models.CategoryMappingRule
 	err = conn.QueryRow(`
-		INSERT INTO category_mapping_rules (user_id, household_id, rule_type, match_value, category_id, auto_created, priority)
-		VALUES ($1, $2, 'merchant', $3, $4, true, 10)
+		INSERT INTO category_mapping_rules (user_id, household_id, rule_type, match_value, merchant_id, category_id, auto_created, priority)
+		VALUES ($1, $2, 'merchant', $3, $5, $4, true, 10)
 		ON CONFLICT DO NOTHING
-		RETURNING id, user_id, household_id, rule_type, match_value, category_id, priority, auto_created, usage_count, created_at
-	`, userID, householdID, lowerMerchant, req.CategoryID).Scan(
-		&rule.ID, &rule.UserID, &rule.HouseholdID, &rule.RuleType, &rule.MatchValue,
+		RETURNING id, user_id, household_id, rule_type, match_value, merchant_id, category_id, priority, auto_created, usage_count, created_at
+	`, userID, householdID, lowerMerchant, req.CategoryID, merchantID).Scan(
+		&rule.ID, &rule.UserID, &rule.HouseholdID, &rule.RuleType, &rule.MatchValue, &rule.MerchantID,
 		&rule.CategoryID, &rule.Priority, &rule.AutoCreated, &rule.UsageCount, &rule.CreatedAt,
 	)
 
@@ -302,9 +467,9 @@ func CreateRuleFromEdit(w http.ResponseWriter, r *http.Request) {
 		// ON CONFLICT DO NOTHING returned nothing — a rule exists, update it
 		_, err = conn.Exec(`
 			UPDATE category_mapping_rules
-			SET category_id = $1, usage_count = usage_count + 1, updated_at = NOW()
+			SET category_id = $1, merchant_id = COALESCE(merchant_id, $4), usage_count = usage_count + 1, updated_at = NOW()
 			WHERE user_id = $2 AND rule_type = 'merchant' AND LOWER(match_value) = $3
-		`, req.CategoryID, userID, lowerMerchant)
+		`, req.CategoryID, userID, lowerMerchant, merchantID)
 		if err != nil {
 			http.Error(w, "Failed to update existing rule", http.StatusInternalServerError)
 			log.Printf("CreateRuleFromEdit update error: %v", err)
@@ -313,12 +478,12 @@ func CreateRuleFromEdit(w http.ResponseWriter, r *http.Request) {
 
 		// Fetch the updated rule for response
 		err = conn.QueryRow(`
-			SELECT id, user_id, household_id, rule_type, match_value, category_id,
+			SELECT id, user_id, household_id, rule_type, match_value, merchant_id, category_id,
 			       priority, auto_created, usage_count, created_at
 			FROM category_mapping_rules
 			WHERE user_id = $1 AND rule_type = 'merchant' AND LOWER(match_value) = $2
 		`, userID, lowerMerchant).Scan(
-			&rule.ID, &rule.UserID, &rule.HouseholdID, &rule.RuleType, &rule.MatchValue,
+			&rule.ID, &rule.UserID, &rule.HouseholdID, &rule.RuleType, &rule.MatchValue, &rule.MerchantID,
 			&rule.CategoryID, &rule.Priority, &rule.AutoCreated, &rule.UsageCount, &rule.CreatedAt,
 		)
 		if err != nil {
@@ -337,3 +502,78 @@ func CreateRuleFromEdit(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(rule)
 }
+
+// splitTemplateAmounts divides amount by a split template's percentages,
+// rounded to cents with the remainder on the last entry.
+func splitTemplateAmounts(amount float64, entries []models.SplitTemplateEntry) []float64 {
+	amounts := make([]float64, len(entries))
+	remaining := amount
+	for i, e := range entries {
+		if i == len(entries)-1 {
+			amounts[i] = math.Round(remaining*100) / 100
+			break
+		}
+		amounts[i] = math.Round(amount*e.Percent) / 100
+		remaining -= amounts[i]
+	}
+	return amounts
+}
+
+// execer is satisfied by both *db.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// applyRuleActions carries out an advanced rule's actions on a stored
+// transaction. A split template is only applied to rows that aren't split yet.
+func applyRuleActions(client execer, txID string, amount float64, actions *models.RuleActions) error {
+	if actions == nil {
+		return nil
+	}
+	if actions.BudgetID != nil {
+		if _, err := client.Exec(`UPDATE transactions SET budget_id = $1 WHERE id = $2`, *actions.BudgetID, txID); err != nil {
+			return err
+		}
+	}
+	if actions.Tag != nil {
+		if err := tagTransactionByName(client, txID, *actions.Tag); err != nil {
+			return err
+		}
+	}
+	if actions.MarkTransfer {
+		// Budgets and insights only count income and expense rows.
+		if _, err := client.Exec(`UPDATE transactions SET type = 'transfer' WHERE id = $1`, txID); err != nil {
+			return err
+		}
+	}
+	if len(actions.SplitTemplate) == 0 {
+		return nil
+	}
+
+	amounts := splitTemplateAmounts(amount, actions.SplitTemplate)
+	largest := 0
+	for i := range amounts {
+		if amounts[i] > amounts[largest] {
+			largest = i
+		}
+	}
+	res, err := client.Exec(`
+		UPDATE transactions SET is_split = true, category_id = $2
+		WHERE id = $1 AND NOT COALESCE(is_split, false)
+	`, txID, actions.SplitTemplate[largest].CategoryID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	for i, e := range actions.SplitTemplate {
+		if _, err := client.Exec(`
+			INSERT INTO transaction_splits (transaction_id, category_id, amount, note)
+			VALUES ($1, $2, $3, 'Split by rule')
+		`, txID, e.CategoryID, amounts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+func TestSplitTemplateAmounts(t *testing.T) {
+	entries := []models.SplitTemplateEntry{
+		{CategoryID: "a", Percent: 33.33},
+		{CategoryID: "b", Percent: 33.33},
+		{CategoryID: "c", Percent: 33.34},
+	}
+	got := splitTemplateAmounts(100, entries)
+	want := []float64{33.33, 33.33, 33.34}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestValidateRuleRequest(t *testing.T) {
+	tag := "rent"
+	cases := []struct {
+		name    string
+		req     models.CreateRuleRequest
+		partial bool
+		ok      bool
+	}{
+		{"simple", models.CreateRuleRequest{RuleType: "merchant", MatchValue: "starbucks", CategoryID: "c1"}, false, true},
+		{"simple missing category", models.CreateRuleRequest{RuleType: "keyword", MatchValue: "uber"}, false, false},
+		{"simple with conditions", models.CreateRuleRequest{RuleType: "merchant", MatchValue: "x", CategoryID: "c1",
+			Conditions: &models.RuleConditions{TransactionType: "expense"}}, false, false},
+		{"advanced tag only", models.CreateRuleRequest{RuleType: "advanced",
+			Conditions:  &models.RuleConditions{MerchantRegex: "landlord"},
+			RuleActions: models.RuleActions{Tag: &tag}}, false, true},
+		{"advanced no action", models.CreateRuleRequest{RuleType: "advanced",
+			Conditions: &models.RuleConditions{MerchantRegex: "landlord"}}, false, false},
+		{"advanced no conditions", models.CreateRuleRequest{RuleType: "advanced", CategoryID: "c1"}, false, false},
+		{"partial priority only", models.CreateRuleRequest{Priority: 5}, true, true},
+		{"unknown type", models.CreateRuleRequest{RuleType: "regex"}, true, false},
+	}
+	for _, tc := range cases {
+		if msg := validateRuleRequest(&tc.req, tc.partial); (msg == "") != tc.ok {
+			t.Errorf("%s: expected ok=%v, got %q", tc.name, tc.ok, msg)
+		}
+	}
+}
+
+func TestCreateCategoryRule_InvalidRegex(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/auth/category-rules", strings.NewReader(
+		`{"rule_type":"advanced","category_id":"c1","conditions":{"merchant_regex":"("}}`))
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, "11111111-1111-1111-1111-111111111111"))
+	rr := httptest.NewRecorder()
+
+	CreateCategoryRule(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,512 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/debts"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// debtLedgerDBFactory allows swapping the DB in tests.
+var debtLedgerDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+// ledgerExecer is satisfied by db.DBTX and *sql.Tx.
+type ledgerExecer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// ApplyDebtPayment records a payment against a debt. Interest due through the
+// payment date is charged first and the payment covers it before principal.
+// PATCH /auth/debts/{id}/payment
+// body: { amount, paid_on?, transaction_id?, bill_payment_id?, note? }
+func ApplyDebtPayment(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	debtID := mux.Vars(r)["id"]
+	if debtID == "" {
+		http.Error(w, "Missing debt id", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Amount        float64 `json:"amount"`
+		PaidOn        string  `json:"paid_on"`
+		TransactionID *string `json:"transaction_id"`
+		BillPaymentID *string `json:"bill_payment_id"`
+		Note          string  `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	paidOn := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var errs []ValidationError
+	if body.Amount <= 0 {
+		errs = append(errs, ValidationError{Field: "amount", Message: "must be greater than 0"})
+	}
+	if body.PaidOn != "" {
+		parsed, err := time.Parse("2006-01-02", body.PaidOn)
+		switch {
+		case err != nil:
+			errs = append(errs, ValidationError{Field: "paid_on", Message: "must be a date (YYYY-MM-DD)"})
+		case parsed.After(paidOn):
+			errs = append(errs, ValidationError{Field: "paid_on", Message: "cannot be in the future"})
+		default:
+			paidOn = parsed
+		}
+	}
+	if len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+
+	conn, err := debtLedgerDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	if !householdAccessCheck(w, conn.Raw(), "debt_accounts", debtID, userID) {
+		return
+	}
+	if body.TransactionID != nil && !ownershipCheck(w, conn.Raw(), "transactions", *body.TransactionID, userID) {
+		return
+	}
+	if body.BillPaymentID != nil && !ownershipCheck(w, conn.Raw(), "bill_payments", *body.BillPaymentID, userID) {
+		return
+	}
+
+	payment := models.DebtPayment{
+		Amount:        math.Round(body.Amount*100) / 100,
+		TransactionID: body.TransactionID,
+		BillPaymentID: body.BillPaymentID,
+		Source:        "manual",
+		Note:          body.Note,
+	}
+	err = inDebtLedgerTx(conn.Raw(), func(q ledgerExecer) error {
+		return recordDebtPayment(q, debtID, &payment, paidOn)
+	})
+	if err != nil {
+		log.Printf("ApplyDebtPayment error: %v", err)
+		http.Error(w, "Update error", http.StatusInternalServerError)
+		return
+	}
+
+	updated, _, err := loadLedgerDebtAccount(conn, debtID)
+	if err != nil {
+		http.Error(w, "Debt not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		models.DebtAccount
+		Payment models.DebtPayment `json:"payment"`
+	}{updated, payment})
+}
+
+// ListDebtPayments returns a debt's ledger, newest first, with payoff
+// progress month by month since the ledger was opened.
+// GET /auth/debts/{id}/payments
+func ListDebtPayments(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	debtID := mux.Vars(r)["id"]
+	if debtID == "" {
+		http.Error(w, "Missing debt id", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := debtLedgerDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	if !householdAccessCheck(w, conn.Raw(), "debt_accounts", debtID, userID) {
+		return
+	}
+
+	debt, unpaid, err := loadLedgerDebtAccount(conn, debtID)
+	if err != nil {
+		http.Error(w, "Debt not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := conn.Query(`
+		SELECT id, debt_account_id, user_id, kind, amount, principal, interest, balance_after,
+		       paid_on, transaction_id, bill_payment_id, source, COALESCE(note, ''), created_at
+		FROM debt_payments
+		WHERE debt_account_id = $1
+		ORDER BY paid_on DESC, created_at DESC
+	`, debtID)
+	if err != nil {
+		log.Printf("ListDebtPayments query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	payments := []models.DebtPayment{}
+	var entries []debts.Entry
+	for rows.Next() {
+		var p models.DebtPayment
+		var paidOn time.Time
+		var txID, billPaymentID sql.NullString
+		if err := rows.Scan(&p.ID, &p.DebtAccountID, &p.UserID, &p.Kind, &p.Amount, &p.Principal, &p.Interest,
+			&p.BalanceAfter, &paidOn, &txID, &billPaymentID, &p.Source, &p.Note, &p.CreatedAt); err != nil {
+			log.Printf("ListDebtPayments scan error: %v", err)
+			continue
+		}
+		p.PaidOn = paidOn.Format("2006-01-02")
+		if txID.Valid {
+			p.TransactionID = &txID.String
+		}
+		if billPaymentID.Valid {
+			p.BillPaymentID = &billPaymentID.String
+		}
+		payments = append(payments, p)
+		entries = append(entries, debts.Entry{
+			Date: paidOn, Kind: p.Kind, Amount: p.Amount, Principal: p.Principal, Interest: p.Interest,
+		})
+	}
+
+	progress := debts.Summarize(entries, debt.Balance, time.Now().UTC())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.DebtPaymentHistory{
+		Debt:            debt,
+		UnpaidInterest:  unpaid,
+		StartingBalance: progress.StartingBalance,
+		PrincipalPaid:   progress.PrincipalPaid,
+		InterestPaid:    progress.InterestPaid,
+		InterestAccrued: progress.InterestAccrued,
+		PercentPaid:     progress.PercentPaid,
+		Progress:        progress.Months,
+		Payments:        payments,
+	})
+}
+
+// loadLedgerDebtAccount reads a debt along with the interest it has accrued
+// that hasn't been paid yet.
+func loadLedgerDebtAccount(conn db.DBTX, debtID string) (models.DebtAccount, float64, error) {
+	var d models.DebtAccount
+	var dueDay sql.NullInt32
+	var unpaid float64
+	err := conn.QueryRow(`
+		SELECT id, user_id, name, balance, COALESCE(apr, 0), COALESCE(min_payment, 0), due_day,
+		       COALESCE(strategy, ''), is_shared, COALESCE(source, 'manual'), unpaid_interest
+		FROM debt_accounts WHERE id = $1
+	`, debtID).Scan(&d.ID, &d.UserID, &d.Name, &d.Balance, &d.APR, &d.MinPayment, &dueDay,
+		&d.Strategy, &d.IsShared, &d.Source, &unpaid)
+	if dueDay.Valid {
+		val := int(dueDay.Int32)
+		d.DueDay = &val
+	}
+	return d, unpaid, err
+}
+
+// inDebtLedgerTx runs fn in a transaction so a ledger update and the debt's
+// balance change commit together.
+func inDebtLedgerTx(conn *sql.DB, fn func(ledgerExecer) error) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recordDebtPayment applies a payment to a debt's ledger and fills in how it
+// split between interest and principal.
+func recordDebtPayment(q ledgerExecer, debtID string, p *models.DebtPayment, paidOn time.Time) error {
+	paidOn = paidOn.UTC()
+	paidOn = time.Date(paidOn.Year(), paidOn.Month(), paidOn.Day(), 0, 0, 0, 0, time.UTC)
+	l, err := openDebtLedger(q, debtID)
+	if err != nil {
+		return err
+	}
+	if l.accruedThrough.IsZero() {
+		// The recorded balance is what's owed today, so a new ledger starts
+		// charging interest today even when its first payment is backdated.
+		now := time.Now().UTC()
+		l.accruedThrough = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if err := l.reconcile(paidOn, "Balance edited"); err != nil {
+		return err
+	}
+	if err := l.accrue(paidOn); err != nil {
+		return err
+	}
+	if err := l.pay(p, paidOn); err != nil {
+		return err
+	}
+	return l.save()
+}
+
+// reconcileLinkedDebt brings a Plaid-linked debt's ledger in line with the
+// balance the lender reported. Interest is charged through today, the
+// liability's last payment is recorded if the ledger doesn't have it yet, and
+// whatever difference remains is recorded as an adjustment.
+func reconcileLinkedDebt(q ledgerExecer, debtID string, now time.Time) error {
+	l, err := openDebtLedger(q, debtID)
+	if err != nil {
+		return err
+	}
+	if l.plaidAccountID == "" {
+		return nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if l.entries > 0 {
+		if err := l.accrue(today); err != nil {
+			return err
+		}
+		var amount float64
+		var date string
+		_ = q.QueryRow(`
+			SELECT COALESCE(last_payment_amount, 0), COALESCE(last_payment_date, '')
+			FROM liabilities
+			WHERE user_id = $1 AND plaid_account_id = $2
+			ORDER BY updated_at DESC LIMIT 1
+		`, l.userID, l.plaidAccountID).Scan(&amount, &date)
+		paidOn, err := time.Parse("2006-01-02", date)
+		if amount > 0 && err == nil && !paidOn.Before(l.openedOn) && !paidOn.After(today) {
+			var recorded bool
+			if err := q.QueryRow(`
+				SELECT EXISTS (
+					SELECT 1 FROM debt_payments
+					WHERE debt_account_id = $1 AND kind = 'payment' AND amount = $2
+					  AND paid_on BETWEEN $3 AND $4
+				)
+			`, debtID, amount, paidOn.AddDate(0, 0, -3).Format("2006-01-02"),
+				paidOn.AddDate(0, 0, 3).Format("2006-01-02")).Scan(&recorded); err != nil {
+				return fmt.Errorf("payment lookup: %w", err)
+			}
+			if !recorded {
+				p := models.DebtPayment{Amount: math.Round(amount*100) / 100, Source: "plaid", Note: "Reported by lender"}
+				if err := l.pay(&p, paidOn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := l.reconcile(today, "Reconciled with linked account"); err != nil {
+		return err
+	}
+	return l.save()
+}
+
+// RunDebtInterestAccrual charges each debt with an APR its monthly interest
+// once the month has ended. Debts without a ledger yet are opened at their
+// current balance and start accruing from today.
+func RunDebtInterestAccrual() {
+	client, err := db.New()
+	if err != nil {
+		log.Printf("debt interest: db error: %v", err)
+		return
+	}
+	defer client.Close()
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	lastMonthEnd := time.Date(today.Year(), today.Month(), 0, 0, 0, 0, 0, time.UTC)
+
+	rows, err := client.Query(`
+		SELECT id FROM debt_accounts
+		WHERE balance > 0 AND COALESCE(apr, 0) > 0
+		  AND (interest_accrued_through IS NULL OR interest_accrued_through < $1)
+	`, lastMonthEnd.Format("2006-01-02"))
+	if err != nil {
+		log.Printf("debt interest: query error: %v", err)
+		return
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	accrued := 0
+	for _, id := range ids {
+		err := inDebtLedgerTx(client.Raw(), func(q ledgerExecer) error {
+			l, err := openDebtLedger(q, id)
+			if err != nil {
+				return err
+			}
+			if err := l.reconcile(today, "Balance edited"); err != nil {
+				return err
+			}
+			if err := l.accrue(today); err != nil {
+				return err
+			}
+			return l.save()
+		})
+		if err != nil {
+			log.Printf("debt interest: accrual error for debt %s: %v", id, err)
+			continue
+		}
+		accrued++
+	}
+	log.Printf("debt interest: accrued %d debts", accrued)
+}
+
+// debtLedger is a debt locked for a ledger update. The ledger's running
+// balance and unpaid interest move as entries are added and are written back
+// to the debt by save.
+type debtLedger struct {
+	q              ledgerExecer
+	id             string
+	userID         string
+	plaidAccountID string
+	apr            float64
+	recorded       float64 // debt_accounts.balance when opened
+	balance        float64 // the ledger's running balance
+	unpaidInterest float64
+	accruedThrough time.Time // zero until the ledger is opened
+	entries        int
+	openedOn       time.Time
+}
+
+func openDebtLedger(q ledgerExecer, debtID string) (*debtLedger, error) {
+	l := &debtLedger{q: q, id: debtID}
+	var through, opened sql.NullTime
+	err := q.QueryRow(`
+		SELECT d.user_id, COALESCE(d.plaid_account_id, ''), COALESCE(d.apr, 0), d.balance,
+		       d.unpaid_interest, d.interest_accrued_through,
+		       l.entries, l.balance, l.opened_on
+		FROM debt_accounts d
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS entries,
+			       COALESCE(SUM(CASE WHEN kind = 'payment' THEN -(principal + interest) ELSE amount END), 0) AS balance,
+			       MIN(paid_on) AS opened_on
+			FROM debt_payments WHERE debt_account_id = d.id
+		) l
+		WHERE d.id = $1
+		FOR UPDATE OF d
+	`, debtID).Scan(&l.userID, &l.plaidAccountID, &l.apr, &l.recorded, &l.unpaidInterest, &through,
+		&l.entries, &l.balance, &opened)
+	if err != nil {
+		return nil, fmt.Errorf("load debt %s: %w", debtID, err)
+	}
+	if through.Valid {
+		l.accruedThrough = through.Time
+	}
+	if opened.Valid {
+		l.openedOn = opened.Time
+	}
+	return l, nil
+}
+
+// reconcile records the gap between the debt's balance and its ledger: an
+// opening entry for a new ledger, or an adjustment when the balance was
+// edited directly or reported by the lender.
+func (l *debtLedger) reconcile(on time.Time, note string) error {
+	if l.entries == 0 {
+		l.balance = l.recorded
+		l.openedOn = on
+		if l.accruedThrough.IsZero() {
+			l.accruedThrough = on
+		}
+		return l.add(&models.DebtPayment{Kind: "opening", Amount: l.recorded, Source: "manual", Note: "Opening balance"}, on)
+	}
+	diff := math.Round((l.recorded-l.balance)*100) / 100
+	if diff == 0 {
+		return nil
+	}
+	l.balance = l.recorded
+	source := "manual"
+	if l.plaidAccountID != "" {
+		source = "plaid"
+	}
+	return l.add(&models.DebtPayment{Kind: "adjustment", Amount: diff, Source: source, Note: note}, on)
+}
+
+// accrue charges interest at each month end since the ledger last accrued,
+// up to through.
+func (l *debtLedger) accrue(through time.Time) error {
+	if l.accruedThrough.IsZero() {
+		l.accruedThrough = through
+		return nil
+	}
+	for _, a := range debts.Accrue(l.balance, l.apr, l.accruedThrough, through) {
+		l.balance = a.Balance
+		l.unpaidInterest += a.Interest
+		note := fmt.Sprintf("Interest at %.2f%% APR", l.apr)
+		if err := l.add(&models.DebtPayment{Kind: "interest", Amount: a.Interest, Interest: a.Interest, Source: "accrual", Note: note}, a.Date); err != nil {
+			return err
+		}
+		l.accruedThrough = a.Date
+	}
+	return nil
+}
+
+// pay records a payment, covering unpaid interest before principal.
+func (l *debtLedger) pay(p *models.DebtPayment, paidOn time.Time) error {
+	p.Kind = "payment"
+	p.Interest, p.Principal = debts.Split(p.Amount, l.balance, l.unpaidInterest)
+	l.balance -= p.Interest + p.Principal
+	l.unpaidInterest -= p.Interest
+	return l.add(p, paidOn)
+}
+
+// add writes an entry at the ledger's current balance.
+func (l *debtLedger) add(p *models.DebtPayment, on time.Time) error {
+	p.ID = uuid.New().String()
+	p.DebtAccountID = l.id
+	p.UserID = l.userID
+	p.BalanceAfter = math.Round(l.balance*100) / 100
+	p.PaidOn = on.Format("2006-01-02")
+	p.CreatedAt = time.Now().UTC()
+	_, err := l.q.Exec(`
+		INSERT INTO debt_payments (id, debt_account_id, user_id, kind, amount, principal, interest, balance_after,
+		  paid_on, transaction_id, bill_payment_id, source, note)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+	`, p.ID, p.DebtAccountID, p.UserID, p.Kind, p.Amount, p.Principal, p.Interest, p.BalanceAfter,
+		p.PaidOn, p.TransactionID, p.BillPaymentID, p.Source, nullableStr(p.Note))
+	if err != nil {
+		return fmt.Errorf("insert %s entry: %w", p.Kind, err)
+	}
+	l.entries++
+	return nil
+}
+
+// save writes the ledger's balance and unpaid interest back to the debt.
+func (l *debtLedger) save() error {
+	balance := math.Max(math.Round(l.balance*100)/100, 0)
+	unpaid := math.Min(math.Max(math.Round(l.unpaidInterest*100)/100, 0), balance)
+	_, err := l.q.Exec(`
+		UPDATE debt_accounts
+		SET balance = $1, unpaid_interest = $2, interest_accrued_through = $3
+		WHERE id = $4
+	`, balance, unpaid, l.accruedThrough.Format("2006-01-02"), l.id)
+	return err
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/gorilla/mux"
+)
+
+var debtLedgerColumns = []string{
+	"user_id", "plaid_account_id", "apr", "balance", "unpaid_interest",
+	"interest_accrued_through", "entries", "ledger_balance", "opened_on",
+}
+
+func withDebtLedgerMockDB(t *testing.T, setup func(sqlmock.Sqlmock)) *sql.DB {
+	t.Helper()
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockSQL.Close() })
+
+	oldFactory := debtLedgerDBFactory
+	debtLedgerDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	t.Cleanup(func() { debtLedgerDBFactory = oldFactory })
+
+	setup(mock)
+	return mockSQL
+}
+
+func debtPaymentRequest(t *testing.T, userID, debtID string, body map[string]any) *http.Request {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPatch, "/auth/debts/"+debtID+"/payment", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+	return mux.SetURLVars(req, map[string]string{"id": debtID})
+}
+
+func TestApplyDebtPayment_RejectsNonPositiveAmount(t *testing.T) {
+	req := debtPaymentRequest(t, "11111111-1111-1111-1111-111111111111", "d1", map[string]any{"amount": 0})
+	rr := httptest.NewRecorder()
+
+	ApplyDebtPayment(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestApplyDebtPayment_OtherUsersDebt(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	withDebtLedgerMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT user_id, household_id, COALESCE`).
+			WithArgs("d1").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "is_shared"}).
+				AddRow("22222222-2222-2222-2222-222222222222", nil, false))
+	})
+
+	rr := httptest.NewRecorder()
+	ApplyDebtPayment(rr, debtPaymentRequest(t, userID, "d1", map[string]any{"amount": 100}))
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
+func TestListDebtPayments_HouseholdMemberNeedsSharedDebt(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	withDebtLedgerMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT user_id, household_id, COALESCE`).
+			WithArgs("d1").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "is_shared"}).
+				AddRow("22222222-2222-2222-2222-222222222222", "hh1", false))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/debts/d1/payments", nil)
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+	req = mux.SetURLVars(req, map[string]string{"id": "d1"})
+	rr := httptest.NewRecorder()
+	ListDebtPayments(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
+func TestApplyDebtPayment_AccruesInterestFirst(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	debtID := "dddddddd-dddd-dddd-dddd-dddddddddddd"
+
+	var mock sqlmock.Sqlmock
+	withDebtLedgerMockDB(t, func(m sqlmock.Sqlmock) {
+		mock = m
+		mock.ExpectQuery(`SELECT user_id, household_id, COALESCE`).
+			WithArgs(debtID).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "is_shared"}).
+				AddRow(userID, nil, false))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM debt_accounts d`).
+			WithArgs(debtID).
+			WillReturnRows(sqlmock.NewRows(debtLedgerColumns).
+				AddRow(userID, "", 12.0, 1000.0, 0.0, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC),
+					1, 1000.0, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)))
+		// February's interest at 1% a month.
+		mock.ExpectExec(`INSERT INTO debt_payments`).
+			WithArgs(sqlmock.AnyArg(), debtID, userID, "interest", 10.0, 0.0, 10.0, 1010.0,
+				"2026-02-28", nil, nil, "accrual", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO debt_payments`).
+			WithArgs(sqlmock.AnyArg(), debtID, userID, "payment", 200.0, 190.0, 10.0, 810.0,
+				"2026-03-10", nil, nil, "manual", nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE debt_accounts`).
+			WithArgs(810.0, 0.0, "2026-02-28", debtID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`FROM debt_accounts WHERE id`).
+			WithArgs(debtID).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "user_id", "name", "balance", "apr", "min_payment", "due_day",
+				"strategy", "is_shared", "source", "unpaid_interest",
+			}).AddRow(debtID, userID, "Visa", 810.0, 12.0, 35.0, 15, "avalanche", false, "manual", 0.0))
+	})
+
+	rr := httptest.NewRecorder()
+	ApplyDebtPayment(rr, debtPaymentRequest(t, userID, debtID, map[string]any{"amount": 200, "paid_on": "2026-03-10"}))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Balance float64 `json:"balance"`
+		Payment struct {
+			Interest  float64 `json:"interest"`
+			Principal float64 `json:"principal"`
+		} `json:"payment"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("bad response: %v", err)
+	}
+	if resp.Balance != 810 || resp.Payment.Interest != 10 || resp.Payment.Principal != 190 {
+		t.Errorf("response = %+v", resp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApplyDebtPayment_BackdatedFirstPaymentStartsAccrualToday(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	debtID := "dddddddd-dddd-dddd-dddd-dddddddddddd"
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	paidOn := today.AddDate(0, -6, 0).Format("2006-01-02")
+
+	var mock sqlmock.Sqlmock
+	withDebtLedgerMockDB(t, func(m sqlmock.Sqlmock) {
+		mock = m
+		mock.ExpectQuery(`SELECT user_id, household_id, COALESCE`).
+			WithArgs(debtID).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "is_shared"}).
+				AddRow(userID, nil, false))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM debt_accounts d`).
+			WithArgs(debtID).
+			WillReturnRows(sqlmock.NewRows(debtLedgerColumns).
+				AddRow(userID, "", 12.0, 1000.0, 0.0, nil, 0, 0.0, nil))
+		// No interest entries: today's balance isn't charged for past months.
+		mock.ExpectExec(`INSERT INTO debt_payments`).
+			WithArgs(sqlmock.AnyArg(), debtID, userID, "opening", 1000.0, 0.0, 0.0, 1000.0,
+				paidOn, nil, nil, "manual", "Opening balance").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO debt_payments`).
+			WithArgs(sqlmock.AnyArg(), debtID, userID, "payment", 100.0, 100.0, 0.0, 900.0,
+				paidOn, nil, nil, "manual", nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE debt_accounts`).
+			WithArgs(900.0, 0.0, today.Format("2006-01-02"), debtID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`FROM debt_accounts WHERE id`).
+			WithArgs(debtID).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "user_id", "name", "balance", "apr", "min_payment", "due_day",
+				"strategy", "is_shared", "source", "unpaid_interest",
+			}).AddRow(debtID, userID, "Visa", 900.0, 12.0, 35.0, 15, "avalanche", false, "manual", 0.0))
+	})
+
+	rr := httptest.NewRecorder()
+	ApplyDebtPayment(rr, debtPaymentRequest(t, userID, debtID, map[string]any{"amount": 100, "paid_on": paidOn}))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReconcileLinkedDebt_RecordsLenderPaymentAndAdjustment(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	debtID := "dddddddd-dddd-dddd-dddd-dddddddddddd"
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+
+	// Plaid reports 1,320; the ledger last stood at 1,500.
+	mock.ExpectQuery(`FROM debt_accounts d`).
+		WithArgs(debtID).
+		WillReturnRows(sqlmock.NewRows(debtLedgerColumns).
+			AddRow(userID, "plaid-acct", 0.0, 1320.0, 0.0, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC),
+				3, 1500.0, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	mock.ExpectQuery(`FROM liabilities`).
+		WithArgs(userID, "plaid-acct").
+		WillReturnRows(sqlmock.NewRows([]string{"last_payment_amount", "last_payment_date"}).
+			AddRow(200.0, "2026-03-05"))
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs(debtID, 200.0, "2026-03-02", "2026-03-08").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`INSERT INTO debt_payments`).
+		WithArgs(sqlmock.AnyArg(), debtID, userID, "payment", 200.0, 200.0, 0.0, 1300.0,
+			"2026-03-05", nil, nil, "plaid", "Reported by lender").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO debt_payments`).
+		WithArgs(sqlmock.AnyArg(), debtID, userID, "adjustment", 20.0, 0.0, 0.0, 1320.0,
+			"2026-03-10", nil, nil, "plaid", "Reconciled with linked account").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE debt_accounts`).
+		WithArgs(1320.0, 0.0, "2026-02-28", debtID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := reconcileLinkedDebt(mockSQL, debtID, time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
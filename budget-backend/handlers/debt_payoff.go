@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/ai"
+	"github.com/aboogie/budget-backend/models"
+)
+
+var payoffDebtCategories = []string{"attack", "structured", "all"}
+
+// payoffMaxMonths matches the 30-year horizon of the payoff calculator.
+const payoffMaxMonths = 360
+
+// ComparePayoffStrategies runs snowball, avalanche, hybrid and an optional
+// custom order over the user's debts side by side, with total interest,
+// payoff date per debt and months saved against paying only the minimums.
+// POST /auth/debts/payoff/compare
+// body: { extra_payment, extra_changes, lump_sums, custom_order, promo_rates, debt_category }
+func ComparePayoffStrategies(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.PayoffComparisonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	if req.DebtCategory == "" {
+		req.DebtCategory = "attack"
+	}
+	if e := validateEnum(req.DebtCategory, "debt_category", payoffDebtCategories); e != nil {
+		respondValidationError(w, []ValidationError{*e})
+		return
+	}
+
+	conn, err := plannerDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	householdID := db.ResolveHouseholdID(conn.Raw(), userID)
+	debts, err := loadPayoffDebts(conn, userID, householdID, req.DebtCategory)
+	if err != nil {
+		log.Printf("ComparePayoffStrategies query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+
+	if errs := validatePayoffOptions(req.PayoffOptions, debts); len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ai.CompareDebtStrategies(debts, req.PayoffOptions))
+}
+
+// loadPayoffDebts returns the user's debts with a balance, plus shared
+// household debts, in the given category.
+func loadPayoffDebts(conn db.DBTX, userID, householdID, category string) ([]models.DebtInfo, error) {
+	rows, err := conn.Query(`
+		SELECT id, name, balance, COALESCE(apr, 0), COALESCE(min_payment, 0)
+		FROM debt_accounts
+		WHERE (user_id = $1 OR ($2 <> '' AND household_id::text = $2 AND is_shared))
+		  AND balance > 0
+		  AND ($3 = 'all' OR COALESCE(debt_category, 'attack') = $3)
+		ORDER BY name
+	`, userID, householdID, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	debts := []models.DebtInfo{}
+	for rows.Next() {
+		var d models.DebtInfo
+		if err := rows.Scan(&d.ID, &d.Name, &d.Balance, &d.APR, &d.MinPayment); err != nil {
+			return nil, err
+		}
+		debts = append(debts, d)
+	}
+	return debts, rows.Err()
+}
+
+// validatePayoffOptions checks amounts and months are in range and that
+// every debt the options name is one of the debts being compared.
+func validatePayoffOptions(opts models.PayoffOptions, debts []models.DebtInfo) []ValidationError {
+	known := map[string]bool{}
+	for _, d := range debts {
+		known[d.ID] = true
+	}
+	validMonth := func(m int) bool { return m >= 1 && m <= payoffMaxMonths }
+	monthMsg := fmt.Sprintf("must be between 1 and %d", payoffMaxMonths)
+
+	var errs []ValidationError
+	if opts.ExtraPayment < 0 {
+		errs = append(errs, ValidationError{Field: "extra_payment", Message: "cannot be negative"})
+	}
+	for i, c := range opts.ExtraChanges {
+		if !validMonth(c.FromMonth) {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("extra_changes[%d].from_month", i), Message: monthMsg})
+		}
+		if c.Amount < 0 {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("extra_changes[%d].amount", i), Message: "cannot be negative"})
+		}
+	}
+	for i, l := range opts.LumpSums {
+		if !validMonth(l.Month) {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("lump_sums[%d].month", i), Message: monthMsg})
+		}
+		if l.Amount <= 0 {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("lump_sums[%d].amount", i), Message: "must be greater than 0"})
+		}
+		if l.DebtID != "" && !known[l.DebtID] {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("lump_sums[%d].debt_id", i), Message: "unknown debt"})
+		}
+	}
+	seen := map[string]bool{}
+	for i, id := range opts.CustomOrder {
+		field := fmt.Sprintf("custom_order[%d]", i)
+		switch {
+		case !known[id]:
+			errs = append(errs, ValidationError{Field: field, Message: "unknown debt"})
+		case seen[id]:
+			errs = append(errs, ValidationError{Field: field, Message: "debt listed twice"})
+		}
+		seen[id] = true
+	}
+	promos := map[string]bool{}
+	for i, p := range opts.PromoRates {
+		switch {
+		case !known[p.DebtID]:
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("promo_rates[%d].debt_id", i), Message: "unknown debt"})
+		case promos[p.DebtID]:
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("promo_rates[%d].debt_id", i), Message: "only one promotional rate per debt"})
+		}
+		promos[p.DebtID] = true
+		if p.APR < 0 {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("promo_rates[%d].apr", i), Message: "cannot be negative"})
+		}
+		if !validMonth(p.Months) {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("promo_rates[%d].months", i), Message: monthMsg})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/models"
+)
+
+func comparePayoff(t *testing.T, body map[string]any) (*httptest.ResponseRecorder, models.PayoffComparison) {
+	t.Helper()
+	userID := "11111111-1111-1111-1111-111111111111"
+	withMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`FROM household_members`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"household_id"}))
+		mock.ExpectQuery(`FROM debt_accounts`).
+			WithArgs(userID, "", "attack").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "balance", "apr", "min_payment"}).
+				AddRow("card", "Card", 1000.0, 24.0, 50.0).
+				AddRow("loan", "Loan", 3000.0, 6.0, 100.0))
+	})
+
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/auth/debts/payoff/compare", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+	rr := httptest.NewRecorder()
+
+	ComparePayoffStrategies(rr, req)
+
+	var cmp models.PayoffComparison
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &cmp); err != nil {
+			t.Fatalf("bad response: %v", err)
+		}
+	}
+	return rr, cmp
+}
+
+func strategyResult(t *testing.T, cmp models.PayoffComparison, name string) models.PayoffStrategyResult {
+	t.Helper()
+	for _, s := range cmp.Strategies {
+		if s.Strategy == name {
+			return s
+		}
+	}
+	t.Fatalf("no %s strategy in %+v", name, cmp.Strategies)
+	return models.PayoffStrategyResult{}
+}
+
+func TestComparePayoffStrategies_SideBySide(t *testing.T) {
+	rr, cmp := comparePayoff(t, map[string]any{
+		"extra_payment": 100,
+		"custom_order":  []string{"loan", "card"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(cmp.Strategies) != 4 {
+		t.Fatalf("expected snowball, avalanche, hybrid and custom, got %d", len(cmp.Strategies))
+	}
+
+	avalanche := strategyResult(t, cmp, "avalanche")
+	custom := strategyResult(t, cmp, "custom")
+	if !avalanche.DebtFree || avalanche.MonthsSaved <= 0 || avalanche.InterestSaved <= 0 {
+		t.Errorf("avalanche vs minimums = %+v", avalanche)
+	}
+	if avalanche.Debts[0].DebtID != "card" || custom.Debts[0].DebtID != "loan" {
+		t.Errorf("payoff order: avalanche %+v, custom %+v", avalanche.Debts, custom.Debts)
+	}
+	if custom.TotalInterest <= avalanche.TotalInterest {
+		t.Errorf("custom interest %v should exceed avalanche %v", custom.TotalInterest, avalanche.TotalInterest)
+	}
+	if cmp.Recommended == "custom" {
+		t.Errorf("custom order should not be recommended")
+	}
+}
+
+func TestComparePayoffStrategies_LumpSumAndPromo(t *testing.T) {
+	// A lump sum bigger than the card pays it off in month one and the rest
+	// carries over to the loan.
+	rr, cmp := comparePayoff(t, map[string]any{
+		"lump_sums": []map[string]any{{"month": 1, "amount": 2000}},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	avalanche := strategyResult(t, cmp, "avalanche")
+	if d := avalanche.Debts[0]; d.DebtID != "card" || d.PayoffMonth != 1 {
+		t.Errorf("card = %+v", d)
+	}
+
+	// While the card is at 0% avalanche goes after the loan first.
+	rr, cmp = comparePayoff(t, map[string]any{
+		"extra_payment": 100,
+		"promo_rates":   []map[string]any{{"debt_id": "card", "apr": 0, "months": 24}},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	avalanche = strategyResult(t, cmp, "avalanche")
+	if avalanche.Debts[0].DebtID != "loan" {
+		t.Errorf("expected loan first during the card's promo, got %+v", avalanche.Debts)
+	}
+	if avalanche.Debts[1].TotalInterest != 0 {
+		t.Errorf("card should be paid off interest-free, got %v", avalanche.Debts[1].TotalInterest)
+	}
+}
+
+func TestComparePayoffStrategies_UnknownDebt(t *testing.T) {
+	rr, _ := comparePayoff(t, map[string]any{
+		"custom_order": []string{"card", "mortgage"},
+		"lump_sums":    []map[string]any{{"month": 0, "amount": 500}},
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var resp ValidationErrors
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Errors) != 2 {
+		t.Errorf("errors = %+v", resp.Errors)
+	}
+}
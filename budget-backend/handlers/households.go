@@ -357,6 +357,11 @@ func GetHouseholdSummary(w http.ResponseWriter, r *http.Request) {
 				"total_savings_target":   0.0,
 				"total_savings_current":  0.0,
 				"savings_progress":       0.0,
+				"total_assets":           0.0,
+				"total_liabilities":      0.0,
+				"manual_assets":          0.0,
+				"manual_liabilities":     0.0,
+				"net_worth":              0.0,
 			})
 			return
 		}
@@ -370,11 +375,20 @@ func GetHouseholdSummary(w http.ResponseWriter, r *http.Request) {
 			COALESCE((SELECT SUM(amount) FROM transactions WHERE household_id = $1 AND type = 'expense' AND date >= date_trunc('month', CURRENT_DATE)), 0),
 			COALESCE((SELECT SUM(balance) FROM debt_accounts WHERE household_id = $1), 0),
 			COALESCE((SELECT SUM(target_amount) FROM savings_goals WHERE household_id = $1), 0),
-			COALESCE((SELECT SUM(current_amount) FROM savings_goals WHERE household_id = $1), 0)
+			COALESCE((SELECT SUM(current_amount) FROM savings_goals WHERE household_id = $1), 0),
+			COALESCE((SELECT SUM(current_balance) FROM account_balances WHERE household_id = $1 AND NOT is_liability AND sync_enabled), 0),
+			COALESCE((SELECT SUM(current_balance) FROM account_balances WHERE household_id = $1 AND is_liability AND sync_enabled), 0),
+			COALESCE((SELECT SUM(current_balance) FROM account_balances WHERE household_id = $1 AND is_manual AND NOT is_liability AND sync_enabled), 0),
+			COALESCE((SELECT SUM(current_balance) FROM account_balances WHERE household_id = $1 AND is_manual AND is_liability AND sync_enabled), 0),
+			COALESCE((SELECT SUM(balance) FROM debt_accounts WHERE household_id = $1 AND plaid_account_id IS NULL), 0)
 	`
 
 	var totalIncome, totalExpenses, totalDebt, totalSavingsTarget, totalSavingsCurrent float64
-	err = client.Raw().QueryRow(query, householdID).Scan(&totalIncome, &totalExpenses, &totalDebt, &totalSavingsTarget, &totalSavingsCurrent)
+	// Linked and manual accounts; debts already tracked as a linked account are
+	// counted once, via their account balance.
+	var totalAssets, totalLiabilities, manualAssets, manualLiabilities, untrackedDebt float64
+	err = client.Raw().QueryRow(query, householdID).Scan(&totalIncome, &totalExpenses, &totalDebt, &totalSavingsTarget, &totalSavingsCurrent,
+		&totalAssets, &totalLiabilities, &manualAssets, &manualLiabilities, &untrackedDebt)
 	if err != nil && err != sql.ErrNoRows {
 		log.Printf("GetHouseholdSummary query error: %v", err)
 		http.Error(w, `{"error": "Query error"}`, http.StatusInternalServerError)
@@ -409,6 +423,11 @@ func GetHouseholdSummary(w http.ResponseWriter, r *http.Request) {
 		"total_savings_target":   totalSavingsTarget,
 		"total_savings_current":  totalSavingsCurrent,
 		"savings_progress":       calculateSavingsProgress(totalSavingsCurrent, totalSavingsTarget),
+		"total_assets":           totalAssets,
+		"total_liabilities":      totalLiabilities + untrackedDebt,
+		"manual_assets":          manualAssets,
+		"manual_liabilities":     manualLiabilities,
+		"net_worth":              totalAssets - totalLiabilities - untrackedDebt,
 	})
 }
 
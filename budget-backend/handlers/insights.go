@@ -14,6 +14,8 @@ import (
 //   - Spending broken down by category for the requested month
 //   - Month-over-month totals (current vs previous)
 //   - Daily spending for the requested month
+//
+// Pass tag_id to limit everything to transactions carrying those tags.
 func GetSpendingInsights(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
@@ -58,14 +60,19 @@ func GetSpendingInsights(w http.ResponseWriter, r *http.Request) {
 	query := `
 		SELECT
 			t.type,
-			t.amount,
+			CASE WHEN t.type = 'expense' THEN ` + netExpenseAmountSQL + ` ELSE t.amount END,
 			t.date,
 			COALESCE(c.name, t.category_name, '') AS cat_name,
 			COALESCE(c.color, '') AS cat_color
 		FROM transactions t
 		LEFT JOIN categories c ON t.category_id = c.id
 		WHERE t.date >= $1 AND t.date < $2
-		  AND ` + scopeWhere
+		  AND ` + scopeWhere + budgetAccountFilter + reimbursementPaymentFilter
+	query, args, verr := withTagFilter(r, query, args)
+	if verr != nil {
+		respondValidationError(w, []ValidationError{*verr})
+		return
+	}
 
 	rows, err := dbClient.Query(query, args...)
 	if err != nil {
@@ -191,8 +198,8 @@ func GetSpendingInsights(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetTopMerchants returns the top spending categories (used as a quick "top merchants" view).
-func GetTopMerchants(w http.ResponseWriter, r *http.Request) {
+// GetTopCategories returns the top spending categories.
+func GetTopCategories(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
 		http.Error(w, "Missing user_id", http.StatusBadRequest)
@@ -221,11 +228,16 @@ func GetTopMerchants(w http.ResponseWriter, r *http.Request) {
 		args = []any{hhID, userID}
 	}
 
+	where, args, verr := withTagFilter(r, "t.type = 'expense' AND "+scopeWhere+budgetAccountFilter, args)
+	if verr != nil {
+		respondValidationError(w, []ValidationError{*verr})
+		return
+	}
 	query := `
-		SELECT COALESCE(c.name, t.category_name, 'Uncategorized') AS cat, SUM(t.amount) AS total, COUNT(*) AS cnt
+		SELECT COALESCE(c.name, t.category_name, 'Uncategorized') AS cat, SUM(` + netExpenseAmountSQL + `) AS total, COUNT(*) AS cnt
 		FROM transactions t
 		LEFT JOIN categories c ON t.category_id = c.id
-		WHERE t.type = 'expense' AND ` + scopeWhere + `
+		WHERE ` + where + `
 		GROUP BY cat
 		ORDER BY total DESC
 		LIMIT ` + strconv.Itoa(limit)
@@ -257,3 +269,78 @@ func GetTopMerchants(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(results)
 }
+
+// GetTopMerchants returns the top merchants by spend, grouped on the
+// normalized merchant entity so store numbers and processor prefixes don't
+// split one merchant across rows. Transactions without a merchant fall back
+// to their description.
+func GetTopMerchants(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "Missing user_id", http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 5
+	}
+
+	dbClient, err := db.New()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer dbClient.Close()
+
+	hhID := db.ResolveHouseholdID(dbClient.Conn, userID)
+
+	scopeWhere := "t.user_id = $1"
+	args := []any{userID}
+	if hhID != "" {
+		scopeWhere = "(t.household_id = $1 OR (t.household_id IS NULL AND t.user_id = $2))"
+		args = []any{hhID, userID}
+	}
+
+	where, args, verr := withTagFilter(r, "t.type = 'expense' AND "+scopeWhere+budgetAccountFilter, args)
+	if verr != nil {
+		respondValidationError(w, []ValidationError{*verr})
+		return
+	}
+	query := `
+		SELECT COALESCE(m.id::text, ''), COALESCE(m.name, NULLIF(t.note, ''), 'Unknown') AS merchant,
+		       m.logo_url, m.color, SUM(` + netExpenseAmountSQL + `) AS total, COUNT(*) AS cnt
+		FROM transactions t
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE ` + where + `
+		GROUP BY 1, 2, 3, 4
+		ORDER BY total DESC
+		LIMIT ` + strconv.Itoa(limit)
+
+	rows, err := dbClient.Query(query, args...)
+	if err != nil {
+		log.Printf("top merchants query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	type topMerchant struct {
+		MerchantID   string  `json:"merchant_id,omitempty"`
+		Name         string  `json:"name"`
+		LogoURL      *string `json:"logo_url,omitempty"`
+		Color        *string `json:"color,omitempty"`
+		TotalSpent   float64 `json:"total_spent"`
+		Transactions int     `json:"transactions"`
+	}
+	results := []topMerchant{}
+	for rows.Next() {
+		var tm topMerchant
+		if err := rows.Scan(&tm.MerchantID, &tm.Name, &tm.LogoURL, &tm.Color, &tm.TotalSpent, &tm.Transactions); err == nil {
+			results = append(results, tm)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
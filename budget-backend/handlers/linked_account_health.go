@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/plaid/plaid-go/v20/plaid"
+)
+
+// Products tracked in linked_account_sync_status.
+const (
+	syncProductTransactions = "transactions"
+	syncProductBalances     = "balances"
+	syncProductInvestments  = "investments"
+	syncProductLiabilities  = "liabilities"
+)
+
+// reauthErrorCodes are Plaid item errors that only the user can fix by going
+// through Link in update mode.
+var reauthErrorCodes = map[string]bool{
+	"ITEM_LOGIN_REQUIRED":      true,
+	"INVALID_CREDENTIALS":      true,
+	"INVALID_MFA":              true,
+	"INVALID_UPDATED_USERNAME": true,
+	"INSUFFICIENT_CREDENTIALS": true,
+	"ITEM_LOCKED":              true,
+	"USER_SETUP_REQUIRED":      true,
+	"MFA_NOT_SUPPORTED":        true,
+	"NO_ACCOUNTS":              true,
+	"ACCESS_NOT_GRANTED":       true,
+}
+
+// itemErrorReasons maps Plaid error codes to text we can show the user.
+var itemErrorReasons = map[string]string{
+	"ITEM_LOGIN_REQUIRED":        "Your bank needs you to sign in again to keep syncing.",
+	"INVALID_CREDENTIALS":        "Your bank username or password has changed. Sign in again to reconnect.",
+	"INVALID_MFA":                "Your bank's verification step didn't go through. Sign in again to reconnect.",
+	"INVALID_UPDATED_USERNAME":   "Your bank username has changed. Sign in again to reconnect.",
+	"INSUFFICIENT_CREDENTIALS":   "Your bank needs more information to finish connecting. Sign in again to continue.",
+	"ITEM_LOCKED":                "Your bank has locked this login after too many attempts. Unlock it with your bank, then reconnect.",
+	"USER_SETUP_REQUIRED":        "Your bank needs you to finish an action on their website before we can sync.",
+	"MFA_NOT_SUPPORTED":          "This login uses a verification method your bank doesn't support here. Reconnect with a different method.",
+	"NO_ACCOUNTS":                "We couldn't find any open accounts at this bank. Reconnect to choose accounts again.",
+	"ACCESS_NOT_GRANTED":         "Account access wasn't shared during connection. Reconnect and allow access to your accounts.",
+	"INSTITUTION_DOWN":           "Your bank is temporarily unavailable. We'll keep retrying automatically.",
+	"INSTITUTION_NOT_RESPONDING": "Your bank isn't responding right now. We'll keep retrying automatically.",
+	"INSTITUTION_NOT_AVAILABLE":  "Your bank is temporarily unavailable. We'll keep retrying automatically.",
+	"PRODUCT_NOT_READY":          "Your bank is still preparing your data. This usually resolves within a few minutes.",
+	"RATE_LIMIT_EXCEEDED":        "Your bank is limiting requests right now. We'll try again shortly.",
+}
+
+// itemErrorReason returns a user-facing explanation for an item's state.
+func itemErrorReason(status string, code *string) string {
+	if code != nil && *code != "" {
+		if reason, ok := itemErrorReasons[*code]; ok {
+			return reason
+		}
+	}
+	switch status {
+	case "pending_expiration":
+		return "Your bank connection is about to expire. Sign in again to keep syncing."
+	case "revoked":
+		return "Access to this bank was revoked. Reconnect to resume syncing."
+	case "error":
+		return "Something went wrong syncing with your bank. Reconnect if this keeps happening."
+	}
+	return ""
+}
+
+// itemNeedsReauth reports whether the user has to go through Link in update
+// mode for the item to sync again.
+func itemNeedsReauth(status string, code *string) bool {
+	switch status {
+	case "pending_expiration", "revoked":
+		return true
+	case "error":
+		return code != nil && reauthErrorCodes[*code]
+	}
+	return false
+}
+
+// plaidErrorCode extracts the Plaid error_code from an API error, if any.
+func plaidErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	pe, convErr := plaid.ToPlaidError(err)
+	if convErr != nil {
+		return ""
+	}
+	return pe.GetErrorCode()
+}
+
+// recordSyncSuccess resets the failure streak for a product on an item.
+func recordSyncSuccess(client db.DBTX, linkedAccountID, product string) {
+	_, err := client.Exec(`
+		INSERT INTO linked_account_sync_status
+			(linked_account_id, product, last_attempt_at, last_success_at, last_error_code, consecutive_failures, updated_at)
+		VALUES ($1, $2, NOW(), NOW(), NULL, 0, NOW())
+		ON CONFLICT (linked_account_id, product) DO UPDATE SET
+			last_attempt_at = NOW(), last_success_at = NOW(),
+			last_error_code = NULL, consecutive_failures = 0, updated_at = NOW()
+	`, linkedAccountID, product)
+	if err != nil {
+		log.Printf("recordSyncSuccess %s/%s error: %v", linkedAccountID, product, err)
+	}
+}
+
+// recordSyncFailure bumps the failure streak for a product on an item. When
+// the Plaid error means the login is broken the item is flagged and the owner
+// is notified.
+func recordSyncFailure(client db.DBTX, linkedAccountID, product string, syncErr error) {
+	code := plaidErrorCode(syncErr)
+	_, err := client.Exec(`
+		INSERT INTO linked_account_sync_status
+			(linked_account_id, product, last_attempt_at, last_error_code, consecutive_failures, updated_at)
+		VALUES ($1, $2, NOW(), $3, 1, NOW())
+		ON CONFLICT (linked_account_id, product) DO UPDATE SET
+			last_attempt_at = NOW(), last_error_code = EXCLUDED.last_error_code,
+			consecutive_failures = linked_account_sync_status.consecutive_failures + 1, updated_at = NOW()
+	`, linkedAccountID, product, nilIfEmpty(code))
+	if err != nil {
+		log.Printf("recordSyncFailure %s/%s error: %v", linkedAccountID, product, err)
+	}
+
+	if !reauthErrorCodes[code] {
+		return
+	}
+	if _, err := client.Exec(`
+		UPDATE linked_accounts SET item_status = 'error', error_code = $1, updated_at = NOW()
+		WHERE id = $2
+	`, code, linkedAccountID); err != nil {
+		log.Printf("recordSyncFailure item status error: %v", err)
+		return
+	}
+	notifyReauthNeeded(client, linkedAccountID, itemErrorReason("error", &code))
+}
+
+// notifyReauthNeeded pushes a reconnect prompt to the item's owner. It only
+// fires once per broken connection; ResetItemError and LOGIN_REPAIRED clear
+// reauth_notified_at so the next break notifies again.
+func notifyReauthNeeded(client db.DBTX, linkedAccountID, reason string) {
+	var userID, institution string
+	err := client.QueryRow(`
+		UPDATE linked_accounts SET reauth_notified_at = NOW()
+		WHERE id = $1 AND reauth_notified_at IS NULL
+		RETURNING user_id, COALESCE(institution_name, '')
+	`, linkedAccountID).Scan(&userID, &institution)
+	if err != nil {
+		// sql.ErrNoRows means we already told them.
+		return
+	}
+
+	title := "Reconnect your bank"
+	if institution != "" {
+		title = fmt.Sprintf("Reconnect %s", institution)
+	}
+	SendPushNotification(userID, title, reason, map[string]string{
+		"screen":            "/linked-accounts",
+		"linked_account_id": linkedAccountID,
+		"action":            "reauth",
+	})
+}
+
+// createUpdateLinkToken creates a Link token in update mode for an existing item.
+func createUpdateLinkToken(ctx context.Context, client *models.Client, userID, accessToken string) (string, error) {
+	user := plaid.LinkTokenCreateRequestUser{ClientUserId: userID}
+	linkReq := plaid.NewLinkTokenCreateRequest("Budget App", "en", []plaid.CountryCode{plaid.COUNTRYCODE_US}, user)
+	// For update mode, set AccessToken instead of Products
+	linkReq.SetAccessToken(accessToken)
+	if redirect := os.Getenv("PLAID_REDIRECT_URI"); redirect != "" {
+		linkReq.SetRedirectUri(redirect)
+	}
+
+	resp, _, err := client.API.PlaidApi.LinkTokenCreate(ctx).
+		LinkTokenCreateRequest(*linkReq).
+		Execute()
+	if err != nil {
+		return "", err
+	}
+	return resp.GetLinkToken(), nil
+}
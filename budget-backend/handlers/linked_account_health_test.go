@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/plaid/plaid-go/v20/plaid"
+)
+
+func TestItemNeedsReauth(t *testing.T) {
+	login := "ITEM_LOGIN_REQUIRED"
+	down := "INSTITUTION_DOWN"
+	cases := []struct {
+		status string
+		code   *string
+		want   bool
+	}{
+		{"good", nil, false},
+		{"error", &login, true},
+		{"error", &down, false},
+		{"error", nil, false},
+		{"pending_expiration", nil, true},
+		{"revoked", nil, true},
+	}
+	for _, tc := range cases {
+		if got := itemNeedsReauth(tc.status, tc.code); got != tc.want {
+			t.Errorf("status=%s code=%v: expected %v, got %v", tc.status, tc.code, tc.want, got)
+		}
+	}
+}
+
+func TestItemErrorReason(t *testing.T) {
+	login := "ITEM_LOGIN_REQUIRED"
+	if got := itemErrorReason("error", &login); got != itemErrorReasons[login] {
+		t.Errorf("expected mapped reason, got %q", got)
+	}
+	unknown := "SOMETHING_NEW"
+	if got := itemErrorReason("error", &unknown); got == "" {
+		t.Error("expected fallback reason for unknown error code")
+	}
+	if got := itemErrorReason("good", nil); got != "" {
+		t.Errorf("expected no reason for healthy item, got %q", got)
+	}
+}
+
+func TestPlaidErrorCode(t *testing.T) {
+	apiErr := plaid.MakeGenericOpenAPIError(nil, "400 Bad Request", plaid.PlaidError{ErrorCode: "ITEM_LOGIN_REQUIRED"})
+	if got := plaidErrorCode(apiErr); got != "ITEM_LOGIN_REQUIRED" {
+		t.Errorf("expected ITEM_LOGIN_REQUIRED, got %q", got)
+	}
+	if got := plaidErrorCode(errors.New("connection reset")); got != "" {
+		t.Errorf("expected empty code for non-Plaid error, got %q", got)
+	}
+}
+
+func TestRecordSyncFailure_TransientErrorDoesNotFlagItem(t *testing.T) {
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+
+	mock.ExpectExec(`INSERT INTO linked_account_sync_status`).
+		WithArgs("la1", syncProductTransactions, "INSTITUTION_DOWN").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	apiErr := plaid.MakeGenericOpenAPIError(nil, "400 Bad Request", plaid.PlaidError{ErrorCode: "INSTITUTION_DOWN"})
+	recordSyncFailure(&mockDB{db: mockSQL}, "la1", syncProductTransactions, apiErr)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unexpected queries: %v", err)
+	}
+}
+
+func TestNotifyReauthNeeded_AlreadyNotified(t *testing.T) {
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+
+	mock.ExpectQuery(`UPDATE linked_accounts SET reauth_notified_at = NOW\(\)`).
+		WithArgs("la1").
+		WillReturnError(sql.ErrNoRows)
+
+	notifyReauthNeeded(&mockDB{db: mockSQL}, "la1", "reason")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unexpected queries: %v", err)
+	}
+}
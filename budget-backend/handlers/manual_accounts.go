@@ -0,0 +1,571 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+var manualAccountsDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+// manualAccountTypes are the account types a user can create by hand. The
+// first five mirror Plaid's types so manual and linked accounts group together.
+var manualAccountTypes = []string{"depository", "investment", "credit", "loan", "other", "cash", "property", "vehicle"}
+
+// isLiabilityAccountType reports whether balances of this account type are
+// money owed rather than money held.
+func isLiabilityAccountType(t string) bool {
+	switch strings.ToLower(t) {
+	case "credit", "loan":
+		return true
+	}
+	return false
+}
+
+// manualBalanceDelta returns how a manual transaction moves the account
+// balance. For assets, income adds and expenses subtract. For liabilities the
+// balance is what's owed, so expenses add to it and income (a payment) pays
+// it down. Transfers carry no direction of their own, so they leave the
+// balance alone.
+func manualBalanceDelta(isLiability bool, txType string, amount float64) float64 {
+	if txType == "transfer" {
+		return 0
+	}
+	delta := amount
+	if txType == "expense" {
+		delta = -amount
+	}
+	if isLiability {
+		delta = -delta
+	}
+	return delta
+}
+
+// manualEditDelta returns how editing a manual transaction from its old type
+// and amount to the new ones moves the account balance, rounded to cents.
+func manualEditDelta(isLiability bool, oldType string, oldAmount float64, newType string, newAmount float64) float64 {
+	delta := manualBalanceDelta(isLiability, newType, newAmount) - manualBalanceDelta(isLiability, oldType, oldAmount)
+	return math.Round(delta*100) / 100
+}
+
+const accountBalanceColumns = `
+	id, user_id, household_id, linked_account_id, plaid_account_id,
+	name, official_name, type, subtype, current_balance, available_balance,
+	iso_currency_code, institution_name, mask, is_manual, is_liability, notes,
+	sync_enabled, include_in_budgets, scope, low_balance_threshold, created_at, updated_at`
+
+func scanAccountBalance(row interface{ Scan(...any) error }) (models.AccountBalance, error) {
+	var b models.AccountBalance
+	err := row.Scan(
+		&b.ID, &b.UserID, &b.HouseholdID, &b.LinkedAccountID,
+		&b.PlaidAccountID, &b.Name, &b.OfficialName, &b.Type, &b.Subtype,
+		&b.CurrentBalance, &b.AvailableBalance,
+		&b.IsoCurrencyCode, &b.InstitutionName, &b.Mask,
+		&b.IsManual, &b.IsLiability, &b.Notes,
+		&b.SyncEnabled, &b.IncludeInBudgets, &b.Scope, &b.LowBalanceThreshold,
+		&b.CreatedAt, &b.UpdatedAt,
+	)
+	return b, err
+}
+
+// ListManualAccounts returns manual accounts owned by the user or their household.
+// GET /auth/accounts/manual?user_id=...
+func ListManualAccounts(w http.ResponseWriter, r *http.Request) {
+	userID, err := sanitizeUserID(r.URL.Query().Get("user_id"))
+	if err != nil {
+		http.Error(w, "Missing or invalid user_id", http.StatusBadRequest)
+		return
+	}
+
+	client, err := manualAccountsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	hh := db.ResolveHouseholdID(client.Raw(), userID)
+
+	var rows *sql.Rows
+	if hh == "" {
+		rows, err = client.Query(`SELECT `+accountBalanceColumns+`
+			FROM account_balances
+			WHERE is_manual = true AND user_id = $1
+			ORDER BY is_liability, current_balance DESC`, userID)
+	} else {
+		rows, err = client.Query(`SELECT `+accountBalanceColumns+`
+			FROM account_balances
+			WHERE is_manual = true AND (user_id = $1 OR household_id = $2)
+			ORDER BY is_liability, current_balance DESC`, userID, hh)
+	}
+	if err != nil {
+		log.Printf("ListManualAccounts query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	accounts := []models.AccountBalance{}
+	for rows.Next() {
+		b, err := scanAccountBalance(rows)
+		if err != nil {
+			log.Printf("ListManualAccounts scan error: %v", err)
+			continue
+		}
+		accounts = append(accounts, b)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
+
+// CreateManualAccount adds an offline asset or liability.
+// POST /auth/accounts/manual
+func CreateManualAccount(w http.ResponseWriter, r *http.Request) {
+	var req models.ManualAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	var errs []ValidationError
+	if e := validateRequired(req.UserID, "user_id"); e != nil {
+		errs = append(errs, *e)
+	}
+	if e := validateRequired(req.Name, "name"); e != nil {
+		errs = append(errs, *e)
+	}
+	req.Type = strings.ToLower(req.Type)
+	if req.Type == "" {
+		req.Type = "other"
+	}
+	if e := validateEnum(req.Type, "type", manualAccountTypes); e != nil {
+		errs = append(errs, *e)
+	}
+	if req.CurrentBalance < 0 {
+		errs = append(errs, ValidationError{Field: "current_balance", Message: "current_balance cannot be negative; use is_liability for money owed"})
+	}
+	if len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+
+	isLiability := isLiabilityAccountType(req.Type)
+	if req.IsLiability != nil {
+		isLiability = *req.IsLiability
+	}
+	if req.IsoCurrencyCode == "" {
+		req.IsoCurrencyCode = "USD"
+	}
+
+	client, err := manualAccountsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	var hhVal any
+	if hh := db.ResolveHouseholdID(client.Raw(), req.UserID); hh != "" {
+		hhVal = hh
+	}
+
+	id := uuid.New().String()
+	row := client.QueryRow(`
+		INSERT INTO account_balances
+			(id, user_id, household_id, plaid_account_id, name, type, subtype,
+			 current_balance, iso_currency_code, institution_name, is_manual, is_liability, notes)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,true,$11,$12)
+		RETURNING `+accountBalanceColumns,
+		id, req.UserID, hhVal, "manual:"+id, req.Name, req.Type, req.Subtype,
+		req.CurrentBalance, req.IsoCurrencyCode, req.InstitutionName, isLiability, req.Notes,
+	)
+	account, err := scanAccountBalance(row)
+	if err != nil {
+		log.Printf("CreateManualAccount insert error: %v", err)
+		http.Error(w, "Insert error", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := client.Exec(`
+		INSERT INTO account_balance_history (id, account_balance_id, balance, source, note)
+		VALUES ($1, $2, $3, 'manual', 'Opening balance')
+	`, uuid.New().String(), id, req.CurrentBalance); err != nil {
+		log.Printf("CreateManualAccount history error: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(account)
+}
+
+// UpdateManualAccount edits a manual account's descriptive fields. Balance
+// changes go through RecordManualAccountValue so they are kept in history.
+// PUT /auth/accounts/manual/{id}
+func UpdateManualAccount(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var req models.ManualAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = r.URL.Query().Get("user_id")
+	}
+	if req.UserID == "" {
+		http.Error(w, "Missing user_id", http.StatusBadRequest)
+		return
+	}
+	req.Type = strings.ToLower(req.Type)
+	if req.Type != "" {
+		if e := validateEnum(req.Type, "type", manualAccountTypes); e != nil {
+			respondValidationError(w, []ValidationError{*e})
+			return
+		}
+	}
+
+	client, err := manualAccountsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), "account_balances", id, req.UserID) {
+		return
+	}
+
+	row := client.QueryRow(`
+		UPDATE account_balances
+		SET name = COALESCE(NULLIF($1, ''), name),
+		    type = COALESCE(NULLIF($2, ''), type),
+		    subtype = COALESCE($3, subtype),
+		    is_liability = COALESCE($4, is_liability),
+		    institution_name = COALESCE($5, institution_name),
+		    notes = COALESCE($6, notes),
+		    updated_at = NOW()
+		WHERE id = $7 AND is_manual = true
+		RETURNING `+accountBalanceColumns,
+		req.Name, req.Type, req.Subtype, req.IsLiability, req.InstitutionName, req.Notes, id,
+	)
+	account, err := scanAccountBalance(row)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Manual account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("UpdateManualAccount error: %v", err)
+		http.Error(w, "Update error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+// DeleteManualAccount removes a manual account and its value history.
+// Transactions posted against it are kept but unlinked.
+// DELETE /auth/accounts/manual/{id}?user_id=...
+func DeleteManualAccount(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "Missing user_id", http.StatusBadRequest)
+		return
+	}
+
+	client, err := manualAccountsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), "account_balances", id, userID) {
+		return
+	}
+
+	res, err := client.Exec(`DELETE FROM account_balances WHERE id = $1 AND is_manual = true`, id)
+	if err != nil {
+		log.Printf("DeleteManualAccount error: %v", err)
+		http.Error(w, "Delete error", http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		http.Error(w, "Manual account not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RecordManualAccountValue sets a new value for a manual account (e.g. a
+// 401k statement or a car's resale value) and appends it to history.
+// Back-dated values are kept in history without replacing a newer balance.
+// POST /auth/accounts/manual/{id}/value
+func RecordManualAccountValue(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var req models.AccountValueUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" {
+		http.Error(w, "Missing user_id", http.StatusBadRequest)
+		return
+	}
+	if req.Balance < 0 {
+		validationError(w, "Balance cannot be negative")
+		return
+	}
+	recordedOn := time.Now().UTC().Format("2006-01-02")
+	if req.RecordedOn != "" {
+		if _, err := time.Parse("2006-01-02", req.RecordedOn); err != nil {
+			validationError(w, "recorded_on must be YYYY-MM-DD")
+			return
+		}
+		recordedOn = req.RecordedOn
+	}
+
+	client, err := manualAccountsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), "account_balances", id, req.UserID) {
+		return
+	}
+
+	var isManual bool
+	if err := client.QueryRow(`SELECT is_manual FROM account_balances WHERE id = $1`, id).Scan(&isManual); err != nil || !isManual {
+		http.Error(w, "Manual account not found", http.StatusNotFound)
+		return
+	}
+
+	var latest sql.NullString
+	_ = client.QueryRow(`
+		SELECT MAX(recorded_on)::text FROM account_balance_history WHERE account_balance_id = $1
+	`, id).Scan(&latest)
+
+	entry := models.AccountBalanceHistory{
+		ID:               uuid.New().String(),
+		AccountBalanceID: id,
+		Balance:          req.Balance,
+		RecordedOn:       recordedOn,
+		Source:           "manual",
+		Note:             req.Note,
+	}
+	err = client.QueryRow(`
+		INSERT INTO account_balance_history (id, account_balance_id, balance, recorded_on, source, note)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`, entry.ID, id, req.Balance, recordedOn, entry.Source, req.Note).Scan(&entry.CreatedAt)
+	if err != nil {
+		log.Printf("RecordManualAccountValue insert error: %v", err)
+		http.Error(w, "Insert error", http.StatusInternalServerError)
+		return
+	}
+
+	if !latest.Valid || recordedOn >= latest.String {
+		if _, err := client.Exec(`
+			UPDATE account_balances SET current_balance = $1, updated_at = NOW() WHERE id = $2
+		`, req.Balance, id); err != nil {
+			log.Printf("RecordManualAccountValue update error: %v", err)
+			http.Error(w, "Update error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// GetAccountBalanceHistory returns recorded values for an account, oldest first.
+// GET /auth/accounts/manual/{id}/history?user_id=...
+func GetAccountBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "Missing user_id", http.StatusBadRequest)
+		return
+	}
+
+	client, err := manualAccountsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), "account_balances", id, userID) {
+		return
+	}
+
+	rows, err := client.Query(`
+		SELECT id, account_balance_id, balance, recorded_on::text, source, note, created_at
+		FROM account_balance_history
+		WHERE account_balance_id = $1
+		ORDER BY recorded_on, created_at
+	`, id)
+	if err != nil {
+		log.Printf("GetAccountBalanceHistory query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := []models.AccountBalanceHistory{}
+	for rows.Next() {
+		var h models.AccountBalanceHistory
+		if err := rows.Scan(&h.ID, &h.AccountBalanceID, &h.Balance, &h.RecordedOn, &h.Source, &h.Note, &h.CreatedAt); err != nil {
+			log.Printf("GetAccountBalanceHistory scan error: %v", err)
+			continue
+		}
+		history = append(history, h)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// CreateManualAccountTransaction posts an income or expense against a manual
+// account and moves its balance in the same database transaction.
+// POST /auth/accounts/manual/{id}/transactions
+func CreateManualAccountTransaction(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var tx models.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	if tx.UserID == "" {
+		validationError(w, "User ID is required")
+		return
+	}
+	if tx.Amount <= 0 {
+		validationError(w, "Amount must be greater than zero")
+		return
+	}
+	if !isValidBudgetType(tx.Type) {
+		validationError(w, "Type must be 'income' or 'expense'")
+		return
+	}
+	if tx.Date.IsZero() {
+		tx.Date = time.Now().UTC()
+	}
+	if tx.Currency == "" {
+		tx.Currency = "USD"
+	}
+	if tx.CategoryID != nil && *tx.CategoryID == "" {
+		tx.CategoryID = nil
+	}
+	if tx.BudgetID != nil && *tx.BudgetID == "" {
+		tx.BudgetID = nil
+	}
+
+	client, err := manualAccountsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), "account_balances", id, tx.UserID) {
+		return
+	}
+
+	var isManual, isLiability bool
+	var hhID sql.NullString
+	if err := client.QueryRow(`
+		SELECT is_manual, is_liability, household_id FROM account_balances WHERE id = $1
+	`, id).Scan(&isManual, &isLiability, &hhID); err != nil || !isManual {
+		http.Error(w, "Manual account not found", http.StatusNotFound)
+		return
+	}
+	if hhID.Valid {
+		tx.HouseholdID = &hhID.String
+	}
+
+	tx.ID = uuid.New().String()
+	source := "manual"
+	tx.Source = &source
+	tx.AccountBalanceID = &id
+	delta := manualBalanceDelta(isLiability, tx.Type, tx.Amount)
+
+	dbTx, err := client.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer dbTx.Rollback()
+
+	if _, err := dbTx.Exec(`
+		INSERT INTO transactions (id, user_id, household_id, budget_id, category_id, type, amount, currency,
+		  category_name, note, date, source, account_balance_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+	`, tx.ID, tx.UserID, tx.HouseholdID, tx.BudgetID, tx.CategoryID, tx.Type, tx.Amount, tx.Currency,
+		tx.Category, tx.Note, tx.Date, source, id); err != nil {
+		log.Printf("CreateManualAccountTransaction insert error: %v", err)
+		http.Error(w, "Failed to insert transaction", http.StatusInternalServerError)
+		return
+	}
+
+	if err := applyManualAccountDelta(dbTx, id, delta, tx.Date, tx.Note); err != nil {
+		log.Printf("CreateManualAccountTransaction balance error: %v", err)
+		http.Error(w, "Failed to update balance", http.StatusInternalServerError)
+		return
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		http.Error(w, "Commit error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tx)
+}
+
+// applyManualAccountDelta moves a manual account's balance and records the
+// resulting value in history. It is a no-op for linked accounts, whose
+// balances come from the provider, and for a zero delta.
+func applyManualAccountDelta(exec interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}, accountID string, delta float64, date time.Time, note string) error {
+	if delta == 0 {
+		return nil
+	}
+	var balance float64
+	err := exec.QueryRow(`
+		UPDATE account_balances
+		SET current_balance = current_balance + $1, updated_at = NOW()
+		WHERE id = $2 AND is_manual = true
+		RETURNING current_balance
+	`, delta, accountID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = exec.Exec(`
+		INSERT INTO account_balance_history (id, account_balance_id, balance, recorded_on, source, note)
+		VALUES ($1, $2, $3, $4, 'transaction', $5)
+	`, uuid.New().String(), accountID, balance, date.Format("2006-01-02"), nullableStr(note))
+	return err
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+)
+
+func withManualAccountsMockDB(t *testing.T, setup func(sqlmock.Sqlmock)) {
+	t.Helper()
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockSQL.Close() })
+
+	oldFactory := manualAccountsDBFactory
+	manualAccountsDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	t.Cleanup(func() { manualAccountsDBFactory = oldFactory })
+
+	setup(mock)
+}
+
+func TestManualBalanceDelta(t *testing.T) {
+	cases := []struct {
+		liability bool
+		txType    string
+		want      float64
+	}{
+		{false, "income", 100},
+		{false, "expense", -100},
+		{true, "expense", 100},
+		{true, "income", -100},
+		{false, "transfer", 0},
+		{true, "transfer", 0},
+	}
+	for _, tc := range cases {
+		if got := manualBalanceDelta(tc.liability, tc.txType, 100); got != tc.want {
+			t.Errorf("liability=%v type=%s: expected %v, got %v", tc.liability, tc.txType, tc.want, got)
+		}
+	}
+}
+
+func TestManualEditDelta(t *testing.T) {
+	cases := []struct {
+		liability        bool
+		oldType, newType string
+		want             float64
+	}{
+		// The expense had taken 100 out; as a transfer it no longer does.
+		{false, "expense", "transfer", 100},
+		{false, "transfer", "expense", -100},
+		{true, "expense", "transfer", -100},
+		{true, "transfer", "expense", 100},
+		{false, "expense", "income", 200},
+	}
+	for _, tc := range cases {
+		if got := manualEditDelta(tc.liability, tc.oldType, 100, tc.newType, 100); got != tc.want {
+			t.Errorf("liability=%v %s->%s: expected %v, got %v", tc.liability, tc.oldType, tc.newType, tc.want, got)
+		}
+	}
+}
+
+func TestCreateManualAccount_ValidationErrors(t *testing.T) {
+	cases := []string{
+		`{"name":"Cash envelope","type":"cash"}`,
+		`{"user_id":"u1","type":"cash"}`,
+		`{"user_id":"u1","name":"Boat","type":"yacht"}`,
+		`{"user_id":"u1","name":"Loan","type":"loan","current_balance":-5}`,
+	}
+	for _, body := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/auth/accounts/manual", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+
+		CreateManualAccount(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestCreateManualAccount_LiabilityFromType(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	now := time.Now()
+
+	withManualAccountsMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT household_id FROM household_members`).
+			WithArgs(userID).
+			WillReturnError(sql.ErrNoRows)
+
+		cols := []string{
+			"id", "user_id", "household_id", "linked_account_id", "plaid_account_id",
+			"name", "official_name", "type", "subtype", "current_balance", "available_balance",
+			"iso_currency_code", "institution_name", "mask", "is_manual", "is_liability", "notes",
+			"sync_enabled", "include_in_budgets", "scope", "low_balance_threshold", "created_at", "updated_at",
+		}
+		mock.ExpectQuery(`INSERT INTO account_balances`).
+			WithArgs(sqlmock.AnyArg(), userID, nil, sqlmock.AnyArg(), "Family loan", "loan", nil,
+				2500.0, "USD", nil, true, nil).
+			WillReturnRows(sqlmock.NewRows(cols).AddRow(
+				"a1", userID, nil, nil, "manual:a1",
+				"Family loan", nil, "loan", nil, 2500.0, nil,
+				"USD", nil, nil, true, true, nil,
+				true, true, "inherit", nil, now, now,
+			))
+
+		mock.ExpectExec(`INSERT INTO account_balance_history`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	})
+
+	body := `{"user_id":"` + userID + `","name":"Family loan","type":"loan","current_balance":2500}`
+	req := httptest.NewRequest(http.MethodPost, "/auth/accounts/manual", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	CreateManualAccount(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp["is_manual"] != true || resp["is_liability"] != true {
+		t.Fatalf("expected manual liability, got %#v", resp)
+	}
+}
+
+func TestListManualAccounts_MissingUserID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/auth/accounts/manual", nil)
+	rr := httptest.NewRecorder()
+
+	ListManualAccounts(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
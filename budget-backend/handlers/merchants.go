@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/merchants"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+)
+
+var merchantsDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+// merchantDefaultScope matches merchant_category_defaults rows (aliased d)
+// belonging to the user ($1) or their household ($2).
+const merchantDefaultScope = `(($2 <> '' AND d.household_id::text = $2) OR (d.household_id IS NULL AND d.user_id = $1))`
+
+// ListMerchants searches the merchants the user or their household has
+// transactions or bills with, along with the household's default category
+// for each. The merchants table is shared across users, so it is never
+// listed whole.
+// GET /auth/merchants?q=blue
+func ListMerchants(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	dbClient, err := merchantsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer dbClient.Close()
+
+	hhID := db.ResolveHouseholdID(dbClient.Raw(), userID)
+
+	rows, err := dbClient.Query(`
+		SELECT `+merchants.Columns+`,
+		       (SELECT d.category_id::text FROM merchant_category_defaults d
+		        WHERE d.merchant_id = m.id AND `+merchantDefaultScope+`
+		        ORDER BY d.household_id IS NULL LIMIT 1)
+		FROM merchants m
+		WHERE ($3 = '' OR m.name ILIKE '%' || $3 || '%' OR m.normalized_key LIKE '%' || $4 || '%')
+		  AND (
+		    EXISTS (
+		      SELECT 1 FROM transactions t
+		      WHERE t.merchant_id = m.id AND (t.user_id = $1 OR ($2 <> '' AND t.household_id::text = $2))
+		    )
+		    OR EXISTS (
+		      SELECT 1 FROM bills b
+		      WHERE b.merchant_id = m.id AND (b.user_id = $1 OR ($2 <> '' AND b.household_id::text = $2))
+		    )
+		  )
+		ORDER BY m.name
+		LIMIT 50
+	`, userID, hhID, q, merchants.Key(q))
+	if err != nil {
+		log.Printf("ListMerchants query error: %v", err)
+		http.Error(w, "Failed to fetch merchants", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []models.Merchant{}
+	for rows.Next() {
+		var defaultID sql.NullString
+		m, err := merchants.ScanMerchant(rows, &defaultID)
+		if err != nil {
+			log.Printf("ListMerchants scan error: %v", err)
+			continue
+		}
+		if defaultID.Valid {
+			m.DefaultCategoryID = &defaultID.String
+		}
+		list = append(list, m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// SetMerchantDefaultCategory sets the category new transactions from a
+// merchant get when no rule of the household's matches them. An empty or
+// null category_id removes the default. Defaults belong to the household, or
+// to the user when they aren't in one.
+// PUT /auth/merchants/{id}/default-category
+func SetMerchantDefaultCategory(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	merchantID := mux.Vars(r)["id"]
+
+	var req struct {
+		CategoryID *string `json:"category_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if verr := validateUUIDs([]string{merchantID}, "id"); verr != nil {
+		respondValidationError(w, []ValidationError{*verr})
+		return
+	}
+	if req.CategoryID != nil && *req.CategoryID == "" {
+		req.CategoryID = nil
+	}
+	if req.CategoryID != nil {
+		if verr := validateUUIDs([]string{*req.CategoryID}, "category_id"); verr != nil {
+			respondValidationError(w, []ValidationError{*verr})
+			return
+		}
+	}
+
+	client, err := merchantsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	hhID := db.ResolveHouseholdID(client.Raw(), userID)
+
+	var exists bool
+	if err := client.QueryRow(`SELECT EXISTS (SELECT 1 FROM merchants WHERE id = $1)`, merchantID).Scan(&exists); err != nil {
+		log.Printf("SetMerchantDefaultCategory merchant lookup error: %v", err)
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Merchant not found", http.StatusNotFound)
+		return
+	}
+
+	tx, err := client.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Only the caller's own scope is replaced; a default the user set before
+	// joining a household is cleared along with it.
+	if _, err := tx.Exec(`
+		DELETE FROM merchant_category_defaults d
+		WHERE d.merchant_id = $3 AND `+merchantDefaultScope,
+		userID, hhID, merchantID); err != nil {
+		log.Printf("SetMerchantDefaultCategory delete error: %v", err)
+		http.Error(w, "Failed to update merchant", http.StatusInternalServerError)
+		return
+	}
+	if req.CategoryID != nil {
+		var hh any
+		if hhID != "" {
+			hh = hhID
+		}
+		res, err := tx.Exec(`
+			INSERT INTO merchant_category_defaults (merchant_id, user_id, household_id, category_id)
+			SELECT $1, $2, $3, c.id FROM categories c
+			WHERE c.id = $4 AND (c.user_id IS NULL OR c.user_id = $2 OR c.household_id = $3)
+		`, merchantID, userID, hh, *req.CategoryID)
+		if err != nil {
+			log.Printf("SetMerchantDefaultCategory insert error: %v", err)
+			http.Error(w, "Failed to update merchant", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondValidationError(w, []ValidationError{{Field: "category_id", Message: "category not found"}})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Commit error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"merchant_id": merchantID, "default_category_id": req.CategoryID})
+}
+
+// BackfillTransactionMerchants links bank transactions synced before merchant
+// normalization existed to their merchant, using the note as the raw
+// description. This is a one-time management endpoint.
+// POST /auth/transactions/backfill-merchants
+func BackfillTransactionMerchants(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	dbClient, err := merchantsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer dbClient.Close()
+
+	hhID := db.ResolveHouseholdID(dbClient.Raw(), userID)
+
+	rows, err := dbClient.Query(`
+		SELECT id, note
+		FROM transactions
+		WHERE merchant_id IS NULL AND source = 'bank' AND note IS NOT NULL AND note != ''
+		  AND (user_id = $1 OR household_id::text = $2)
+	`, userID, hhID)
+	if err != nil {
+		log.Printf("BackfillTransactionMerchants query error: %v", err)
+		http.Error(w, "Failed to query transactions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	type pendingTx struct{ id, note string }
+	var pending []pendingTx
+	for rows.Next() {
+		var p pendingTx
+		if err := rows.Scan(&p.id, &p.note); err != nil {
+			log.Printf("BackfillTransactionMerchants scan error: %v", err)
+			continue
+		}
+		pending = append(pending, p)
+	}
+
+	// Many rows share a description; resolve each distinct one once.
+	resolved := map[string]string{}
+	updated := 0
+	for _, p := range pending {
+		merchantID, seen := resolved[p.note]
+		if !seen {
+			m, err := merchants.Resolve(dbClient.Raw(), p.note, "")
+			if err != nil {
+				log.Printf("BackfillTransactionMerchants resolve error for tx %s: %v", p.id, err)
+				continue
+			}
+			if m != nil {
+				merchantID = m.ID
+			}
+			resolved[p.note] = merchantID
+		}
+		if merchantID == "" {
+			continue
+		}
+		if _, err := dbClient.Exec(`UPDATE transactions SET merchant_id = $1 WHERE id = $2`, merchantID, p.id); err != nil {
+			log.Printf("BackfillTransactionMerchants update error for tx %s: %v", p.id, err)
+			continue
+		}
+		updated++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total_pending": len(pending),
+		"updated":       updated,
+	})
+}
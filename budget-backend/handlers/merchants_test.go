@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/gorilla/mux"
+)
+
+const (
+	merchantTestUser = "11111111-1111-1111-1111-111111111111"
+	merchantTestID   = "22222222-2222-2222-2222-222222222222"
+	merchantTestCat  = "33333333-3333-3333-3333-333333333333"
+)
+
+func withMerchantsMockDB(t *testing.T, setup func(sqlmock.Sqlmock)) {
+	t.Helper()
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockSQL.Close() })
+
+	oldFactory := merchantsDBFactory
+	merchantsDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	t.Cleanup(func() { merchantsDBFactory = oldFactory })
+
+	setup(mock)
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+}
+
+func setMerchantDefault(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPut, "/auth/merchants/"+merchantTestID+"/default-category", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, merchantTestUser))
+	req = mux.SetURLVars(req, map[string]string{"id": merchantTestID})
+	rr := httptest.NewRecorder()
+	SetMerchantDefaultCategory(rr, req)
+	return rr
+}
+
+// expectMerchantDefaultStart mocks the household lookup, the merchant check
+// and clearing the household's current default.
+func expectMerchantDefaultStart(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT household_id FROM household_members`).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow("hh1"))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM merchants`).
+		WithArgs(merchantTestID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM merchant_category_defaults`).
+		WithArgs(merchantTestUser, "hh1", merchantTestID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestSetMerchantDefaultCategory_SetsHouseholdDefault(t *testing.T) {
+	withMerchantsMockDB(t, func(mock sqlmock.Sqlmock) {
+		expectMerchantDefaultStart(mock)
+		mock.ExpectExec(`INSERT INTO merchant_category_defaults`).
+			WithArgs(merchantTestID, merchantTestUser, "hh1", merchantTestCat).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	})
+
+	rr := setMerchantDefault(t, `{"category_id":"`+merchantTestCat+`"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestSetMerchantDefaultCategory_NullClears(t *testing.T) {
+	withMerchantsMockDB(t, func(mock sqlmock.Sqlmock) {
+		expectMerchantDefaultStart(mock)
+		mock.ExpectCommit()
+	})
+
+	rr := setMerchantDefault(t, `{"category_id":null}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestSetMerchantDefaultCategory_UnknownCategoryKeepsDefault(t *testing.T) {
+	withMerchantsMockDB(t, func(mock sqlmock.Sqlmock) {
+		expectMerchantDefaultStart(mock)
+		mock.ExpectExec(`INSERT INTO merchant_category_defaults`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+	})
+
+	rr := setMerchantDefault(t, `{"category_id":"`+merchantTestCat+`"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
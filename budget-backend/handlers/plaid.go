@@ -11,7 +11,7 @@ import (
 	"time"
 
 	"github.com/aboogie/budget-backend/db"
-	"github.com/aboogie/budget-backend/internal/categories"
+	"github.com/aboogie/budget-backend/internal/investments"
 	"github.com/aboogie/budget-backend/models"
 	"github.com/gofrs/uuid"
 	"github.com/plaid/plaid-go/v20/plaid"
@@ -22,6 +22,9 @@ type exchangeTokenRequest struct {
 	UserID      string `json:"user_id"`
 	HouseholdID string `json:"household_id,omitempty"`
 	Institution string `json:"institution_name,omitempty"`
+	// ExcludedAccountIDs are Plaid account_ids the user chose not to sync
+	// (e.g. business or kids' accounts in the same login).
+	ExcludedAccountIDs []string `json:"excluded_account_ids,omitempty"`
 }
 
 type exchangeTokenResponse struct {
@@ -70,6 +73,7 @@ func ExchangeToken(client *models.Client) http.HandlerFunc {
 				req.Institution,
 				"plaid",
 			)
+			seedAccountSettings(dbClient, client, linkedID, req, resp.GetAccessToken())
 		}
 
 		json.NewEncoder(w).Encode(exchangeTokenResponse{
@@ -79,6 +83,48 @@ func ExchangeToken(client *models.Client) http.HandlerFunc {
 	}
 }
 
+// seedAccountSettings creates account_balances rows for a newly linked item so
+// per-account settings exist before the first sync, and switches off sync for
+// accounts the user excluded during linking.
+func seedAccountSettings(dbClient *db.DB, client *models.Client, linkedID string, req exchangeTokenRequest, accessToken string) {
+	resp, _, err := client.API.PlaidApi.AccountsGet(context.Background()).
+		AccountsGetRequest(*plaid.NewAccountsGetRequest(accessToken)).
+		Execute()
+	if err != nil {
+		log.Printf("seedAccountSettings accounts fetch failed for %s: %v", linkedID, err)
+		return
+	}
+
+	excluded := map[string]bool{}
+	for _, accountID := range req.ExcludedAccountIDs {
+		excluded[accountID] = true
+	}
+
+	hh := scopedHouseholdID("inherit", nil, req.HouseholdID)
+	for _, pa := range resp.GetAccounts() {
+		if excluded[pa.GetAccountId()] {
+			// Keep the setting but not the balance, which would otherwise
+			// sit frozen in net worth.
+			if _, err := dbClient.Exec(`
+				INSERT INTO account_balances
+					(id, user_id, household_id, linked_account_id, plaid_account_id,
+					 name, type, institution_name, current_balance, sync_enabled, updated_at)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8, NULL, false, NOW())
+				ON CONFLICT (user_id, plaid_account_id) DO UPDATE SET
+					sync_enabled = false,
+					updated_at = NOW()
+			`, uuid.Must(uuid.NewV4()).String(), req.UserID, hh, linkedID, pa.GetAccountId(),
+				pa.GetName(), string(pa.GetType()), req.Institution); err != nil {
+				log.Printf("seedAccountSettings exclude %s failed: %v", pa.GetAccountId(), err)
+			}
+			continue
+		}
+		if err := upsertPlaidAccountBalance(dbClient, req.UserID, hh, linkedID, req.Institution, pa); err != nil {
+			log.Printf("seedAccountSettings upsert failed: %v", err)
+		}
+	}
+}
+
 // CreateLinkToken issues a one-time link_token for Plaid Link.
 func CreateLinkToken(client *models.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -176,11 +222,13 @@ func SyncTransactions(client *models.Client) http.HandlerFunc {
 		var syncErrors []string
 
 		for _, acct := range accounts {
-			// Use Plaid TransactionsSync to get incremental updates.
-			// For simplicity we use an empty cursor (full sync) — in production you'd
-			// persist the cursor per linked_account for incremental syncs.
+			// Use Plaid TransactionsSync from an empty cursor (full sync). Rows are
+			// keyed by provider transaction ID so repeated syncs don't duplicate;
+			// incremental updates arrive via webhooks using linked_accounts.last_cursor.
 			cursor := ""
 			hasMore := true
+			var syncErr error
+			settings := loadAccountSettings(dbClient, acct.id)
 
 			for hasMore {
 				syncReq := plaid.NewTransactionsSyncRequest(acct.accessToken)
@@ -194,79 +242,29 @@ func SyncTransactions(client *models.Client) http.HandlerFunc {
 				if err != nil {
 					log.Printf("Plaid sync failed for account %s: %v", acct.id, err)
 					syncErrors = append(syncErrors, acct.id)
+					syncErr = err
 					break
 				}
 
 				added := resp.GetAdded()
 				for _, tx := range added {
-					txID := uuid.Must(uuid.NewV4()).String()
-					txType := "expense"
-					amount := tx.GetAmount()
-					// Plaid amounts: positive = money leaving account (expense),
-					// negative = money entering (income/refund).
-					if amount < 0 {
-						txType = "income"
-						amount = -amount
-					}
-
-					catName := ""
-					plaidCats := tx.GetCategory()
-					if len(plaidCats) > 0 {
-						catName = plaidCats[0]
+					acctSettings, known := settings[tx.GetAccountId()]
+					if known && !acctSettings.SyncEnabled {
+						continue
 					}
 
-					effectiveHH := acct.householdID
-					if effectiveHH == nil && hhID != "" {
-						effectiveHH = &hhID
+					var accountBalanceID *string
+					if known {
+						accountBalanceID = &acctSettings.AccountBalanceID
 					}
+					effectiveHH := scopedHouseholdID(acctSettings.Scope, acct.householdID, hhID)
 
-					// Resolve category using mapping rules
-					var resolvedCatID *string
-					var matchConfidence *string
-					var matchedRuleID *string
-					merchantName := tx.GetMerchantName()
-					if merchantName == "" {
-						merchantName = tx.GetName()
-					}
-					hhForResolver := ""
-					if effectiveHH != nil {
-						hhForResolver = *effectiveHH
-					}
-					catID, conf, ruleID, resolveErr := categories.ResolveCategory(dbClient.Conn, userID, hhForResolver, merchantName, plaidCats)
-					if resolveErr != nil {
-						log.Printf("Category resolve error (non-fatal): %v", resolveErr)
-					}
-					if catID != "" {
-						resolvedCatID = &catID
-					}
-					if conf != "" && conf != "low" {
-						matchConfidence = &conf
-					}
-					if ruleID != nil {
-						matchedRuleID = ruleID
+					outcome, err := upsertPlaidTransaction(dbClient, tx, userID, effectiveHH, accountBalanceID, false)
+					if err != nil {
+						log.Printf("Failed to insert Plaid transaction: %v", err)
+						continue
 					}
-
-					source := "bank"
-					_, insertErr := dbClient.Exec(`
-						INSERT INTO transactions (id, user_id, household_id, type, amount, category_id, category_name, note, date, source, match_confidence, matched_rule_id)
-						VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-						ON CONFLICT DO NOTHING
-					`,
-						txID,
-						userID,
-						effectiveHH,
-						txType,
-						amount,
-						resolvedCatID,
-						catName,
-						tx.GetName(),
-						tx.GetDate(),
-						source,
-						matchConfidence,
-						matchedRuleID,
-					)
-					if insertErr != nil {
-						log.Printf("Failed to insert Plaid transaction: %v", insertErr)
+					if outcome != syncOutcomeAdded {
 						continue
 					}
 					totalSynced++
@@ -275,6 +273,12 @@ func SyncTransactions(client *models.Client) http.HandlerFunc {
 				cursor = resp.GetNextCursor()
 				hasMore = resp.GetHasMore()
 			}
+
+			if syncErr != nil {
+				recordSyncFailure(dbClient, acct.id, syncProductTransactions, syncErr)
+			} else {
+				recordSyncSuccess(dbClient, acct.id, syncProductTransactions)
+			}
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -409,6 +413,7 @@ func SyncInvestments(client *models.Client) http.HandlerFunc {
 		}
 
 		totalSynced := 0
+		transactionsSynced := 0
 		var syncErrors []string
 
 		for _, acct := range accounts {
@@ -419,6 +424,7 @@ func SyncInvestments(client *models.Client) http.HandlerFunc {
 			if err != nil {
 				log.Printf("Plaid investments sync failed for account %s: %v", acct.id, err)
 				syncErrors = append(syncErrors, acct.id)
+				recordSyncFailure(dbClient, acct.id, syncProductInvestments, err)
 				continue
 			}
 
@@ -484,10 +490,24 @@ func SyncInvestments(client *models.Client) http.HandlerFunc {
 				}
 				totalSynced++
 			}
+
+			// Buys, sells, dividends and fees feed the performance analytics.
+			txSynced, err := syncInvestmentTransactions(context.Background(), dbClient, client, acct.id, acct.accessToken, userID, effectiveHH)
+			if err != nil {
+				log.Printf("Plaid investment transactions sync failed for account %s: %v", acct.id, err)
+				syncErrors = append(syncErrors, acct.id)
+				recordSyncFailure(dbClient, acct.id, syncProductInvestments, err)
+			} else {
+				recordSyncSuccess(dbClient, acct.id, syncProductInvestments)
+			}
+			transactionsSynced += txSynced
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		result := map[string]interface{}{"synced": totalSynced}
+		result := map[string]interface{}{
+			"synced":              totalSynced,
+			"transactions_synced": transactionsSynced,
+		}
 		if len(syncErrors) > 0 {
 			result["failed_accounts"] = syncErrors
 		}
@@ -495,6 +515,102 @@ func SyncInvestments(client *models.Client) http.HandlerFunc {
 	}
 }
 
+// investmentHistoryMonths is how far back investment transactions are pulled
+// (Plaid keeps up to 24 months).
+const investmentHistoryMonths = 24
+
+// syncInvestmentTransactions pages through /investments/transactions/get for one
+// linked account and upserts each row by Plaid's investment_transaction_id.
+func syncInvestmentTransactions(ctx context.Context, dbClient *db.DB, client *models.Client,
+	linkedAccountID, accessToken, userID string, householdID *string) (int, error) {
+	now := time.Now().UTC()
+	startDate := now.AddDate(0, -investmentHistoryMonths, 0).Format("2006-01-02")
+	endDate := now.Format("2006-01-02")
+
+	synced := 0
+	offset := int32(0)
+	for {
+		req := plaid.NewInvestmentsTransactionsGetRequest(accessToken, startDate, endDate)
+		opts := plaid.NewInvestmentsTransactionsGetRequestOptions()
+		opts.SetCount(500)
+		opts.SetOffset(offset)
+		req.SetOptions(*opts)
+
+		resp, _, err := client.API.PlaidApi.InvestmentsTransactionsGet(ctx).
+			InvestmentsTransactionsGetRequest(*req).
+			Execute()
+		if err != nil {
+			return synced, err
+		}
+
+		secMap := map[string]plaid.Security{}
+		for _, s := range resp.GetSecurities() {
+			secMap[s.GetSecurityId()] = s
+		}
+
+		page := resp.GetInvestmentTransactions()
+		for _, t := range page {
+			var securityID, secName, ticker, secType *string
+			if v, ok := t.GetSecurityIdOk(); ok && v != nil && *v != "" {
+				securityID = v
+				sec := secMap[*v]
+				if n, ok := sec.GetNameOk(); ok && n != nil {
+					secName = n
+				}
+				if tk, ok := sec.GetTickerSymbolOk(); ok && tk != nil {
+					ticker = tk
+				}
+				if st, ok := sec.GetTypeOk(); ok && st != nil {
+					secType = st
+				}
+			}
+
+			currency := "USD"
+			if v, ok := t.GetIsoCurrencyCodeOk(); ok && v != nil && *v != "" {
+				currency = *v
+			}
+
+			_, err := dbClient.Exec(`
+				INSERT INTO investment_transactions
+					(id, user_id, household_id, linked_account_id, plaid_account_id,
+					 plaid_investment_transaction_id, plaid_security_id,
+					 security_name, ticker_symbol, security_type,
+					 type, subtype, name, quantity, price, amount, fees,
+					 iso_currency_code, date)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19)
+				ON CONFLICT (plaid_investment_transaction_id) DO UPDATE SET
+					type = EXCLUDED.type,
+					subtype = EXCLUDED.subtype,
+					name = EXCLUDED.name,
+					quantity = EXCLUDED.quantity,
+					price = EXCLUDED.price,
+					amount = EXCLUDED.amount,
+					fees = EXCLUDED.fees,
+					date = EXCLUDED.date,
+					updated_at = NOW()
+			`,
+				uuid.Must(uuid.NewV4()).String(), userID, householdID, linkedAccountID, t.GetAccountId(),
+				t.GetInvestmentTransactionId(), securityID,
+				secName, ticker, secType,
+				string(t.GetType()), string(t.GetSubtype()), t.GetName(),
+				t.GetQuantity(), t.GetPrice(), t.GetAmount(), t.GetFees(),
+				currency, t.GetDate(),
+			)
+			if err != nil {
+				log.Printf("Failed to upsert investment transaction: %v", err)
+				continue
+			}
+			synced++
+		}
+
+		offset += int32(len(page))
+		if len(page) == 0 || offset >= resp.GetTotalInvestmentTransactions() {
+			break
+		}
+	}
+	return synced, nil
+}
+
 // SyncLiabilities pulls liabilities from Plaid for all linked accounts
 // belonging to a user and upserts them into the liabilities table.
 // POST /auth/plaid/liabilities?user_id=...
@@ -560,8 +676,10 @@ func SyncLiabilities(client *models.Client) http.HandlerFunc {
 			if err != nil {
 				log.Printf("Plaid liabilities sync failed for account %s: %v", acct.id, err)
 				syncErrors = append(syncErrors, acct.id)
+				recordSyncFailure(dbClient, acct.id, syncProductLiabilities, err)
 				continue
 			}
+			recordSyncSuccess(dbClient, acct.id, syncProductLiabilities)
 
 			effectiveHH := acct.householdID
 			if effectiveHH == nil && hhID != "" {
@@ -773,6 +891,11 @@ func SyncLiabilities(client *models.Client) http.HandlerFunc {
 					log.Printf("Failed to upsert debt for plaid acct %s: %v", aid, debtErr)
 					continue
 				}
+				if err := inDebtLedgerTx(dbClient.Raw(), func(q ledgerExecer) error {
+					return reconcileLinkedDebt(q, debtID, time.Now().UTC())
+				}); err != nil {
+					log.Printf("Failed to reconcile debt ledger for %s: %v", debtID, err)
+				}
 
 				// Auto-create a bill linked to this debt if none exists yet.
 				var billCount int
@@ -855,6 +978,36 @@ func GetInvestmentHoldings(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(holdings)
 }
 
+// GetInvestmentPerformance returns time- and money-weighted returns, realized
+// and unrealized gains, and allocation by security type.
+// GET /auth/plaid/investments/performance?user_id=...
+func GetInvestmentPerformance(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "Missing user_id", http.StatusBadRequest)
+		return
+	}
+
+	dbClient, err := db.New()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer dbClient.Close()
+
+	hhID := db.ResolveHouseholdID(dbClient.Conn, userID)
+
+	perf, err := investments.Analyze(dbClient.Conn, userID, hhID)
+	if err != nil {
+		log.Printf("GetInvestmentPerformance error: %v", err)
+		http.Error(w, "query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(perf)
+}
+
 // GetLiabilities returns all liabilities for a user.
 // GET /auth/plaid/liabilities?user_id=...
 func GetLiabilities(w http.ResponseWriter, r *http.Request) {
@@ -978,68 +1131,20 @@ func SyncAccountBalances(client *models.Client) http.HandlerFunc {
 				Execute()
 			if err != nil {
 				log.Printf("Plaid balance sync failed for account %s: %v", acct.id, err)
+				recordSyncFailure(dbClient, acct.id, syncProductBalances, err)
 				continue
 			}
-
-			effectiveHH := acct.householdID
-			if effectiveHH == nil && hhID != "" {
-				effectiveHH = &hhID
-			}
+			recordSyncSuccess(dbClient, acct.id, syncProductBalances)
+			settings := loadAccountSettings(dbClient, acct.id)
 
 			for _, pa := range resp.GetAccounts() {
-				acctType := string(pa.GetType())
-				subtype := ""
-				if st, ok := pa.GetSubtypeOk(); ok && st != nil {
-					subtype = string(*st)
-				}
-
-				bal := pa.GetBalances()
-				current := bal.GetCurrent()
-				var available *float64
-				if v := bal.GetAvailable(); v > 0 {
-					available = &v
-				}
-
-				currency := "USD"
-				if v, ok := bal.GetIsoCurrencyCodeOk(); ok && v != nil && *v != "" {
-					currency = *v
-				}
-
-				var mask *string
-				if v, ok := pa.GetMaskOk(); ok && v != nil {
-					mask = v
-				}
-
-				var officialName *string
-				if v, ok := pa.GetOfficialNameOk(); ok && v != nil {
-					officialName = v
+				acctSettings, known := settings[pa.GetAccountId()]
+				if known && !acctSettings.SyncEnabled {
+					continue
 				}
-
-				newID := uuid.Must(uuid.NewV4()).String()
-				_, insertErr := dbClient.Exec(`
-					INSERT INTO account_balances
-						(id, user_id, household_id, linked_account_id, plaid_account_id,
-						 name, official_name, type, subtype, current_balance, available_balance,
-						 iso_currency_code, institution_name, mask, updated_at)
-					VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14, NOW())
-					ON CONFLICT (user_id, plaid_account_id) DO UPDATE SET
-						name = EXCLUDED.name,
-						official_name = EXCLUDED.official_name,
-						type = EXCLUDED.type,
-						subtype = EXCLUDED.subtype,
-						current_balance = EXCLUDED.current_balance,
-						available_balance = EXCLUDED.available_balance,
-						iso_currency_code = EXCLUDED.iso_currency_code,
-						institution_name = EXCLUDED.institution_name,
-						mask = EXCLUDED.mask,
-						updated_at = NOW()
-				`,
-					newID, userID, effectiveHH, acct.id, pa.GetAccountId(),
-					pa.GetName(), officialName, acctType, nullableStr(subtype),
-					current, available, currency, acct.institutionName, mask,
-				)
-				if insertErr != nil {
-					log.Printf("Failed to upsert account balance: %v", insertErr)
+				effectiveHH := scopedHouseholdID(acctSettings.Scope, acct.householdID, hhID)
+				if err := upsertPlaidAccountBalance(dbClient, userID, effectiveHH, acct.id, acct.institutionName, pa); err != nil {
+					log.Printf("Failed to upsert account balance: %v", err)
 					continue
 				}
 				totalSynced++
@@ -1051,6 +1156,66 @@ func SyncAccountBalances(client *models.Client) http.HandlerFunc {
 	}
 }
 
+// upsertPlaidAccountBalance inserts or refreshes the account_balances row for
+// one Plaid account. Per-account settings and household scope are left alone
+// on update.
+func upsertPlaidAccountBalance(dbClient *db.DB, userID string, householdID *string, linkedAccountID, institutionName string, pa plaid.AccountBase) error {
+	acctType := string(pa.GetType())
+	subtype := ""
+	if st, ok := pa.GetSubtypeOk(); ok && st != nil {
+		subtype = string(*st)
+	}
+
+	bal := pa.GetBalances()
+	current := bal.GetCurrent()
+	var available *float64
+	if v := bal.GetAvailable(); v > 0 {
+		available = &v
+	}
+
+	currency := "USD"
+	if v, ok := bal.GetIsoCurrencyCodeOk(); ok && v != nil && *v != "" {
+		currency = *v
+	}
+
+	var mask *string
+	if v, ok := pa.GetMaskOk(); ok && v != nil {
+		mask = v
+	}
+
+	var officialName *string
+	if v, ok := pa.GetOfficialNameOk(); ok && v != nil {
+		officialName = v
+	}
+
+	newID := uuid.Must(uuid.NewV4()).String()
+	_, err := dbClient.Exec(`
+		INSERT INTO account_balances
+			(id, user_id, household_id, linked_account_id, plaid_account_id,
+			 name, official_name, type, subtype, current_balance, available_balance,
+			 iso_currency_code, institution_name, mask, is_liability, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15, NOW())
+		ON CONFLICT (user_id, plaid_account_id) DO UPDATE SET
+			name = EXCLUDED.name,
+			official_name = EXCLUDED.official_name,
+			type = EXCLUDED.type,
+			is_liability = EXCLUDED.is_liability,
+			subtype = EXCLUDED.subtype,
+			current_balance = EXCLUDED.current_balance,
+			available_balance = EXCLUDED.available_balance,
+			iso_currency_code = EXCLUDED.iso_currency_code,
+			institution_name = EXCLUDED.institution_name,
+			mask = EXCLUDED.mask,
+			updated_at = NOW()
+	`,
+		newID, userID, householdID, linkedAccountID, pa.GetAccountId(),
+		pa.GetName(), officialName, acctType, nullableStr(subtype),
+		current, available, currency, institutionName, mask,
+		isLiabilityAccountType(acctType),
+	)
+	return err
+}
+
 // GetAccountBalances returns cached account balances for a user.
 // GET /auth/plaid/balances?user_id=...&type=depository (optional type filter)
 func GetAccountBalances(w http.ResponseWriter, r *http.Request) {
@@ -1073,7 +1238,8 @@ func GetAccountBalances(w http.ResponseWriter, r *http.Request) {
 		balRows, err = dbClient.Query(`
 			SELECT id, user_id, household_id, linked_account_id, plaid_account_id,
 			       name, official_name, type, subtype, current_balance, available_balance,
-			       iso_currency_code, institution_name, mask, created_at, updated_at
+			       iso_currency_code, institution_name, mask, is_manual, is_liability, notes,
+			       sync_enabled, include_in_budgets, scope, created_at, updated_at
 			FROM account_balances WHERE user_id = $1 AND type = $2
 			ORDER BY current_balance DESC
 		`, userID, typeFilter)
@@ -1081,7 +1247,8 @@ func GetAccountBalances(w http.ResponseWriter, r *http.Request) {
 		balRows, err = dbClient.Query(`
 			SELECT id, user_id, household_id, linked_account_id, plaid_account_id,
 			       name, official_name, type, subtype, current_balance, available_balance,
-			       iso_currency_code, institution_name, mask, created_at, updated_at
+			       iso_currency_code, institution_name, mask, is_manual, is_liability, notes,
+			       sync_enabled, include_in_budgets, scope, created_at, updated_at
 			FROM account_balances WHERE user_id = $1
 			ORDER BY current_balance DESC
 		`, userID)
@@ -1094,14 +1261,8 @@ func GetAccountBalances(w http.ResponseWriter, r *http.Request) {
 
 	var balances []models.AccountBalance
 	for balRows.Next() {
-		var b models.AccountBalance
-		if err := balRows.Scan(
-			&b.ID, &b.UserID, &b.HouseholdID, &b.LinkedAccountID,
-			&b.PlaidAccountID, &b.Name, &b.OfficialName, &b.Type, &b.Subtype,
-			&b.CurrentBalance, &b.AvailableBalance,
-			&b.IsoCurrencyCode, &b.InstitutionName, &b.Mask,
-			&b.CreatedAt, &b.UpdatedAt,
-		); err != nil {
+		b, err := scanAccountBalance(balRows)
+		if err != nil {
 			log.Printf("Failed to scan account balance: %v", err)
 			continue
 		}
@@ -1137,63 +1298,110 @@ func nullableBool(b bool) interface{} {
 	return b
 }
 
-// GetLinkedAccountStatus returns all linked accounts for the user with status information.
+// GetLinkedAccountStatus returns all linked accounts for the user with status information:
+// last successful sync per product, failure streaks, a user-facing error reason,
+// consent expiry, and an update-mode link token for items the user must re-authenticate.
 // GET /auth/linked-accounts/status
-func GetLinkedAccountStatus(w http.ResponseWriter, r *http.Request) {
-	userID := r.Header.Get("X-User-ID")
-	if userID == "" {
-		userID, _ = getUserIDFromRequest(r)
-	}
-	if userID == "" {
-		http.Error(w, "Missing user ID", http.StatusUnauthorized)
-		return
-	}
+func GetLinkedAccountStatus(client *models.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Header.Get("X-User-ID")
+		if userID == "" {
+			userID, _ = getUserIDFromRequest(r)
+		}
+		if userID == "" {
+			http.Error(w, "Missing user ID", http.StatusUnauthorized)
+			return
+		}
 
-	dbClient, err := db.New()
-	if err != nil {
-		http.Error(w, "DB connection error", http.StatusInternalServerError)
-		return
-	}
-	defer dbClient.Close()
+		dbClient, err := db.New()
+		if err != nil {
+			http.Error(w, "DB connection error", http.StatusInternalServerError)
+			return
+		}
+		defer dbClient.Close()
 
-	// Get household ID if user is in a household
-	hhID := db.ResolveHouseholdID(dbClient.Conn, userID)
+		// Get household ID if user is in a household
+		hhID := db.ResolveHouseholdID(dbClient.Conn, userID)
 
-	// Query linked accounts for the user and household members
-	rows, err := dbClient.Query(`
-		SELECT id, institution_name, item_status, error_code, created_at, updated_at, provider
-		FROM linked_accounts
-		WHERE user_id = $1 OR household_id = $2
-		ORDER BY created_at DESC
-	`, userID, nullable(hhID))
-	if err != nil {
-		http.Error(w, "Query error", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
+		// Query linked accounts for the user and household members
+		rows, err := dbClient.Query(`
+			SELECT id, user_id, COALESCE(access_token, ''), COALESCE(institution_name, ''), item_status, error_code,
+			       consent_expiration_time, last_webhook_at, created_at, updated_at, provider
+			FROM linked_accounts
+			WHERE user_id = $1 OR household_id = $2
+			ORDER BY created_at DESC
+		`, userID, nullable(hhID))
+		if err != nil {
+			http.Error(w, "Query error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
 
-	type LinkedAccountStatus struct {
-		ID              string    `json:"id"`
-		InstitutionName string    `json:"institution_name"`
-		ItemStatus      string    `json:"item_status"`
-		ErrorCode       *string   `json:"error_code"`
-		CreatedAt       time.Time `json:"created_at"`
-		UpdatedAt       time.Time `json:"updated_at"`
-		Provider        string    `json:"provider"`
-	}
+		accounts := []models.LinkedAccountHealth{}
+		owners := map[string]string{}
+		tokens := map[string]string{}
+		index := map[string]int{}
+		for rows.Next() {
+			var acct models.LinkedAccountHealth
+			var ownerID, accessToken string
+			if err := rows.Scan(&acct.ID, &ownerID, &accessToken, &acct.InstitutionName, &acct.ItemStatus, &acct.ErrorCode,
+				&acct.ConsentExpirationTime, &acct.LastWebhookAt, &acct.CreatedAt, &acct.UpdatedAt, &acct.Provider); err != nil {
+				log.Printf("Failed to scan linked account: %v", err)
+				continue
+			}
+			acct.ErrorReason = itemErrorReason(acct.ItemStatus, acct.ErrorCode)
+			acct.NeedsReauth = itemNeedsReauth(acct.ItemStatus, acct.ErrorCode)
+			acct.Products = []models.ProductSyncStatus{}
+			owners[acct.ID] = ownerID
+			tokens[acct.ID] = accessToken
+			index[acct.ID] = len(accounts)
+			accounts = append(accounts, acct)
+		}
+		rows.Close()
+
+		statusRows, err := dbClient.Query(`
+			SELECT s.linked_account_id, s.product, s.last_attempt_at, s.last_success_at,
+			       s.last_error_code, s.consecutive_failures
+			FROM linked_account_sync_status s
+			JOIN linked_accounts la ON la.id = s.linked_account_id
+			WHERE la.user_id = $1 OR la.household_id = $2
+			ORDER BY s.product
+		`, userID, nullable(hhID))
+		if err != nil {
+			log.Printf("GetLinkedAccountStatus sync status error: %v", err)
+		} else {
+			defer statusRows.Close()
+			for statusRows.Next() {
+				var laID string
+				var ps models.ProductSyncStatus
+				if err := statusRows.Scan(&laID, &ps.Product, &ps.LastAttemptAt, &ps.LastSuccessAt,
+					&ps.LastErrorCode, &ps.ConsecutiveFailures); err != nil {
+					log.Printf("GetLinkedAccountStatus scan sync status error: %v", err)
+					continue
+				}
+				if i, ok := index[laID]; ok {
+					accounts[i].Products = append(accounts[i].Products, ps)
+				}
+			}
+		}
 
-	var accounts []LinkedAccountStatus
-	for rows.Next() {
-		var acct LinkedAccountStatus
-		if err := rows.Scan(&acct.ID, &acct.InstitutionName, &acct.ItemStatus, &acct.ErrorCode, &acct.CreatedAt, &acct.UpdatedAt, &acct.Provider); err != nil {
-			log.Printf("Failed to scan linked account: %v", err)
-			continue
+		// Only the member who linked an item can run Link against it.
+		for i := range accounts {
+			acct := &accounts[i]
+			if !acct.NeedsReauth || owners[acct.ID] != userID || acct.Provider != "plaid" {
+				continue
+			}
+			token, err := createUpdateLinkToken(r.Context(), client, userID, tokens[acct.ID])
+			if err != nil {
+				log.Printf("GetLinkedAccountStatus update link token error for %s: %v", acct.ID, err)
+				continue
+			}
+			acct.UpdateLinkToken = token
 		}
-		accounts = append(accounts, acct)
-	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(accounts)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(accounts)
+	}
 }
 
 // CreateUpdateLinkToken creates an update-mode link token for re-authenticating an account.
@@ -1243,19 +1451,7 @@ func CreateUpdateLinkToken(client *models.Client) http.HandlerFunc {
 			return
 		}
 
-		// Create update-mode link token
-		user := plaid.LinkTokenCreateRequestUser{ClientUserId: userID}
-		linkReq := plaid.NewLinkTokenCreateRequest("Budget App", "en", []plaid.CountryCode{plaid.COUNTRYCODE_US}, user)
-		// For update mode, set AccessToken instead of Products
-		linkReq.SetAccessToken(accessToken)
-		if redirect := os.Getenv("PLAID_REDIRECT_URI"); redirect != "" {
-			linkReq.SetRedirectUri(redirect)
-		}
-
-		resp, _, err := client.API.PlaidApi.LinkTokenCreate(context.Background()).
-			LinkTokenCreateRequest(*linkReq).
-			Execute()
-
+		linkToken, err := createUpdateLinkToken(context.Background(), client, userID, accessToken)
 		if err != nil {
 			http.Error(w, "Failed to create link token: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -1263,7 +1459,7 @@ func CreateUpdateLinkToken(client *models.Client) http.HandlerFunc {
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
-			"link_token": resp.GetLinkToken(),
+			"link_token": linkToken,
 		})
 	}
 }
@@ -1295,7 +1491,7 @@ func ResetItemError(w http.ResponseWriter, r *http.Request) {
 	// Update the account status
 	result, err := dbClient.Exec(`
 		UPDATE linked_accounts
-		SET item_status = 'good', error_code = NULL, updated_at = NOW()
+		SET item_status = 'good', error_code = NULL, reauth_notified_at = NULL, updated_at = NOW()
 		WHERE id = $1 AND user_id = $2
 	`, accountID, userID)
 
@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/categories"
+	"github.com/aboogie/budget-backend/internal/merchants"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/mux"
+	"github.com/plaid/plaid-go/v20/plaid"
+)
+
+// transactionHistoryDays is how far back a fresh sync asks Plaid to go (24 months).
+const transactionHistoryDays = 730
+
+var resyncModes = []string{"backfill", "rebuild"}
+
+// Outcomes of storing one Plaid transaction.
+const (
+	syncOutcomeAdded   = "added"
+	syncOutcomeUpdated = "updated"
+	syncOutcomeSkipped = "skipped"
+)
+
+// plaidTransactionType converts Plaid's signed amount into our type + positive
+// amount. Plaid amounts: positive = money leaving account (expense),
+// negative = money entering (income/refund).
+func plaidTransactionType(amount float64) (string, float64) {
+	if amount < 0 {
+		return "income", -amount
+	}
+	return "expense", amount
+}
+
+// resolvePlaidCategory runs a Plaid transaction through the category resolver,
+// including advanced rules on amount, account and date. Low-confidence
+// matches keep the category but are not reported as a match.
+func resolvePlaidCategory(conn *sql.DB, userID, householdID string, tx plaid.Transaction, accountBalanceID, merchantID *string) (catID, confidence, ruleID *string, actions *models.RuleActions) {
+	txType, amount := plaidTransactionType(tx.GetAmount())
+	in := categories.Transaction{
+		Merchant:        plaidMerchantName(tx),
+		PlaidCategories: tx.GetCategory(),
+		Amount:          amount,
+		Type:            txType,
+	}
+	if accountBalanceID != nil {
+		in.AccountBalanceID = *accountBalanceID
+	}
+	if merchantID != nil {
+		in.MerchantID = *merchantID
+	}
+	if d, err := time.Parse("2006-01-02", tx.GetDate()); err == nil {
+		in.Date = d
+	}
+
+	res, err := categories.Resolve(conn, userID, householdID, in)
+	if err != nil {
+		log.Printf("Category resolve error (non-fatal): %v", err)
+	}
+	if res.CategoryID != "" {
+		catID = &res.CategoryID
+	}
+	if res.Confidence != "" && res.Confidence != "low" {
+		confidence = &res.Confidence
+	}
+	return catID, confidence, res.RuleID, res.Actions
+}
+
+// plaidMerchantName prefers Plaid's cleaned merchant name over the raw
+// description.
+func plaidMerchantName(tx plaid.Transaction) string {
+	if name := tx.GetMerchantName(); name != "" {
+		return name
+	}
+	return tx.GetName()
+}
+
+// resolvePlaidMerchant links a Plaid transaction to its merchant entity.
+func resolvePlaidMerchant(conn *sql.DB, tx plaid.Transaction) *string {
+	m, err := merchants.Resolve(conn, plaidMerchantName(tx), tx.GetLogoUrl())
+	if err != nil {
+		log.Printf("Merchant resolve error (non-fatal): %v", err)
+		return nil
+	}
+	if m == nil {
+		return nil
+	}
+	return &m.ID
+}
+
+// adoptLegacyTransaction attaches a provider ID to a bank row imported before
+// provider IDs were stored, so a re-import matches it instead of duplicating it.
+func adoptLegacyTransaction(dbClient *db.DB, tx plaid.Transaction, userID string) {
+	_, amount := plaidTransactionType(tx.GetAmount())
+	_, err := dbClient.Exec(`
+		UPDATE transactions SET plaid_transaction_id = $1
+		WHERE id = (
+			SELECT id FROM transactions
+			WHERE plaid_transaction_id IS NULL AND source = 'bank'
+			  AND user_id = $2 AND date = $3 AND amount = $4 AND note = $5
+			ORDER BY created_at
+			LIMIT 1
+		)
+	`, tx.GetTransactionId(), userID, tx.GetDate(), amount, tx.GetName())
+	if err != nil {
+		log.Printf("adoptLegacyTransaction %s error: %v", tx.GetTransactionId(), err)
+	}
+}
+
+// upsertPlaidTransaction stores one Plaid transaction keyed by its provider ID.
+// A matching row imported before provider IDs were stored is adopted first.
+// With refresh=false existing rows only get a missing account filled in. With
+// refresh=true amount, date, description and the resolved category are
+// updated too, except on rows the user has verified or split.
+func upsertPlaidTransaction(dbClient *db.DB, tx plaid.Transaction, userID string, householdID, accountBalanceID *string, refresh bool) (string, error) {
+	adoptLegacyTransaction(dbClient, tx, userID)
+	txType, amount := plaidTransactionType(tx.GetAmount())
+
+	catName := ""
+	if cats := tx.GetCategory(); len(cats) > 0 {
+		catName = cats[0]
+	}
+	hh := ""
+	if householdID != nil {
+		hh = *householdID
+	}
+	merchantID := resolvePlaidMerchant(dbClient.Conn, tx)
+	catID, confidence, ruleID, actions := resolvePlaidCategory(dbClient.Conn, userID, hh, tx, accountBalanceID, merchantID)
+
+	onConflict := `ON CONFLICT (plaid_transaction_id) WHERE plaid_transaction_id IS NOT NULL DO UPDATE SET
+			account_balance_id = COALESCE(transactions.account_balance_id, EXCLUDED.account_balance_id),
+			plaid_account_id = COALESCE(transactions.plaid_account_id, EXCLUDED.plaid_account_id)
+		WHERE (transactions.account_balance_id IS NULL AND EXCLUDED.account_balance_id IS NOT NULL)
+		   OR transactions.plaid_account_id IS NULL`
+	if refresh {
+		onConflict = `ON CONFLICT (plaid_transaction_id) WHERE plaid_transaction_id IS NOT NULL DO UPDATE SET
+			type = EXCLUDED.type,
+			amount = EXCLUDED.amount,
+			date = EXCLUDED.date,
+			note = EXCLUDED.note,
+			category_id = EXCLUDED.category_id,
+			category_name = EXCLUDED.category_name,
+			match_confidence = EXCLUDED.match_confidence,
+			matched_rule_id = EXCLUDED.matched_rule_id,
+			account_balance_id = COALESCE(EXCLUDED.account_balance_id, transactions.account_balance_id),
+			plaid_account_id = EXCLUDED.plaid_account_id,
+			merchant_id = COALESCE(EXCLUDED.merchant_id, transactions.merchant_id),
+			updated_at = NOW()
+		WHERE NOT COALESCE(transactions.user_verified, false)
+		  AND NOT COALESCE(transactions.is_split, false)`
+	}
+
+	var id string
+	var inserted bool
+	err := dbClient.QueryRow(`
+		INSERT INTO transactions (id, user_id, household_id, type, amount, category_id, category_name, note, date, source,
+			match_confidence, matched_rule_id, account_balance_id, plaid_transaction_id, merchant_id, plaid_account_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'bank', $10, $11, $12, $13, $14, $15)
+		`+onConflict+`
+		RETURNING id, (xmax = 0)
+	`,
+		uuid.Must(uuid.NewV4()).String(), userID, householdID, txType, amount,
+		catID, catName, tx.GetName(), tx.GetDate(),
+		confidence, ruleID, accountBalanceID, tx.GetTransactionId(), merchantID, tx.GetAccountId(),
+	).Scan(&id, &inserted)
+	if err == sql.ErrNoRows {
+		return syncOutcomeSkipped, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !inserted && !refresh {
+		return syncOutcomeSkipped, nil
+	}
+	if err := applyRuleActions(dbClient, id, amount, actions); err != nil {
+		log.Printf("applyRuleActions %s error: %v", id, err)
+	}
+	if inserted {
+		if txType == "income" {
+			matchIncomingReimbursement(dbClient, id)
+		}
+		return syncOutcomeAdded, nil
+	}
+	return syncOutcomeUpdated, nil
+}
+
+// removePlaidTransactions deletes rows Plaid reports as removed, keeping any
+// the user has verified or split. Attachments on a removed row are deleted
+// with it.
+func removePlaidTransactions(dbClient *db.DB, removed []plaid.RemovedTransaction) int {
+	const guard = `plaid_transaction_id = $1
+		  AND NOT COALESCE(user_verified, false)
+		  AND NOT COALESCE(is_split, false)`
+	count := 0
+	for _, r := range removed {
+		n, blobKeys, err := removePlaidTransaction(dbClient, guard, r.GetTransactionId())
+		if err != nil {
+			log.Printf("removePlaidTransactions %s error: %v", r.GetTransactionId(), err)
+			continue
+		}
+		removeAttachmentBlobs(context.Background(), blobKeys)
+		count += n
+	}
+	return count
+}
+
+// removePlaidTransaction deletes the rows matching guard and their
+// attachments in one transaction, returning the attachments' file keys.
+func removePlaidTransaction(dbClient *db.DB, guard, plaidTxID string) (int, []string, error) {
+	tx, err := dbClient.Conn.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+	blobKeys, err := detachAttachments(tx, `transaction_id IN (SELECT id FROM transactions WHERE `+guard+`)`, plaidTxID)
+	if err != nil {
+		return 0, nil, err
+	}
+	res, err := tx.Exec(`DELETE FROM transactions WHERE `+guard, plaidTxID)
+	if err != nil {
+		return 0, nil, err
+	}
+	n, _ := res.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return int(n), blobKeys, nil
+}
+
+const resyncJobColumns = `id, linked_account_id, user_id, mode, status, pages_fetched,
+	added, updated, skipped, removed, error, started_at, finished_at, created_at`
+
+func scanResyncJob(row interface{ Scan(...any) error }) (models.PlaidResyncJob, error) {
+	var j models.PlaidResyncJob
+	err := row.Scan(&j.ID, &j.LinkedAccountID, &j.UserID, &j.Mode, &j.Status, &j.PagesFetched,
+		&j.Added, &j.Updated, &j.Skipped, &j.Removed, &j.Error, &j.StartedAt, &j.FinishedAt, &j.CreatedAt)
+	return j, err
+}
+
+// ResyncLinkedAccount resets the transaction cursor for a linked item and
+// re-imports its full history in the background. Only one job per item can
+// be queued or running at a time.
+// POST /auth/linked-accounts/{id}/resync  {"mode": "backfill" | "rebuild"}
+func ResyncLinkedAccount(client *models.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Header.Get("X-User-ID")
+		if userID == "" {
+			http.Error(w, "Missing user ID", http.StatusUnauthorized)
+			return
+		}
+		linkedAccountID := mux.Vars(r)["id"]
+
+		var req struct {
+			Mode string `json:"mode"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		if req.Mode == "" {
+			req.Mode = "backfill"
+		}
+		if e := validateEnum(req.Mode, "mode", resyncModes); e != nil {
+			respondValidationError(w, []ValidationError{*e})
+			return
+		}
+
+		dbClient, err := db.New()
+		if err != nil {
+			http.Error(w, "DB connection error", http.StatusInternalServerError)
+			return
+		}
+
+		var accessToken string
+		var itemHH *string
+		err = dbClient.QueryRow(`
+			SELECT COALESCE(access_token, ''), household_id FROM linked_accounts
+			WHERE id = $1 AND user_id = $2 AND provider = 'plaid'
+		`, linkedAccountID, userID).Scan(&accessToken, &itemHH)
+		if err != nil || accessToken == "" {
+			dbClient.Close()
+			http.Error(w, "Account not found", http.StatusNotFound)
+			return
+		}
+
+		var active int
+		_ = dbClient.QueryRow(`
+			SELECT COUNT(*) FROM plaid_resync_jobs
+			WHERE linked_account_id = $1 AND status IN ('queued', 'running')
+		`, linkedAccountID).Scan(&active)
+		if active > 0 {
+			dbClient.Close()
+			http.Error(w, "A resync is already in progress for this account", http.StatusConflict)
+			return
+		}
+
+		job, err := scanResyncJob(dbClient.QueryRow(`
+			INSERT INTO plaid_resync_jobs (id, linked_account_id, user_id, mode)
+			VALUES ($1, $2, $3, $4)
+			RETURNING `+resyncJobColumns,
+			uuid.Must(uuid.NewV4()).String(), linkedAccountID, userID, req.Mode,
+		))
+		if err != nil {
+			dbClient.Close()
+			log.Printf("ResyncLinkedAccount insert error: %v", err)
+			http.Error(w, "Failed to start resync", http.StatusInternalServerError)
+			return
+		}
+
+		// The job owns the connection from here on.
+		go func() {
+			defer dbClient.Close()
+			runResyncJob(dbClient, client, job, accessToken, itemHH)
+		}()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+	}
+}
+
+// GetResyncStatus returns the most recent resync job for a linked item.
+// GET /auth/linked-accounts/{id}/resync
+func GetResyncStatus(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	if userID == "" {
+		http.Error(w, "Missing user ID", http.StatusUnauthorized)
+		return
+	}
+
+	dbClient, err := db.New()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer dbClient.Close()
+
+	job, err := scanResyncJob(dbClient.QueryRow(`
+		SELECT `+resyncJobColumns+`
+		FROM plaid_resync_jobs
+		WHERE linked_account_id = $1 AND user_id = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, mux.Vars(r)["id"], userID))
+	if err == sql.ErrNoRows {
+		http.Error(w, "No resync found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("GetResyncStatus error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// runResyncJob pages through /transactions/sync from an empty cursor,
+// reporting progress on the job row after every page. The final cursor is
+// saved so webhooks carry on incrementally afterwards.
+func runResyncJob(dbClient *db.DB, client *models.Client, job models.PlaidResyncJob, accessToken string, itemHH *string) {
+	ctx := context.Background()
+	refresh := job.Mode == "rebuild"
+
+	if _, err := dbClient.Exec(`
+		UPDATE plaid_resync_jobs SET status = 'running', started_at = NOW() WHERE id = $1
+	`, job.ID); err != nil {
+		log.Printf("resync %s start error: %v", job.ID, err)
+	}
+	if _, err := dbClient.Exec(`UPDATE linked_accounts SET last_cursor = '' WHERE id = $1`, job.LinkedAccountID); err != nil {
+		log.Printf("resync %s cursor reset error: %v", job.ID, err)
+	}
+
+	settings := loadAccountSettings(dbClient, job.LinkedAccountID)
+	userHH := db.ResolveHouseholdID(dbClient.Conn, job.UserID)
+
+	fail := func(err error) {
+		log.Printf("resync %s failed: %v", job.ID, err)
+		recordSyncFailure(dbClient, job.LinkedAccountID, syncProductTransactions, err)
+		if _, uerr := dbClient.Exec(`
+			UPDATE plaid_resync_jobs SET status = 'failed', error = $1, finished_at = NOW() WHERE id = $2
+		`, err.Error(), job.ID); uerr != nil {
+			log.Printf("resync %s status error: %v", job.ID, uerr)
+		}
+	}
+
+	cursor := ""
+	hasMore := true
+	for hasMore {
+		syncReq := plaid.NewTransactionsSyncRequest(accessToken)
+		if cursor != "" {
+			syncReq.SetCursor(cursor)
+		} else {
+			opts := plaid.NewTransactionsSyncRequestOptions()
+			opts.SetDaysRequested(int32(transactionHistoryDays))
+			syncReq.SetOptions(*opts)
+		}
+
+		resp, _, err := client.API.PlaidApi.TransactionsSync(ctx).
+			TransactionsSyncRequest(*syncReq).
+			Execute()
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		for _, tx := range append(resp.GetAdded(), resp.GetModified()...) {
+			acctSettings, known := settings[tx.GetAccountId()]
+			if known && !acctSettings.SyncEnabled {
+				job.Skipped++
+				continue
+			}
+			var accountBalanceID *string
+			if known {
+				accountBalanceID = &acctSettings.AccountBalanceID
+			}
+
+			outcome, err := upsertPlaidTransaction(dbClient, tx, job.UserID,
+				scopedHouseholdID(acctSettings.Scope, itemHH, userHH), accountBalanceID, refresh)
+			if err != nil {
+				log.Printf("resync %s upsert %s error: %v", job.ID, tx.GetTransactionId(), err)
+				job.Skipped++
+				continue
+			}
+			switch outcome {
+			case syncOutcomeAdded:
+				job.Added++
+			case syncOutcomeUpdated:
+				job.Updated++
+			default:
+				job.Skipped++
+			}
+		}
+		job.Removed += removePlaidTransactions(dbClient, resp.GetRemoved())
+		job.PagesFetched++
+
+		if _, err := dbClient.Exec(`
+			UPDATE plaid_resync_jobs
+			SET pages_fetched = $1, added = $2, updated = $3, skipped = $4, removed = $5
+			WHERE id = $6
+		`, job.PagesFetched, job.Added, job.Updated, job.Skipped, job.Removed, job.ID); err != nil {
+			log.Printf("resync %s progress error: %v", job.ID, err)
+		}
+
+		cursor = resp.GetNextCursor()
+		hasMore = resp.GetHasMore()
+	}
+
+	if _, err := dbClient.Exec(`UPDATE linked_accounts SET last_cursor = $1 WHERE id = $2`, cursor, job.LinkedAccountID); err != nil {
+		log.Printf("resync %s cursor save error: %v", job.ID, err)
+	}
+	recordSyncSuccess(dbClient, job.LinkedAccountID, syncProductTransactions)
+
+	if _, err := dbClient.Exec(`
+		UPDATE plaid_resync_jobs SET status = 'completed', finished_at = NOW() WHERE id = $1
+	`, job.ID); err != nil {
+		log.Printf("resync %s status error: %v", job.ID, err)
+	}
+	log.Printf("resync %s completed: %d added, %d updated, %d skipped, %d removed",
+		job.ID, job.Added, job.Updated, job.Skipped, job.Removed)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/gorilla/mux"
+	"github.com/plaid/plaid-go/v20/plaid"
+)
+
+func TestPlaidTransactionType(t *testing.T) {
+	if typ, amt := plaidTransactionType(42.5); typ != "expense" || amt != 42.5 {
+		t.Errorf("expected expense 42.5, got %s %v", typ, amt)
+	}
+	if typ, amt := plaidTransactionType(-1200); typ != "income" || amt != 1200 {
+		t.Errorf("expected income 1200, got %s %v", typ, amt)
+	}
+}
+
+func TestRemovePlaidTransactions_KeepsUserEdits(t *testing.T) {
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+
+	// The first row is gone; the second was verified by the user so the
+	// guarded DELETE matches nothing.
+	for _, id := range []string{"ptx-1", "ptx-2"} {
+		var deleted int64
+		if id == "ptx-1" {
+			deleted = 1
+		}
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM attachments WHERE transaction_id IN`).
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key", "thumbnail_key"}))
+		mock.ExpectExec(`DELETE FROM transactions WHERE plaid_transaction_id = \$1\s+AND NOT COALESCE\(user_verified, false\)`).
+			WithArgs(id).
+			WillReturnResult(sqlmock.NewResult(0, deleted))
+		mock.ExpectCommit()
+	}
+
+	removed := []plaid.RemovedTransaction{}
+	for _, id := range []string{"ptx-1", "ptx-2"} {
+		r := plaid.NewRemovedTransactionWithDefaults()
+		r.SetTransactionId(id)
+		removed = append(removed, *r)
+	}
+
+	if n := removePlaidTransactions(&db.DB{Conn: mockSQL}, removed); n != 1 {
+		t.Errorf("expected 1 removed, got %d", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unexpected queries: %v", err)
+	}
+}
+
+func TestResyncLinkedAccount_InvalidMode(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/auth/linked-accounts/la1/resync",
+		strings.NewReader(`{"mode":"everything"}`))
+	req.Header.Set("X-User-ID", "u1")
+	req = mux.SetURLVars(req, map[string]string{"id": "la1"})
+	rr := httptest.NewRecorder()
+
+	ResyncLinkedAccount(nil)(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestGetResyncStatus_MissingUser(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/auth/linked-accounts/la1/resync", nil)
+	rr := httptest.NewRecorder()
+
+	GetResyncStatus(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rr.Code)
+	}
+}
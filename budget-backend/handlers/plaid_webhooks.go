@@ -99,9 +99,16 @@ func processWebhookAsync(dbClient *db.DB, client *models.Client, req models.Plai
 	case "ITEM":
 		handleItemWebhook(dbClient, linkedAccountID, req)
 	case "HOLDINGS":
-		handleHoldingsWebhook(ctx, dbClient, client, accessToken, userID, householdID)
+		handleHoldingsWebhook(ctx, dbClient, client, linkedAccountID, accessToken, userID, householdID)
+	case "INVESTMENTS_TRANSACTIONS":
+		if _, err := syncInvestmentTransactions(ctx, dbClient, client, linkedAccountID, accessToken, userID, householdID); err != nil {
+			log.Printf("Failed to sync investment transactions: %v", err)
+			recordSyncFailure(dbClient, linkedAccountID, syncProductInvestments, err)
+		} else {
+			recordSyncSuccess(dbClient, linkedAccountID, syncProductInvestments)
+		}
 	case "LIABILITIES":
-		handleLiabilitiesWebhook(ctx, dbClient, client, accessToken, userID, householdID)
+		handleLiabilitiesWebhook(ctx, dbClient, client, linkedAccountID, accessToken, userID, householdID)
 	default:
 		log.Printf("Unknown webhook type: %s", req.WebhookType)
 	}
@@ -138,6 +145,22 @@ func handleTransactionsWebhook(ctx context.Context, dbClient *db.DB, client *mod
 		// Perform incremental transaction sync
 		cursor := lastCursor
 		hasMore := true
+		settings := loadAccountSettings(dbClient, linkedAccountID)
+		userHH := db.ResolveHouseholdID(dbClient.Conn, userID)
+		store := func(tx plaid.Transaction, refresh bool) {
+			acctSettings, known := settings[tx.GetAccountId()]
+			if known && !acctSettings.SyncEnabled {
+				return
+			}
+			var accountBalanceID *string
+			if known {
+				accountBalanceID = &acctSettings.AccountBalanceID
+			}
+			hh := scopedHouseholdID(acctSettings.Scope, householdID, userHH)
+			if _, err := upsertPlaidTransaction(dbClient, tx, userID, hh, accountBalanceID, refresh); err != nil {
+				log.Printf("Failed to store transaction %s: %v", tx.GetTransactionId(), err)
+			}
+		}
 
 		for hasMore {
 			syncReq := plaid.NewTransactionsSyncRequest(accessToken)
@@ -150,6 +173,7 @@ func handleTransactionsWebhook(ctx context.Context, dbClient *db.DB, client *mod
 				Execute()
 			if err != nil {
 				log.Printf("Plaid sync failed for item %s: %v", req.ItemID, err)
+				recordSyncFailure(dbClient, linkedAccountID, syncProductTransactions, err)
 				return
 			}
 
@@ -158,21 +182,17 @@ func handleTransactionsWebhook(ctx context.Context, dbClient *db.DB, client *mod
 
 			// Process added transactions
 			for _, tx := range added {
-				insertTransaction(dbClient, tx, userID, householdID)
+				store(tx, false)
 			}
 
-			// Process modified transactions
+			// Process modified transactions; rows the user verified or split are kept as-is
 			for _, tx := range modified {
-				// For modified transactions, update or insert
-				insertTransaction(dbClient, tx, userID, householdID)
+				store(tx, true)
 			}
 
-			// Get removed transaction IDs
-			removed := resp.GetRemoved()
-			for _, txID := range removed {
-				// Mark as removed or delete from the appropriate place
-				// For now we log it, but in production you might want a soft delete
-				log.Printf("Transaction removed: %v", txID)
+			// Delete removed transactions unless the user has edited them
+			if n := removePlaidTransactions(dbClient, resp.GetRemoved()); n > 0 {
+				log.Printf("Removed %d transactions for item %s", n, req.ItemID)
 			}
 
 			cursor = resp.GetNextCursor()
@@ -188,6 +208,7 @@ func handleTransactionsWebhook(ctx context.Context, dbClient *db.DB, client *mod
 		if err != nil {
 			log.Printf("Failed to update cursor for item %s: %v", req.ItemID, err)
 		}
+		recordSyncSuccess(dbClient, linkedAccountID, syncProductTransactions)
 
 	case "TRANSACTIONS_REMOVED":
 		// Handle removed transactions
@@ -197,41 +218,67 @@ func handleTransactionsWebhook(ctx context.Context, dbClient *db.DB, client *mod
 	}
 }
 
-// handleItemWebhook processes item-related webhooks
+// handleItemWebhook processes item-related webhooks. States the user has to
+// fix through Link update mode trigger a one-time push notification.
 func handleItemWebhook(dbClient *db.DB, linkedAccountID string, req models.PlaidWebhookRequest) {
+	var errorCode *string
+	if req.Error != nil && req.Error.ErrorCode != "" {
+		errorCode = &req.Error.ErrorCode
+	}
+
 	switch req.WebhookCode {
 	case "ERROR":
-		errorCode := ""
-		if req.Error != nil {
-			errorCode = req.Error.ErrorCode
-		}
 		_, err := dbClient.Exec(`
 			UPDATE linked_accounts
-			SET item_status = $1, error_code = $2
+			SET item_status = $1, error_code = $2, updated_at = NOW()
 			WHERE id = $3
 		`, "error", errorCode, linkedAccountID)
 		if err != nil {
 			log.Printf("Failed to update item status to error: %v", err)
+			return
+		}
+		if itemNeedsReauth("error", errorCode) {
+			notifyReauthNeeded(dbClient, linkedAccountID, itemErrorReason("error", errorCode))
 		}
 
-	case "PENDING_EXPIRATION":
+	case "PENDING_EXPIRATION", "PENDING_DISCONNECT":
+		var consentExpiry *time.Time
+		if req.ConsentExpirationTime != nil {
+			if t, err := time.Parse(time.RFC3339, *req.ConsentExpirationTime); err == nil {
+				consentExpiry = &t
+			}
+		}
 		_, err := dbClient.Exec(`
 			UPDATE linked_accounts
-			SET item_status = $1
-			WHERE id = $2
-		`, "pending_expiration", linkedAccountID)
+			SET item_status = $1, consent_expiration_time = COALESCE($2, consent_expiration_time), updated_at = NOW()
+			WHERE id = $3
+		`, "pending_expiration", consentExpiry, linkedAccountID)
 		if err != nil {
 			log.Printf("Failed to update item status to pending_expiration: %v", err)
+			return
 		}
+		notifyReauthNeeded(dbClient, linkedAccountID, itemErrorReason("pending_expiration", nil))
 
-	case "USER_PERMISSION_REVOKED":
+	case "USER_PERMISSION_REVOKED", "USER_ACCOUNT_REVOKED":
 		_, err := dbClient.Exec(`
 			UPDATE linked_accounts
-			SET item_status = $1
-			WHERE id = $2
-		`, "revoked", linkedAccountID)
+			SET item_status = $1, error_code = $2, updated_at = NOW()
+			WHERE id = $3
+		`, "revoked", errorCode, linkedAccountID)
 		if err != nil {
 			log.Printf("Failed to update item status to revoked: %v", err)
+			return
+		}
+		notifyReauthNeeded(dbClient, linkedAccountID, itemErrorReason("revoked", errorCode))
+
+	case "LOGIN_REPAIRED":
+		_, err := dbClient.Exec(`
+			UPDATE linked_accounts
+			SET item_status = 'good', error_code = NULL, reauth_notified_at = NULL, updated_at = NOW()
+			WHERE id = $1
+		`, linkedAccountID)
+		if err != nil {
+			log.Printf("Failed to update item status after login repair: %v", err)
 		}
 
 	case "WEBHOOK_UPDATE_ACKNOWLEDGED":
@@ -245,7 +292,7 @@ func handleItemWebhook(dbClient *db.DB, linkedAccountID string, req models.Plaid
 
 // handleHoldingsWebhook processes holdings-related webhooks
 func handleHoldingsWebhook(ctx context.Context, dbClient *db.DB, client *models.Client,
-	accessToken, userID string, householdID *string) {
+	linkedAccountID, accessToken, userID string, householdID *string) {
 	log.Printf("Processing holdings sync for user: %s", userID)
 
 	// Fetch investment holdings and store them
@@ -255,8 +302,10 @@ func handleHoldingsWebhook(ctx context.Context, dbClient *db.DB, client *models.
 		Execute()
 	if err != nil {
 		log.Printf("Failed to get investment holdings: %v", err)
+		recordSyncFailure(dbClient, linkedAccountID, syncProductInvestments, err)
 		return
 	}
+	recordSyncSuccess(dbClient, linkedAccountID, syncProductInvestments)
 
 	holdings := resp.GetHoldings()
 	for _, holding := range holdings {
@@ -282,7 +331,7 @@ func handleHoldingsWebhook(ctx context.Context, dbClient *db.DB, client *models.
 
 // handleLiabilitiesWebhook processes liabilities-related webhooks
 func handleLiabilitiesWebhook(ctx context.Context, dbClient *db.DB, client *models.Client,
-	accessToken, userID string, householdID *string) {
+	linkedAccountID, accessToken, userID string, householdID *string) {
 	log.Printf("Processing liabilities sync for user: %s", userID)
 
 	// Fetch liabilities and store them
@@ -292,8 +341,10 @@ func handleLiabilitiesWebhook(ctx context.Context, dbClient *db.DB, client *mode
 		Execute()
 	if err != nil {
 		log.Printf("Failed to get liabilities: %v", err)
+		recordSyncFailure(dbClient, linkedAccountID, syncProductLiabilities, err)
 		return
 	}
+	recordSyncSuccess(dbClient, linkedAccountID, syncProductLiabilities)
 
 	liabs := resp.GetLiabilities()
 	for _, cc := range liabs.GetCredit() {
@@ -320,50 +371,3 @@ func handleLiabilitiesWebhook(ctx context.Context, dbClient *db.DB, client *mode
 		}
 	}
 }
-
-// insertTransaction inserts or updates a single transaction
-func insertTransaction(dbClient *db.DB, tx plaid.Transaction, userID string, householdID *string) {
-	txID := uuid.Must(uuid.NewV4()).String()
-	txType := "expense"
-	amount := tx.GetAmount()
-
-	// Plaid amounts: positive = money leaving account (expense),
-	// negative = money entering (income/refund).
-	if amount < 0 {
-		txType = "income"
-		amount = -amount
-	}
-
-	catName := ""
-	if cats := tx.GetCategory(); len(cats) > 0 {
-		catName = cats[0]
-	}
-
-	source := "bank"
-	date := time.Now()
-	if txDate := tx.GetDate(); txDate != "" {
-		// Parse the date if it's in the expected format
-		if parsed, err := time.Parse("2006-01-02", txDate); err == nil {
-			date = parsed
-		}
-	}
-
-	_, err := dbClient.Exec(`
-		INSERT INTO transactions (id, user_id, household_id, type, amount, category_name, note, date, source, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
-		ON CONFLICT DO NOTHING
-	`,
-		txID,
-		userID,
-		householdID,
-		txType,
-		amount,
-		catName,
-		tx.GetName(),
-		date,
-		source,
-	)
-	if err != nil {
-		log.Printf("Failed to insert transaction: %v", err)
-	}
-}
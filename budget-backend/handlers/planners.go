@@ -399,57 +399,6 @@ func UpdateDebt(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(d)
 }
 
-func ApplyDebtPayment(w http.ResponseWriter, r *http.Request) {
-	debtID := mux.Vars(r)["id"]
-	if debtID == "" {
-		http.Error(w, "Missing debt id", http.StatusBadRequest)
-		return
-	}
-
-	var body struct {
-		Amount float64 `json:"amount"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "Invalid body", http.StatusBadRequest)
-		return
-	}
-
-	client, err := db.New()
-	if err != nil {
-		http.Error(w, "DB connection error", http.StatusInternalServerError)
-		return
-	}
-	defer client.Close()
-
-	// Decrease balance but not below zero
-	_, err = client.Exec(`
-		UPDATE debt_accounts
-		SET balance = GREATEST(balance - $1, 0)
-		WHERE id = $2
-	`, body.Amount, debtID)
-	if err != nil {
-		http.Error(w, "Update error", http.StatusInternalServerError)
-		return
-	}
-
-	var updated models.DebtAccount
-	row := client.QueryRow(`
-		SELECT id, user_id, name, balance, apr, min_payment, due_day, COALESCE(strategy, ''), is_shared
-		FROM debt_accounts WHERE id = $1
-	`, debtID)
-	var dueDay sql.NullInt32
-	if err := row.Scan(&updated.ID, &updated.UserID, &updated.Name, &updated.Balance, &updated.APR, &updated.MinPayment, &dueDay, &updated.Strategy, &updated.IsShared); err != nil {
-		http.Error(w, "Debt not found", http.StatusNotFound)
-		return
-	}
-	if dueDay.Valid {
-		val := int(dueDay.Int32)
-		updated.DueDay = &val
-	}
-
-	json.NewEncoder(w).Encode(updated)
-}
-
 func ListFinancialPriorities(w http.ResponseWriter, r *http.Request) {
 	userID, err := sanitizeUserID(r.URL.Query().Get("user_id"))
 	if err != nil {
@@ -3,11 +3,13 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/valuation"
 	"github.com/aboogie/budget-backend/models"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -17,13 +19,6 @@ var propertiesDBFactory = func() (db.DBTX, error) {
 	return db.New()
 }
 
-func nilIfZero(v float64) any {
-	if v == 0 {
-		return nil
-	}
-	return v
-}
-
 func nilIfEmpty(s string) any {
 	if s == "" {
 		return nil
@@ -50,13 +45,13 @@ func ListProperties(w http.ResponseWriter, r *http.Request) {
 	query := `
 		SELECT p.id, p.user_id, COALESCE(p.household_id::text, ''),
 		       p.street_address, p.city, p.state, p.zip_code,
-		       p.zestimate, p.manual_value,
+		       p.zestimate, p.manual_value, p.original_value,
 		       COALESCE(p.zillow_url, ''), COALESCE(p.zpid, ''),
 		       p.debt_account_id, p.last_fetched_at, p.is_shared,
-		       d.name, d.balance
+		       d.name, d.balance, v.value, v.source, v.valued_on
 		FROM properties p
 		LEFT JOIN debt_accounts d ON p.debt_account_id = d.id
-	`
+	` + latestValuationJoin
 
 	var rows *sql.Rows
 	if hh == "" {
@@ -82,20 +77,23 @@ func ListProperties(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var p models.Property
 		var hhID sql.NullString
-		var zest, manVal sql.NullFloat64
+		var zest, manVal, origVal sql.NullFloat64
 		var zURL, zpid sql.NullString
 		var debtAcctID sql.NullString
 		var lastFetched sql.NullTime
 		var debtName sql.NullString
 		var debtBalance sql.NullFloat64
+		var latestValue sql.NullFloat64
+		var latestSource sql.NullString
+		var latestOn sql.NullTime
 
 		if err := rows.Scan(
 			&p.ID, &p.UserID, &hhID,
 			&p.StreetAddress, &p.City, &p.State, &p.ZipCode,
-			&zest, &manVal,
+			&zest, &manVal, &origVal,
 			&zURL, &zpid,
 			&debtAcctID, &lastFetched, &p.IsShared,
-			&debtName, &debtBalance,
+			&debtName, &debtBalance, &latestValue, &latestSource, &latestOn,
 		); err != nil {
 			log.Printf("ListProperties scan error: %v", err)
 			continue
@@ -110,6 +108,9 @@ func ListProperties(w http.ResponseWriter, r *http.Request) {
 		if manVal.Valid {
 			p.ManualValue = &manVal.Float64
 		}
+		if origVal.Valid {
+			p.OriginalValue = &origVal.Float64
+		}
 		if zURL.Valid && zURL.String != "" {
 			p.ZillowURL = &zURL.String
 		}
@@ -128,6 +129,7 @@ func ListProperties(w http.ResponseWriter, r *http.Request) {
 		if debtBalance.Valid {
 			p.DebtBalance = &debtBalance.Float64
 		}
+		scanLatestValuation(&p, latestValue, latestSource, latestOn)
 
 		properties = append(properties, p)
 	}
@@ -181,23 +183,25 @@ func CreateProperty(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Attempt Zillow scrape — save whatever we get (ZPID/URL even without Zestimate)
-	result, scrapeErr := FetchZestimate(p.StreetAddress, p.City, p.State, p.ZipCode)
-	if result != nil {
-		if result.Zestimate > 0 {
-			p.Zestimate = &result.Zestimate
-			now := time.Now()
-			p.LastFetchedAt = &now
-		}
-		if result.ZillowURL != "" {
-			p.ZillowURL = &result.ZillowURL
-		}
-		if result.ZPID != "" {
-			p.ZPID = &result.ZPID
-		}
+	// Value the new property with the configured providers. It has no
+	// history yet, so only a valuation API can answer.
+	var estimate *valuation.Estimate
+	chain, err := propertyValuers()
+	if err == nil {
+		estimate, err = chain.Value(r.Context(), valuation.Subject{
+			PropertyID:    p.ID,
+			StreetAddress: p.StreetAddress,
+			City:          p.City,
+			State:         p.State,
+			ZipCode:       p.ZipCode,
+		})
+	}
+	if err != nil {
+		log.Printf("CreateProperty: valuation failed: %v", err)
 	}
-	if scrapeErr != nil {
-		log.Printf("CreateProperty: Zillow scrape failed: %v", scrapeErr)
+	if estimate != nil {
+		now := time.Now()
+		p.LastFetchedAt = &now
 	}
 
 	var hhVal, debtVal any
@@ -214,15 +218,27 @@ func CreateProperty(w http.ResponseWriter, r *http.Request) {
 
 	_, err = client.Exec(`
 		INSERT INTO properties (id, user_id, household_id, street_address, city, state, zip_code,
-		  zestimate, manual_value, zillow_url, zpid, debt_account_id, last_fetched_at, is_shared)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+		  zestimate, manual_value, original_value, zillow_url, zpid, debt_account_id, last_fetched_at, is_shared)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)
 	`, p.ID, p.UserID, hhVal, p.StreetAddress, p.City, p.State, p.ZipCode,
-		p.Zestimate, p.ManualValue, p.ZillowURL, p.ZPID, debtVal, p.LastFetchedAt, p.IsShared)
+		p.Zestimate, p.ManualValue, p.OriginalValue, p.ZillowURL, p.ZPID, debtVal, p.LastFetchedAt, p.IsShared)
 	if err != nil {
 		log.Printf("CreateProperty insert error: %v", err)
 		http.Error(w, "Insert error", http.StatusInternalServerError)
 		return
 	}
+	if estimate != nil {
+		if err := recordPropertyValuation(client, p.ID, estimate.Value, estimate.Source, estimate.AsOf); err != nil {
+			log.Printf("CreateProperty valuation error: %v", err)
+		}
+		valuedOn := estimate.AsOf.Format("2006-01-02")
+		p.LatestValue, p.LatestValueSource, p.LatestValuedOn = &estimate.Value, &estimate.Source, &valuedOn
+	}
+	if p.ManualValue != nil && *p.ManualValue > 0 {
+		if err := recordPropertyValuation(client, p.ID, *p.ManualValue, "manual", time.Now()); err != nil {
+			log.Printf("CreateProperty valuation error: %v", err)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -265,22 +281,32 @@ func UpdateProperty(w http.ResponseWriter, r *http.Request) {
 		debtVal = *p.DebtAccountID
 	}
 
-	res, err := client.Exec(`
-		UPDATE properties
+	// Return the manual value as it was so only an actual change is recorded
+	// as a valuation; otherwise editing the address would make a stale
+	// manual value look current.
+	var oldManual sql.NullFloat64
+	err = client.QueryRow(`
+		UPDATE properties p
 		SET street_address=$1, city=$2, state=$3, zip_code=$4,
-		    manual_value=$5, debt_account_id=$6, is_shared=$7, updated_at=NOW()
-		WHERE id=$8
+		    manual_value=$5, original_value=$6, debt_account_id=$7, is_shared=$8, updated_at=NOW()
+		FROM (SELECT id, manual_value FROM properties WHERE id=$9 FOR UPDATE) old
+		WHERE p.id = old.id
+		RETURNING old.manual_value
 	`, p.StreetAddress, p.City, p.State, p.ZipCode,
-		p.ManualValue, debtVal, p.IsShared, propID)
+		p.ManualValue, p.OriginalValue, debtVal, p.IsShared, propID).Scan(&oldManual)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Property not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Printf("UpdateProperty error: %v", err)
 		http.Error(w, "Update error", http.StatusInternalServerError)
 		return
 	}
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		http.Error(w, "Property not found", http.StatusNotFound)
-		return
+	if p.ManualValue != nil && *p.ManualValue > 0 && (!oldManual.Valid || oldManual.Float64 != *p.ManualValue) {
+		if err := recordPropertyValuation(client, propID, *p.ManualValue, "manual", time.Now()); err != nil {
+			log.Printf("UpdateProperty valuation error: %v", err)
+		}
 	}
 
 	p.ID = propID
@@ -321,6 +347,9 @@ func DeleteProperty(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// RefreshPropertyValue asks the configured valuation providers for a new
+// value and adds it to the property's valuation history.
+// POST /auth/properties/{id}/refresh?user_id=
 func RefreshPropertyValue(w http.ResponseWriter, r *http.Request) {
 	propID := mux.Vars(r)["id"]
 	if propID == "" {
@@ -341,14 +370,8 @@ func RefreshPropertyValue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Load existing property
-	var street, city, state, zip string
 	var lastFetched sql.NullTime
-	err = client.QueryRow(`
-		SELECT street_address, city, state, zip_code, last_fetched_at
-		FROM properties WHERE id = $1
-	`, propID).Scan(&street, &city, &state, &zip, &lastFetched)
-	if err != nil {
+	if err := client.QueryRow(`SELECT last_fetched_at FROM properties WHERE id = $1`, propID).Scan(&lastFetched); err != nil {
 		http.Error(w, "Property not found", http.StatusNotFound)
 		return
 	}
@@ -359,35 +382,47 @@ func RefreshPropertyValue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, scrapeErr := FetchZestimate(street, city, state, zip)
+	subject, err := valuationSubject(client, propID)
+	if err != nil {
+		log.Printf("RefreshPropertyValue load error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
 
-	// Save any partial results (ZPID/URL) even if Zestimate extraction failed
-	now := time.Now()
-	if result != nil && (result.Zestimate > 0 || result.ZPID != "" || result.ZillowURL != "") {
-		_, err = client.Exec(`
-			UPDATE properties
-			SET zestimate=COALESCE($1, zestimate), zillow_url=COALESCE($2, zillow_url),
-			    zpid=COALESCE($3, zpid), last_fetched_at=$4, updated_at=NOW()
-			WHERE id=$5
-		`, nilIfZero(result.Zestimate), nilIfEmpty(result.ZillowURL), nilIfEmpty(result.ZPID), now, propID)
-		if err != nil {
-			log.Printf("RefreshPropertyValue update error: %v", err)
-			http.Error(w, "Update error", http.StatusInternalServerError)
-			return
+	chain, err := propertyValuers()
+	if err != nil {
+		log.Printf("RefreshPropertyValue: valuation providers: %v", err)
+		http.Error(w, "Property valuation is not configured", http.StatusInternalServerError)
+		return
+	}
+	estimate, err := chain.Value(r.Context(), subject)
+	if err != nil {
+		log.Printf("RefreshPropertyValue: %v", err)
+		if errors.Is(err, valuation.ErrNoValue) {
+			http.Error(w, "No value available for this property", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to fetch property value", http.StatusBadGateway)
 		}
+		return
 	}
 
-	if scrapeErr != nil && (result == nil || result.Zestimate == 0) {
-		log.Printf("RefreshPropertyValue: scrape failed: %v", scrapeErr)
-		http.Error(w, "Failed to fetch value from Zillow: "+scrapeErr.Error(), http.StatusBadGateway)
+	now := time.Now()
+	if err := recordPropertyValuation(client, propID, estimate.Value, estimate.Source, estimate.AsOf); err != nil {
+		log.Printf("RefreshPropertyValue valuation error: %v", err)
+		http.Error(w, "Update error", http.StatusInternalServerError)
+		return
+	}
+	if _, err := client.Exec(`UPDATE properties SET last_fetched_at=$1, updated_at=NOW() WHERE id=$2`, now, propID); err != nil {
+		log.Printf("RefreshPropertyValue update error: %v", err)
+		http.Error(w, "Update error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"zestimate":       result.Zestimate,
-		"zillow_url":      result.ZillowURL,
-		"zpid":            result.ZPID,
+		"value":           estimate.Value,
+		"source":          estimate.Source,
+		"valued_on":       estimate.AsOf.Format("2006-01-02"),
 		"last_fetched_at": now,
 	})
 }
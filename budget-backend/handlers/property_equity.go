@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/debts"
+	"github.com/aboogie/budget-backend/internal/equity"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+)
+
+// defaultEquityExtras are the extra principal scenarios shown when the
+// request doesn't ask for specific amounts.
+var defaultEquityExtras = []float64{100, 250, 500}
+
+// GetPropertyEquity returns a property's equity and loan-to-value ratio now
+// and month by month, when PMI can come off, and how extra principal
+// payments change the payoff.
+// GET /auth/properties/{id}/equity?extra=100,250
+func GetPropertyEquity(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	propID := mux.Vars(r)["id"]
+	if propID == "" {
+		http.Error(w, "Missing property id", http.StatusBadRequest)
+		return
+	}
+
+	extras := defaultEquityExtras
+	if raw := r.URL.Query().Get("extra"); raw != "" {
+		extras = nil
+		for _, part := range strings.Split(raw, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || v <= 0 || v > 100000 {
+				respondValidationError(w, []ValidationError{{Field: "extra", Message: "must be amounts between 0 and 100000, separated by commas"}})
+				return
+			}
+			extras = append(extras, v)
+		}
+	}
+
+	client, err := propertiesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), "properties", propID, userID) {
+		return
+	}
+
+	p, err := loadEquityProperty(client, propID)
+	if err != nil {
+		http.Error(w, "Property not found", http.StatusNotFound)
+		return
+	}
+	valuations, err := loadPropertyValuations(client, propID)
+	if err != nil {
+		log.Printf("GetPropertyEquity valuations error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	setLatestValuation(&p, valuations)
+
+	now := time.Now().UTC()
+	out := models.PropertyEquity{
+		Property:     p,
+		CurrentValue: p.EffectiveValue(),
+		Valuations:   valuations,
+		History:      []models.EquityPoint{},
+		Scenarios:    []models.EquityScenario{},
+	}
+	var values []equity.Valuation
+	for _, v := range valuations {
+		on, _ := time.Parse("2006-01-02", v.ValuedOn)
+		values = append(values, equity.Valuation{Date: on, Value: v.Value})
+	}
+
+	switch {
+	case p.OriginalValue != nil && *p.OriginalValue > 0:
+		out.OriginalValue, out.OriginalValueSource = *p.OriginalValue, "property"
+	case len(valuations) > 0:
+		out.OriginalValue, out.OriginalValueSource = valuations[0].Value, "valuation_history"
+	case out.CurrentValue > 0:
+		out.OriginalValue, out.OriginalValueSource = out.CurrentValue, "current_value"
+	}
+
+	var balances []models.DebtProgressPoint
+	if p.DebtAccountID != nil {
+		m, err := loadEquityMortgage(client, *p.DebtAccountID, &out)
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("GetPropertyEquity mortgage error: %v", err)
+			http.Error(w, "Query error", http.StatusInternalServerError)
+			return
+		}
+		if err == nil {
+			entries, err := loadEquityLedger(client, *p.DebtAccountID)
+			if err != nil {
+				log.Printf("GetPropertyEquity ledger error: %v", err)
+				http.Error(w, "Query error", http.StatusInternalServerError)
+				return
+			}
+			balances = debts.Summarize(entries, m.Balance, now).Months
+
+			m.OriginalValue = out.OriginalValue
+			if out.HasPMI == nil || *out.HasPMI {
+				out.PMIRequestDate, out.PMIAutoDate = equity.PMIDates(m, now)
+			}
+			out.Scenarios = equity.Scenarios(m, extras, now)
+		}
+	}
+
+	out.Equity = math.Round((out.CurrentValue-out.MortgageBalance)*100) / 100
+	out.LTV = equity.LTV(out.MortgageBalance, out.CurrentValue)
+	out.History = equity.History(values, balances, out.CurrentValue, out.MortgageBalance, now)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// loadEquityProperty loads the property with its linked mortgage's name.
+func loadEquityProperty(conn db.DBTX, propID string) (models.Property, error) {
+	var p models.Property
+	var hhID, debtID, debtName sql.NullString
+	var zest, manVal, origVal, debtBalance sql.NullFloat64
+	err := conn.QueryRow(`
+		SELECT p.id, p.user_id, p.household_id::text, p.street_address, p.city, p.state, p.zip_code,
+		       p.zestimate, p.manual_value, p.original_value, p.debt_account_id::text, p.is_shared,
+		       d.name, d.balance
+		FROM properties p
+		LEFT JOIN debt_accounts d ON p.debt_account_id = d.id
+		WHERE p.id = $1
+	`, propID).Scan(&p.ID, &p.UserID, &hhID, &p.StreetAddress, &p.City, &p.State, &p.ZipCode,
+		&zest, &manVal, &origVal, &debtID, &p.IsShared, &debtName, &debtBalance)
+	if err != nil {
+		return p, err
+	}
+	p.HouseholdID = hhID.String
+	if zest.Valid {
+		p.Zestimate = &zest.Float64
+	}
+	if manVal.Valid {
+		p.ManualValue = &manVal.Float64
+	}
+	if origVal.Valid {
+		p.OriginalValue = &origVal.Float64
+	}
+	if debtID.Valid {
+		p.DebtAccountID = &debtID.String
+	}
+	if debtName.Valid {
+		p.DebtName = &debtName.String
+	}
+	if debtBalance.Valid {
+		p.DebtBalance = &debtBalance.Float64
+	}
+	return p, nil
+}
+
+// loadEquityMortgage loads the linked mortgage and fills in what Plaid
+// reports about it: escrow, PMI and the original principal. A rate or
+// payment missing on the debt falls back to the liability's.
+func loadEquityMortgage(conn db.DBTX, debtID string, out *models.PropertyEquity) (equity.Mortgage, error) {
+	var m equity.Mortgage
+	var escrow, origPrincipal sql.NullFloat64
+	var hasPMI sql.NullBool
+	err := conn.QueryRow(`
+		SELECT d.balance,
+		       COALESCE(NULLIF(d.apr, 0), l.interest_rate, 0),
+		       COALESCE(NULLIF(d.min_payment, 0), l.minimum_payment_amount, 0),
+		       l.escrow_balance, l.origination_principal, l.has_pmi
+		FROM debt_accounts d
+		LEFT JOIN LATERAL (
+			SELECT interest_rate, minimum_payment_amount, escrow_balance, origination_principal, has_pmi
+			FROM liabilities
+			WHERE d.plaid_account_id IS NOT NULL
+			  AND plaid_account_id = d.plaid_account_id AND user_id = d.user_id::text
+			ORDER BY updated_at DESC LIMIT 1
+		) l ON true
+		WHERE d.id = $1
+	`, debtID).Scan(&m.Balance, &m.APR, &m.Payment, &escrow, &origPrincipal, &hasPMI)
+	if err != nil {
+		return m, err
+	}
+	out.MortgageBalance = m.Balance
+	out.APR = m.APR
+	out.MonthlyPayment = m.Payment
+	if escrow.Valid {
+		out.EscrowBalance = &escrow.Float64
+	}
+	if origPrincipal.Valid {
+		out.OriginationPrincipal = &origPrincipal.Float64
+	}
+	if hasPMI.Valid {
+		out.HasPMI = &hasPMI.Bool
+	}
+	return m, nil
+}
+
+// loadEquityLedger reads the mortgage's payment ledger for its balance
+// history.
+func loadEquityLedger(conn db.DBTX, debtID string) ([]debts.Entry, error) {
+	rows, err := conn.Query(`
+		SELECT paid_on, kind, amount, principal, interest
+		FROM debt_payments
+		WHERE debt_account_id = $1
+		ORDER BY paid_on, created_at
+	`, debtID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []debts.Entry
+	for rows.Next() {
+		var e debts.Entry
+		if err := rows.Scan(&e.Date, &e.Kind, &e.Amount, &e.Principal, &e.Interest); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+)
+
+const equityTestUser = "11111111-1111-1111-1111-111111111111"
+
+func withPropertiesMockDB(t *testing.T, setup func(sqlmock.Sqlmock)) {
+	t.Helper()
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockSQL.Close() })
+
+	oldFactory := propertiesDBFactory
+	propertiesDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	t.Cleanup(func() { propertiesDBFactory = oldFactory })
+
+	setup(mock)
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+}
+
+// expectEquityProperty mocks the ownership check, the property and its
+// valuation history.
+func expectEquityProperty(mock sqlmock.Sqlmock, debtID, manualValue, originalValue any, valuations *sqlmock.Rows) {
+	mock.ExpectQuery(`SELECT user_id, household_id FROM properties`).
+		WithArgs("prop1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id"}).AddRow(equityTestUser, nil))
+	mock.ExpectQuery(`FROM properties p`).
+		WithArgs("prop1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "household_id", "street_address", "city", "state", "zip_code",
+			"zestimate", "manual_value", "original_value", "debt_account_id", "is_shared", "name", "balance"}).
+			AddRow("prop1", equityTestUser, nil, "1 Main St", "Springfield", "IL", "62701",
+				280000.0, manualValue, originalValue, debtID, false, nil, nil))
+	mock.ExpectQuery(`FROM property_valuations`).
+		WithArgs("prop1").
+		WillReturnRows(valuations)
+}
+
+func getPropertyEquity(t *testing.T, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/auth/properties/prop1/equity"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, equityTestUser))
+	req = mux.SetURLVars(req, map[string]string{"id": "prop1"})
+	rr := httptest.NewRecorder()
+	GetPropertyEquity(rr, req)
+	return rr
+}
+
+func TestGetPropertyEquity_WithMortgage(t *testing.T) {
+	now := time.Now().UTC()
+	now = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	withPropertiesMockDB(t, func(mock sqlmock.Sqlmock) {
+		expectEquityProperty(mock, "mortgage1", nil, 250000.0,
+			sqlmock.NewRows([]string{"id", "property_id", "value", "source", "valued_on", "created_at"}).
+				AddRow("v1", "prop1", 300000.0, "zillow", now.AddDate(0, -3, 0), now).
+				AddRow("v2", "prop1", 310000.0, "api", now, now))
+		mock.ExpectQuery(`FROM debt_accounts d`).
+			WithArgs("mortgage1").
+			WillReturnRows(sqlmock.NewRows([]string{"balance", "apr", "min_payment", "escrow_balance", "origination_principal", "has_pmi"}).
+				AddRow(200000.0, 6.0, 1199.11, 2400.0, 225000.0, true))
+		mock.ExpectQuery(`FROM debt_payments`).
+			WithArgs("mortgage1").
+			WillReturnRows(sqlmock.NewRows([]string{"paid_on", "kind", "amount", "principal", "interest"}).
+				AddRow(now.AddDate(0, -2, 0), "opening", 200400.0, 0.0, 0.0).
+				AddRow(now.AddDate(0, -1, 0), "payment", 1199.11, 199.11, 1000.0))
+	})
+
+	rr := getPropertyEquity(t, "?extra=200,400")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var eq models.PropertyEquity
+	if err := json.Unmarshal(rr.Body.Bytes(), &eq); err != nil {
+		t.Fatalf("bad response: %v", err)
+	}
+
+	if eq.CurrentValue != 310000 || eq.MortgageBalance != 200000 || eq.Equity != 110000 || eq.LTV != 64.52 {
+		t.Errorf("value %v, balance %v, equity %v, LTV %v", eq.CurrentValue, eq.MortgageBalance, eq.Equity, eq.LTV)
+	}
+	if eq.OriginalValue != 250000 || eq.OriginalValueSource != "property" {
+		t.Errorf("original value %v from %q", eq.OriginalValue, eq.OriginalValueSource)
+	}
+	if eq.EscrowBalance == nil || *eq.EscrowBalance != 2400 || eq.HasPMI == nil || !*eq.HasPMI {
+		t.Errorf("escrow %v, PMI %v", eq.EscrowBalance, eq.HasPMI)
+	}
+	// At exactly 80% of the original value PMI can be cancelled now.
+	if eq.PMIRequestDate != now.Format("2006-01") || eq.PMIAutoDate <= eq.PMIRequestDate {
+		t.Errorf("PMI request %q, auto %q", eq.PMIRequestDate, eq.PMIAutoDate)
+	}
+	if len(eq.Scenarios) != 3 || eq.Scenarios[2].ExtraPrincipal != 400 || eq.Scenarios[2].MonthsSaved <= eq.Scenarios[1].MonthsSaved {
+		t.Errorf("scenarios = %+v", eq.Scenarios)
+	}
+
+	if len(eq.History) != 3 {
+		t.Fatalf("expected three months of history, got %+v", eq.History)
+	}
+	if first := eq.History[0]; first.Value != 300000 || first.MortgageBalance != 200400 {
+		t.Errorf("first month = %+v", first)
+	}
+	if last := eq.History[2]; last.Value != 310000 || last.MortgageBalance != 200000 || last.LTV != eq.LTV {
+		t.Errorf("this month = %+v", last)
+	}
+}
+
+func TestGetPropertyEquity_NoMortgage(t *testing.T) {
+	now := time.Now().UTC()
+	now = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	withPropertiesMockDB(t, func(mock sqlmock.Sqlmock) {
+		expectEquityProperty(mock, nil, 275000.0, nil,
+			sqlmock.NewRows([]string{"id", "property_id", "value", "source", "valued_on", "created_at"}).
+				AddRow("v1", "prop1", 290000.0, "manual", now.AddDate(0, -1, 0), now))
+	})
+
+	rr := getPropertyEquity(t, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var eq models.PropertyEquity
+	json.Unmarshal(rr.Body.Bytes(), &eq)
+	// The valuation history takes over from the legacy manual value and
+	// zestimate.
+	if eq.CurrentValue != 290000 || eq.Equity != 290000 || eq.LTV != 0 || len(eq.Scenarios) != 0 || eq.PMIAutoDate != "" {
+		t.Errorf("equity = %+v", eq)
+	}
+	if eq.OriginalValue != 290000 || eq.OriginalValueSource != "valuation_history" {
+		t.Errorf("original value %v from %q", eq.OriginalValue, eq.OriginalValueSource)
+	}
+	if len(eq.History) != 2 || eq.History[0].Value != 290000 || eq.History[0].Equity != 290000 {
+		t.Errorf("history = %+v", eq.History)
+	}
+}
+
+func TestGetPropertyEquity_InvalidExtra(t *testing.T) {
+	rr := getPropertyEquity(t, "?extra=100,abc")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var resp ValidationErrors
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Errors) != 1 || resp.Errors[0].Field != "extra" {
+		t.Errorf("errors = %+v", resp.Errors)
+	}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/valuation"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+)
+
+// propertyValuers allows swapping the valuation providers in tests.
+var propertyValuers = valuation.NewChainFromEnv
+
+var propertyValuationSources = []string{"manual", "appraisal"}
+
+// latestValuationJoin joins a property (aliased p) to its most recent
+// valuation as v.value, v.source and v.valued_on.
+const latestValuationJoin = `
+	LEFT JOIN LATERAL (
+		SELECT value, source, valued_on FROM property_valuations
+		WHERE property_id = p.id
+		ORDER BY valued_on DESC, created_at DESC LIMIT 1
+	) v ON true
+`
+
+// ListPropertyValuations returns a property's valuation history, oldest
+// first.
+// GET /auth/properties/{id}/valuations
+func ListPropertyValuations(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	propID := mux.Vars(r)["id"]
+	if propID == "" {
+		http.Error(w, "Missing property id", http.StatusBadRequest)
+		return
+	}
+
+	client, err := propertiesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), "properties", propID, userID) {
+		return
+	}
+
+	valuations, err := loadPropertyValuations(client, propID)
+	if err != nil {
+		log.Printf("ListPropertyValuations query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(valuations)
+}
+
+// AddPropertyValuation records a value the owner entered or an appraisal.
+// The "manual" valuation provider uses these for up to a year.
+// POST /auth/properties/{id}/valuations
+// body: { value, source?: "manual"|"appraisal", valued_on? }
+func AddPropertyValuation(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	propID := mux.Vars(r)["id"]
+	if propID == "" {
+		http.Error(w, "Missing property id", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Value    float64 `json:"value"`
+		Source   string  `json:"source"`
+		ValuedOn string  `json:"valued_on"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	valuedOn := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if body.Source == "" {
+		body.Source = "manual"
+	}
+	var errs []ValidationError
+	if body.Value <= 0 {
+		errs = append(errs, ValidationError{Field: "value", Message: "must be greater than 0"})
+	}
+	if e := validateEnum(body.Source, "source", propertyValuationSources); e != nil {
+		errs = append(errs, *e)
+	}
+	if body.ValuedOn != "" {
+		parsed, err := time.Parse("2006-01-02", body.ValuedOn)
+		switch {
+		case err != nil:
+			errs = append(errs, ValidationError{Field: "valued_on", Message: "must be a date (YYYY-MM-DD)"})
+		case parsed.After(valuedOn):
+			errs = append(errs, ValidationError{Field: "valued_on", Message: "cannot be in the future"})
+		default:
+			valuedOn = parsed
+		}
+	}
+	if len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+
+	client, err := propertiesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if !ownershipCheck(w, client.Raw(), "properties", propID, userID) {
+		return
+	}
+
+	v := models.PropertyValuation{
+		PropertyID: propID,
+		Value:      math.Round(body.Value*100) / 100,
+		Source:     body.Source,
+		ValuedOn:   valuedOn.Format("2006-01-02"),
+	}
+	err = client.QueryRow(`
+		INSERT INTO property_valuations (property_id, value, source, valued_on)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, v.PropertyID, v.Value, v.Source, v.ValuedOn).Scan(&v.ID, &v.CreatedAt)
+	if err != nil {
+		log.Printf("AddPropertyValuation insert error: %v", err)
+		http.Error(w, "Insert error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(v)
+}
+
+// valuationSubject loads what the valuation providers need: the address and
+// the valuation history.
+func valuationSubject(conn db.DBTX, propID string) (valuation.Subject, error) {
+	s := valuation.Subject{PropertyID: propID}
+	err := conn.QueryRow(`
+		SELECT street_address, city, state, zip_code FROM properties WHERE id = $1
+	`, propID).Scan(&s.StreetAddress, &s.City, &s.State, &s.ZipCode)
+	if err != nil {
+		return s, err
+	}
+	s.History, err = loadPropertyValuations(conn, propID)
+	return s, err
+}
+
+// setLatestValuation fills in the property's latest value from its history,
+// which is oldest first.
+func setLatestValuation(p *models.Property, valuations []models.PropertyValuation) {
+	if len(valuations) == 0 {
+		return
+	}
+	latest := valuations[len(valuations)-1]
+	p.LatestValue = &latest.Value
+	p.LatestValueSource = &latest.Source
+	p.LatestValuedOn = &latest.ValuedOn
+}
+
+// scanLatestValuation fills in the property's latest value from the columns
+// of latestValuationJoin.
+func scanLatestValuation(p *models.Property, value sql.NullFloat64, source sql.NullString, on sql.NullTime) {
+	if !value.Valid {
+		return
+	}
+	day := on.Time.Format("2006-01-02")
+	p.LatestValue = &value.Float64
+	p.LatestValueSource = &source.String
+	p.LatestValuedOn = &day
+}
+
+// loadPropertyValuations returns a property's valuation history, oldest
+// first.
+func loadPropertyValuations(conn db.DBTX, propID string) ([]models.PropertyValuation, error) {
+	rows, err := conn.Query(`
+		SELECT id, property_id, value, source, valued_on, created_at
+		FROM property_valuations
+		WHERE property_id = $1
+		ORDER BY valued_on, created_at
+	`, propID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	valuations := []models.PropertyValuation{}
+	for rows.Next() {
+		var v models.PropertyValuation
+		var on time.Time
+		if err := rows.Scan(&v.ID, &v.PropertyID, &v.Value, &v.Source, &on, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		v.ValuedOn = on.Format("2006-01-02")
+		valuations = append(valuations, v)
+	}
+	return valuations, rows.Err()
+}
+
+// recordPropertyValuation adds a value to the property's history. The same
+// value from the same source on the same day is only recorded once.
+func recordPropertyValuation(conn db.DBTX, propID string, value float64, source string, on time.Time) error {
+	if value <= 0 {
+		return fmt.Errorf("valuation must be positive, got %v", value)
+	}
+	_, err := conn.Exec(`
+		INSERT INTO property_valuations (property_id, value, source, valued_on)
+		SELECT $1, $2, $3, $4
+		WHERE NOT EXISTS (
+			SELECT 1 FROM property_valuations
+			WHERE property_id = $1 AND value = $2 AND source = $3 AND valued_on = $4
+		)
+	`, propID, math.Round(value*100)/100, source, on.Format("2006-01-02"))
+	return err
+}
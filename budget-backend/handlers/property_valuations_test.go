@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/internal/valuation"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+)
+
+// stubValuer answers with a fixed estimate and remembers what it was asked.
+type stubValuer struct {
+	est  *valuation.Estimate
+	seen valuation.Subject
+}
+
+func (s *stubValuer) Name() string { return "stub" }
+
+func (s *stubValuer) Value(_ context.Context, subj valuation.Subject) (*valuation.Estimate, error) {
+	s.seen = subj
+	if s.est == nil {
+		return nil, valuation.ErrNoValue
+	}
+	return s.est, nil
+}
+
+func withStubValuer(t *testing.T, est *valuation.Estimate) *stubValuer {
+	t.Helper()
+	stub := &stubValuer{est: est}
+	old := propertyValuers
+	propertyValuers = func() (valuation.Chain, error) { return valuation.Chain{stub}, nil }
+	t.Cleanup(func() { propertyValuers = old })
+	return stub
+}
+
+// expectRefreshLoad mocks the ownership check, the cooldown check and the
+// valuation subject.
+func expectRefreshLoad(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT user_id, household_id FROM properties`).
+		WithArgs("prop1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id"}).AddRow(equityTestUser, nil))
+	mock.ExpectQuery(`SELECT last_fetched_at FROM properties`).
+		WithArgs("prop1").
+		WillReturnRows(sqlmock.NewRows([]string{"last_fetched_at"}).AddRow(time.Now().Add(-2 * time.Hour)))
+	mock.ExpectQuery(`SELECT street_address, city, state, zip_code FROM properties`).
+		WithArgs("prop1").
+		WillReturnRows(sqlmock.NewRows([]string{"street_address", "city", "state", "zip_code"}).
+			AddRow("1 Main St", "Springfield", "IL", "62701"))
+	mock.ExpectQuery(`FROM property_valuations`).
+		WithArgs("prop1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "property_id", "value", "source", "valued_on", "created_at"}).
+			AddRow("v1", "prop1", 300000.0, "appraisal", time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC), time.Now()))
+}
+
+func refreshProperty(t *testing.T) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/auth/properties/prop1/refresh?user_id="+equityTestUser, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "prop1"})
+	rr := httptest.NewRecorder()
+	RefreshPropertyValue(rr, req)
+	return rr
+}
+
+func TestRefreshPropertyValue_RecordsValuation(t *testing.T) {
+	asOf := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	stub := withStubValuer(t, &valuation.Estimate{Value: 315000, Source: "hpi", AsOf: asOf})
+	withPropertiesMockDB(t, func(mock sqlmock.Sqlmock) {
+		expectRefreshLoad(mock)
+		mock.ExpectExec(`INSERT INTO property_valuations`).
+			WithArgs("prop1", 315000.0, "hpi", "2026-01-01").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		// Only the fetch time changes; the legacy zestimate is left alone.
+		mock.ExpectExec(`UPDATE properties SET last_fetched_at=\$1, updated_at=NOW\(\) WHERE id=\$2`).
+			WithArgs(sqlmock.AnyArg(), "prop1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	})
+
+	rr := refreshProperty(t)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Value    float64 `json:"value"`
+		Source   string  `json:"source"`
+		ValuedOn string  `json:"valued_on"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if resp.Value != 315000 || resp.Source != "hpi" || resp.ValuedOn != "2026-01-01" {
+		t.Errorf("response = %+v", resp)
+	}
+	if stub.seen.ZipCode != "62701" || len(stub.seen.History) != 1 || stub.seen.History[0].Source != "appraisal" {
+		t.Errorf("providers were given %+v", stub.seen)
+	}
+}
+
+func TestRefreshPropertyValue_NoValue(t *testing.T) {
+	withStubValuer(t, nil)
+	withPropertiesMockDB(t, expectRefreshLoad)
+
+	if rr := refreshProperty(t); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func addValuation(t *testing.T, body map[string]any) *httptest.ResponseRecorder {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/auth/properties/prop1/valuations", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, equityTestUser))
+	req = mux.SetURLVars(req, map[string]string{"id": "prop1"})
+	rr := httptest.NewRecorder()
+	AddPropertyValuation(rr, req)
+	return rr
+}
+
+func TestAddPropertyValuation_Appraisal(t *testing.T) {
+	withPropertiesMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT user_id, household_id FROM properties`).
+			WithArgs("prop1").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id"}).AddRow(equityTestUser, nil))
+		mock.ExpectQuery(`INSERT INTO property_valuations`).
+			WithArgs("prop1", 325000.5, "appraisal", "2026-02-14").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("v9", time.Now()))
+	})
+
+	rr := addValuation(t, map[string]any{"value": 325000.499, "source": "appraisal", "valued_on": "2026-02-14"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var v models.PropertyValuation
+	json.Unmarshal(rr.Body.Bytes(), &v)
+	if v.ID != "v9" || v.Value != 325000.5 || v.Source != "appraisal" {
+		t.Errorf("valuation = %+v", v)
+	}
+}
+
+func TestAddPropertyValuation_Validation(t *testing.T) {
+	rr := addValuation(t, map[string]any{"value": 0, "source": "zillow", "valued_on": "2999-01-01"})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var resp ValidationErrors
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Errors) != 3 {
+		t.Errorf("errors = %+v", resp.Errors)
+	}
+}
+
+func updateProperty(t *testing.T, body map[string]any) *httptest.ResponseRecorder {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPut, "/auth/properties/prop1?user_id="+equityTestUser, bytes.NewReader(b))
+	req = mux.SetURLVars(req, map[string]string{"id": "prop1"})
+	rr := httptest.NewRecorder()
+	UpdateProperty(rr, req)
+	return rr
+}
+
+func TestUpdateProperty_RecordsOnlyChangedManualValue(t *testing.T) {
+	cases := []struct {
+		name   string
+		old    float64
+		record bool
+	}{
+		{"address edit keeps the manual value", 300000, false},
+		{"new manual value", 250000, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			withPropertiesMockDB(t, func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT user_id, household_id FROM properties`).
+					WithArgs("prop1").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id"}).AddRow(equityTestUser, nil))
+				mock.ExpectQuery(`UPDATE properties p`).
+					WillReturnRows(sqlmock.NewRows([]string{"manual_value"}).AddRow(tc.old))
+				if tc.record {
+					mock.ExpectExec(`INSERT INTO property_valuations`).
+						WithArgs("prop1", 300000.0, "manual", sqlmock.AnyArg()).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+			})
+
+			rr := updateProperty(t, map[string]any{"street_address": "2 Elm St", "manual_value": 300000})
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
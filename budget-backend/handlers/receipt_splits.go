@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/categories"
+	"github.com/aboogie/budget-backend/internal/receipts"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+var receiptSplitsDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+// maxReceiptTextLength bounds the receipt text accepted for parsing.
+const maxReceiptTextLength = 20000
+
+// extractReceipt reads receipt text with the rule-based parser, and with
+// Claude when asked or when the parser finds nothing. The AI result is only
+// used if it finds items; any AI error falls back to the parser.
+func extractReceipt(text string, useAI bool) (models.ParsedReceipt, string) {
+	parsed := receipts.Parse(text)
+	if !useAI && len(parsed.Items) > 0 {
+		return parsed, "parser"
+	}
+	client := getAIClient()
+	if !client.IsAvailable() {
+		return parsed, "parser"
+	}
+	extracted, err := client.ExtractReceipt(text)
+	if err != nil {
+		log.Printf("SuggestReceiptSplits: AI extraction failed: %v", err)
+		return parsed, "parser"
+	}
+	if len(extracted.Items) == 0 {
+		return parsed, "parser"
+	}
+	return extracted, "ai"
+}
+
+// SuggestReceiptSplits turns receipt text into a draft split for a
+// transaction (POST /auth/transactions/{id}/split/suggest). Each line item is
+// categorized with the user's rules and learned model; items nothing matches
+// fall back to the transaction's category. Tax, tip and discounts are spread
+// across items so the draft adds up to the transaction amount. Nothing is
+// saved — the client reviews the draft and posts it to SplitTransaction.
+func SuggestReceiptSplits(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	txID := mux.Vars(r)["id"]
+
+	var req models.ReceiptSplitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		respondValidationError(w, []ValidationError{{Field: "text", Message: "text is required"}})
+		return
+	}
+	if len(req.Text) > maxReceiptTextLength {
+		respondValidationError(w, []ValidationError{{Field: "text", Message: fmt.Sprintf("text must be at most %d characters", maxReceiptTextLength)}})
+		return
+	}
+
+	conn, err := receiptSplitsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	var txOwner, householdID, txCategoryID string
+	var txAmount float64
+	err = conn.QueryRow(`
+		SELECT user_id, COALESCE(household_id::text, ''), amount, COALESCE(category_id::text, '')
+		FROM transactions WHERE id = $1`, txID).Scan(&txOwner, &householdID, &txAmount, &txCategoryID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("SuggestReceiptSplits: query error: %v", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	if txOwner != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	txAmount = math.Abs(txAmount)
+
+	receipt, source := extractReceipt(req.Text, req.UseAI)
+	suggestion := models.ReceiptSplitSuggestion{
+		TransactionID: txID,
+		Source:        source,
+		Receipt:       receipt,
+		Items:         []models.SuggestedLineItem{},
+		Draft:         models.SplitRequest{Splits: []models.SplitEntry{}},
+		Warnings:      []string{},
+	}
+	if len(receipt.Items) == 0 {
+		suggestion.Warnings = append(suggestion.Warnings, "No line items were found in the receipt text")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(suggestion)
+		return
+	}
+
+	// Receipts repeat items, so resolve each description once.
+	type resolved struct{ categoryID, confidence string }
+	cache := map[string]resolved{}
+	uncategorized := 0
+	for _, it := range receipt.Items {
+		key := strings.ToLower(it.Description)
+		res, ok := cache[key]
+		if !ok {
+			catID, conf, _, err := categories.ResolveCategory(conn.Raw(), userID, householdID, it.Description, nil)
+			if err != nil {
+				log.Printf("SuggestReceiptSplits: resolve %q: %v", it.Description, err)
+			}
+			if catID == "" {
+				catID, conf = txCategoryID, ""
+			}
+			res = resolved{catID, conf}
+			cache[key] = res
+		}
+		if res.categoryID == "" {
+			uncategorized++
+		}
+		suggestion.Items = append(suggestion.Items, models.SuggestedLineItem{
+			ReceiptItem: it,
+			CategoryID:  res.categoryID,
+			Confidence:  res.confidence,
+		})
+	}
+
+	// Attach category names for display.
+	var ids []string
+	for _, res := range cache {
+		if res.categoryID != "" {
+			ids = append(ids, res.categoryID)
+		}
+	}
+	if len(ids) > 0 {
+		names := map[string]string{}
+		rows, err := conn.Query(`SELECT id, name FROM categories WHERE id = ANY($1)`, pq.Array(ids))
+		if err != nil {
+			log.Printf("SuggestReceiptSplits: category names: %v", err)
+		} else {
+			for rows.Next() {
+				var id, name string
+				if rows.Scan(&id, &name) == nil {
+					names[id] = name
+				}
+			}
+			rows.Close()
+		}
+		for i := range suggestion.Items {
+			suggestion.Items[i].CategoryName = names[suggestion.Items[i].CategoryID]
+		}
+	}
+
+	suggestion.Draft.Splits = receipts.Allocate(suggestion.Items, txAmount)
+
+	if total := receipts.GrandTotal(receipt); math.Abs(total-txAmount) > 0.01 {
+		suggestion.Warnings = append(suggestion.Warnings, fmt.Sprintf(
+			"Receipt total %.2f doesn't match the transaction amount %.2f; item amounts were scaled to match", total, txAmount))
+	}
+	if uncategorized > 0 {
+		suggestion.Warnings = append(suggestion.Warnings, fmt.Sprintf("%d item(s) need a category before saving", uncategorized))
+	}
+	if len(suggestion.Draft.Splits) < 2 {
+		suggestion.Warnings = append(suggestion.Warnings, "All items fall in one category, so there is nothing to split")
+	}
+	suggestion.Ready = uncategorized == 0 && len(suggestion.Draft.Splits) >= 2
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestion)
+}
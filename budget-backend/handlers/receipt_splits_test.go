@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+)
+
+func suggestReceiptRequest(t *testing.T, userID, txID, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/auth/transactions/"+txID+"/split/suggest", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+	req = mux.SetURLVars(req, map[string]string{"id": txID})
+	rr := httptest.NewRecorder()
+	SuggestReceiptSplits(rr, req)
+	return rr
+}
+
+func TestSuggestReceiptSplits_Validation(t *testing.T) {
+	rr := suggestReceiptRequest(t, "u1", "tx1", `{"text":"   "}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestSuggestReceiptSplits_Forbidden(t *testing.T) {
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+	orig := receiptSplitsDBFactory
+	receiptSplitsDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	defer func() { receiptSplitsDBFactory = orig }()
+
+	mock.ExpectQuery(`FROM transactions WHERE id = \$1`).
+		WithArgs("tx1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "amount", "category_id"}).
+			AddRow("someone-else", "", 20.0, ""))
+
+	rr := suggestReceiptRequest(t, "u1", "tx1", `{"text":"MILK 3.99"}`)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
+func TestSuggestReceiptSplits_FallsBackToTransactionCategory(t *testing.T) {
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+	orig := receiptSplitsDBFactory
+	receiptSplitsDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	defer func() { receiptSplitsDBFactory = orig }()
+
+	mock.ExpectQuery(`FROM transactions WHERE id = \$1`).
+		WithArgs("tx1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id", "amount", "category_id"}).
+			AddRow("u1", "", 13.06, "groceries"))
+	// No rule queries are mocked, so every item falls back to the
+	// transaction's own category.
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(`SELECT id, name FROM categories`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("groceries", "Groceries"))
+
+	text := "BANANAS 1.18\nMILK 7.98\nSOAP 4.49\nCOUPON 1.00-\nTAX 0.41\nTOTAL 13.06"
+	body, _ := json.Marshal(models.ReceiptSplitRequest{Text: text})
+	rr := suggestReceiptRequest(t, "u1", "tx1", string(body))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var got models.ReceiptSplitSuggestion
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("bad response: %v", err)
+	}
+	if got.Source != "parser" || len(got.Items) != 3 {
+		t.Fatalf("unexpected suggestion: %+v", got)
+	}
+	if got.Items[0].CategoryID != "groceries" {
+		t.Errorf("item category = %q, want fallback", got.Items[0].CategoryID)
+	}
+	if len(got.Draft.Splits) != 1 || math.Abs(got.Draft.Splits[0].Amount-13.06) > 0.001 {
+		t.Fatalf("unexpected draft: %+v", got.Draft)
+	}
+	if got.Ready {
+		t.Error("a single-category draft should not be ready")
+	}
+}
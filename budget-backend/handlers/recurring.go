@@ -178,6 +178,19 @@ func advanceDate(from time.Time, frequency string, dueDay *int) time.Time {
 	}
 }
 
+// templateOccurrences lists the dates between from and to, inclusive, on
+// which a recurring template falls, stepping from its date with advanceDate.
+func templateOccurrences(date time.Time, frequency string, dueDay *int, from, to time.Time) []time.Time {
+	var dates []time.Time
+	next := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	for ; !next.After(to); next = advanceDate(next, frequency, dueDay) {
+		if !next.Before(from) {
+			dates = append(dates, next)
+		}
+	}
+	return dates
+}
+
 // addMonth advances by one calendar month, clamping to the last day of the
 // target month (e.g. Jan 31 → Feb 28).
 func addMonth(from time.Time) time.Time {
@@ -206,6 +219,8 @@ func StartRecurringTicker() {
 
 		RunBillReminders()
 		RunBudgetAlerts()
+		RunCashFlowWarnings()
+		RunDebtInterestAccrual()
 		RunNudgeGeneration()
 
 		ticker := time.NewTicker(24 * time.Hour)
@@ -218,6 +233,8 @@ func StartRecurringTicker() {
 			}
 			RunBillReminders()
 			RunBudgetAlerts()
+			RunCashFlowWarnings()
+			RunDebtInterestAccrual()
 			RunNudgeGeneration()
 		}
 	}()
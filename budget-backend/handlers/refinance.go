@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/ai"
+	"github.com/aboogie/budget-backend/models"
+)
+
+var refinanceDebtCategories = []string{"attack", "structured"}
+
+// refinanceAssessFramework allows swapping the framework assessment in tests.
+var refinanceAssessFramework = ai.AssessFrameworkLevel
+
+// refinanceDebt is a debt the user could refinance, with the category that
+// decides whether it counts toward the Attack Debt level.
+type refinanceDebt struct {
+	models.DebtInfo
+	Category string
+}
+
+// AnalyzeRefinance compares keeping some debts on their current payments
+// with replacing them by one new loan: monthly payment, total interest,
+// break-even month on the fees, and the effect on the framework level.
+// POST /auth/debts/refinance/analyze
+// body: { debt_ids, rate, term_months, fees, fees_financed, debt_category? }
+func AnalyzeRefinance(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.RefinanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	var errs []ValidationError
+	if len(req.DebtIDs) == 0 {
+		errs = append(errs, ValidationError{Field: "debt_ids", Message: "choose at least one debt to replace"})
+	}
+	if req.Rate < 0 || req.Rate > 100 {
+		errs = append(errs, ValidationError{Field: "rate", Message: "must be between 0 and 100"})
+	}
+	if req.TermMonths < 1 || req.TermMonths > payoffMaxMonths {
+		errs = append(errs, ValidationError{Field: "term_months", Message: fmt.Sprintf("must be between 1 and %d", payoffMaxMonths)})
+	}
+	if req.Fees < 0 {
+		errs = append(errs, ValidationError{Field: "fees", Message: "cannot be negative"})
+	}
+	if req.DebtCategory != "" {
+		if e := validateEnum(req.DebtCategory, "debt_category", refinanceDebtCategories); e != nil {
+			errs = append(errs, *e)
+		}
+	}
+	if len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+
+	conn, err := plannerDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	householdID := db.ResolveHouseholdID(conn.Raw(), userID)
+	debts, err := loadRefinanceDebts(conn, userID, householdID)
+	if err != nil {
+		log.Printf("AnalyzeRefinance query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+
+	byID := map[string]refinanceDebt{}
+	for _, d := range debts {
+		byID[d.ID] = d
+	}
+	chosen := map[string]bool{}
+	var replaced []models.DebtInfo
+	newCategory := "attack"
+	for i, id := range req.DebtIDs {
+		field := fmt.Sprintf("debt_ids[%d]", i)
+		d, ok := byID[id]
+		switch {
+		case !ok:
+			errs = append(errs, ValidationError{Field: field, Message: "unknown debt"})
+		case chosen[id]:
+			errs = append(errs, ValidationError{Field: field, Message: "debt listed twice"})
+		case d.MinPayment <= 0:
+			errs = append(errs, ValidationError{Field: field, Message: d.Name + " has no minimum payment to compare against"})
+		default:
+			replaced = append(replaced, d.DebtInfo)
+			if d.Category == "structured" {
+				newCategory = "structured"
+			}
+		}
+		chosen[id] = true
+	}
+	if len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+	if req.DebtCategory != "" {
+		newCategory = req.DebtCategory
+	}
+
+	analysis := ai.AnalyzeRefinance(replaced, req)
+
+	var attackBefore, attackAfter []models.DebtInfo
+	for _, d := range debts {
+		if d.Category != "attack" {
+			continue
+		}
+		attackBefore = append(attackBefore, d.DebtInfo)
+		if !chosen[d.ID] {
+			attackAfter = append(attackAfter, d.DebtInfo)
+		}
+	}
+	if newCategory == "attack" {
+		attackAfter = append(attackAfter, models.DebtInfo{
+			ID:         "refinance",
+			Name:       "New loan",
+			Balance:    analysis.Principal,
+			APR:        req.Rate,
+			MinPayment: analysis.Refinanced.MonthlyPayment,
+		})
+	}
+	assessment := refinanceAssessFramework(conn.Raw(), userID, householdID)
+	analysis.Framework = ai.RefinanceFramework(assessment, attackBefore, attackAfter)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analysis)
+}
+
+// loadRefinanceDebts returns the user's debts with a balance, plus shared
+// household debts. Linked debts missing a rate or minimum payment fall back
+// to what Plaid reported on the liability.
+func loadRefinanceDebts(conn db.DBTX, userID, householdID string) ([]refinanceDebt, error) {
+	rows, err := conn.Query(`
+		SELECT d.id, d.name, d.balance,
+		       COALESCE(NULLIF(d.apr, 0), l.interest_rate, l.interest_rate_pct, 0),
+		       COALESCE(NULLIF(d.min_payment, 0), l.minimum_payment_amount, 0),
+		       COALESCE(d.debt_category, 'attack')
+		FROM debt_accounts d
+		LEFT JOIN LATERAL (
+			SELECT interest_rate, interest_rate_pct, minimum_payment_amount
+			FROM liabilities
+			WHERE d.plaid_account_id IS NOT NULL
+			  AND plaid_account_id = d.plaid_account_id AND user_id = d.user_id::text
+			ORDER BY updated_at DESC LIMIT 1
+		) l ON true
+		WHERE (d.user_id = $1 OR ($2 <> '' AND d.household_id::text = $2 AND d.is_shared))
+		  AND d.balance > 0
+		ORDER BY d.name
+	`, userID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var debts []refinanceDebt
+	for rows.Next() {
+		var d refinanceDebt
+		if err := rows.Scan(&d.ID, &d.Name, &d.Balance, &d.APR, &d.MinPayment, &d.Category); err != nil {
+			return nil, err
+		}
+		debts = append(debts, d)
+	}
+	return debts, rows.Err()
+}
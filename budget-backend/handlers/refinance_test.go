@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/models"
+)
+
+func analyzeRefinance(t *testing.T, body map[string]any) *httptest.ResponseRecorder {
+	t.Helper()
+	userID := "11111111-1111-1111-1111-111111111111"
+	withMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`FROM household_members`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"household_id"}))
+		mock.ExpectQuery(`FROM debt_accounts d`).
+			WithArgs(userID, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "balance", "apr", "min_payment", "debt_category"}).
+				AddRow("card1", "Visa", 3000.0, 24.0, 90.0, "attack").
+				AddRow("card2", "Store card", 2000.0, 20.0, 60.0, "attack").
+				AddRow("home", "Mortgage", 200000.0, 6.0, 1300.0, "structured"))
+	})
+	oldAssess := refinanceAssessFramework
+	refinanceAssessFramework = func(*sql.DB, string, string) models.FrameworkAssessment {
+		return models.FrameworkAssessment{Level: 2, LevelName: "Attack Debt"}
+	}
+	t.Cleanup(func() { refinanceAssessFramework = oldAssess })
+
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/auth/debts/refinance/analyze", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+	rr := httptest.NewRecorder()
+	AnalyzeRefinance(rr, req)
+	return rr
+}
+
+func TestAnalyzeRefinance_ConsolidateCards(t *testing.T) {
+	rr := analyzeRefinance(t, map[string]any{
+		"debt_ids":    []string{"card1", "card2"},
+		"rate":        10,
+		"term_months": 36,
+		"fees":        300,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var a models.RefinanceAnalysis
+	if err := json.Unmarshal(rr.Body.Bytes(), &a); err != nil {
+		t.Fatalf("bad response: %v", err)
+	}
+
+	if a.Principal != 5000 || a.Current.MonthlyPayment != 150 || a.Refinanced.MonthlyPayment != 161.34 {
+		t.Errorf("principal %v, payments %v -> %v", a.Principal, a.Current.MonthlyPayment, a.Refinanced.MonthlyPayment)
+	}
+	if a.Refinanced.Months != 36 || !a.Refinanced.PaysOff || math.Abs(a.Refinanced.TotalCost-(a.Refinanced.TotalInterest+300)) > 0.005 {
+		t.Errorf("refinanced = %+v", a.Refinanced)
+	}
+	if a.InterestSaved <= 0 || math.Abs(a.NetSavings-(a.InterestSaved-300)) > 0.005 {
+		t.Errorf("interest saved %v, net %v", a.InterestSaved, a.NetSavings)
+	}
+	if a.BreakEvenMonth < 1 || a.BreakEvenMonth > 12 {
+		t.Errorf("break-even month = %d", a.BreakEvenMonth)
+	}
+
+	f := a.Framework
+	if f.AttackDebtsBefore != 2 || f.AttackDebtsAfter != 1 {
+		t.Errorf("attack debts %d -> %d", f.AttackDebtsBefore, f.AttackDebtsAfter)
+	}
+	if f.AttackFreeMonthsAfter != 36 || f.AttackFreeMonths <= 36 || f.ProjectedLevel != 2 {
+		t.Errorf("framework = %+v", f)
+	}
+}
+
+func TestAnalyzeRefinance_StructuredLoanClearsAttackDebt(t *testing.T) {
+	// Rolling the cards into the mortgage leaves no attack debts.
+	rr := analyzeRefinance(t, map[string]any{
+		"debt_ids":      []string{"home", "card1", "card2"},
+		"rate":          5.5,
+		"term_months":   360,
+		"fees":          4000,
+		"fees_financed": true,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var a models.RefinanceAnalysis
+	json.Unmarshal(rr.Body.Bytes(), &a)
+	if a.Principal != 209000 {
+		t.Errorf("principal = %v", a.Principal)
+	}
+	if f := a.Framework; f.AttackDebtsAfter != 0 || f.ProjectedLevel != 3 || f.ProjectedLevelName != "Build Security" {
+		t.Errorf("framework = %+v", f)
+	}
+}
+
+func TestAnalyzeRefinance_Validation(t *testing.T) {
+	rr := analyzeRefinance(t, map[string]any{
+		"debt_ids":    []string{"card1", "boat"},
+		"rate":        8,
+		"term_months": 60,
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var resp ValidationErrors
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Errors) != 1 || resp.Errors[0].Field != "debt_ids[1]" {
+		t.Errorf("errors = %+v", resp.Errors)
+	}
+}
@@ -0,0 +1,609 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// reimbursementsDBFactory allows swapping the DB in tests.
+var reimbursementsDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+var reimbursementStatuses = []string{"outstanding", "received", "written_off"}
+
+const (
+	defaultReimbursementWindow = 60
+	maxReimbursementWindow     = 365
+	// reimbursementAmountSlack is how far a payment may be from the expected
+	// amount when its description names the payer (fees, FX, rounding).
+	reimbursementAmountSlack = 0.05
+)
+
+// reimbursedAmountSQL is how much of expense t comes back: the expected
+// amount while outstanding, what actually arrived once received, nothing if
+// written off.
+const reimbursedAmountSQL = `COALESCE((
+		SELECT CASE r.status
+			WHEN 'outstanding' THEN r.expected_amount
+			WHEN 'received' THEN COALESCE(r.received_amount, r.expected_amount)
+			ELSE 0 END
+		FROM reimbursements r WHERE r.transaction_id = t.id), 0)`
+
+// netExpenseAmountSQL is an expense's amount net of reimbursement, for
+// spend totals. netSplitAmountSQL scales a split of it by the same factor.
+const (
+	netExpenseAmountSQL = `GREATEST(t.amount - ` + reimbursedAmountSQL + `, 0)`
+	netSplitAmountSQL   = `ts.amount * GREATEST(1 - ` + reimbursedAmountSQL + ` / NULLIF(t.amount, 0), 0)`
+)
+
+// reimbursementPaymentFilter drops incoming reimbursement payments from
+// income totals; they repay an expense rather than add income.
+const reimbursementPaymentFilter = `
+	AND NOT EXISTS (SELECT 1 FROM reimbursements rp WHERE rp.matched_transaction_id = t.id)`
+
+// reimbursementSelect loads reimbursements with their expense transaction.
+const reimbursementSelect = `
+	SELECT r.id, r.transaction_id, r.user_id, r.household_id, r.expected_amount, r.source,
+	       r.payer_keywords, r.window_days, r.status, r.received_amount, r.matched_transaction_id,
+	       r.received_at, r.note, r.created_at, r.updated_at,
+	       COALESCE(e.note, ''), e.amount, e.date
+	FROM reimbursements r
+	JOIN transactions e ON e.id = r.transaction_id`
+
+func scanReimbursement(row interface{ Scan(...any) error }, now time.Time) (models.Reimbursement, error) {
+	var r models.Reimbursement
+	var keywords pq.StringArray
+	err := row.Scan(&r.ID, &r.TransactionID, &r.UserID, &r.HouseholdID, &r.ExpectedAmount, &r.Source,
+		&keywords, &r.WindowDays, &r.Status, &r.ReceivedAmount, &r.MatchedTransactionID,
+		&r.ReceivedAt, &r.Note, &r.CreatedAt, &r.UpdatedAt,
+		&r.Description, &r.SpentAmount, &r.SpentOn)
+	r.PayerKeywords = []string(keywords)
+	if r.PayerKeywords == nil {
+		r.PayerKeywords = []string{}
+	}
+	r.AgeDays = int(now.Sub(r.SpentOn).Hours() / 24)
+	if r.AgeDays < 0 {
+		r.AgeDays = 0
+	}
+	return r, err
+}
+
+// reimbursementCandidate is an incoming transaction that may repay an expense.
+type reimbursementCandidate struct {
+	ID     string
+	Amount float64
+	Date   time.Time
+	Note   string
+}
+
+// scoreReimbursementMatch reports whether an incoming payment looks like the
+// reimbursement and how good a match it is. It has to land within the
+// window after the expense. With payer keywords, one must appear in the
+// description and the amount may be a little off; without them only an
+// exact amount counts. Closer amounts and earlier dates score higher.
+func scoreReimbursementMatch(expected float64, keywords []string, spentOn time.Time, windowDays int, c reimbursementCandidate) (float64, bool) {
+	spentDay := spentOn.Truncate(24 * time.Hour)
+	if c.Date.Before(spentDay) || c.Date.After(spentDay.AddDate(0, 0, windowDays+1)) {
+		return 0, false
+	}
+	diff := math.Abs(c.Amount - expected)
+	if len(keywords) == 0 {
+		if diff > 0.01 {
+			return 0, false
+		}
+	} else {
+		note := strings.ToLower(c.Note)
+		hit := false
+		for _, kw := range keywords {
+			if kw = strings.ToLower(strings.TrimSpace(kw)); kw != "" && strings.Contains(note, kw) {
+				hit = true
+				break
+			}
+		}
+		if !hit || diff > math.Max(0.01, expected*reimbursementAmountSlack) {
+			return 0, false
+		}
+	}
+	days := c.Date.Sub(spentDay).Hours() / 24
+	return 1 - diff/expected - days/float64(windowDays*10), true
+}
+
+// markReimbursementReceived links a payment to an outstanding reimbursement.
+func markReimbursementReceived(conn db.DBTX, reimbursementID string, payment reimbursementCandidate) (bool, error) {
+	res, err := conn.Exec(`
+		UPDATE reimbursements
+		SET status = 'received', received_amount = $2, matched_transaction_id = $3, received_at = $4, updated_at = NOW()
+		WHERE id = $1 AND status = 'outstanding'
+	`, reimbursementID, payment.Amount, payment.ID, payment.Date)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// findReimbursementPayment looks for an already-imported payment that
+// repays r and links the best one.
+func findReimbursementPayment(conn db.DBTX, r models.Reimbursement) (bool, error) {
+	hh := ""
+	if r.HouseholdID != nil {
+		hh = *r.HouseholdID
+	}
+	rows, err := conn.Query(`
+		SELECT t.id, t.amount, t.date, COALESCE(t.note, '')
+		FROM transactions t
+		WHERE t.type = 'income'
+		  AND (t.user_id = $1 OR ($2 <> '' AND t.household_id::text = $2))
+		  AND t.date >= $3::date AND t.date < $3::date + $4::int + 1`+reimbursementPaymentFilter,
+		r.UserID, hh, r.SpentOn, r.WindowDays)
+	if err != nil {
+		return false, err
+	}
+	var best *reimbursementCandidate
+	bestScore := 0.0
+	for rows.Next() {
+		var c reimbursementCandidate
+		if err := rows.Scan(&c.ID, &c.Amount, &c.Date, &c.Note); err != nil {
+			rows.Close()
+			return false, err
+		}
+		if score, ok := scoreReimbursementMatch(r.ExpectedAmount, r.PayerKeywords, r.SpentOn, r.WindowDays, c); ok && (best == nil || score > bestScore) {
+			best, bestScore = &c, score
+		}
+	}
+	rows.Close()
+	if best == nil {
+		return false, nil
+	}
+	return markReimbursementReceived(conn, r.ID, *best)
+}
+
+// matchIncomingReimbursement checks a newly recorded income transaction
+// against outstanding reimbursements and links it to the best match. It is
+// called after bank imports and manual entry; failures are only logged.
+func matchIncomingReimbursement(conn db.DBTX, txID string) {
+	var c reimbursementCandidate
+	var userID, hh string
+	err := conn.QueryRow(`
+		SELECT id, user_id, COALESCE(household_id::text, ''), amount, date, COALESCE(note, '')
+		FROM transactions WHERE id = $1 AND type = 'income'
+	`, txID).Scan(&c.ID, &userID, &hh, &c.Amount, &c.Date, &c.Note)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("matchIncomingReimbursement load %s: %v", txID, err)
+		}
+		return
+	}
+
+	rows, err := conn.Query(reimbursementSelect+`
+		WHERE r.status = 'outstanding'
+		  AND (r.user_id = $1 OR ($2 <> '' AND r.household_id::text = $2))
+		  AND e.date <= $3 AND e.date + r.window_days * INTERVAL '1 day' >= $3::date
+	`, userID, hh, c.Date)
+	if err != nil {
+		log.Printf("matchIncomingReimbursement query: %v", err)
+		return
+	}
+	var best *models.Reimbursement
+	bestScore := 0.0
+	now := time.Now()
+	for rows.Next() {
+		r, err := scanReimbursement(rows, now)
+		if err != nil {
+			log.Printf("matchIncomingReimbursement scan: %v", err)
+			continue
+		}
+		if score, ok := scoreReimbursementMatch(r.ExpectedAmount, r.PayerKeywords, r.SpentOn, r.WindowDays, c); ok && (best == nil || score > bestScore) {
+			best, bestScore = &r, score
+		}
+	}
+	rows.Close()
+	if best == nil {
+		return
+	}
+	matched, err := markReimbursementReceived(conn, best.ID, c)
+	if err != nil {
+		log.Printf("matchIncomingReimbursement update: %v", err)
+		return
+	}
+	if matched {
+		SendPushNotification(best.UserID, "Reimbursement received",
+			fmt.Sprintf("$%.2f from %s came in for %s", c.Amount, best.Source, best.Description),
+			map[string]string{"screen": "/reimbursements"})
+	}
+}
+
+// reimbursementAging totals outstanding reimbursements by age.
+func reimbursementAging(items []models.Reimbursement) ([]models.ReimbursementAgingBucket, float64) {
+	buckets := []models.ReimbursementAgingBucket{
+		{Label: "0-30 days"}, {Label: "31-60 days"}, {Label: "61-90 days"}, {Label: "90+ days"},
+	}
+	total := 0.0
+	for _, r := range items {
+		if r.Status != "outstanding" {
+			continue
+		}
+		i := 3
+		switch {
+		case r.AgeDays <= 30:
+			i = 0
+		case r.AgeDays <= 60:
+			i = 1
+		case r.AgeDays <= 90:
+			i = 2
+		}
+		buckets[i].Count++
+		buckets[i].Amount = math.Round((buckets[i].Amount+r.ExpectedAmount)*100) / 100
+		total += r.ExpectedAmount
+	}
+	return buckets, math.Round(total*100) / 100
+}
+
+func cleanPayerKeywords(in []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, kw := range in {
+		kw = strings.ToLower(strings.TrimSpace(kw))
+		if kw != "" && !seen[kw] {
+			seen[kw] = true
+			out = append(out, kw)
+		}
+	}
+	return out
+}
+
+// MarkReimbursable marks an expense as reimbursable (PUT /auth/transactions/{id}/reimbursement).
+// If the payment has already been imported it is matched straight away.
+func MarkReimbursable(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	txID := mux.Vars(r)["id"]
+
+	var req models.ReimbursementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Source = strings.TrimSpace(req.Source)
+	var errs []ValidationError
+	if req.Source == "" {
+		errs = append(errs, ValidationError{Field: "source", Message: "source is required"})
+	}
+	if req.ExpectedAmount != nil && *req.ExpectedAmount <= 0 {
+		errs = append(errs, ValidationError{Field: "expected_amount", Message: "expected_amount must be greater than zero"})
+	}
+	if req.WindowDays == 0 {
+		req.WindowDays = defaultReimbursementWindow
+	}
+	if req.WindowDays < 1 || req.WindowDays > maxReimbursementWindow {
+		errs = append(errs, ValidationError{Field: "window_days", Message: fmt.Sprintf("window_days must be between 1 and %d", maxReimbursementWindow)})
+	}
+	if len(errs) > 0 {
+		respondValidationError(w, errs)
+		return
+	}
+
+	conn, err := reimbursementsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	if !ownershipCheck(w, conn.Raw(), "transactions", txID, userID) {
+		return
+	}
+	var txType string
+	var amount float64
+	var hh sql.NullString
+	if err := conn.QueryRow(`SELECT type, amount, household_id FROM transactions WHERE id = $1`, txID).Scan(&txType, &amount, &hh); err != nil {
+		log.Printf("MarkReimbursable load error: %v", err)
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if txType != "expense" {
+		validationError(w, "Only expenses can be reimbursable")
+		return
+	}
+	expected := amount
+	if req.ExpectedAmount != nil {
+		expected = *req.ExpectedAmount
+	}
+	if expected > amount+0.005 {
+		respondValidationError(w, []ValidationError{{Field: "expected_amount", Message: "expected_amount can't be more than the transaction amount"}})
+		return
+	}
+	var note *string
+	if req.Note != "" {
+		note = &req.Note
+	}
+
+	_, err = conn.Exec(`
+		INSERT INTO reimbursements (transaction_id, user_id, household_id, expected_amount, source, payer_keywords, window_days, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (transaction_id) DO UPDATE SET
+			expected_amount = EXCLUDED.expected_amount,
+			source = EXCLUDED.source,
+			payer_keywords = EXCLUDED.payer_keywords,
+			window_days = EXCLUDED.window_days,
+			note = EXCLUDED.note,
+			updated_at = NOW()
+	`, txID, userID, nullStringPtr(hh), expected, req.Source, pq.Array(cleanPayerKeywords(req.PayerKeywords)), req.WindowDays, note)
+	if err != nil {
+		log.Printf("MarkReimbursable upsert error: %v", err)
+		http.Error(w, "Failed to save reimbursement", http.StatusInternalServerError)
+		return
+	}
+
+	reimbursement, err := scanReimbursement(conn.QueryRow(reimbursementSelect+` WHERE r.transaction_id = $1`, txID), time.Now())
+	if err != nil {
+		log.Printf("MarkReimbursable reload error: %v", err)
+		http.Error(w, "Failed to load reimbursement", http.StatusInternalServerError)
+		return
+	}
+	if reimbursement.Status == "outstanding" {
+		if matched, err := findReimbursementPayment(conn, reimbursement); err != nil {
+			log.Printf("MarkReimbursable match error: %v", err)
+		} else if matched {
+			if reloaded, err := scanReimbursement(conn.QueryRow(reimbursementSelect+` WHERE r.transaction_id = $1`, txID), time.Now()); err == nil {
+				reimbursement = reloaded
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reimbursement)
+}
+
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+// UnmarkReimbursable removes the reimbursement from a transaction (DELETE /auth/transactions/{id}/reimbursement).
+func UnmarkReimbursable(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	txID := mux.Vars(r)["id"]
+	conn, err := reimbursementsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	if !ownershipCheck(w, conn.Raw(), "transactions", txID, userID) {
+		return
+	}
+	if _, err := conn.Exec(`DELETE FROM reimbursements WHERE transaction_id = $1`, txID); err != nil {
+		log.Printf("UnmarkReimbursable error: %v", err)
+		http.Error(w, "Failed to delete reimbursement", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListReimbursements lists reimbursements with outstanding aging
+// (GET /auth/reimbursements?status=outstanding). Without a status filter
+// every reimbursement is returned.
+func ListReimbursements(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" {
+		if verr := validateEnum(status, "status", reimbursementStatuses); verr != nil {
+			respondValidationError(w, []ValidationError{*verr})
+			return
+		}
+	}
+
+	conn, err := reimbursementsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	hh := db.ResolveHouseholdID(conn.Raw(), userID)
+	query := reimbursementSelect + `
+		WHERE (r.user_id = $1 OR ($2 <> '' AND r.household_id::text = $2))`
+	args := []any{userID, hh}
+	if status != "" {
+		query += ` AND r.status = $3`
+		args = append(args, status)
+	}
+	query += ` ORDER BY e.date ASC`
+
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		log.Printf("ListReimbursements error: %v", err)
+		http.Error(w, "Failed to load reimbursements", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := models.ReimbursementList{Reimbursements: []models.Reimbursement{}}
+	now := time.Now()
+	for rows.Next() {
+		rb, err := scanReimbursement(rows, now)
+		if err != nil {
+			log.Printf("ListReimbursements scan error: %v", err)
+			http.Error(w, "Failed to load reimbursements", http.StatusInternalServerError)
+			return
+		}
+		list.Reimbursements = append(list.Reimbursements, rb)
+	}
+	list.Aging, list.TotalOutstanding = reimbursementAging(list.Reimbursements)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// UpdateReimbursementStatus settles a reimbursement by hand (PATCH /auth/reimbursements/{id}).
+// Marking it received needs the incoming transaction; moving it back to
+// outstanding clears any match.
+func UpdateReimbursementStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := mux.Vars(r)["id"]
+
+	var req models.ReimbursementStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if verr := validateEnum(req.Status, "status", reimbursementStatuses); verr != nil {
+		respondValidationError(w, []ValidationError{*verr})
+		return
+	}
+	if req.Status == "received" && (req.MatchedTransactionID == nil || *req.MatchedTransactionID == "") {
+		respondValidationError(w, []ValidationError{{Field: "matched_transaction_id", Message: "matched_transaction_id is required when marking received"}})
+		return
+	}
+
+	conn, err := reimbursementsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	if !ownershipCheck(w, conn.Raw(), "reimbursements", id, userID) {
+		return
+	}
+
+	switch req.Status {
+	case "received":
+		payment := reimbursementCandidate{ID: *req.MatchedTransactionID}
+		var payType string
+		err := conn.QueryRow(`SELECT type, amount, date FROM transactions WHERE id = $1`, payment.ID).Scan(&payType, &payment.Amount, &payment.Date)
+		if err == sql.ErrNoRows {
+			respondValidationError(w, []ValidationError{{Field: "matched_transaction_id", Message: "transaction not found"}})
+			return
+		} else if err != nil {
+			http.Error(w, "DB error", http.StatusInternalServerError)
+			return
+		}
+		if payType != "income" {
+			respondValidationError(w, []ValidationError{{Field: "matched_transaction_id", Message: "the payment must be an income transaction"}})
+			return
+		}
+		if !ownershipCheck(w, conn.Raw(), "transactions", payment.ID, userID) {
+			return
+		}
+		_, err = conn.Exec(`
+			UPDATE reimbursements
+			SET status = 'received', received_amount = $2, matched_transaction_id = $3, received_at = $4, updated_at = NOW()
+			WHERE id = $1
+		`, id, payment.Amount, payment.ID, payment.Date)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				respondValidationError(w, []ValidationError{{Field: "matched_transaction_id", Message: "that payment is already matched to another reimbursement"}})
+				return
+			}
+			log.Printf("UpdateReimbursementStatus received error: %v", err)
+			http.Error(w, "Failed to update reimbursement", http.StatusInternalServerError)
+			return
+		}
+	default:
+		_, err = conn.Exec(`
+			UPDATE reimbursements
+			SET status = $2, received_amount = NULL, matched_transaction_id = NULL, received_at = NULL, updated_at = NOW()
+			WHERE id = $1
+		`, id, req.Status)
+		if err != nil {
+			log.Printf("UpdateReimbursementStatus error: %v", err)
+			http.Error(w, "Failed to update reimbursement", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	reimbursement, err := scanReimbursement(conn.QueryRow(reimbursementSelect+` WHERE r.id = $1`, id), time.Now())
+	if err != nil {
+		log.Printf("UpdateReimbursementStatus reload error: %v", err)
+		http.Error(w, "Failed to load reimbursement", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reimbursement)
+}
+
+// MatchReimbursements re-runs payment matching for every outstanding
+// reimbursement the user can see (POST /auth/reimbursements/match).
+func MatchReimbursements(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := reimbursementsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	hh := db.ResolveHouseholdID(conn.Raw(), userID)
+	rows, err := conn.Query(reimbursementSelect+`
+		WHERE r.status = 'outstanding'
+		  AND (r.user_id = $1 OR ($2 <> '' AND r.household_id::text = $2))
+		ORDER BY e.date ASC
+	`, userID, hh)
+	if err != nil {
+		log.Printf("MatchReimbursements query error: %v", err)
+		http.Error(w, "Failed to load reimbursements", http.StatusInternalServerError)
+		return
+	}
+	var outstanding []models.Reimbursement
+	now := time.Now()
+	for rows.Next() {
+		rb, err := scanReimbursement(rows, now)
+		if err != nil {
+			log.Printf("MatchReimbursements scan error: %v", err)
+			continue
+		}
+		outstanding = append(outstanding, rb)
+	}
+	rows.Close()
+
+	matched := 0
+	for _, rb := range outstanding {
+		ok, err := findReimbursementPayment(conn, rb)
+		if err != nil {
+			log.Printf("MatchReimbursements %s error: %v", rb.ID, err)
+			continue
+		}
+		if ok {
+			matched++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"checked": len(outstanding), "matched": matched})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/gorilla/mux"
+)
+
+func TestScoreReimbursementMatch(t *testing.T) {
+	spent := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return spent.AddDate(0, 0, d) }
+	keywords := []string{"acme payroll"}
+
+	cases := []struct {
+		name     string
+		keywords []string
+		c        reimbursementCandidate
+		ok       bool
+	}{
+		{"keyword and exact amount", keywords, reimbursementCandidate{Amount: 84.20, Date: day(12), Note: "ACME PAYROLL EXP REIMB"}, true},
+		{"keyword and amount within slack", keywords, reimbursementCandidate{Amount: 82.00, Date: day(12), Note: "Acme Payroll"}, true},
+		{"keyword but amount too far", keywords, reimbursementCandidate{Amount: 60.00, Date: day(12), Note: "ACME PAYROLL"}, false},
+		{"amount but no keyword", keywords, reimbursementCandidate{Amount: 84.20, Date: day(12), Note: "VENMO CASHOUT"}, false},
+		{"before the expense", keywords, reimbursementCandidate{Amount: 84.20, Date: day(-1), Note: "ACME PAYROLL"}, false},
+		{"after the window", keywords, reimbursementCandidate{Amount: 84.20, Date: day(45), Note: "ACME PAYROLL"}, false},
+		{"no keywords needs exact amount", nil, reimbursementCandidate{Amount: 84.20, Date: day(3), Note: "ZELLE FROM SAM"}, true},
+		{"no keywords and close amount", nil, reimbursementCandidate{Amount: 84.00, Date: day(3), Note: "ZELLE FROM SAM"}, false},
+	}
+	for _, tc := range cases {
+		if _, ok := scoreReimbursementMatch(84.20, tc.keywords, spent, 30, tc.c); ok != tc.ok {
+			t.Errorf("%s: ok = %v, want %v", tc.name, ok, tc.ok)
+		}
+	}
+
+	early, _ := scoreReimbursementMatch(84.20, keywords, spent, 30, reimbursementCandidate{Amount: 84.20, Date: day(5), Note: "ACME PAYROLL"})
+	late, _ := scoreReimbursementMatch(84.20, keywords, spent, 30, reimbursementCandidate{Amount: 84.20, Date: day(25), Note: "ACME PAYROLL"})
+	off, _ := scoreReimbursementMatch(84.20, keywords, spent, 30, reimbursementCandidate{Amount: 83.00, Date: day(5), Note: "ACME PAYROLL"})
+	if !(early > late && early > off) {
+		t.Errorf("expected the earliest exact payment to score best: early=%v late=%v off=%v", early, late, off)
+	}
+}
+
+func TestReimbursementAging(t *testing.T) {
+	items := []models.Reimbursement{
+		{Status: "outstanding", ExpectedAmount: 40, AgeDays: 3},
+		{Status: "outstanding", ExpectedAmount: 60.5, AgeDays: 45},
+		{Status: "outstanding", ExpectedAmount: 12, AgeDays: 120},
+		{Status: "received", ExpectedAmount: 500, AgeDays: 10},
+	}
+
+	buckets, total := reimbursementAging(items)
+
+	if total != 112.5 {
+		t.Errorf("total = %v, want 112.5", total)
+	}
+	want := []int{1, 1, 0, 1}
+	for i, b := range buckets {
+		if b.Count != want[i] {
+			t.Errorf("bucket %s count = %d, want %d", b.Label, b.Count, want[i])
+		}
+	}
+}
+
+func TestMarkReimbursable_RejectsIncome(t *testing.T) {
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+	orig := reimbursementsDBFactory
+	reimbursementsDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	defer func() { reimbursementsDBFactory = orig }()
+
+	mock.ExpectQuery(`SELECT user_id, household_id FROM transactions`).
+		WithArgs("tx1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "household_id"}).AddRow("u1", nil))
+	mock.ExpectQuery(`SELECT type, amount, household_id FROM transactions`).
+		WithArgs("tx1").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "amount", "household_id"}).AddRow("income", 100.0, nil))
+
+	req := httptest.NewRequest(http.MethodPut, "/auth/transactions/tx1/reimbursement", strings.NewReader(`{"source":"Acme"}`))
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, "u1"))
+	req = mux.SetURLVars(req, map[string]string{"id": "tx1"})
+	rr := httptest.NewRecorder()
+
+	MarkReimbursable(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
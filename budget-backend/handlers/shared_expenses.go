@@ -0,0 +1,870 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// sharedExpensesDBFactory allows swapping the DB in tests.
+var sharedExpensesDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+var splitMethods = []string{"equal", "income", "custom"}
+
+// incomeRatioDays is the income window the "income" split method looks at.
+const incomeRatioDays = 90
+
+// householdMember is a member row with their custom split ratio, if set.
+type householdMember struct {
+	userID string
+	name   string
+	ratio  sql.NullFloat64
+}
+
+func loadHouseholdMembers(conn db.DBTX, householdID string) ([]householdMember, error) {
+	rows, err := conn.Query(`
+		SELECT hm.user_id, COALESCE(u.full_name, u.email, ''), hm.split_ratio
+		FROM household_members hm
+		JOIN users u ON u.id = hm.user_id
+		WHERE hm.household_id = $1
+		ORDER BY hm.user_id
+	`, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var members []householdMember
+	for rows.Next() {
+		var m householdMember
+		if err := rows.Scan(&m.userID, &m.name, &m.ratio); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func memberNames(members []householdMember) map[string]string {
+	names := make(map[string]string, len(members))
+	for _, m := range members {
+		names[m.userID] = m.name
+	}
+	return names
+}
+
+// proportionalRatios turns weights into ratios that add up to 1, falling
+// back to an equal split when there is nothing to weigh.
+func proportionalRatios(members []householdMember, weights map[string]float64) []models.MemberSplitRatio {
+	total := 0.0
+	for _, m := range members {
+		total += math.Max(weights[m.userID], 0)
+	}
+	out := make([]models.MemberSplitRatio, len(members))
+	for i, m := range members {
+		ratio := 1 / float64(len(members))
+		if total > 0 {
+			ratio = math.Max(weights[m.userID], 0) / total
+		}
+		out[i] = models.MemberSplitRatio{UserID: m.userID, Name: m.name, Ratio: math.Round(ratio*10000) / 10000}
+	}
+	return out
+}
+
+// householdSplitSettings loads the household's split method and the ratios
+// it produces right now.
+func householdSplitSettings(conn db.DBTX, householdID string) (models.HouseholdSplitSettings, []householdMember, error) {
+	settings := models.HouseholdSplitSettings{HouseholdID: householdID, Members: []models.MemberSplitRatio{}}
+	if err := conn.QueryRow(`SELECT split_method FROM households WHERE id = $1`, householdID).Scan(&settings.Method); err != nil {
+		return settings, nil, err
+	}
+	members, err := loadHouseholdMembers(conn, householdID)
+	if err != nil || len(members) == 0 {
+		return settings, members, err
+	}
+
+	weights := map[string]float64{}
+	switch settings.Method {
+	case "custom":
+		for _, m := range members {
+			if m.ratio.Valid {
+				weights[m.userID] = m.ratio.Float64
+			}
+		}
+	case "income":
+		ids := make([]string, len(members))
+		for i, m := range members {
+			ids[i] = m.userID
+		}
+		rows, err := conn.Query(`
+			SELECT user_id, SUM(amount)
+			FROM transactions
+			WHERE user_id = ANY($1::uuid[]) AND type = 'income' AND date >= CURRENT_DATE - $2::int
+			GROUP BY user_id
+		`, pq.Array(ids), incomeRatioDays)
+		if err != nil {
+			return settings, members, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			var income float64
+			if err := rows.Scan(&id, &income); err != nil {
+				return settings, members, err
+			}
+			weights[id] = income
+		}
+	}
+	settings.Members = proportionalRatios(members, weights)
+	return settings, members, nil
+}
+
+// splitByRatios divides amount by ratio in whole cents; the largest share
+// absorbs the rounding so the shares add up exactly.
+func splitByRatios(amount float64, ratios []models.MemberSplitRatio) []models.ShareEntry {
+	out := make([]models.ShareEntry, len(ratios))
+	allocated, largest := 0.0, 0
+	for i, r := range ratios {
+		out[i] = models.ShareEntry{UserID: r.UserID, Amount: math.Round(amount*r.Ratio*100) / 100}
+		allocated += out[i].Amount
+		if out[i].Amount > out[largest].Amount {
+			largest = i
+		}
+	}
+	if len(out) > 0 {
+		out[largest].Amount = math.Round((out[largest].Amount+amount-allocated)*100) / 100
+	}
+	return out
+}
+
+// GetSplitSettings returns the household's default split (GET /auth/households/split-settings).
+func GetSplitSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := sharedExpensesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	householdID := db.ResolveHouseholdID(conn.Raw(), userID)
+	if householdID == "" {
+		http.Error(w, "User not in a household", http.StatusBadRequest)
+		return
+	}
+	settings, _, err := householdSplitSettings(conn, householdID)
+	if err != nil {
+		log.Printf("GetSplitSettings error: %v", err)
+		http.Error(w, "Failed to load split settings", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateSplitSettings changes the household's default split (PUT /auth/households/split-settings).
+func UpdateSplitSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.SplitSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if verr := validateEnum(req.Method, "method", splitMethods); verr != nil {
+		respondValidationError(w, []ValidationError{*verr})
+		return
+	}
+
+	conn, err := sharedExpensesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	householdID := db.ResolveHouseholdID(conn.Raw(), userID)
+	if householdID == "" {
+		http.Error(w, "User not in a household", http.StatusBadRequest)
+		return
+	}
+	members, err := loadHouseholdMembers(conn, householdID)
+	if err != nil {
+		log.Printf("UpdateSplitSettings members error: %v", err)
+		http.Error(w, "Failed to load household", http.StatusInternalServerError)
+		return
+	}
+
+	if req.Method == "custom" {
+		names := memberNames(members)
+		total := 0.0
+		for id, ratio := range req.Ratios {
+			if _, ok := names[id]; !ok {
+				respondValidationError(w, []ValidationError{{Field: "ratios", Message: "ratios must only include household members"}})
+				return
+			}
+			if ratio < 0 || ratio > 1 {
+				respondValidationError(w, []ValidationError{{Field: "ratios", Message: "each ratio must be between 0 and 1"}})
+				return
+			}
+			total += ratio
+		}
+		if math.Abs(total-1) > 0.001 {
+			respondValidationError(w, []ValidationError{{Field: "ratios", Message: "ratios must add up to 1"}})
+			return
+		}
+	}
+
+	tx, err := conn.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE households SET split_method = $2 WHERE id = $1`, householdID, req.Method); err != nil {
+		log.Printf("UpdateSplitSettings update error: %v", err)
+		http.Error(w, "Failed to update split settings", http.StatusInternalServerError)
+		return
+	}
+	if req.Method == "custom" {
+		for _, m := range members {
+			if _, err := tx.Exec(`UPDATE household_members SET split_ratio = $3 WHERE household_id = $1 AND user_id = $2`,
+				householdID, m.userID, req.Ratios[m.userID]); err != nil {
+				log.Printf("UpdateSplitSettings ratio error: %v", err)
+				http.Error(w, "Failed to update split settings", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	settings, _, err := householdSplitSettings(conn, householdID)
+	if err != nil {
+		log.Printf("UpdateSplitSettings reload error: %v", err)
+		http.Error(w, "Failed to load split settings", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// loadTransactionShares returns the shares on each of the given
+// transactions, split-level shares included.
+func loadTransactionShares(conn *sql.DB, txIDs []string) (map[string][]models.TransactionShare, error) {
+	out := map[string][]models.TransactionShare{}
+	if len(txIDs) == 0 {
+		return out, nil
+	}
+	rows, err := conn.Query(`
+		SELECT s.id, s.transaction_id, s.split_id, s.user_id, COALESCE(u.full_name, u.email, ''), s.amount, s.created_at
+		FROM transaction_shares s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.transaction_id = ANY($1::uuid[])
+		ORDER BY s.split_id NULLS FIRST, s.amount DESC
+	`, pq.Array(txIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s models.TransactionShare
+		if err := rows.Scan(&s.ID, &s.TransactionID, &s.SplitID, &s.UserID, &s.UserName, &s.Amount, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		out[s.TransactionID] = append(out[s.TransactionID], s)
+	}
+	return out, rows.Err()
+}
+
+// shareableTransaction loads a transaction for share editing. The caller
+// needs access to it and its owner must belong to the caller's household.
+// It writes the HTTP error and returns ok=false otherwise.
+func shareableTransaction(w http.ResponseWriter, conn db.DBTX, txID, userID string) (householdID string, members []householdMember, amount float64, ok bool) {
+	if !ownershipCheck(w, conn.Raw(), "transactions", txID, userID) {
+		return "", nil, 0, false
+	}
+	householdID = db.ResolveHouseholdID(conn.Raw(), userID)
+	if householdID == "" {
+		http.Error(w, "User not in a household", http.StatusBadRequest)
+		return "", nil, 0, false
+	}
+	var owner string
+	if err := conn.QueryRow(`SELECT user_id, amount FROM transactions WHERE id = $1`, txID).Scan(&owner, &amount); err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return "", nil, 0, false
+	}
+	members, err := loadHouseholdMembers(conn, householdID)
+	if err != nil {
+		log.Printf("shareableTransaction members error: %v", err)
+		http.Error(w, "Failed to load household", http.StatusInternalServerError)
+		return "", nil, 0, false
+	}
+	if _, found := memberNames(members)[owner]; !found {
+		validationError(w, "Only transactions paid by a household member can be shared")
+		return "", nil, 0, false
+	}
+	return householdID, members, math.Abs(amount), true
+}
+
+// GetTransactionShares lists who shares a transaction (GET /auth/transactions/{id}/shares).
+func GetTransactionShares(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	txID := mux.Vars(r)["id"]
+	conn, err := sharedExpensesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	if !ownershipCheck(w, conn.Raw(), "transactions", txID, userID) {
+		return
+	}
+	shares, err := loadTransactionShares(conn.Raw(), []string{txID})
+	if err != nil {
+		log.Printf("GetTransactionShares error: %v", err)
+		http.Error(w, "Failed to load shares", http.StatusInternalServerError)
+		return
+	}
+	out := shares[txID]
+	if out == nil {
+		out = []models.TransactionShare{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// SetTransactionShares sets each member's share of a transaction, or of one
+// of its splits (PUT /auth/transactions/{id}/shares). Shares must add up to
+// the amount being shared. A transaction is shared either as a whole or
+// split by split, so setting one replaces the other.
+func SetTransactionShares(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	txID := mux.Vars(r)["id"]
+
+	var req models.ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.SplitID != nil && *req.SplitID == "" {
+		req.SplitID = nil
+	}
+
+	conn, err := sharedExpensesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	householdID, members, amount, ok := shareableTransaction(w, conn, txID, userID)
+	if !ok {
+		return
+	}
+	if req.SplitID != nil {
+		err := conn.QueryRow(`SELECT amount FROM transaction_splits WHERE id = $1 AND transaction_id = $2`, *req.SplitID, txID).Scan(&amount)
+		if err == sql.ErrNoRows {
+			respondValidationError(w, []ValidationError{{Field: "split_id", Message: "split not found on this transaction"}})
+			return
+		} else if err != nil {
+			log.Printf("SetTransactionShares split error: %v", err)
+			http.Error(w, "DB error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.UseDefault {
+		settings, _, err := householdSplitSettings(conn, householdID)
+		if err != nil {
+			log.Printf("SetTransactionShares settings error: %v", err)
+			http.Error(w, "Failed to load split settings", http.StatusInternalServerError)
+			return
+		}
+		req.Shares = splitByRatios(amount, settings.Members)
+	}
+
+	names := memberNames(members)
+	seen := map[string]bool{}
+	sum := 0.0
+	for _, s := range req.Shares {
+		if _, ok := names[s.UserID]; !ok {
+			respondValidationError(w, []ValidationError{{Field: "shares", Message: "shares must only include household members"}})
+			return
+		}
+		if seen[s.UserID] {
+			respondValidationError(w, []ValidationError{{Field: "shares", Message: "each member can only appear once"}})
+			return
+		}
+		if s.Amount < 0 {
+			respondValidationError(w, []ValidationError{{Field: "shares", Message: "share amounts cannot be negative"}})
+			return
+		}
+		seen[s.UserID] = true
+		sum += s.Amount
+	}
+	if len(req.Shares) == 0 {
+		respondValidationError(w, []ValidationError{{Field: "shares", Message: "at least one share is required"}})
+		return
+	}
+	if math.Abs(sum-amount) > 0.01 {
+		respondValidationError(w, []ValidationError{{Field: "shares", Message: fmt.Sprintf("shares must add up to %.2f", amount)}})
+		return
+	}
+
+	tx, err := conn.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	clear := `DELETE FROM transaction_shares WHERE transaction_id = $1`
+	args := []any{txID}
+	if req.SplitID != nil {
+		clear += ` AND (split_id IS NULL OR split_id = $2)`
+		args = append(args, *req.SplitID)
+	}
+	if _, err := tx.Exec(clear, args...); err != nil {
+		log.Printf("SetTransactionShares delete error: %v", err)
+		http.Error(w, "Failed to save shares", http.StatusInternalServerError)
+		return
+	}
+	for _, s := range req.Shares {
+		if _, err := tx.Exec(`
+			INSERT INTO transaction_shares (transaction_id, split_id, user_id, amount)
+			VALUES ($1, $2, $3, $4)
+		`, txID, req.SplitID, s.UserID, s.Amount); err != nil {
+			log.Printf("SetTransactionShares insert error: %v", err)
+			http.Error(w, "Failed to save shares", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	shares, err := loadTransactionShares(conn.Raw(), []string{txID})
+	if err != nil {
+		log.Printf("SetTransactionShares reload error: %v", err)
+	}
+	out := shares[txID]
+	if out == nil {
+		out = []models.TransactionShare{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// DeleteTransactionShares stops sharing a transaction, or one split of it
+// with ?split_id= (DELETE /auth/transactions/{id}/shares).
+func DeleteTransactionShares(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	txID := mux.Vars(r)["id"]
+	conn, err := sharedExpensesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	if !ownershipCheck(w, conn.Raw(), "transactions", txID, userID) {
+		return
+	}
+	query := `DELETE FROM transaction_shares WHERE transaction_id = $1`
+	args := []any{txID}
+	if splitID := r.URL.Query().Get("split_id"); splitID != "" {
+		query += ` AND split_id = $2`
+		args = append(args, splitID)
+	}
+	if _, err := conn.Exec(query, args...); err != nil {
+		log.Printf("DeleteTransactionShares error: %v", err)
+		http.Error(w, "Failed to delete shares", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// memberPair is a debtor and creditor, in that order.
+type memberPair [2]string
+
+// netDebts nets what each pair of members owes the other, so at most one
+// debt remains per pair. Debts come back largest first.
+func netDebts(owed map[memberPair]float64, names map[string]string) ([]models.MemberDebt, []models.MemberNet) {
+	pairs := map[memberPair]bool{}
+	for pair := range owed {
+		if pair[0] > pair[1] {
+			pair = memberPair{pair[1], pair[0]}
+		}
+		pairs[pair] = true
+	}
+
+	net := map[string]float64{}
+	debts := []models.MemberDebt{}
+	for pair := range pairs {
+		from, to := pair[0], pair[1]
+		diff := owed[memberPair{from, to}] - owed[memberPair{to, from}]
+		if diff < 0 {
+			from, to, diff = to, from, -diff
+		}
+		diff = math.Round(diff*100) / 100
+		if diff == 0 {
+			continue
+		}
+		debts = append(debts, models.MemberDebt{FromUserID: from, FromName: names[from], ToUserID: to, ToName: names[to], Amount: diff})
+		net[from] -= diff
+		net[to] += diff
+	}
+	sort.Slice(debts, func(i, j int) bool {
+		if debts[i].Amount != debts[j].Amount {
+			return debts[i].Amount > debts[j].Amount
+		}
+		return debts[i].FromUserID < debts[j].FromUserID
+	})
+
+	ids := make([]string, 0, len(names))
+	for id := range names {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	members := make([]models.MemberNet, len(ids))
+	for i, id := range ids {
+		members[i] = models.MemberNet{UserID: id, Name: names[id], Net: math.Round(net[id]*100) / 100}
+	}
+	return debts, members
+}
+
+// householdBalance totals shared expenses and settlements into the running
+// who-owes-whom. A member owes the payer their share of each expense; shared
+// income (refunds) runs the other way, and a settlement credits its payer.
+func householdBalance(conn db.DBTX, householdID string, members []householdMember) (models.HouseholdBalance, error) {
+	balance := models.HouseholdBalance{HouseholdID: householdID}
+	owed := map[memberPair]float64{}
+
+	rows, err := conn.Query(`
+		SELECT s.user_id, t.user_id,
+		       SUM(CASE WHEN t.type = 'income' THEN -s.amount ELSE s.amount END)
+		FROM transaction_shares s
+		JOIN transactions t ON t.id = s.transaction_id
+		JOIN household_members payer ON payer.user_id = t.user_id AND payer.household_id = $1
+		JOIN household_members debtor ON debtor.user_id = s.user_id AND debtor.household_id = $1
+		WHERE s.user_id <> t.user_id AND t.type IN ('expense', 'income')
+		GROUP BY s.user_id, t.user_id
+	`, householdID)
+	if err != nil {
+		return balance, err
+	}
+	for rows.Next() {
+		var debtor, payer string
+		var amount float64
+		if err := rows.Scan(&debtor, &payer, &amount); err != nil {
+			rows.Close()
+			return balance, err
+		}
+		owed[memberPair{debtor, payer}] += amount
+	}
+	rows.Close()
+
+	rows, err = conn.Query(`
+		SELECT from_user_id, to_user_id, SUM(amount)
+		FROM settlements WHERE household_id = $1
+		GROUP BY from_user_id, to_user_id
+	`, householdID)
+	if err != nil {
+		return balance, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var from, to string
+		var amount float64
+		if err := rows.Scan(&from, &to, &amount); err != nil {
+			return balance, err
+		}
+		owed[memberPair{to, from}] += amount
+	}
+	if err := rows.Err(); err != nil {
+		return balance, err
+	}
+
+	balance.Debts, balance.Members = netDebts(owed, memberNames(members))
+	return balance, nil
+}
+
+// GetHouseholdBalance returns who owes whom (GET /auth/households/balance).
+func GetHouseholdBalance(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := sharedExpensesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	householdID := db.ResolveHouseholdID(conn.Raw(), userID)
+	if householdID == "" {
+		http.Error(w, "User not in a household", http.StatusBadRequest)
+		return
+	}
+	members, err := loadHouseholdMembers(conn, householdID)
+	if err != nil {
+		log.Printf("GetHouseholdBalance members error: %v", err)
+		http.Error(w, "Failed to load household", http.StatusInternalServerError)
+		return
+	}
+	balance, err := householdBalance(conn, householdID, members)
+	if err != nil {
+		log.Printf("GetHouseholdBalance error: %v", err)
+		http.Error(w, "Failed to compute balance", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balance)
+}
+
+// SettleUp records a payment between two members (POST /auth/households/settle-up).
+// It adds a transfer transaction for the payer and a settlement that
+// clears that much of their balance. Either party can record it.
+func SettleUp(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.SettleUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.FromUserID == "" {
+		req.FromUserID = userID
+	}
+	if req.Amount < 0 {
+		respondValidationError(w, []ValidationError{{Field: "amount", Message: "amount cannot be negative"}})
+		return
+	}
+
+	conn, err := sharedExpensesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	householdID := db.ResolveHouseholdID(conn.Raw(), userID)
+	if householdID == "" {
+		http.Error(w, "User not in a household", http.StatusBadRequest)
+		return
+	}
+	members, err := loadHouseholdMembers(conn, householdID)
+	if err != nil {
+		log.Printf("SettleUp members error: %v", err)
+		http.Error(w, "Failed to load household", http.StatusInternalServerError)
+		return
+	}
+	names := memberNames(members)
+	if req.ToUserID == "" && len(members) == 2 {
+		for _, m := range members {
+			if m.userID != req.FromUserID {
+				req.ToUserID = m.userID
+			}
+		}
+	}
+	if _, ok := names[req.FromUserID]; !ok {
+		respondValidationError(w, []ValidationError{{Field: "from_user_id", Message: "must be a household member"}})
+		return
+	}
+	if _, ok := names[req.ToUserID]; !ok || req.ToUserID == req.FromUserID {
+		respondValidationError(w, []ValidationError{{Field: "to_user_id", Message: "must be another household member"}})
+		return
+	}
+	if userID != req.FromUserID && userID != req.ToUserID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	balance, err := householdBalance(conn, householdID, members)
+	if err != nil {
+		log.Printf("SettleUp balance error: %v", err)
+		http.Error(w, "Failed to compute balance", http.StatusInternalServerError)
+		return
+	}
+	owes := 0.0
+	for _, d := range balance.Debts {
+		if d.FromUserID == req.FromUserID && d.ToUserID == req.ToUserID {
+			owes = d.Amount
+		}
+	}
+	if owes == 0 {
+		validationError(w, "Nothing to settle between these members")
+		return
+	}
+	if req.Amount == 0 {
+		req.Amount = owes
+	}
+	if req.Amount > owes+0.005 {
+		respondValidationError(w, []ValidationError{{Field: "amount", Message: fmt.Sprintf("amount is more than the %.2f owed", owes)}})
+		return
+	}
+	req.Amount = math.Round(req.Amount*100) / 100
+
+	tx, err := conn.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	txID := uuid.New().String()
+	note := fmt.Sprintf("Settle up with %s", names[req.ToUserID])
+	if req.Note != "" {
+		note = req.Note
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO transactions (id, user_id, household_id, type, amount, currency, note, date, source, user_verified)
+		VALUES ($1, $2, $3, 'transfer', $4, 'USD', $5, $6, 'settlement', true)
+	`, txID, req.FromUserID, householdID, req.Amount, note, time.Now()); err != nil {
+		log.Printf("SettleUp transaction insert error: %v", err)
+		http.Error(w, "Failed to record settlement", http.StatusInternalServerError)
+		return
+	}
+
+	settlement := models.Settlement{
+		HouseholdID:   householdID,
+		FromUserID:    req.FromUserID,
+		FromName:      names[req.FromUserID],
+		ToUserID:      req.ToUserID,
+		ToName:        names[req.ToUserID],
+		Amount:        req.Amount,
+		TransactionID: &txID,
+		CreatedBy:     &userID,
+	}
+	if req.Note != "" {
+		settlement.Note = &req.Note
+	}
+	if err := tx.QueryRow(`
+		INSERT INTO settlements (household_id, from_user_id, to_user_id, amount, transaction_id, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, householdID, req.FromUserID, req.ToUserID, req.Amount, txID, settlement.Note, userID).Scan(&settlement.ID, &settlement.CreatedAt); err != nil {
+		log.Printf("SettleUp settlement insert error: %v", err)
+		http.Error(w, "Failed to record settlement", http.StatusInternalServerError)
+		return
+	}
+
+	description := fmt.Sprintf("%s paid %s $%.2f to settle up", names[req.FromUserID], names[req.ToUserID], req.Amount)
+	metadata, _ := json.Marshal(map[string]any{"settlement_id": settlement.ID, "amount": req.Amount})
+	if err := recordActivityMetadata(tx, householdID, userID, "settled_up", "settlement", description, string(metadata)); err != nil {
+		http.Error(w, "Failed to record settlement", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	other := req.ToUserID
+	if userID == req.ToUserID {
+		other = req.FromUserID
+	}
+	SendPushNotification(other, "Settled up", description, map[string]string{"screen": "/(tabs)/household"})
+
+	balance, err = householdBalance(conn, householdID, members)
+	if err != nil {
+		log.Printf("SettleUp balance reload error: %v", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"settlement": settlement, "balance": balance})
+}
+
+// ListSettlements returns the household's settle-up history (GET /auth/households/settlements).
+func ListSettlements(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := sharedExpensesDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	householdID := db.ResolveHouseholdID(conn.Raw(), userID)
+	if householdID == "" {
+		http.Error(w, "User not in a household", http.StatusBadRequest)
+		return
+	}
+	rows, err := conn.Query(`
+		SELECT s.id, s.household_id, s.from_user_id, COALESCE(fu.full_name, fu.email, ''),
+		       s.to_user_id, COALESCE(tu.full_name, tu.email, ''), s.amount, s.transaction_id,
+		       s.note, s.created_by, s.created_at
+		FROM settlements s
+		JOIN users fu ON fu.id = s.from_user_id
+		JOIN users tu ON tu.id = s.to_user_id
+		WHERE s.household_id = $1
+		ORDER BY s.created_at DESC
+		LIMIT 100
+	`, householdID)
+	if err != nil {
+		log.Printf("ListSettlements error: %v", err)
+		http.Error(w, "Failed to load settlements", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	settlements := []models.Settlement{}
+	for rows.Next() {
+		var s models.Settlement
+		if err := rows.Scan(&s.ID, &s.HouseholdID, &s.FromUserID, &s.FromName, &s.ToUserID, &s.ToName,
+			&s.Amount, &s.TransactionID, &s.Note, &s.CreatedBy, &s.CreatedAt); err != nil {
+			log.Printf("ListSettlements scan error: %v", err)
+			http.Error(w, "Failed to load settlements", http.StatusInternalServerError)
+			return
+		}
+		settlements = append(settlements, s)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settlements)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+)
+
+func TestProportionalRatiosAndSplit(t *testing.T) {
+	members := []householdMember{{userID: "a", name: "Alex"}, {userID: "b", name: "Bo"}}
+
+	ratios := proportionalRatios(members, map[string]float64{"a": 6000, "b": 4000})
+	if ratios[0].Ratio != 0.6 || ratios[1].Ratio != 0.4 {
+		t.Fatalf("income ratios = %+v", ratios)
+	}
+	if equal := proportionalRatios(members, nil); equal[0].Ratio != 0.5 || equal[1].Ratio != 0.5 {
+		t.Fatalf("expected an equal split without income, got %+v", equal)
+	}
+
+	shares := splitByRatios(100.01, proportionalRatios(members, nil))
+	if math.Round((shares[0].Amount+shares[1].Amount)*100) != 10001 {
+		t.Fatalf("shares don't add up: %+v", shares)
+	}
+}
+
+func TestNetDebts(t *testing.T) {
+	names := map[string]string{"a": "Alex", "b": "Bo", "c": "Cam"}
+	owed := map[memberPair]float64{
+		{"b", "a"}: 60,  // Bo owes Alex for dinner
+		{"a", "b"}: 25,  // Alex owes Bo for groceries
+		{"c", "a"}: 10,  // Cam owes Alex
+		{"a", "c"}: 10,  // and Alex owes Cam the same
+		{"b", "c"}: 0.5, // rounding-sized debt still counts
+	}
+
+	debts, members := netDebts(owed, names)
+
+	if len(debts) != 2 {
+		t.Fatalf("debts = %+v", debts)
+	}
+	if debts[0].FromUserID != "b" || debts[0].ToUserID != "a" || debts[0].Amount != 35 {
+		t.Errorf("first debt = %+v", debts[0])
+	}
+	if debts[1].FromUserID != "b" || debts[1].ToUserID != "c" || debts[1].Amount != 0.5 {
+		t.Errorf("second debt = %+v", debts[1])
+	}
+	net := map[string]float64{}
+	for _, m := range members {
+		net[m.UserID] = m.Net
+	}
+	if net["a"] != 35 || net["b"] != -35.5 || net["c"] != 0.5 {
+		t.Errorf("net = %v", net)
+	}
+}
+
+func TestSettleUp_SettlesFullBalance(t *testing.T) {
+	const alex = "11111111-1111-1111-1111-111111111111"
+	const bo = "22222222-2222-2222-2222-222222222222"
+
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+	orig := sharedExpensesDBFactory
+	sharedExpensesDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	defer func() { sharedExpensesDBFactory = orig }()
+	pushed := stubPush(t)
+
+	mock.ExpectQuery(`SELECT household_id FROM household_members`).
+		WithArgs(bo).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow("hh1"))
+	mock.ExpectQuery(`FROM household_members hm`).
+		WithArgs("hh1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "name", "split_ratio"}).
+			AddRow(alex, "Alex", nil).
+			AddRow(bo, "Bo", nil))
+	mock.ExpectQuery(`FROM transaction_shares s`).
+		WithArgs("hh1").
+		WillReturnRows(sqlmock.NewRows([]string{"debtor", "payer", "amount"}).AddRow(bo, alex, 60.0))
+	mock.ExpectQuery(`FROM settlements WHERE household_id`).
+		WithArgs("hh1").
+		WillReturnRows(sqlmock.NewRows([]string{"from", "to", "amount"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO transactions`).
+		WithArgs(sqlmock.AnyArg(), bo, "hh1", 60.0, "Settle up with Alex", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO settlements`).
+		WithArgs("hh1", bo, alex, 60.0, sqlmock.AnyArg(), nil, bo).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("s1", "2026-04-01T00:00:00Z"))
+	mock.ExpectExec(`INSERT INTO activity_events`).
+		WithArgs(sqlmock.AnyArg(), "hh1", bo, "settled_up", "settlement", "Bo paid Alex $60.00 to settle up", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`FROM transaction_shares s`).
+		WillReturnRows(sqlmock.NewRows([]string{"debtor", "payer", "amount"}).AddRow(bo, alex, 60.0))
+	mock.ExpectQuery(`FROM settlements WHERE household_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"from", "to", "amount"}).AddRow(bo, alex, 60.0))
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/households/settle-up", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, bo))
+	rr := httptest.NewRecorder()
+
+	SettleUp(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Settlement models.Settlement       `json:"settlement"`
+		Balance    models.HouseholdBalance `json:"balance"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("bad response: %v", err)
+	}
+	if resp.Settlement.ToUserID != alex || resp.Settlement.Amount != 60 {
+		t.Errorf("unexpected settlement: %+v", resp.Settlement)
+	}
+	if len(resp.Balance.Debts) != 0 {
+		t.Errorf("expected the balance to be cleared, got %+v", resp.Balance.Debts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+	waitForPush(t, pushed)
+}
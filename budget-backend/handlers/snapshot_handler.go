@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/internal/ai"
 	"github.com/aboogie/budget-backend/models"
 	"github.com/gorilla/mux"
 )
@@ -59,7 +60,12 @@ func CreateSnapshot(w http.ResponseWriter, r *http.Request) {
 	_ = conn.QueryRow(sq, sArgs...).Scan(&totalSavings)
 
 	var totalBalance float64
-	_ = conn.QueryRow(`SELECT COALESCE(SUM(current_balance), 0) FROM account_balances WHERE user_id = $1`, userID).Scan(&totalBalance)
+	_ = conn.QueryRow(`SELECT COALESCE(SUM(current_balance), 0) FROM account_balances WHERE user_id = $1 AND NOT is_liability AND sync_enabled`, userID).Scan(&totalBalance)
+
+	nw, err := ai.CalculateNetWorth(conn.Raw(), userID, householdID)
+	if err != nil {
+		log.Printf("CreateSnapshot net worth error: %v", err)
+	}
 
 	var monthlyIncome float64
 	_ = conn.QueryRow(`
@@ -79,7 +85,7 @@ func CreateSnapshot(w http.ResponseWriter, r *http.Request) {
 		"total_balance":    totalBalance,
 		"monthly_income":   monthlyIncome,
 		"monthly_expenses": monthlyExpenses,
-		"net_worth":        totalSavings + totalBalance - totalDebt,
+		"net_worth":        totalSavings + nw.NetWorth,
 	}
 
 	// Gather progress metrics
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/aboogie/budget-backend/internal/recurring"
+	"github.com/aboogie/budget-backend/models"
+)
+
+// summarizeSubscriptions builds the subscriptions view: each service's cost
+// per month and year, its last and next charge, and any alerts.
+func summarizeSubscriptions(subs []recurring.Subscription, now time.Time) models.SubscriptionSummary {
+	summary := models.SubscriptionSummary{
+		Subscriptions: []models.Subscription{},
+		Alerts:        []models.SubscriptionAlert{},
+	}
+	for _, s := range subs {
+		view := models.Subscription{
+			BillID:      s.BillID,
+			Name:        s.Name,
+			Frequency:   s.Frequency,
+			AmountDue:   s.AmountDue,
+			IsAutopay:   s.IsAutopay,
+			MonthlyCost: recurring.MonthlyCost(s.AmountDue, s.Frequency),
+			Alerts:      recurring.Alerts(s, now),
+		}
+		view.AnnualCost = math.Round(view.MonthlyCost*12*100) / 100
+		if view.Alerts == nil {
+			view.Alerts = []models.SubscriptionAlert{}
+		}
+		if s.TrialEndsAt != nil {
+			d := s.TrialEndsAt.Format("2006-01-02")
+			view.TrialEndsAt = &d
+		}
+		if n := len(s.Payments); n > 0 {
+			last := s.Payments[n-1]
+			d := last.Date.Format("2006-01-02")
+			view.LastCharged = &d
+			view.LastAmount = &last.Amount
+		}
+		if next, ok := s.NextCharge(now); ok {
+			d := next.Format("2006-01-02")
+			view.NextCharge = &d
+		}
+
+		summary.MonthlyTotal += view.MonthlyCost
+		summary.Alerts = append(summary.Alerts, view.Alerts...)
+		summary.Subscriptions = append(summary.Subscriptions, view)
+	}
+
+	sort.SliceStable(summary.Subscriptions, func(i, j int) bool {
+		return summary.Subscriptions[i].MonthlyCost > summary.Subscriptions[j].MonthlyCost
+	})
+	summary.MonthlyTotal = math.Round(summary.MonthlyTotal*100) / 100
+	summary.AnnualTotal = math.Round(summary.MonthlyTotal*12*100) / 100
+	return summary
+}
+
+// ListSubscriptions returns the subscriptions view (GET /auth/subscriptions).
+func ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := billsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	now := time.Now().UTC()
+	subs, err := recurring.LoadSubscriptions(conn.Raw(), userID, now.AddDate(-1, -1, 0))
+	if err != nil {
+		log.Printf("ListSubscriptions query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summarizeSubscriptions(subs, now))
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/models"
+)
+
+func TestListSubscriptions_SummarizesCost(t *testing.T) {
+	userID := "11111111-1111-1111-1111-111111111111"
+	now := time.Now().UTC()
+
+	withBillsMockDB(t, func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`FROM bills`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "frequency", "amount_due", "is_autopay", "trial_ends_at"}).
+				AddRow("b1", "Netflix", "monthly", 17.99, true, nil).
+				AddRow("b2", "Prime", "yearly", 139.0, false, nil))
+		mock.ExpectQuery(`FROM bill_payments`).
+			WillReturnRows(sqlmock.NewRows([]string{"bill_id", "amount_paid", "paid_date"}).
+				AddRow("b1", 15.49, now.AddDate(0, -1, -2)).
+				AddRow("b1", 17.99, now.AddDate(0, 0, -2)))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/subscriptions", nil)
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+	rr := httptest.NewRecorder()
+
+	ListSubscriptions(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var summary models.SubscriptionSummary
+	if err := json.Unmarshal(rr.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("bad response: %v", err)
+	}
+	if summary.MonthlyTotal != 29.57 || summary.AnnualTotal != 354.84 {
+		t.Errorf("totals = %v / %v", summary.MonthlyTotal, summary.AnnualTotal)
+	}
+	if len(summary.Subscriptions) != 2 || summary.Subscriptions[0].Name != "Netflix" {
+		t.Fatalf("expected Netflix first, got %+v", summary.Subscriptions)
+	}
+	if !summary.Subscriptions[0].IsAutopay || summary.Subscriptions[1].IsAutopay {
+		t.Errorf("autopay should come from the bills, got %+v", summary.Subscriptions)
+	}
+	if len(summary.Alerts) != 1 || summary.Alerts[0].Type != "price_increase" {
+		t.Errorf("expected the Netflix price increase, got %+v", summary.Alerts)
+	}
+}
@@ -0,0 +1,622 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// tagsDBFactory allows swapping the DB in tests.
+var tagsDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+const maxTagNameLength = 50
+
+// tagScopeWhere limits the tags table (aliased tg) to the user's household
+// tags plus personal tags made outside a household. $1 is the user ID and $2
+// the household ID.
+const tagScopeWhere = `(tg.household_id::text = $2 OR (tg.household_id IS NULL AND tg.user_id = $1))`
+
+// cleanTagName trims a tag name and collapses inner whitespace, returning ""
+// for names that are empty or too long.
+func cleanTagName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || len(name) > maxTagNameLength {
+		return ""
+	}
+	return name
+}
+
+// parseTagFilter reads the tag_id query parameter, which may be repeated or
+// comma-separated. Transactions match when they carry any of the tags.
+func parseTagFilter(r *http.Request) ([]string, *ValidationError) {
+	var ids []string
+	for _, v := range r.URL.Query()["tag_id"] {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, validateUUIDs(ids, "tag_id")
+}
+
+// validateUUIDs checks ids that queries cast to uuid[], where a malformed
+// one would fail the whole query.
+func validateUUIDs(ids []string, field string) *ValidationError {
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return &ValidationError{Field: field, Message: fmt.Sprintf("%q is not a valid id", id)}
+		}
+	}
+	return nil
+}
+
+// tagFilterClause returns a condition on transactions (aliased t) matching
+// rows tagged, directly or through one of their splits, with any tag in the
+// uuid[] parameter at position pos.
+func tagFilterClause(pos int) string {
+	return fmt.Sprintf(`
+		AND t.id IN (
+			SELECT tt.transaction_id FROM transaction_tags tt WHERE tt.tag_id = ANY($%[1]d::uuid[])
+			UNION
+			SELECT s.transaction_id FROM split_tags st
+			JOIN transaction_splits s ON s.id = st.split_id
+			WHERE st.tag_id = ANY($%[1]d::uuid[])
+		)`, pos)
+}
+
+// withTagFilter appends the tag filter from the request to a query whose
+// transactions are aliased t. Queries are returned unchanged without one.
+func withTagFilter(r *http.Request, query string, args []any) (string, []any, *ValidationError) {
+	tagIDs, verr := parseTagFilter(r)
+	if verr != nil || len(tagIDs) == 0 {
+		return query, args, verr
+	}
+	args = append(args, pq.Array(tagIDs))
+	return query + tagFilterClause(len(args)), args, nil
+}
+
+// loadTransactionTags returns the tags on each of the given transactions.
+func loadTransactionTags(conn *sql.DB, txIDs []string) (map[string][]models.TagRef, error) {
+	out := map[string][]models.TagRef{}
+	if len(txIDs) == 0 {
+		return out, nil
+	}
+	rows, err := conn.Query(`
+		SELECT tt.transaction_id, tg.id, tg.name, tg.color
+		FROM transaction_tags tt
+		JOIN tags tg ON tg.id = tt.tag_id
+		WHERE tt.transaction_id = ANY($1::uuid[])
+		ORDER BY tg.name
+	`, pq.Array(txIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var txID string
+		var t models.TagRef
+		if err := rows.Scan(&txID, &t.ID, &t.Name, &t.Color); err != nil {
+			return nil, err
+		}
+		out[txID] = append(out[txID], t)
+	}
+	return out, rows.Err()
+}
+
+// loadSplitTags returns the tags on each split of a transaction.
+func loadSplitTags(conn *sql.DB, txID string) (map[string][]models.TagRef, error) {
+	rows, err := conn.Query(`
+		SELECT st.split_id, tg.id, tg.name, tg.color
+		FROM split_tags st
+		JOIN transaction_splits s ON s.id = st.split_id
+		JOIN tags tg ON tg.id = st.tag_id
+		WHERE s.transaction_id = $1
+		ORDER BY tg.name
+	`, txID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string][]models.TagRef{}
+	for rows.Next() {
+		var splitID string
+		var t models.TagRef
+		if err := rows.Scan(&splitID, &t.ID, &t.Name, &t.Color); err != nil {
+			return nil, err
+		}
+		out[splitID] = append(out[splitID], t)
+	}
+	return out, rows.Err()
+}
+
+// tagTransactionByName tags a transaction, creating the tag in the
+// transaction's household (or its owner's personal scope) if needed. Used
+// by rules, which name tags rather than reference them.
+func tagTransactionByName(client execer, txID, name string) error {
+	name = cleanTagName(name)
+	if name == "" {
+		return nil
+	}
+	if _, err := client.Exec(`
+		INSERT INTO tags (user_id, household_id, name)
+		SELECT user_id, household_id, $1 FROM transactions WHERE id = $2
+		ON CONFLICT DO NOTHING
+	`, name, txID); err != nil {
+		return err
+	}
+	_, err := client.Exec(`
+		INSERT INTO transaction_tags (transaction_id, tag_id)
+		SELECT t.id, tg.id
+		FROM transactions t
+		JOIN tags tg ON LOWER(tg.name) = LOWER($1)
+		 AND COALESCE(tg.household_id::text, tg.user_id::text) = COALESCE(t.household_id::text, t.user_id::text)
+		WHERE t.id = $2
+		ON CONFLICT DO NOTHING
+	`, name, txID)
+	return err
+}
+
+// ensureTags returns the IDs of the named tags in the user's scope, creating
+// any that don't exist yet. Names must already be cleaned.
+func ensureTags(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, userID, householdID string, names []string) ([]string, error) {
+	var hhVal any
+	if householdID != "" {
+		hhVal = householdID
+	}
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		var id string
+		err := q.QueryRow(`
+			WITH ins AS (
+				INSERT INTO tags (user_id, household_id, name) VALUES ($1, $3, $4)
+				ON CONFLICT DO NOTHING
+				RETURNING id
+			)
+			SELECT id FROM ins
+			UNION ALL
+			SELECT tg.id FROM tags tg WHERE LOWER(tg.name) = LOWER($4) AND `+tagScopeWhere+`
+			LIMIT 1
+		`, userID, householdID, hhVal, name).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("tag %q: %w", name, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ListTags returns the household's tags with how often each is used.
+// GET /auth/tags
+func ListTags(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	client, err := tagsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	hh := db.ResolveHouseholdID(client.Raw(), userID)
+	rows, err := client.Query(`
+		SELECT tg.id, tg.user_id, tg.household_id, tg.name, tg.color, tg.created_at,
+		       (SELECT COUNT(*) FROM transaction_tags tt WHERE tt.tag_id = tg.id)
+		     + (SELECT COUNT(*) FROM split_tags st WHERE st.tag_id = tg.id)
+		FROM tags tg
+		WHERE `+tagScopeWhere+`
+		ORDER BY LOWER(tg.name)
+	`, userID, hh)
+	if err != nil {
+		log.Printf("ListTags query error: %v", err)
+		http.Error(w, "Failed to fetch tags", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.ID, &t.UserID, &t.HouseholdID, &t.Name, &t.Color, &t.CreatedAt, &t.UsageCount); err != nil {
+			log.Printf("ListTags scan error: %v", err)
+			continue
+		}
+		tags = append(tags, t)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// CreateTag adds a tag to the user's household.
+// POST /auth/tags
+func CreateTag(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.TagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name := cleanTagName(req.Name)
+	if name == "" {
+		respondValidationError(w, []ValidationError{{Field: "name", Message: fmt.Sprintf("name is required and must be at most %d characters", maxTagNameLength)}})
+		return
+	}
+
+	client, err := tagsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	hh := db.ResolveHouseholdID(client.Raw(), userID)
+	var hhVal any
+	if hh != "" {
+		hhVal = hh
+	}
+
+	t := models.Tag{UserID: userID, Name: name, Color: req.Color}
+	err = client.QueryRow(`
+		INSERT INTO tags (user_id, household_id, name, color)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+		RETURNING id, household_id, created_at
+	`, userID, hhVal, name, req.Color).Scan(&t.ID, &t.HouseholdID, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		http.Error(w, "A tag with that name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("CreateTag insert error: %v", err)
+		http.Error(w, "Failed to create tag", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+// UpdateTag renames or recolors a tag.
+// PUT /auth/tags/{id}
+func UpdateTag(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.TagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name := cleanTagName(req.Name)
+	if name == "" {
+		respondValidationError(w, []ValidationError{{Field: "name", Message: fmt.Sprintf("name is required and must be at most %d characters", maxTagNameLength)}})
+		return
+	}
+
+	client, err := tagsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	hh := db.ResolveHouseholdID(client.Raw(), userID)
+	var t models.Tag
+	err = client.QueryRow(`
+		UPDATE tags tg SET name = $3, color = $4
+		WHERE tg.id = $5 AND `+tagScopeWhere+`
+		RETURNING tg.id, tg.user_id, tg.household_id, tg.name, tg.color, tg.created_at
+	`, userID, hh, name, req.Color, mux.Vars(r)["id"]).Scan(&t.ID, &t.UserID, &t.HouseholdID, &t.Name, &t.Color, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			http.Error(w, "A tag with that name already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("UpdateTag error: %v", err)
+		http.Error(w, "Failed to update tag", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
+
+// DeleteTag removes a tag from every transaction and split it was on.
+// DELETE /auth/tags/{id}
+func DeleteTag(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	client, err := tagsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	hh := db.ResolveHouseholdID(client.Raw(), userID)
+	res, err := client.Exec(`DELETE FROM tags tg WHERE tg.id = $3 AND `+tagScopeWhere, userID, hh, mux.Vars(r)["id"])
+	if err != nil {
+		log.Printf("DeleteTag error: %v", err)
+		http.Error(w, "Failed to delete tag", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BulkTagTransactions adds and removes tags across many transactions and
+// splits in one database transaction. IDs the user can't see are ignored.
+// POST /auth/tags/bulk
+func BulkTagTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.BulkTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.TransactionIDs) == 0 && len(req.SplitIDs) == 0 {
+		http.Error(w, "transaction_ids or split_ids is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.AddTagIDs) == 0 && len(req.AddTagNames) == 0 && len(req.RemoveTagIDs) == 0 {
+		http.Error(w, "Nothing to add or remove", http.StatusBadRequest)
+		return
+	}
+	for _, ids := range []struct {
+		field string
+		ids   []string
+	}{
+		{"transaction_ids", req.TransactionIDs}, {"split_ids", req.SplitIDs},
+		{"add_tag_ids", req.AddTagIDs}, {"remove_tag_ids", req.RemoveTagIDs},
+	} {
+		if verr := validateUUIDs(ids.ids, ids.field); verr != nil {
+			respondValidationError(w, []ValidationError{*verr})
+			return
+		}
+	}
+	var names []string
+	for _, n := range req.AddTagNames {
+		clean := cleanTagName(n)
+		if clean == "" {
+			respondValidationError(w, []ValidationError{{Field: "add_tag_names", Message: fmt.Sprintf("tag names must be non-empty and at most %d characters", maxTagNameLength)}})
+			return
+		}
+		names = append(names, clean)
+	}
+
+	client, err := tagsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	hh := db.ResolveHouseholdID(client.Raw(), userID)
+
+	tx, err := client.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	created, err := ensureTags(tx, userID, hh, names)
+	if err != nil {
+		log.Printf("BulkTagTransactions tags error: %v", err)
+		http.Error(w, "Failed to create tag", http.StatusInternalServerError)
+		return
+	}
+	addIDs := append(append([]string{}, req.AddTagIDs...), created...)
+
+	// Every statement re-checks that the tag is in scope and the transaction
+	// or split belongs to the user or their household.
+	var result models.BulkTagResult
+	if len(req.TransactionIDs) > 0 && len(addIDs) > 0 {
+		res, err := tx.Exec(`
+			INSERT INTO transaction_tags (transaction_id, tag_id)
+			SELECT t.id, tg.id
+			FROM transactions t, tags tg
+			WHERE t.id = ANY($3::uuid[]) AND tg.id = ANY($4::uuid[])
+			  AND (t.user_id = $1 OR t.household_id::text = $2)
+			  AND `+tagScopeWhere+`
+			ON CONFLICT DO NOTHING
+		`, userID, hh, pq.Array(req.TransactionIDs), pq.Array(addIDs))
+		if err != nil {
+			log.Printf("BulkTagTransactions add error: %v", err)
+			http.Error(w, "Failed to tag transactions", http.StatusInternalServerError)
+			return
+		}
+		n, _ := res.RowsAffected()
+		result.Added += int(n)
+	}
+	if len(req.SplitIDs) > 0 && len(addIDs) > 0 {
+		res, err := tx.Exec(`
+			INSERT INTO split_tags (split_id, tag_id)
+			SELECT s.id, tg.id
+			FROM transaction_splits s
+			JOIN transactions t ON t.id = s.transaction_id,
+			     tags tg
+			WHERE s.id = ANY($3::uuid[]) AND tg.id = ANY($4::uuid[])
+			  AND (t.user_id = $1 OR t.household_id::text = $2)
+			  AND `+tagScopeWhere+`
+			ON CONFLICT DO NOTHING
+		`, userID, hh, pq.Array(req.SplitIDs), pq.Array(addIDs))
+		if err != nil {
+			log.Printf("BulkTagTransactions add split error: %v", err)
+			http.Error(w, "Failed to tag splits", http.StatusInternalServerError)
+			return
+		}
+		n, _ := res.RowsAffected()
+		result.Added += int(n)
+	}
+	if len(req.TransactionIDs) > 0 && len(req.RemoveTagIDs) > 0 {
+		res, err := tx.Exec(`
+			DELETE FROM transaction_tags tt
+			USING transactions t
+			WHERE t.id = tt.transaction_id
+			  AND tt.transaction_id = ANY($3::uuid[]) AND tt.tag_id = ANY($4::uuid[])
+			  AND (t.user_id = $1 OR t.household_id::text = $2)
+		`, userID, hh, pq.Array(req.TransactionIDs), pq.Array(req.RemoveTagIDs))
+		if err != nil {
+			log.Printf("BulkTagTransactions remove error: %v", err)
+			http.Error(w, "Failed to untag transactions", http.StatusInternalServerError)
+			return
+		}
+		n, _ := res.RowsAffected()
+		result.Removed += int(n)
+	}
+	if len(req.SplitIDs) > 0 && len(req.RemoveTagIDs) > 0 {
+		res, err := tx.Exec(`
+			DELETE FROM split_tags st
+			USING transaction_splits s, transactions t
+			WHERE s.id = st.split_id AND t.id = s.transaction_id
+			  AND st.split_id = ANY($3::uuid[]) AND st.tag_id = ANY($4::uuid[])
+			  AND (t.user_id = $1 OR t.household_id::text = $2)
+		`, userID, hh, pq.Array(req.SplitIDs), pq.Array(req.RemoveTagIDs))
+		if err != nil {
+			log.Printf("BulkTagTransactions remove split error: %v", err)
+			http.Error(w, "Failed to untag splits", http.StatusInternalServerError)
+			return
+		}
+		n, _ := res.RowsAffected()
+		result.Removed += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Commit error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetTagSpending totals income and spending per tag between start and end
+// (YYYY-MM-DD, default the current month). A split's tag counts only the
+// split's amount; when a transaction and one of its splits carry the same
+// tag, the split amounts count in place of the whole transaction.
+// GET /auth/insights/tags
+func GetTagSpending(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	if s := r.URL.Query().Get("start"); s != "" {
+		if start, err = time.Parse("2006-01-02", s); err != nil {
+			http.Error(w, "Invalid start date", http.StatusBadRequest)
+			return
+		}
+	}
+	if s := r.URL.Query().Get("end"); s != "" {
+		if end, err = time.Parse("2006-01-02", s); err != nil {
+			http.Error(w, "Invalid end date", http.StatusBadRequest)
+			return
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+
+	client, err := tagsDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	hh := db.ResolveHouseholdID(client.Raw(), userID)
+	rows, err := client.Query(`
+		WITH tagged AS (
+			SELECT tt.tag_id, t.id AS transaction_id, t.type, t.amount
+			FROM transaction_tags tt
+			JOIN transactions t ON t.id = tt.transaction_id
+			WHERE t.date >= $3 AND t.date < $4
+			  AND NOT EXISTS (
+			    SELECT 1 FROM split_tags st
+			    JOIN transaction_splits s ON s.id = st.split_id
+			    WHERE s.transaction_id = t.id AND st.tag_id = tt.tag_id
+			  )
+			UNION ALL
+			SELECT st.tag_id, t.id, t.type, s.amount
+			FROM split_tags st
+			JOIN transaction_splits s ON s.id = st.split_id
+			JOIN transactions t ON t.id = s.transaction_id
+			WHERE t.date >= $3 AND t.date < $4
+		)
+		SELECT tg.id, tg.name, tg.color,
+		       COALESCE(SUM(x.amount) FILTER (WHERE x.type = 'expense'), 0),
+		       COALESCE(SUM(x.amount) FILTER (WHERE x.type = 'income'), 0),
+		       COUNT(DISTINCT x.transaction_id)
+		FROM tags tg
+		JOIN tagged x ON x.tag_id = tg.id
+		WHERE `+tagScopeWhere+`
+		GROUP BY tg.id, tg.name, tg.color
+		ORDER BY 4 DESC
+	`, userID, hh, start, end)
+	if err != nil {
+		log.Printf("GetTagSpending query error: %v", err)
+		http.Error(w, "Query error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	out := []models.TagSpending{}
+	for rows.Next() {
+		var s models.TagSpending
+		if err := rows.Scan(&s.ID, &s.Name, &s.Color, &s.Expenses, &s.Income, &s.Transactions); err != nil {
+			log.Printf("GetTagSpending scan error: %v", err)
+			continue
+		}
+		out = append(out, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
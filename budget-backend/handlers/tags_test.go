@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+)
+
+func TestCleanTagName(t *testing.T) {
+	cases := map[string]string{
+		"  vacation   2026 ":    "vacation 2026",
+		"tax-deductible":        "tax-deductible",
+		"   ":                   "",
+		strings.Repeat("x", 51): "",
+	}
+	for in, want := range cases {
+		if got := cleanTagName(in); got != want {
+			t.Errorf("cleanTagName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseTagFilter(t *testing.T) {
+	a, b, c := "aaaaaaaa-0000-0000-0000-000000000001", "aaaaaaaa-0000-0000-0000-000000000002", "aaaaaaaa-0000-0000-0000-000000000003"
+	req := httptest.NewRequest(http.MethodGet, "/auth/transactions?tag_id="+a+","+b+"&tag_id="+c+"&tag_id=", nil)
+	got, verr := parseTagFilter(req)
+	if verr != nil || strings.Join(got, "|") != a+"|"+b+"|"+c {
+		t.Fatalf("expected [a b c], got %v (%v)", got, verr)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/auth/transactions?tag_id="+a+",groceries", nil)
+	if _, verr := parseTagFilter(req); verr == nil || verr.Field != "tag_id" {
+		t.Fatalf("expected a tag_id validation error, got %v", verr)
+	}
+}
+
+func TestTagTransactionByName(t *testing.T) {
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+
+	mock.ExpectExec(`INSERT INTO tags`).WithArgs("wedding", "tx1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO transaction_tags`).WithArgs("wedding", "tx1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := tagTransactionByName(mockSQL, "tx1", " wedding "); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestBulkTagTransactions_Validation(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"no targets", `{"add_tag_ids":["t1"]}`},
+		{"no changes", `{"transaction_ids":["tx1"]}`},
+		{"blank name", `{"transaction_ids":["tx1"],"add_tag_names":["  "]}`},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/auth/tags/bulk", strings.NewReader(tc.body))
+		req.Header.Set("Authorization", "Bearer "+planTestToken(t, "11111111-1111-1111-1111-111111111111"))
+		rr := httptest.NewRecorder()
+
+		BulkTagTransactions(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", tc.name, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestGetTagSpending_SplitTagReplacesParent(t *testing.T) {
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+	orig := tagsDBFactory
+	tagsDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	defer func() { tagsDBFactory = orig }()
+
+	mock.ExpectQuery(`SELECT household_id FROM household_members`).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}))
+	// The transaction's own tag is skipped when a split carries it too.
+	mock.ExpectQuery(`FROM transaction_tags tt\s+JOIN transactions t ON t.id = tt.transaction_id\s+WHERE .*\s+AND NOT EXISTS \(\s+SELECT 1 FROM split_tags st`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "color", "expenses", "income", "count"}).
+			AddRow("tag1", "vacation", nil, 40.0, 0.0, 1))
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/insights/tags", nil)
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, "11111111-1111-1111-1111-111111111111"))
+	rr := httptest.NewRecorder()
+
+	GetTagSpending(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var out []models.TagSpending
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil || len(out) != 1 || out[0].Expenses != 40 {
+		t.Fatalf("unexpected response %s", rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -143,6 +143,8 @@ func SplitTransaction(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal error", http.StatusInternalServerError)
 		return
 	}
+	// Split rows don't train the model; drop what this row taught it.
+	learnCategoryAsync(dbClient.Conn, txID)
 
 	// Return the created splits.
 	splits, err := fetchSplits(dbClient, txID)
@@ -264,6 +266,7 @@ func DeleteTransactionSplits(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal error", http.StatusInternalServerError)
 		return
 	}
+	learnCategoryAsync(dbClient.Conn, txID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -295,5 +298,26 @@ func fetchSplits(dbClient *db.DB, txID string) ([]models.TransactionSplit, error
 	if splits == nil {
 		splits = []models.TransactionSplit{}
 	}
+	tagsBySplit, err := loadSplitTags(dbClient.Conn, txID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range splits {
+		splits[i].Tags = tagsBySplit[splits[i].ID]
+		if splits[i].Tags == nil {
+			splits[i].Tags = []models.TagRef{}
+		}
+	}
+	sharesByTx, err := loadTransactionShares(dbClient.Conn, []string{txID})
+	if err != nil {
+		return nil, err
+	}
+	for _, share := range sharesByTx[txID] {
+		for i := range splits {
+			if share.SplitID != nil && *share.SplitID == splits[i].ID {
+				splits[i].Shares = append(splits[i].Shares, share)
+			}
+		}
+	}
 	return splits, nil
 }
@@ -81,6 +81,10 @@ func CreateTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if tx.Type == "income" {
+		matchIncomingReimbursement(dbClient, tx.ID)
+	}
+
 	// Notify household partner for significant transactions
 	if tx.HouseholdID != nil && *tx.HouseholdID != "" && tx.Amount >= 50 {
 		var userName string
@@ -134,7 +138,7 @@ func GetTransactions(w http.ResponseWriter, r *http.Request) {
 
 	var rows *sql.Rows
 	if hh == "" {
-		rows, err = dbClient.Query(`
+		query := `
 			SELECT
 				t.id,          -- 1
 				t.user_id,     -- 2
@@ -157,9 +161,15 @@ func GetTransactions(w http.ResponseWriter, r *http.Request) {
 			FROM transactions t
 			LEFT JOIN categories c ON t.category_id = c.id
 			WHERE t.household_id IS NULL AND t.user_id = $1
-		`, userID)
+		`
+		query, args, verr := withTagFilter(r, query, []any{userID})
+		if verr != nil {
+			respondValidationError(w, []ValidationError{*verr})
+			return
+		}
+		rows, err = dbClient.Query(query, args...)
 	} else {
-		rows, err = dbClient.Query(`
+		query := `
 			SELECT
 				t.id,          -- 1
 				t.user_id,     -- 2
@@ -181,7 +191,7 @@ func GetTransactions(w http.ResponseWriter, r *http.Request) {
 				COALESCE(t.user_verified, false) -- 18
 			FROM transactions t
 			LEFT JOIN categories c ON t.category_id = c.id
-			WHERE t.user_id = $2
+			WHERE (t.user_id = $2
 			   OR t.household_id::text = $1
 			   OR (t.household_id IS NOT NULL AND t.user_id IN (
 			       SELECT hm.user_id FROM household_members hm
@@ -190,8 +200,14 @@ func GetTransactions(w http.ResponseWriter, r *http.Request) {
 			       WHERE hm.household_id::text = $1
 			         AND hm.user_id != $2
 			         AND COALESCE(sp.share_transactions, true) = true
-			   ))
-		`, hh, userID)
+			   )))
+		`
+		query, args, verr := withTagFilter(r, query, []any{hh, userID})
+		if verr != nil {
+			respondValidationError(w, []ValidationError{*verr})
+			return
+		}
+		rows, err = dbClient.Query(query, args...)
 	}
 
 	if err != nil {
@@ -248,6 +264,28 @@ func GetTransactions(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("Total rows processed: %d", rowCount)
 
+	txIDs := make([]string, len(transactions))
+	for i, t := range transactions {
+		txIDs[i] = t.ID
+	}
+	tagsByTx, err := loadTransactionTags(dbClient.Conn, txIDs)
+	if err != nil {
+		log.Printf("GetTransactions tags error: %v", err)
+	}
+	for i := range transactions {
+		transactions[i].Tags = tagsByTx[transactions[i].ID]
+		if transactions[i].Tags == nil {
+			transactions[i].Tags = []models.TagRef{}
+		}
+	}
+	sharesByTx, err := loadTransactionShares(dbClient.Conn, txIDs)
+	if err != nil {
+		log.Printf("GetTransactions shares error: %v", err)
+	}
+	for i := range transactions {
+		transactions[i].Shares = sharesByTx[transactions[i].ID]
+	}
+
 	json.NewEncoder(w).Encode(transactions)
 }
 
@@ -306,8 +344,34 @@ func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 		tx.Currency = "USD"
 	}
 
+	dbTx, err := dbClient.Conn.Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer dbTx.Rollback()
+
+	// Transactions posted against a manual account moved its balance; a new
+	// amount or type moves it by the difference.
+	var acctID, oldType string
+	var oldAmount float64
+	var isLiability bool
+	err = dbTx.QueryRow(`
+		SELECT t.account_balance_id, t.type, t.amount, a.is_liability
+		FROM transactions t
+		JOIN account_balances a ON a.id = t.account_balance_id AND a.is_manual = true
+		WHERE t.id = $1
+		FOR UPDATE OF t
+	`, id).Scan(&acctID, &oldType, &oldAmount, &isLiability)
+	manual := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("UpdateTransaction manual account lookup error: %v", err)
+		http.Error(w, "Failed to update transaction", http.StatusInternalServerError)
+		return
+	}
+
 	// Execute UPDATE query — when user sets a category, mark as verified
-	_, err = dbClient.Exec(`
+	_, err = dbTx.Exec(`
 		UPDATE transactions
 		SET amount = $1, note = $2, category_id = $3, category_name = $4, type = $5, date = $6,
 		    frequency = $7, due_day = $8, budget_id = $9, currency = $10,
@@ -320,6 +384,21 @@ func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to update transaction", http.StatusInternalServerError)
 		return
 	}
+	if manual && (oldAmount != tx.Amount || oldType != tx.Type) {
+		delta := manualEditDelta(isLiability, oldType, oldAmount, tx.Type, tx.Amount)
+		if err := applyManualAccountDelta(dbTx, acctID, delta, time.Now().UTC(), "Edited transaction"); err != nil {
+			log.Printf("UpdateTransaction balance adjustment error: %v", err)
+			http.Error(w, "Failed to update balance", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := dbTx.Commit(); err != nil {
+		http.Error(w, "Commit error", http.StatusInternalServerError)
+		return
+	}
+	if tx.CategoryID != nil {
+		learnCategoryAsync(dbClient.Conn, id)
+	}
 
 	// Fetch the updated transaction with category join
 	var hh, freq, note sql.NullString
@@ -412,11 +491,47 @@ func DeleteTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = dbClient.Exec("DELETE FROM transactions WHERE id = $1", id)
+	// Transactions posted against a manual account moved its balance; undo that.
+	var acctID, txType string
+	var amount float64
+	var isLiability bool
+	err = dbClient.QueryRow(`
+		SELECT t.account_balance_id, t.type, t.amount, a.is_liability
+		FROM transactions t
+		JOIN account_balances a ON a.id = t.account_balance_id AND a.is_manual = true
+		WHERE t.id = $1
+	`, id).Scan(&acctID, &txType, &amount, &isLiability)
+	manual := err == nil
+
+	dbTx, err := dbClient.Conn.Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer dbTx.Rollback()
+	blobKeys, err := detachAttachments(dbTx, "transaction_id = $1", id)
 	if err != nil {
+		log.Printf("DeleteTransaction attachments error: %v", err)
+		http.Error(w, "Failed to delete transaction", http.StatusInternalServerError)
+		return
+	}
+	if _, err := dbTx.Exec("DELETE FROM transactions WHERE id = $1", id); err != nil {
 		http.Error(w, "Failed to delete transaction", http.StatusInternalServerError)
 		return
 	}
+	if manual {
+		delta := -manualBalanceDelta(isLiability, txType, amount)
+		if err := applyManualAccountDelta(dbTx, acctID, delta, time.Now().UTC(), "Deleted transaction"); err != nil {
+			log.Printf("DeleteTransaction balance reversal error: %v", err)
+			http.Error(w, "Failed to update balance", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := dbTx.Commit(); err != nil {
+		http.Error(w, "Commit error", http.StatusInternalServerError)
+		return
+	}
+	removeAttachmentBlobs(r.Context(), blobKeys)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,566 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// bulkDBFactory allows swapping the DB in tests.
+var bulkDBFactory = func() (db.DBTX, error) {
+	return db.New()
+}
+
+// maxBulkTransactions caps one bulk request so a loose filter can't rewrite
+// years of history by accident.
+const maxBulkTransactions = 500
+
+var errTooManyBulkTargets = fmt.Errorf("filter matches more than %d transactions; narrow it down", maxBulkTransactions)
+
+var bulkOperations = []string{"recategorize", "assign_budget", "mark_verified", "tag", "delete", "convert_to_transfer"}
+
+// bulkAccessClause decides whether the caller may change a transaction
+// (aliased t): their own rows, plus household rows whose owner shares
+// transactions, as in GetTransactions. $1 is the user ID and $2 the
+// household ID.
+const bulkAccessClause = `(t.user_id = $1 OR (t.household_id::text = $2 AND NOT EXISTS (
+	SELECT 1 FROM sharing_preferences sp
+	WHERE sp.user_id = t.user_id
+	  AND (sp.household_id::text = $2 OR sp.household_id IS NULL)
+	  AND sp.share_transactions = false
+)))`
+
+// bulkTarget is a transaction selected for a bulk operation.
+type bulkTarget struct {
+	id      string
+	isSplit bool
+	allowed bool
+}
+
+// validateBulkRequest checks the operation and its parameters, returning a
+// message for the client or "".
+func validateBulkRequest(req *models.BulkTransactionRequest) string {
+	if verr := validateEnum(req.Operation, "operation", bulkOperations); verr != nil {
+		return verr.Message
+	}
+	if len(req.TransactionIDs) > 0 && req.Filter != nil {
+		return "Pass transaction_ids or filter, not both"
+	}
+	if len(req.TransactionIDs) == 0 && req.Filter == nil {
+		return "transaction_ids or filter is required"
+	}
+	if len(req.TransactionIDs) > maxBulkTransactions {
+		return fmt.Sprintf("At most %d transactions per request", maxBulkTransactions)
+	}
+	switch req.Operation {
+	case "recategorize":
+		if req.CategoryID == nil || *req.CategoryID == "" {
+			return "category_id is required to recategorize"
+		}
+	case "tag":
+		if len(req.TagIDs) == 0 && len(req.TagNames) == 0 {
+			return "tag_ids or tag_names is required to tag"
+		}
+		if verr := validateUUIDs(req.TagIDs, "tag_ids"); verr != nil {
+			return "tag_ids: " + verr.Message
+		}
+		for _, n := range req.TagNames {
+			if cleanTagName(n) == "" {
+				return fmt.Sprintf("tag names must be non-empty and at most %d characters", maxTagNameLength)
+			}
+		}
+	}
+	return ""
+}
+
+// bulkFilterWhere turns a filter into conditions on transactions (aliased t),
+// appending its parameters to args.
+func bulkFilterWhere(f *models.BulkTransactionFilter, args []any) (string, []any, error) {
+	var conds []string
+	add := func(cond string, v any) {
+		args = append(args, v)
+		conds = append(conds, strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(args))))
+	}
+	for _, d := range []struct {
+		value, cond string
+	}{{f.StartDate, "t.date >= ?"}, {f.EndDate, "t.date < ?::date + 1"}} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d.value); err != nil {
+			return "", nil, fmt.Errorf("dates must be YYYY-MM-DD")
+		}
+		add(d.cond, d.value)
+	}
+	if f.Type != "" {
+		if verr := validateEnum(f.Type, "type", []string{"income", "expense", "transfer"}); verr != nil {
+			return "", nil, fmt.Errorf("%s", verr.Message)
+		}
+		add("t.type = ?", f.Type)
+	}
+	if f.CategoryID != "" && f.Uncategorized {
+		return "", nil, fmt.Errorf("category_id and uncategorized can't be combined")
+	}
+	if f.CategoryID != "" {
+		add("t.category_id::text = ?", f.CategoryID)
+	}
+	if f.Uncategorized {
+		conds = append(conds, "t.category_id IS NULL")
+	}
+	if f.AccountBalanceID != "" {
+		add("t.account_balance_id::text = ?", f.AccountBalanceID)
+	}
+	if f.MerchantID != "" {
+		add("t.merchant_id::text = ?", f.MerchantID)
+	}
+	if f.Source != "" {
+		add("t.source = ?", f.Source)
+	}
+	if s := strings.TrimSpace(f.Search); s != "" {
+		add("t.note ILIKE '%' || ? || '%'", s)
+	}
+	if f.UnverifiedOnly {
+		conds = append(conds, "NOT COALESCE(t.user_verified, false)")
+	}
+	if verr := validateUUIDs(f.TagIDs, "tag_ids"); verr != nil {
+		return "", nil, fmt.Errorf("tag_ids: %s", verr.Message)
+	}
+	if len(conds) == 0 && len(f.TagIDs) == 0 {
+		return "", nil, fmt.Errorf("filter needs at least one condition")
+	}
+	where := strings.Join(conds, " AND ")
+	if len(f.TagIDs) > 0 {
+		args = append(args, pq.Array(f.TagIDs))
+		if where == "" {
+			where = "TRUE"
+		}
+		where += tagFilterClause(len(args))
+	}
+	return where, args, nil
+}
+
+// loadBulkTargets returns the transactions a request selects. Explicit IDs
+// come back in request order, flagged when the caller can't change them;
+// filters only ever match transactions the caller can change.
+func loadBulkTargets(client db.DBTX, req *models.BulkTransactionRequest, userID, householdID string) ([]bulkTarget, []models.BulkItemResult, error) {
+	var missing []models.BulkItemResult
+	if req.Filter != nil {
+		where, args, err := bulkFilterWhere(req.Filter, []any{userID, householdID})
+		if err != nil {
+			return nil, nil, err
+		}
+		rows, err := client.Query(`
+			SELECT t.id, COALESCE(t.is_split, false), true
+			FROM transactions t
+			WHERE `+bulkAccessClause+` AND `+where+`
+			ORDER BY t.date DESC
+			LIMIT `+fmt.Sprint(maxBulkTransactions+1), args...)
+		if err != nil {
+			return nil, nil, err
+		}
+		targets, err := scanBulkTargets(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(targets) > maxBulkTransactions {
+			return nil, nil, errTooManyBulkTargets
+		}
+		return targets, nil, nil
+	}
+
+	var ids []string
+	seen := map[string]bool{}
+	for _, id := range req.TransactionIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, err := uuid.Parse(id); err != nil {
+			missing = append(missing, models.BulkItemResult{TransactionID: id, Status: "not_found"})
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, missing, nil
+	}
+	rows, err := client.Query(`
+		SELECT t.id, COALESCE(t.is_split, false), `+bulkAccessClause+`
+		FROM transactions t
+		WHERE t.id = ANY($3::uuid[])
+	`, userID, householdID, pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
+	}
+	found, err := scanBulkTargets(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := map[string]bulkTarget{}
+	for _, t := range found {
+		byID[t.id] = t
+	}
+	var targets []bulkTarget
+	for _, id := range ids {
+		t, ok := byID[id]
+		if !ok {
+			missing = append(missing, models.BulkItemResult{TransactionID: id, Status: "not_found"})
+			continue
+		}
+		targets = append(targets, t)
+	}
+	return targets, missing, nil
+}
+
+func scanBulkTargets(rows *sql.Rows) ([]bulkTarget, error) {
+	defer rows.Close()
+	var out []bulkTarget
+	for rows.Next() {
+		var t bulkTarget
+		if err := rows.Scan(&t.id, &t.isSplit, &t.allowed); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// applyBulkOperation changes one transaction inside the bulk DB transaction
+// and returns its status. Deletes add their attachment files to blobKeys.
+func applyBulkOperation(tx *sql.Tx, req *models.BulkTransactionRequest, target bulkTarget, tagIDs []string, blobKeys *[]string) (string, string, error) {
+	var res sql.Result
+	var err error
+	switch req.Operation {
+	case "recategorize":
+		if target.isSplit {
+			return "skipped", "split transaction; edit its splits instead", nil
+		}
+		res, err = tx.Exec(`
+			UPDATE transactions
+			SET category_id = $1, user_verified = true, match_confidence = 'exact', updated_at = NOW()
+			WHERE id = $2 AND (category_id IS DISTINCT FROM $1::uuid OR NOT COALESCE(user_verified, false))
+		`, *req.CategoryID, target.id)
+	case "assign_budget":
+		res, err = tx.Exec(`
+			UPDATE transactions SET budget_id = $1, updated_at = NOW()
+			WHERE id = $2 AND budget_id IS DISTINCT FROM $1::uuid
+		`, req.BudgetID, target.id)
+	case "mark_verified":
+		res, err = tx.Exec(`
+			UPDATE transactions SET user_verified = true, updated_at = NOW()
+			WHERE id = $1 AND NOT COALESCE(user_verified, false)
+		`, target.id)
+	case "tag":
+		res, err = tx.Exec(`
+			INSERT INTO transaction_tags (transaction_id, tag_id)
+			SELECT $1, UNNEST($2::uuid[])
+			ON CONFLICT DO NOTHING
+		`, target.id, pq.Array(tagIDs))
+	case "convert_to_transfer":
+		return convertBulkTransaction(tx, target.id)
+	case "delete":
+		return deleteBulkTransaction(tx, target.id, blobKeys)
+	default:
+		return "", "", fmt.Errorf("unknown operation %q", req.Operation)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "unchanged", "", nil
+	}
+	return "updated", "", nil
+}
+
+// lockManualTransaction locks a transaction recorded against a manual account
+// and returns what it did to that account's balance. manual is false when the
+// transaction isn't on a manual account.
+func lockManualTransaction(tx *sql.Tx, id string) (acctID, txType string, amount float64, isLiability, manual bool, err error) {
+	err = tx.QueryRow(`
+		SELECT t.account_balance_id, t.type, t.amount, a.is_liability
+		FROM transactions t
+		JOIN account_balances a ON a.id = t.account_balance_id AND a.is_manual = true
+		WHERE t.id = $1
+		FOR UPDATE OF t
+	`, id).Scan(&acctID, &txType, &amount, &isLiability)
+	if err == sql.ErrNoRows {
+		return "", "", 0, false, false, nil
+	}
+	return acctID, txType, amount, isLiability, err == nil, err
+}
+
+// convertBulkTransaction marks a transaction as a transfer. Budgets and
+// insights only count income and expense rows, and on a manual account the
+// balance gives back what the income or expense had moved.
+func convertBulkTransaction(tx *sql.Tx, id string) (string, string, error) {
+	acctID, txType, amount, isLiability, manual, err := lockManualTransaction(tx, id)
+	if err != nil {
+		return "", "", err
+	}
+	res, err := tx.Exec(`
+		UPDATE transactions SET type = 'transfer', updated_at = NOW()
+		WHERE id = $1 AND type <> 'transfer'
+	`, id)
+	if err != nil {
+		return "", "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "unchanged", "", nil
+	}
+	if manual {
+		delta := manualEditDelta(isLiability, txType, amount, "transfer", amount)
+		if err := applyManualAccountDelta(tx, acctID, delta, time.Now().UTC(), "Converted to transfer"); err != nil {
+			return "", "", err
+		}
+	}
+	return "updated", "", nil
+}
+
+// deleteBulkTransaction deletes a transaction, reversing its effect on a
+// manual account balance the way DeleteTransaction does. The keys of its
+// attachments' files are added to blobKeys for removal after commit.
+func deleteBulkTransaction(tx *sql.Tx, id string, blobKeys *[]string) (string, string, error) {
+	acctID, txType, amount, isLiability, manual, err := lockManualTransaction(tx, id)
+	if err != nil {
+		return "", "", err
+	}
+	keys, err := detachAttachments(tx, "transaction_id = $1", id)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := tx.Exec(`DELETE FROM transactions WHERE id = $1`, id); err != nil {
+		return "", "", err
+	}
+	if manual {
+		delta := -manualBalanceDelta(isLiability, txType, amount)
+		if err := applyManualAccountDelta(tx, acctID, delta, time.Now().UTC(), "Deleted transaction"); err != nil {
+			return "", "", err
+		}
+	}
+	*blobKeys = append(*blobKeys, keys...)
+	return "deleted", "", nil
+}
+
+// bulkActivityDescription summarizes a bulk operation for the activity feed.
+func bulkActivityDescription(op string, n int, targetName string) string {
+	noun := "transactions"
+	if n == 1 {
+		noun = "transaction"
+	}
+	switch op {
+	case "recategorize":
+		return fmt.Sprintf("Recategorized %d %s as %s", n, noun, targetName)
+	case "assign_budget":
+		if targetName == "" {
+			return fmt.Sprintf("Removed the budget from %d %s", n, noun)
+		}
+		return fmt.Sprintf("Assigned %d %s to %s", n, noun, targetName)
+	case "mark_verified":
+		return fmt.Sprintf("Verified %d %s", n, noun)
+	case "tag":
+		return fmt.Sprintf("Tagged %d %s", n, noun)
+	case "delete":
+		return fmt.Sprintf("Deleted %d %s", n, noun)
+	case "convert_to_transfer":
+		return fmt.Sprintf("Marked %d %s as transfers", n, noun)
+	}
+	return fmt.Sprintf("Updated %d %s", n, noun)
+}
+
+// BulkUpdateTransactions applies one operation to many transactions in a
+// single DB transaction. Each transaction gets its own result; one failing
+// doesn't undo the others. A single activity feed entry summarizes the change.
+// POST /auth/transactions/bulk
+func BulkUpdateTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.BulkTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if msg := validateBulkRequest(&req); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if req.Filter != nil {
+		if _, _, err := bulkFilterWhere(req.Filter, nil); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.BudgetID != nil && *req.BudgetID == "" {
+		req.BudgetID = nil
+	}
+
+	client, err := bulkDBFactory()
+	if err != nil {
+		http.Error(w, "DB connection error", http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	hh := db.ResolveHouseholdID(client.Raw(), userID)
+
+	// The category or budget being assigned must be one the caller can use.
+	var targetName string
+	switch {
+	case req.Operation == "recategorize":
+		err = client.QueryRow(`
+			SELECT name FROM categories
+			WHERE id::text = $1 AND (user_id IS NULL OR user_id = $2 OR household_id::text = $3)
+		`, *req.CategoryID, userID, hh).Scan(&targetName)
+	case req.Operation == "assign_budget" && req.BudgetID != nil:
+		err = client.QueryRow(`
+			SELECT name FROM budgets WHERE id::text = $1 AND (user_id = $2 OR household_id::text = $3)
+		`, *req.BudgetID, userID, hh).Scan(&targetName)
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, "Category or budget not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("BulkUpdateTransactions target lookup error: %v", err)
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	targets, results, err := loadBulkTargets(client, &req, userID, hh)
+	if err == errTooManyBulkTargets {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("BulkUpdateTransactions select error: %v", err)
+		http.Error(w, "Failed to load transactions", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := client.Raw().Begin()
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var tagIDs []string
+	if req.Operation == "tag" {
+		var names []string
+		for _, n := range req.TagNames {
+			names = append(names, cleanTagName(n))
+		}
+		created, err := ensureTags(tx, userID, hh, names)
+		if err != nil {
+			log.Printf("BulkUpdateTransactions tags error: %v", err)
+			http.Error(w, "Failed to create tag", http.StatusInternalServerError)
+			return
+		}
+		var valid int
+		if len(req.TagIDs) > 0 {
+			if err := tx.QueryRow(`
+				SELECT COUNT(*) FROM tags tg WHERE tg.id::text = ANY($3) AND `+tagScopeWhere,
+				userID, hh, pq.Array(req.TagIDs)).Scan(&valid); err != nil {
+				log.Printf("BulkUpdateTransactions tag check error: %v", err)
+				http.Error(w, "DB error", http.StatusInternalServerError)
+				return
+			}
+		}
+		if valid != len(req.TagIDs) {
+			http.Error(w, "Unknown tag in tag_ids", http.StatusBadRequest)
+			return
+		}
+		tagIDs = append(append(tagIDs, req.TagIDs...), created...)
+	}
+
+	result := models.BulkTransactionResult{Operation: req.Operation}
+	var changed, blobKeys []string
+	for _, t := range targets {
+		item := models.BulkItemResult{TransactionID: t.id}
+		if !t.allowed {
+			item.Status = "forbidden"
+			results = append(results, item)
+			continue
+		}
+		// A savepoint per item keeps one failure from aborting the batch.
+		if _, err := tx.Exec(`SAVEPOINT bulk_item`); err != nil {
+			http.Error(w, "DB error", http.StatusInternalServerError)
+			return
+		}
+		status, msg, err := applyBulkOperation(tx, &req, t, tagIDs, &blobKeys)
+		if err != nil {
+			log.Printf("BulkUpdateTransactions %s %s error: %v", req.Operation, t.id, err)
+			if _, rbErr := tx.Exec(`ROLLBACK TO SAVEPOINT bulk_item`); rbErr != nil {
+				http.Error(w, "DB error", http.StatusInternalServerError)
+				return
+			}
+			status, msg = "failed", "could not apply change"
+		} else if _, err := tx.Exec(`RELEASE SAVEPOINT bulk_item`); err != nil {
+			http.Error(w, "DB error", http.StatusInternalServerError)
+			return
+		}
+		item.Status, item.Message = status, msg
+		if status == "updated" || status == "deleted" {
+			changed = append(changed, t.id)
+		}
+		results = append(results, item)
+	}
+
+	for _, item := range results {
+		switch item.Status {
+		case "updated", "deleted", "unchanged":
+			result.Succeeded++
+		case "skipped":
+		default:
+			result.Failed++
+		}
+	}
+	result.Requested = len(results)
+	result.Results = results
+	if result.Results == nil {
+		result.Results = []models.BulkItemResult{}
+	}
+
+	if hh != "" && len(changed) > 0 {
+		meta, _ := json.Marshal(map[string]any{
+			"operation":       req.Operation,
+			"transaction_ids": changed,
+			"category_id":     req.CategoryID,
+			"budget_id":       req.BudgetID,
+			"tag_ids":         tagIDs,
+		})
+		if err := recordActivityMetadata(tx, hh, userID, "transactions_bulk_updated", "transaction",
+			bulkActivityDescription(req.Operation, len(changed), targetName), string(meta)); err != nil {
+			http.Error(w, "Failed to record activity", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Commit error", http.StatusInternalServerError)
+		return
+	}
+	removeAttachmentBlobs(r.Context(), blobKeys)
+
+	if req.Operation == "recategorize" || req.Operation == "mark_verified" {
+		for _, id := range changed {
+			learnCategoryAsync(client.Raw(), id)
+		}
+	}
+
+	log.Printf("Bulk %s by %s: %d changed, %d failed", req.Operation, userID, len(changed), result.Failed)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
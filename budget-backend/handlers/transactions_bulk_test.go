@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aboogie/budget-backend/db"
+	"github.com/aboogie/budget-backend/models"
+)
+
+func TestValidateBulkRequest(t *testing.T) {
+	cat := "c1"
+	cases := []struct {
+		name string
+		req  models.BulkTransactionRequest
+		ok   bool
+	}{
+		{"verify ids", models.BulkTransactionRequest{Operation: "mark_verified", TransactionIDs: []string{"a"}}, true},
+		{"unknown op", models.BulkTransactionRequest{Operation: "archive", TransactionIDs: []string{"a"}}, false},
+		{"no selection", models.BulkTransactionRequest{Operation: "delete"}, false},
+		{"ids and filter", models.BulkTransactionRequest{Operation: "delete", TransactionIDs: []string{"a"},
+			Filter: &models.BulkTransactionFilter{Type: "expense"}}, false},
+		{"recategorize without category", models.BulkTransactionRequest{Operation: "recategorize", TransactionIDs: []string{"a"}}, false},
+		{"recategorize", models.BulkTransactionRequest{Operation: "recategorize", CategoryID: &cat, TransactionIDs: []string{"a"}}, true},
+		{"tag without tags", models.BulkTransactionRequest{Operation: "tag", TransactionIDs: []string{"a"}}, false},
+		{"tag by name", models.BulkTransactionRequest{Operation: "tag", TagNames: []string{"wedding"}, TransactionIDs: []string{"a"}}, true},
+	}
+	for _, tc := range cases {
+		if msg := validateBulkRequest(&tc.req); (msg == "") != tc.ok {
+			t.Errorf("%s: expected ok=%v, got %q", tc.name, tc.ok, msg)
+		}
+	}
+}
+
+func TestBulkFilterWhere(t *testing.T) {
+	where, args, err := bulkFilterWhere(&models.BulkTransactionFilter{
+		StartDate: "2026-03-01", EndDate: "2026-03-31", Uncategorized: true, Search: "amzn",
+	}, []any{"u1", "h1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "t.date >= $3 AND t.date < $4::date + 1 AND t.category_id IS NULL AND t.note ILIKE '%' || $5 || '%'"
+	if where != want {
+		t.Fatalf("where = %q, want %q", where, want)
+	}
+	if len(args) != 5 || args[4] != "amzn" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+
+	for _, f := range []models.BulkTransactionFilter{
+		{},
+		{StartDate: "03/01/2026"},
+		{Type: "refund"},
+		{CategoryID: "c1", Uncategorized: true},
+	} {
+		if _, _, err := bulkFilterWhere(&f, nil); err == nil {
+			t.Errorf("expected error for filter %+v", f)
+		}
+	}
+}
+
+func TestBulkUpdateTransactions_PerItemResults(t *testing.T) {
+	const userID = "11111111-1111-1111-1111-111111111111"
+	const mine = "22222222-2222-2222-2222-222222222222"
+	const private = "33333333-3333-3333-3333-333333333333"
+	const missing = "44444444-4444-4444-4444-444444444444"
+
+	mockSQL, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer mockSQL.Close()
+	orig := bulkDBFactory
+	bulkDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+	defer func() { bulkDBFactory = orig }()
+
+	mock.ExpectQuery(`SELECT household_id FROM household_members`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"household_id"}).AddRow("hh1"))
+	mock.ExpectQuery(`FROM transactions t\s+WHERE t.id = ANY`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_split", "allowed"}).
+			AddRow(mine, false, true).
+			AddRow(private, false, false))
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT bulk_item`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE transactions SET user_verified = true`).
+		WithArgs(mine).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT bulk_item`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO activity_events`).
+		WithArgs(sqlmock.AnyArg(), "hh1", userID, "transactions_bulk_updated", "transaction", "Verified 1 transaction", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	body := `{"operation":"mark_verified","transaction_ids":["` + mine + `","` + private + `","` + missing + `","` + mine + `"]}`
+	req := httptest.NewRequest(http.MethodPost, "/auth/transactions/bulk", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+	rr := httptest.NewRecorder()
+
+	BulkUpdateTransactions(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var result models.BulkTransactionResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("bad response: %v", err)
+	}
+	statuses := map[string]string{}
+	for _, item := range result.Results {
+		statuses[item.TransactionID] = item.Status
+	}
+	if statuses[mine] != "updated" || statuses[private] != "forbidden" || statuses[missing] != "not_found" {
+		t.Fatalf("unexpected statuses: %v", statuses)
+	}
+	if result.Requested != 3 || result.Succeeded != 1 || result.Failed != 2 {
+		t.Fatalf("unexpected counts: %+v", result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+// balanceArg applies each delta it matches to a running balance, so a test can
+// follow a manual account through several requests.
+type balanceArg struct{ balance *float64 }
+
+func (a balanceArg) Match(v driver.Value) bool {
+	delta, ok := v.(float64)
+	if ok {
+		*a.balance += delta
+	}
+	return ok
+}
+
+func TestBulkUpdateTransactions_ConvertThenDeleteRestoresManualBalance(t *testing.T) {
+	const userID = "11111111-1111-1111-1111-111111111111"
+	const txID = "22222222-2222-2222-2222-222222222222"
+	const acctID = "55555555-5555-5555-5555-555555555555"
+
+	// The account held 1000 before a 100 expense was recorded against it.
+	balance := 900.0
+	lockCols := []string{"account_balance_id", "type", "amount", "is_liability"}
+
+	steps := []struct {
+		op     string
+		expect func(mock sqlmock.Sqlmock)
+	}{
+		{"convert_to_transfer", func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(`FOR UPDATE OF t`).WithArgs(txID).
+				WillReturnRows(sqlmock.NewRows(lockCols).AddRow(acctID, "expense", 100.0, false))
+			mock.ExpectExec(`UPDATE transactions SET type = 'transfer'`).
+				WithArgs(txID).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(`UPDATE account_balances`).
+				WithArgs(balanceArg{&balance}, acctID).
+				WillReturnRows(sqlmock.NewRows([]string{"current_balance"}).AddRow(1000.0))
+			mock.ExpectExec(`INSERT INTO account_balance_history`).WillReturnResult(sqlmock.NewResult(0, 1))
+		}},
+		{"delete", func(mock sqlmock.Sqlmock) {
+			// Now a transfer, the row no longer counts toward the balance,
+			// so deleting it leaves the balance alone.
+			mock.ExpectQuery(`FOR UPDATE OF t`).WithArgs(txID).
+				WillReturnRows(sqlmock.NewRows(lockCols).AddRow(acctID, "transfer", 100.0, false))
+			mock.ExpectQuery(`DELETE FROM attachments`).WithArgs(txID).
+				WillReturnRows(sqlmock.NewRows([]string{"storage_key", "thumbnail_key"}))
+			mock.ExpectExec(`DELETE FROM transactions`).WithArgs(txID).WillReturnResult(sqlmock.NewResult(0, 1))
+		}},
+	}
+
+	orig := bulkDBFactory
+	defer func() { bulkDBFactory = orig }()
+	for _, step := range steps {
+		mockSQL, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %v", err)
+		}
+		bulkDBFactory = func() (db.DBTX, error) { return &mockDB{db: mockSQL}, nil }
+
+		mock.ExpectQuery(`SELECT household_id FROM household_members`).
+			WillReturnRows(sqlmock.NewRows([]string{"household_id"}))
+		mock.ExpectQuery(`FROM transactions t\s+WHERE t.id = ANY`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "is_split", "allowed"}).AddRow(txID, false, true))
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT bulk_item`).WillReturnResult(sqlmock.NewResult(0, 0))
+		step.expect(mock)
+		mock.ExpectExec(`RELEASE SAVEPOINT bulk_item`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		body := `{"operation":"` + step.op + `","transaction_ids":["` + txID + `"]}`
+		req := httptest.NewRequest(http.MethodPost, "/auth/transactions/bulk", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+planTestToken(t, userID))
+		rr := httptest.NewRecorder()
+
+		BulkUpdateTransactions(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", step.op, rr.Code, rr.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("%s: unmet expectations: %v", step.op, err)
+		}
+	}
+	if balance != 1000 {
+		t.Fatalf("expected the balance back at 1000, got %v", balance)
+	}
+}
@@ -18,104 +18,165 @@ type debtState struct {
 	months        []models.MonthEntry
 	paidOff       bool
 	payoffMonth   int
+	promo         *models.PromoRate
+	rank          int // position in a custom order
+	interest      float64
+	payment       float64
 }
 
-// firstUnpaid returns the first debt that has not been paid off.
-func firstUnpaid(states []*debtState) *debtState {
-	for _, s := range states {
-		if !s.paidOff {
-			return s
-		}
+// aprFor is the debt's rate in a month, honouring a promotional period.
+func (s *debtState) aprFor(month int) float64 {
+	if s.promo != nil && month <= s.promo.Months {
+		return s.promo.APR
 	}
-	return nil
+	return s.APR
 }
 
+// PayoffStrategies are the strategies CompareDebtStrategies runs.
+var PayoffStrategies = []string{"snowball", "avalanche", "hybrid"}
+
 // CalculateDebtPayoff simulates month-by-month debt payoff using the given strategy.
 // Strategies: "avalanche" (highest APR first), "snowball" (lowest balance first),
 // "hybrid" (highest APR but within 5% APR bands, lowest balance first).
 // extraPayment is the total extra money per month above all minimum payments.
 func CalculateDebtPayoff(debts []models.DebtInfo, strategy string, extraPayment float64) []models.DebtPayoffSchedule {
+	return SimulateDebtPayoff(debts, strategy, models.PayoffOptions{ExtraPayment: extraPayment})
+}
+
+// SimulateDebtPayoff is CalculateDebtPayoff with extra payments that change
+// over time, one-time lump sums, promotional APRs and a "custom" strategy
+// that follows opts.CustomOrder. Minimum payments of paid-off debts roll
+// into the extra payment, and money a debt can't absorb in its final month
+// moves on to the next debt.
+func SimulateDebtPayoff(debts []models.DebtInfo, strategy string, opts models.PayoffOptions) []models.DebtPayoffSchedule {
+	return simulatePayoff(debts, strategy, opts, true)
+}
+
+func simulatePayoff(debts []models.DebtInfo, strategy string, opts models.PayoffOptions, rollover bool) []models.DebtPayoffSchedule {
 	if len(debts) == 0 {
 		return []models.DebtPayoffSchedule{}
 	}
 
+	rank := map[string]int{}
+	for i, id := range opts.CustomOrder {
+		if _, ok := rank[id]; !ok {
+			rank[id] = i
+		}
+	}
 	states := make([]*debtState, len(debts))
 	for i, d := range debts {
 		states[i] = &debtState{
 			DebtInfo: d,
 			balance:  d.Balance,
+			rank:     len(opts.CustomOrder),
+		}
+		if r, ok := rank[d.ID]; ok {
+			states[i].rank = r
+		}
+		for j := range opts.PromoRates {
+			if opts.PromoRates[j].DebtID == d.ID {
+				states[i].promo = &opts.PromoRates[j]
+			}
 		}
 	}
 
-	sortStates := func() {
-		switch strategy {
-		case "snowball":
-			sort.Slice(states, func(i, j int) bool {
-				if states[i].paidOff != states[j].paidOff {
-					return !states[i].paidOff
-				}
-				return states[i].balance < states[j].balance
-			})
-		case "hybrid":
-			sort.Slice(states, func(i, j int) bool {
-				if states[i].paidOff != states[j].paidOff {
-					return !states[i].paidOff
-				}
-				bandI := int(states[i].APR / 5.0)
-				bandJ := int(states[j].APR / 5.0)
-				if bandI != bandJ {
-					return bandI > bandJ
+	changes := append([]models.ExtraPaymentChange(nil), opts.ExtraChanges...)
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].FromMonth < changes[j].FromMonth })
+	extraFor := func(month int) float64 {
+		extra := opts.ExtraPayment
+		for _, c := range changes {
+			if c.FromMonth <= month {
+				extra = c.Amount
+			}
+		}
+		return extra
+	}
+
+	sortStates := func(month int) {
+		sort.SliceStable(states, func(i, j int) bool {
+			a, b := states[i], states[j]
+			if a.paidOff != b.paidOff {
+				return !a.paidOff
+			}
+			switch strategy {
+			case "snowball":
+				return a.balance < b.balance
+			case "hybrid":
+				bandA := int(a.aprFor(month) / 5.0)
+				bandB := int(b.aprFor(month) / 5.0)
+				if bandA != bandB {
+					return bandA > bandB
 				}
-				return states[i].balance < states[j].balance
-			})
-		default: // avalanche
-			sort.Slice(states, func(i, j int) bool {
-				if states[i].paidOff != states[j].paidOff {
-					return !states[i].paidOff
+				return a.balance < b.balance
+			case "custom":
+				if a.rank != b.rank {
+					return a.rank < b.rank
 				}
-				return states[i].APR > states[j].APR
-			})
-		}
+				return a.aprFor(month) > b.aprFor(month)
+			default: // avalanche
+				return a.aprFor(month) > b.aprFor(month)
+			}
+		})
 	}
 
 	const maxMonths = 360
+	var freed float64
 
 	for month := 1; month <= maxMonths; month++ {
-		sortStates()
-
-		allPaid := true
-		for _, s := range states {
-			if !s.paidOff {
-				allPaid = false
-				break
-			}
-		}
-		if allPaid {
+		sortStates(month)
+		if firstUnpaid(states) == nil {
 			break
 		}
 
-		remaining := extraPayment
-		target := firstUnpaid(states)
+		// Lump sums aimed at a debt go to it; the rest join this month's extra.
+		pool := extraFor(month) + freed
+		targeted := map[string]float64{}
+		for _, l := range opts.LumpSums {
+			if l.Month != month {
+				continue
+			}
+			if l.DebtID == "" {
+				pool += l.Amount
+			} else {
+				targeted[l.DebtID] += l.Amount
+			}
+		}
 
+		// Minimums (and targeted lump sums) first; whatever a debt can't
+		// absorb joins the pool.
 		for _, s := range states {
 			if s.paidOff {
+				if amt, ok := targeted[s.ID]; ok {
+					pool += amt
+				}
 				continue
 			}
-
-			monthlyRate := s.APR / 100.0 / 12.0
-			interest := math.Round(s.balance*monthlyRate*100) / 100
-
-			payment := s.MinPayment
-			if s == target {
-				payment += remaining
-				remaining = 0
+			monthlyRate := s.aprFor(month) / 100.0 / 12.0
+			s.interest = math.Round(s.balance*monthlyRate*100) / 100
+			owed := math.Round((s.balance+s.interest)*100) / 100
+			s.payment = s.MinPayment + targeted[s.ID]
+			if s.payment > owed {
+				pool += s.payment - owed
+				s.payment = owed
 			}
+		}
 
-			if payment > s.balance+interest {
-				payment = math.Round((s.balance+interest)*100) / 100
+		// The pool goes to debts in strategy order.
+		for _, s := range states {
+			if s.paidOff || pool <= 0 {
+				continue
 			}
+			owed := math.Round((s.balance+s.interest)*100) / 100
+			add := math.Min(pool, owed-s.payment)
+			s.payment = math.Round((s.payment+add)*100) / 100
+			pool -= add
+		}
 
-			principal := math.Round((payment-interest)*100) / 100
+		for _, s := range states {
+			if s.paidOff {
+				continue
+			}
+			principal := math.Round((s.payment-s.interest)*100) / 100
 			if principal < 0 {
 				principal = 0
 			}
@@ -124,20 +185,22 @@ func CalculateDebtPayoff(debts []models.DebtInfo, strategy string, extraPayment
 			if s.balance < 0.01 {
 				s.balance = 0
 			}
-			s.totalInterest += interest
+			s.totalInterest += s.interest
 
 			s.months = append(s.months, models.MonthEntry{
 				Month:            month,
-				Payment:          payment,
+				Payment:          s.payment,
 				Principal:        principal,
-				Interest:         interest,
+				Interest:         s.interest,
 				RemainingBalance: s.balance,
 			})
 
 			if s.balance <= 0 {
 				s.paidOff = true
 				s.payoffMonth = month
-				extraPayment += s.MinPayment
+				if rollover {
+					freed += s.MinPayment
+				}
 			}
 		}
 	}
@@ -160,6 +223,96 @@ func CalculateDebtPayoff(debts []models.DebtInfo, strategy string, extraPayment
 	return schedules
 }
 
+// firstUnpaid returns the first debt that has not been paid off.
+func firstUnpaid(states []*debtState) *debtState {
+	for _, s := range states {
+		if !s.paidOff {
+			return s
+		}
+	}
+	return nil
+}
+
+// CompareDebtStrategies runs snowball, avalanche and hybrid (plus "custom"
+// when opts has a custom order) over the same debts and options, and
+// measures each against paying only the minimums. The recommended strategy
+// pays the least interest, then finishes soonest.
+func CompareDebtStrategies(debts []models.DebtInfo, opts models.PayoffOptions) models.PayoffComparison {
+	minimums := models.PayoffOptions{PromoRates: opts.PromoRates}
+	cmp := models.PayoffComparison{
+		MinimumOnly: summarizePayoff("minimum_only", simulatePayoff(debts, "avalanche", minimums, false)),
+		Strategies:  []models.PayoffStrategyResult{},
+	}
+
+	strategies := PayoffStrategies
+	if len(opts.CustomOrder) > 0 {
+		strategies = append(append([]string(nil), strategies...), "custom")
+	}
+	for _, strategy := range strategies {
+		res := summarizePayoff(strategy, SimulateDebtPayoff(debts, strategy, opts))
+		res.MonthsSaved = cmp.MinimumOnly.Months - res.Months
+		res.InterestSaved = math.Round((cmp.MinimumOnly.TotalInterest-res.TotalInterest)*100) / 100
+		cmp.Strategies = append(cmp.Strategies, res)
+	}
+
+	best := -1
+	for i, res := range cmp.Strategies {
+		if best < 0 || res.TotalInterest < cmp.Strategies[best].TotalInterest ||
+			(res.TotalInterest == cmp.Strategies[best].TotalInterest && res.Months < cmp.Strategies[best].Months) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		cmp.Recommended = cmp.Strategies[best].Strategy
+	}
+	return cmp
+}
+
+// summarizePayoff totals a simulation and lists debts in the order they
+// are paid off. Debts still owing after the simulation's 30 years come last.
+func summarizePayoff(strategy string, schedules []models.DebtPayoffSchedule) models.PayoffStrategyResult {
+	res := models.PayoffStrategyResult{Strategy: strategy, DebtFree: true, Debts: []models.PayoffDebtResult{}}
+	var interest, paid float64
+	for _, s := range schedules {
+		interest += s.TotalInterest
+		for _, m := range s.Months {
+			paid += m.Payment
+		}
+		payoffMonth := 0
+		if s.PayoffDate != "" {
+			payoffMonth = len(s.Months)
+		} else {
+			res.DebtFree = false
+		}
+		if len(s.Months) > res.Months {
+			res.Months = len(s.Months)
+		}
+		res.Debts = append(res.Debts, models.PayoffDebtResult{
+			DebtID:        s.DebtID,
+			DebtName:      s.DebtName,
+			PayoffMonth:   payoffMonth,
+			PayoffDate:    s.PayoffDate,
+			TotalInterest: s.TotalInterest,
+		})
+	}
+	sort.SliceStable(res.Debts, func(i, j int) bool {
+		a, b := res.Debts[i].PayoffMonth, res.Debts[j].PayoffMonth
+		if (a == 0) != (b == 0) {
+			return b == 0
+		}
+		return a < b
+	})
+	for i := range res.Debts {
+		res.Debts[i].Order = i + 1
+	}
+	if res.DebtFree && res.Months > 0 {
+		res.DebtFreeDate = time.Now().AddDate(0, res.Months, 0).Format("2006-01")
+	}
+	res.TotalInterest = math.Round(interest*100) / 100
+	res.TotalPaid = math.Round(paid*100) / 100
+	return res
+}
+
 // CalculateStructuredDebtAmortization projects a standard amortization schedule for
 // structured debts (e.g., mortgage). No extra payments — just minimum payment over time.
 func CalculateStructuredDebtAmortization(debts []models.DebtInfo) []models.DebtPayoffSchedule {
@@ -6,6 +6,7 @@ import (
 	"log"
 	"strings"
 
+	"github.com/aboogie/budget-backend/internal/investments"
 	"github.com/aboogie/budget-backend/models"
 )
 
@@ -369,7 +370,28 @@ func assessLevel4(conn *sql.DB, userID, householdID string) []models.CriterionSt
 		Detail: fmt.Sprintf("%d investment holding(s)", holdingCount),
 	})
 
-	// 2. Multiple savings goals on track (current >= 50% of target)
+	// 2. Actively investing: contributions in the synced history and a
+	// portfolio that is not concentrated in a single asset type.
+	if holdingCount > 0 {
+		perf, err := investments.Analyze(conn, userID, householdID)
+		if err != nil {
+			log.Printf("assessLevel4 investments error: %v", err)
+		} else {
+			largest := 0.0
+			if len(perf.Allocation) > 0 {
+				largest = perf.Allocation[0].Percent
+			}
+			detail := fmt.Sprintf("$%.0f contributed, TWR %.1f%%, largest allocation %.0f%%",
+				perf.TotalContributed, perf.TimeWeightedReturnPct, largest)
+			criteria = append(criteria, models.CriterionStatus{
+				Name:   "Regular, diversified investing",
+				Met:    perf.TotalContributed > 0 && len(perf.Allocation) > 1 && largest < 90,
+				Detail: detail,
+			})
+		}
+	}
+
+	// 3. Multiple savings goals on track (current >= 50% of target)
 	var onTrack int
 	oq := `SELECT COUNT(*) FROM savings_goals
 	       WHERE user_id = $1 AND target_amount > 0 AND current_amount >= target_amount * 0.5`
@@ -386,7 +408,7 @@ func assessLevel4(conn *sql.DB, userID, householdID string) []models.CriterionSt
 		Detail: fmt.Sprintf("%d goal(s) at 50%%+ of target", onTrack),
 	})
 
-	// 3. Has a combined type financial plan active
+	// 4. Has a combined type financial plan active
 	var combinedPlans int
 	cq := `SELECT COUNT(*) FROM financial_plans
 	       WHERE created_by = $1 AND plan_type = 'combined' AND status = 'active'`
@@ -411,7 +433,7 @@ func assessLevel4(conn *sql.DB, userID, householdID string) []models.CriterionSt
 func assessLevel5(conn *sql.DB, userID, householdID string) []models.CriterionStatus {
 	var criteria []models.CriterionStatus
 
-	// 1. Positive net worth: total savings + balances > total debt
+	// 1. Positive net worth: total savings + account assets > liabilities and debt
 	var totalSavings float64
 	sq := `SELECT COALESCE(SUM(current_amount), 0) FROM savings_goals WHERE user_id = $1`
 	sArgs := []interface{}{userID}
@@ -421,19 +443,13 @@ func assessLevel5(conn *sql.DB, userID, householdID string) []models.CriterionSt
 	}
 	_ = conn.QueryRow(sq, sArgs...).Scan(&totalSavings)
 
-	var totalBalance float64
-	_ = conn.QueryRow(`SELECT COALESCE(SUM(current_balance), 0) FROM account_balances WHERE user_id = $1`, userID).Scan(&totalBalance)
-
-	var totalDebt float64
-	dq := `SELECT COALESCE(SUM(balance), 0) FROM debt_accounts WHERE user_id = $1`
-	dArgs := []interface{}{userID}
-	if householdID != "" {
-		dq = `SELECT COALESCE(SUM(balance), 0) FROM debt_accounts WHERE user_id = $1 OR household_id = $2`
-		dArgs = append(dArgs, householdID)
+	// Linked + manual accounts (assets minus liabilities) and untracked debts
+	nw, err := CalculateNetWorth(conn, userID, householdID)
+	if err != nil {
+		log.Printf("assessLevel5 net worth error: %v", err)
 	}
-	_ = conn.QueryRow(dq, dArgs...).Scan(&totalDebt)
 
-	netWorth := totalSavings + totalBalance - totalDebt
+	netWorth := totalSavings + nw.NetWorth
 	criteria = append(criteria, models.CriterionStatus{
 		Name:   "Positive and growing net worth",
 		Met:    netWorth > 0,
@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// CalculateNetWorth sums linked and manual accounts from account_balances,
+// skipping accounts whose sync is off, and subtracts debts that are not
+// already represented by a linked account (manual debt_accounts rows without
+// a plaid_account_id).
+func CalculateNetWorth(conn *sql.DB, userID, householdID string) (models.NetWorthSummary, error) {
+	var nw models.NetWorthSummary
+
+	aq := `SELECT
+	         COALESCE(SUM(current_balance) FILTER (WHERE NOT is_liability), 0),
+	         COALESCE(SUM(current_balance) FILTER (WHERE is_liability), 0),
+	         COALESCE(SUM(current_balance) FILTER (WHERE NOT is_liability AND is_manual), 0),
+	         COALESCE(SUM(current_balance) FILTER (WHERE is_liability AND is_manual), 0)
+	       FROM account_balances WHERE user_id = $1 AND sync_enabled`
+	aArgs := []interface{}{userID}
+	if householdID != "" {
+		aq = `SELECT
+		        COALESCE(SUM(current_balance) FILTER (WHERE NOT is_liability), 0),
+		        COALESCE(SUM(current_balance) FILTER (WHERE is_liability), 0),
+		        COALESCE(SUM(current_balance) FILTER (WHERE NOT is_liability AND is_manual), 0),
+		        COALESCE(SUM(current_balance) FILTER (WHERE is_liability AND is_manual), 0)
+		      FROM account_balances WHERE (user_id = $1 OR household_id = $2) AND sync_enabled`
+		aArgs = append(aArgs, householdID)
+	}
+	if err := conn.QueryRow(aq, aArgs...).Scan(
+		&nw.TotalAssets, &nw.TotalLiabilities, &nw.ManualAssets, &nw.ManualLiabilities,
+	); err != nil {
+		return nw, fmt.Errorf("account balances query: %w", err)
+	}
+
+	dq := `SELECT COALESCE(SUM(balance), 0) FROM debt_accounts
+	       WHERE user_id = $1 AND plaid_account_id IS NULL`
+	dArgs := []interface{}{userID}
+	if householdID != "" {
+		dq = `SELECT COALESCE(SUM(balance), 0) FROM debt_accounts
+		      WHERE (user_id = $1 OR household_id = $2) AND plaid_account_id IS NULL`
+		dArgs = append(dArgs, householdID)
+	}
+	if err := conn.QueryRow(dq, dArgs...).Scan(&nw.UntrackedDebt); err != nil {
+		return nw, fmt.Errorf("debt query: %w", err)
+	}
+
+	nw.NetWorth = math.Round((nw.TotalAssets-nw.TotalLiabilities-nw.UntrackedDebt)*100) / 100
+	return nw, nil
+}
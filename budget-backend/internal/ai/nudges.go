@@ -7,6 +7,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/aboogie/budget-backend/internal/recurring"
 	"github.com/aboogie/budget-backend/models"
 )
 
@@ -54,6 +55,9 @@ func GenerateNudges(conn *sql.DB, userID, householdID string) []models.AINudge {
 	// 11. Categorization review: unverified auto-categorized transactions
 	nudges = append(nudges, checkCategorizationReview(conn, userID, hhPtr)...)
 
+	// 12. Subscriptions: price increases, late charges, trials and renewals
+	nudges = append(nudges, checkSubscriptionAlerts(conn, userID, hhPtr, now)...)
+
 	return nudges
 }
 
@@ -639,3 +643,53 @@ func checkCategorizationReview(conn *sql.DB, userID string, hhPtr *string) []mod
 		Priority:    4,
 	}}
 }
+
+// ─── Subscription Alerts ─────────────────────────────────────
+// Nudge when a subscription's price goes up, a charge is late or missing,
+// a free trial is about to convert (or just did), or an annual renewal is
+// coming up.
+
+var subscriptionAlertTitles = map[string]string{
+	"price_increase":  "%s raised its price",
+	"late_charge":     "%s charge is late",
+	"missing_charge":  "%s charge is missing",
+	"trial_ending":    "%s trial ends soon",
+	"trial_converted": "%s trial converted to paid",
+	"annual_renewal":  "%s renews soon",
+}
+
+var subscriptionAlertPriority = map[string]int{
+	"price_increase":  3,
+	"late_charge":     3,
+	"missing_charge":  2,
+	"trial_ending":    2,
+	"trial_converted": 3,
+	"annual_renewal":  3,
+}
+
+func checkSubscriptionAlerts(conn *sql.DB, userID string, hhPtr *string, now time.Time) []models.AINudge {
+	subs, err := recurring.LoadSubscriptions(conn, userID, now.AddDate(-1, -1, 0))
+	if err != nil {
+		log.Printf("nudges: subscription query error: %v", err)
+		return nil
+	}
+
+	var nudges []models.AINudge
+	actionType := "navigate_to"
+	actionData := "/subscriptions"
+	for _, s := range subs {
+		for _, a := range recurring.Alerts(s, now) {
+			nudges = append(nudges, models.AINudge{
+				UserID:      userID,
+				HouseholdID: hhPtr,
+				NudgeType:   "subscription_alert",
+				Title:       fmt.Sprintf(subscriptionAlertTitles[a.Type], a.Name),
+				Body:        a.Message,
+				ActionType:  &actionType,
+				ActionData:  &actionData,
+				Priority:    subscriptionAlertPriority[a.Type],
+			})
+		}
+	}
+	return nudges
+}
@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+const receiptPrompt = `You read receipts for a budgeting app. Extract the purchased line items from the receipt text.
+
+Return ONLY a JSON object, no prose, in this shape:
+{"merchant": "", "items": [{"description": "", "quantity": 1, "amount": 0.00}], "subtotal": 0.00, "tax": 0.00, "tip": 0.00, "discount": 0.00, "total": 0.00}
+
+Rules:
+- amount is the line total after quantity, as a positive number
+- expand cryptic abbreviations into plain item names ("ORG BNNA" -> "Organic bananas")
+- put coupons and savings in discount as a positive number, not in items
+- leave out payment, change and loyalty lines
+- use 0 for any summary amount the receipt doesn't show`
+
+// ExtractReceipt asks Claude to read line items from OCR'd or pasted
+// receipt text. It is slower than the rule-based parser but copes with
+// messy OCR and abbreviated item names.
+func (c *Client) ExtractReceipt(text string) (models.ParsedReceipt, error) {
+	resp, err := c.SendMessage(models.ClaudeRequest{
+		System:    receiptPrompt,
+		MaxTokens: 2000,
+		Messages:  []models.ClaudeMessage{{Role: "user", Content: text}},
+	})
+	if err != nil {
+		return models.ParsedReceipt{}, err
+	}
+
+	var out strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			out.WriteString(block.Text)
+		}
+	}
+	raw := out.String()
+	start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}")
+	if start < 0 || end < start {
+		return models.ParsedReceipt{}, fmt.Errorf("no JSON in receipt response")
+	}
+
+	var receipt models.ParsedReceipt
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &receipt); err != nil {
+		return models.ParsedReceipt{}, fmt.Errorf("decode receipt response: %w", err)
+	}
+	items := receipt.Items[:0]
+	for _, it := range receipt.Items {
+		it.Description = strings.TrimSpace(it.Description)
+		if it.Description != "" && it.Amount > 0 {
+			items = append(items, it)
+		}
+	}
+	receipt.Items = items
+	return receipt, nil
+}
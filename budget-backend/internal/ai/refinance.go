@@ -0,0 +1,164 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// AmortizedPayment is the fixed monthly payment that pays off principal over
+// termMonths at an APR percentage, rounded up to the cent so the loan
+// finishes on time.
+func AmortizedPayment(principal, apr float64, termMonths int) float64 {
+	if principal <= 0 || termMonths <= 0 {
+		return 0
+	}
+	r := apr / 100.0 / 12.0
+	if r == 0 {
+		return math.Ceil(principal/float64(termMonths)*100) / 100
+	}
+	payment := principal * r / (1 - math.Pow(1+r, -float64(termMonths)))
+	return math.Ceil(payment*100) / 100
+}
+
+// AnalyzeRefinance compares paying the replaced debts on their minimums, as
+// CalculateStructuredDebtAmortization projects them, with a single new loan.
+// Both sides use the same amortization so the comparison is like for like.
+func AnalyzeRefinance(replaced []models.DebtInfo, req models.RefinanceRequest) models.RefinanceAnalysis {
+	a := models.RefinanceAnalysis{Replaced: replaced}
+
+	a.CurrentSchedules = CalculateStructuredDebtAmortization(replaced)
+	a.Current = scenarioFor(a.CurrentSchedules, 0)
+	for _, d := range replaced {
+		a.Principal += d.Balance
+		a.Current.MonthlyPayment += d.MinPayment
+	}
+	a.Current.MonthlyPayment = math.Round(a.Current.MonthlyPayment*100) / 100
+
+	if req.FeesFinanced {
+		a.Principal += req.Fees
+	}
+	a.Principal = math.Round(a.Principal*100) / 100
+	loan := models.DebtInfo{
+		ID:         "refinance",
+		Name:       "New loan",
+		Balance:    a.Principal,
+		APR:        req.Rate,
+		MinPayment: AmortizedPayment(a.Principal, req.Rate, req.TermMonths),
+	}
+	if schedules := CalculateStructuredDebtAmortization([]models.DebtInfo{loan}); len(schedules) > 0 {
+		a.RefinancedSchedule = schedules[0]
+	}
+	a.Refinanced = scenarioFor([]models.DebtPayoffSchedule{a.RefinancedSchedule}, req.Fees)
+	a.Refinanced.MonthlyPayment = loan.MinPayment
+
+	a.MonthlyPaymentChange = math.Round((a.Refinanced.MonthlyPayment-a.Current.MonthlyPayment)*100) / 100
+	a.InterestSaved = math.Round((a.Current.TotalInterest-a.Refinanced.TotalInterest)*100) / 100
+	a.NetSavings = math.Round((a.InterestSaved-req.Fees)*100) / 100
+
+	// Break-even: the first month the interest saved so far covers the fees.
+	current := monthlyInterest(a.CurrentSchedules)
+	refinanced := monthlyInterest([]models.DebtPayoffSchedule{a.RefinancedSchedule})
+	months := len(current)
+	if len(refinanced) > months {
+		months = len(refinanced)
+	}
+	var saved float64
+	for m := 0; m < months; m++ {
+		if m < len(current) {
+			saved += current[m]
+		}
+		if m < len(refinanced) {
+			saved -= refinanced[m]
+		}
+		if saved > 0 && saved >= req.Fees {
+			a.BreakEvenMonth = m + 1
+			break
+		}
+	}
+	return a
+}
+
+// scenarioFor totals a set of amortization schedules. The debts pay off only
+// if every schedule reaches a zero balance within the 30-year horizon.
+func scenarioFor(schedules []models.DebtPayoffSchedule, fees float64) models.RefinanceScenario {
+	s := models.RefinanceScenario{Fees: fees, PaysOff: len(schedules) > 0}
+	for _, sch := range schedules {
+		s.TotalInterest += sch.TotalInterest
+		if len(sch.Months) > s.Months {
+			s.Months = len(sch.Months)
+		}
+		if sch.PayoffDate == "" {
+			s.PaysOff = false
+		}
+	}
+	s.TotalInterest = math.Round(s.TotalInterest*100) / 100
+	s.TotalCost = math.Round((s.TotalInterest+fees)*100) / 100
+	if s.PaysOff {
+		s.PayoffDate = time.Now().AddDate(0, s.Months, 0).Format("2006-01")
+	}
+	return s
+}
+
+// monthlyInterest is the interest charged across all schedules, month by
+// month.
+func monthlyInterest(schedules []models.DebtPayoffSchedule) []float64 {
+	var out []float64
+	for _, sch := range schedules {
+		for i, m := range sch.Months {
+			for len(out) <= i {
+				out = append(out, 0)
+			}
+			out[i] += m.Interest
+		}
+	}
+	return out
+}
+
+// RefinanceFramework projects how a refinance changes the Attack Debt level:
+// how many attack debts remain and how long until they are paid off on
+// minimum payments. Clearing every attack debt lets someone working on Level
+// 2 move on to Level 3.
+func RefinanceFramework(assessment models.FrameworkAssessment, attackBefore, attackAfter []models.DebtInfo) models.RefinanceFrameworkImpact {
+	impact := models.RefinanceFrameworkImpact{
+		CurrentLevel:          assessment.Level,
+		CurrentLevelName:      levelNames[assessment.Level],
+		ProjectedLevel:        assessment.Level,
+		ProjectedLevelName:    levelNames[assessment.Level],
+		AttackDebtsBefore:     len(attackBefore),
+		AttackDebtsAfter:      len(attackAfter),
+		AttackFreeMonths:      amortizedMonths(attackBefore),
+		AttackFreeMonthsAfter: amortizedMonths(attackAfter),
+	}
+
+	switch {
+	case len(attackBefore) > 0 && len(attackAfter) == 0:
+		if assessment.Level == 2 {
+			impact.ProjectedLevel = 3
+			impact.ProjectedLevelName = levelNames[3]
+		}
+		impact.Summary = "This clears every attack debt, so the Attack Debt level no longer applies."
+	case impact.AttackFreeMonthsAfter < impact.AttackFreeMonths:
+		impact.Summary = fmt.Sprintf("Attack debts would be paid off in %d months instead of %d on minimum payments.",
+			impact.AttackFreeMonthsAfter, impact.AttackFreeMonths)
+	case impact.AttackFreeMonthsAfter > impact.AttackFreeMonths:
+		impact.Summary = fmt.Sprintf("Attack debts would take %d months to pay off instead of %d on minimum payments.",
+			impact.AttackFreeMonthsAfter, impact.AttackFreeMonths)
+	default:
+		impact.Summary = "No change to how long the Attack Debt level takes."
+	}
+	return impact
+}
+
+// amortizedMonths is how long the debts take to pay off on their minimums.
+func amortizedMonths(debts []models.DebtInfo) int {
+	months := 0
+	for _, s := range CalculateStructuredDebtAmortization(debts) {
+		if len(s.Months) > months {
+			months = len(s.Months)
+		}
+	}
+	return months
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/aboogie/budget-backend/internal/investments"
+	"github.com/aboogie/budget-backend/internal/recurring"
 	"github.com/aboogie/budget-backend/models"
 )
 
@@ -14,7 +17,7 @@ func GetToolDefinitions() []models.ClaudeToolDef {
 	tools := []models.ClaudeToolDef{
 		{
 			Name:        "get_financial_snapshot",
-			Description: "Get the user's complete financial snapshot including income, expenses, account balances, and net worth summary. Use this to understand their overall financial picture.",
+			Description: "Get the user's complete financial snapshot including income, expenses, account balances, investment performance, and net worth summary. Use this to understand their overall financial picture.",
 			InputSchema: map[string]interface{}{
 				"type":       "object",
 				"properties": map[string]interface{}{},
@@ -301,18 +304,45 @@ func getFinancialSnapshot(conn *sql.DB, userID, householdID string) (string, err
 	err = conn.QueryRow(`
 		SELECT COALESCE(SUM(current_balance), 0)
 		FROM account_balances
-		WHERE user_id = $1
+		WHERE user_id = $1 AND NOT is_manual AND sync_enabled
 	`, userID).Scan(&totalBankBalance)
 	if err != nil {
 		log.Printf("snapshot balance query error: %v", err)
 	}
 	snapshot["total_bank_balance"] = totalBankBalance.Float64
 
+	// Net worth across linked and manual (offline) accounts
+	if nw, err := CalculateNetWorth(conn, userID, householdID); err != nil {
+		log.Printf("snapshot net worth query error: %v", err)
+	} else {
+		snapshot["total_assets"] = nw.TotalAssets
+		snapshot["total_liabilities"] = nw.TotalLiabilities + nw.UntrackedDebt
+		snapshot["manual_assets"] = nw.ManualAssets
+		snapshot["manual_liabilities"] = nw.ManualLiabilities
+		snapshot["net_worth"] = nw.NetWorth
+	}
+
 	// Number of active budgets
 	var budgetCount int
 	_ = conn.QueryRow(`SELECT COUNT(*) FROM budgets WHERE user_id = $1`, userID).Scan(&budgetCount)
 	snapshot["active_budgets"] = budgetCount
 
+	// Investment performance (holdings + investment transactions)
+	perf, err := investments.Analyze(conn, userID, householdID)
+	if err != nil {
+		log.Printf("snapshot investments query error: %v", err)
+	} else if perf.MarketValue > 0 {
+		snapshot["investments"] = map[string]interface{}{
+			"market_value":              perf.MarketValue,
+			"unrealized_gain":           perf.UnrealizedGain,
+			"realized_gain":             perf.RealizedGain,
+			"dividend_income":           perf.DividendIncome,
+			"time_weighted_return_pct":  perf.TimeWeightedReturnPct,
+			"money_weighted_return_pct": perf.MoneyWeightedReturnPct,
+			"allocation":                perf.Allocation,
+		}
+	}
+
 	result, _ := json.Marshal(snapshot)
 	return string(result), nil
 }
@@ -460,7 +490,7 @@ func getSpendingByCategory(conn *sql.DB, userID, householdID string, input json.
 func getBills(conn *sql.DB, userID, householdID string) (string, error) {
 	rows, err := conn.Query(`
 		SELECT id, name, amount_due, due_day, COALESCE(frequency, 'monthly'),
-		       COALESCE(is_autopay, false)
+		       COALESCE(is_autopay, false), is_variable
 		FROM bills
 		WHERE user_id = $1
 		ORDER BY due_day ASC
@@ -471,24 +501,44 @@ func getBills(conn *sql.DB, userID, householdID string) (string, error) {
 	defer rows.Close()
 
 	var bills []map[string]interface{}
+	var variableIDs []string
 	for rows.Next() {
 		var id, name, frequency string
 		var amount float64
 		var dueDay int
-		var autopay bool
-		if err := rows.Scan(&id, &name, &amount, &dueDay, &frequency, &autopay); err != nil {
+		var autopay, variable bool
+		if err := rows.Scan(&id, &name, &amount, &dueDay, &frequency, &autopay, &variable); err != nil {
 			continue
 		}
+		if variable {
+			variableIDs = append(variableIDs, id)
+		}
 		bills = append(bills, map[string]interface{}{
-			"id":        id,
-			"name":      name,
-			"amount":    amount,
-			"due_day":   dueDay,
-			"frequency": frequency,
-			"autopay":   autopay,
+			"id":          id,
+			"name":        name,
+			"amount":      amount,
+			"due_day":     dueDay,
+			"frequency":   frequency,
+			"autopay":     autopay,
+			"is_variable": variable,
 		})
 	}
 
+	// Variable bills carry a forecast for this month alongside the nominal amount.
+	if len(variableIDs) > 0 {
+		now := time.Now().UTC()
+		payments, err := recurring.LoadPayments(conn, variableIDs, now.AddDate(-3, -1, 0))
+		if err != nil {
+			log.Printf("getBills payments error: %v", err)
+		}
+		for _, b := range bills {
+			if b["is_variable"] == true {
+				f := recurring.ForecastAmount(b["amount"].(float64), payments[b["id"].(string)], now)
+				b["expected_amount"] = f.Amount
+			}
+		}
+	}
+
 	if bills == nil {
 		bills = []map[string]interface{}{}
 	}
@@ -0,0 +1,125 @@
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "u1/a.pdf", strings.NewReader("receipt"), 7, "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	rc, err := store.Get(ctx, "u1/a.pdf")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "receipt" {
+		t.Fatalf("got %q", got)
+	}
+
+	if err := store.Delete(ctx, "u1/a.pdf"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "u1/a.pdf"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Delete(ctx, "u1/a.pdf"); err != nil {
+		t.Fatalf("second Delete should be a no-op, got %v", err)
+	}
+
+	for _, key := range []string{"../escape", "/abs", "u1//x", "u1/./x", ""} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("expected key %q to be rejected", key)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var img bytes.Buffer
+	png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	heic := append([]byte{0, 0, 0, 24}, []byte("ftypheic0000")...)
+
+	cases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"png", img.Bytes(), "image/png"},
+		{"pdf", []byte("%PDF-1.7\n..."), "application/pdf"},
+		{"heic", heic, "image/heic"},
+		{"html", []byte("<html><script>"), ""},
+		{"empty", nil, ""},
+		{"too large", append([]byte("%PDF-"), make([]byte, MaxSize)...), ""},
+	}
+	for _, tc := range cases {
+		ct, err := Validate(tc.data)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", tc.name, ct)
+			}
+			continue
+		}
+		if err != nil || ct != tc.want {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, ct, err, tc.want)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 1000; x++ {
+			src.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var in bytes.Buffer
+	png.Encode(&in, src)
+
+	out, err := Thumbnail(&in, ThumbnailSize)
+	if err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 256 || b.Dy() != 128 {
+		t.Fatalf("expected 256x128, got %dx%d", b.Dx(), b.Dy())
+	}
+	if r, _, _, _ := thumb.At(10, 10).RGBA(); r>>8 < 180 {
+		t.Errorf("expected the red to survive scaling, got r=%d", r>>8)
+	}
+
+	if _, err := Thumbnail(strings.NewReader("%PDF-1.7"), ThumbnailSize); err == nil {
+		t.Error("expected an error for a non-image")
+	}
+}
+
+func TestThumbnail_RejectsHugeDimensions(t *testing.T) {
+	var in bytes.Buffer
+	gif.Encode(&in, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.White}), nil)
+	// A tiny file can claim a 65535x65535 canvas in its header.
+	data := in.Bytes()
+	copy(data[6:10], []byte{0xff, 0xff, 0xff, 0xff})
+
+	if _, err := Thumbnail(bytes.NewReader(data), ThumbnailSize); !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("expected ErrImageTooLarge, got %v", err)
+	}
+}
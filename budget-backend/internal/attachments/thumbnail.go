@@ -0,0 +1,98 @@
+package attachments
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	// Register decoders for image.Decode.
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ThumbnailSize is the longest edge of a generated thumbnail, in pixels.
+const ThumbnailSize = 256
+
+// MaxThumbnailPixels caps the images Thumbnail will decode. A small file can
+// declare huge dimensions and decoding allocates for every pixel; 50
+// megapixels is well above any phone camera.
+const MaxThumbnailPixels = 50_000_000
+
+// ErrImageTooLarge is returned by Thumbnail for images over MaxThumbnailPixels.
+var ErrImageTooLarge = errors.New("image dimensions too large to thumbnail")
+
+// CanThumbnail reports whether Thumbnail can decode a content type.
+func CanThumbnail(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// Thumbnail decodes an image and returns a JPEG no larger than size on its
+// longest edge. Smaller images are re-encoded at their own size. The header
+// is checked against MaxThumbnailPixels before the image is decoded.
+func Thumbnail(r io.Reader, size int) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxThumbnailPixels {
+		return nil, ErrImageTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleDown(src, size), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleDown box-filters src to fit within size x size, averaging every
+// source pixel that falls in each destination pixel. Transparent areas are
+// flattened onto white since JPEG has no alpha.
+func scaleDown(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if w > size || h > size {
+		if w >= h {
+			dw, dh = size, max(1, h*size/w)
+		} else {
+			dw, dh = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+max((x+1)*w/dw, x*w/dw+1)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					// Composite premultiplied color over white.
+					white := 0xffff - uint64(ca)
+					r += uint64(cr) + white
+					g += uint64(cg) + white
+					bl += uint64(cb) + white
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: 0xffff})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,52 @@
+package attachments
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// MaxSize is the largest attachment accepted, in bytes.
+const MaxSize = 10 << 20
+
+// allowedTypes are the content types accepted for receipts and documents.
+var allowedTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/heic":      true,
+	"application/pdf": true,
+}
+
+// DetectType sniffs the content type from the first bytes of a file. The
+// client's declared type is ignored so a renamed file can't slip through.
+func DetectType(head []byte) string {
+	// http.DetectContentType doesn't know HEIC, the iPhone camera default.
+	if len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")) {
+		switch string(head[8:12]) {
+		case "heic", "heix", "mif1", "msf1":
+			return "image/heic"
+		}
+	}
+	ct := http.DetectContentType(head)
+	if i := bytes.IndexByte([]byte(ct), ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	return ct
+}
+
+// Validate checks an upload's size and sniffed type, returning the type.
+func Validate(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", fmt.Errorf("file is empty")
+	}
+	if len(data) > MaxSize {
+		return "", fmt.Errorf("file is larger than %d MB", MaxSize>>20)
+	}
+	ct := DetectType(data)
+	if !allowedTypes[ct] {
+		return "", fmt.Errorf("unsupported file type %s; upload an image or PDF", ct)
+	}
+	return ct, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/aboogie/budget-backend/internal/categories"
 	"github.com/aboogie/budget-backend/internal/flinks"
+	"github.com/aboogie/budget-backend/internal/merchants"
 	"github.com/gofrs/uuid"
 )
 
@@ -76,26 +77,35 @@ func (f *FlinksProvider) SyncTransactions(conn *sql.DB, account LinkedAccount) (
 			var resolvedCatID *string
 			var matchConfidence *string
 			var matchedRuleID *string
-			merchantName := tx.Description
-			plaidCats := []string{} // Flinks doesn't provide Plaid-style categories
-			catID, conf, ruleID, resolveErr := categories.ResolveCategory(conn, account.UserID, account.HouseholdID, merchantName, plaidCats)
+			var merchantID *string
+			merchant, merchantErr := merchants.Resolve(conn, tx.Description, "")
+			if merchantErr != nil {
+				log.Printf("flinks: merchant resolve error (non-fatal): %v", merchantErr)
+			}
+			// Flinks doesn't provide Plaid-style categories
+			in := categories.Transaction{Merchant: tx.Description, Amount: amount, Type: txType, Date: txDate}
+			if merchant != nil {
+				merchantID = &merchant.ID
+				in.MerchantID = merchant.ID
+			}
+			res, resolveErr := categories.Resolve(conn, account.UserID, account.HouseholdID, in)
 			if resolveErr != nil {
 				log.Printf("flinks: category resolve error (non-fatal): %v", resolveErr)
 			}
-			if catID != "" {
-				resolvedCatID = &catID
+			if res.CategoryID != "" {
+				resolvedCatID = &res.CategoryID
 			}
-			if conf != "" && conf != "low" {
-				matchConfidence = &conf
+			if res.Confidence != "" && res.Confidence != "low" {
+				matchConfidence = &res.Confidence
 			}
-			if ruleID != nil {
-				matchedRuleID = ruleID
+			if res.RuleID != nil {
+				matchedRuleID = res.RuleID
 			}
 
 			source := "flinks"
 			_, err := conn.Exec(`
-				INSERT INTO transactions (id, user_id, household_id, type, amount, category_id, note, date, source, match_confidence, matched_rule_id, created_at, updated_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+				INSERT INTO transactions (id, user_id, household_id, type, amount, category_id, note, date, source, match_confidence, matched_rule_id, merchant_id, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
 				ON CONFLICT DO NOTHING
 			`,
 				txID,
@@ -109,6 +119,7 @@ func (f *FlinksProvider) SyncTransactions(conn *sql.DB, account LinkedAccount) (
 				source,
 				matchConfidence,
 				matchedRuleID,
+				merchantID,
 			)
 			if err != nil {
 				log.Printf("flinks: failed to insert transaction: %v", err)
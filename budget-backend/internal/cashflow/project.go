@@ -0,0 +1,178 @@
+// Package cashflow projects cash account balances forward day by day from
+// scheduled income and bills plus an average daily discretionary spend.
+package cashflow
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// Account is a cash account at the start of the projection.
+type Account struct {
+	ID        string
+	Name      string
+	Checking  bool
+	Balance   float64
+	Threshold float64
+}
+
+// Flow is a scheduled movement of money. Amount is positive for money in and
+// negative for money out. Flows without a known account land in the primary
+// account.
+type Flow struct {
+	Date      time.Time
+	AccountID string
+	Name      string
+	Source    string
+	Amount    float64
+}
+
+// PrimaryAccount picks the account that pays the bills: the checking account
+// with the most money, or failing that the largest account. It returns -1
+// when there are no accounts.
+func PrimaryAccount(accounts []Account) int {
+	best := -1
+	for i, a := range accounts {
+		if best < 0 || better(a, accounts[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+func better(a, b Account) bool {
+	if a.Checking != b.Checking {
+		return a.Checking
+	}
+	return a.Balance > b.Balance
+}
+
+// Project runs balances forward for the given number of days after start.
+// Start is today: its balance is the starting balance and flows dated on or
+// before it are assumed to have posted already. Discretionary spending comes
+// out of the primary account every day.
+func Project(accounts []Account, flows []Flow, dailySpend float64, start time.Time, days int) models.CashFlowForecast {
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, days)
+	primary := PrimaryAccount(accounts)
+	index := map[string]int{}
+	for i, a := range accounts {
+		index[a.ID] = i
+	}
+
+	f := models.CashFlowForecast{
+		HorizonDays:        days,
+		StartDate:          start.Format("2006-01-02"),
+		EndDate:            end.Format("2006-01-02"),
+		DailyDiscretionary: round2(dailySpend),
+		Days:               []models.CashFlowDay{},
+		Accounts:           []models.AccountCashFlowForecast{},
+		Events:             []models.CashFlowEvent{},
+	}
+
+	// Bucket flows by day and account. With no accounts the primary is -1 and
+	// everything lands there, so the combined total still shows where
+	// spending leads.
+	type key struct{ day, account int }
+	inflow, outflow := map[key]float64{}, map[key]float64{}
+	sorted := append([]Flow(nil), flows...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+	for _, fl := range sorted {
+		date := time.Date(fl.Date.Year(), fl.Date.Month(), fl.Date.Day(), 0, 0, 0, 0, time.UTC)
+		day := int(date.Sub(start).Hours() / 24)
+		if day < 1 || day > days {
+			continue
+		}
+		acct, ok := index[fl.AccountID]
+		if !ok {
+			acct = primary
+		}
+		k := key{day, acct}
+		if fl.Amount >= 0 {
+			inflow[k] += fl.Amount
+		} else {
+			outflow[k] -= fl.Amount
+		}
+		accountID := ""
+		if acct >= 0 {
+			accountID = accounts[acct].ID
+		}
+		f.Events = append(f.Events, models.CashFlowEvent{
+			Date:      date.Format("2006-01-02"),
+			Name:      fl.Name,
+			Source:    fl.Source,
+			Amount:    round2(fl.Amount),
+			AccountID: accountID,
+		})
+	}
+
+	balances := make([]float64, len(accounts))
+	for i, a := range accounts {
+		balances[i] = a.Balance
+		f.StartingBalance += a.Balance
+		f.Accounts = append(f.Accounts, models.AccountCashFlowForecast{
+			AccountID:         a.ID,
+			Name:              a.Name,
+			IsChecking:        a.Checking,
+			Threshold:         a.Threshold,
+			StartingBalance:   round2(a.Balance),
+			LowestBalance:     round2(a.Balance),
+			LowestBalanceDate: f.StartDate,
+			Days:              []models.CashFlowDay{},
+		})
+	}
+	total := f.StartingBalance
+	f.StartingBalance = round2(f.StartingBalance)
+	f.LowestBalance, f.LowestBalanceDate = f.StartingBalance, f.StartDate
+
+	for day := 1; day <= days; day++ {
+		date := start.AddDate(0, 0, day).Format("2006-01-02")
+		combined := models.CashFlowDay{Date: date}
+		for i := -1; i < len(accounts); i++ {
+			in, out := inflow[key{day, i}], outflow[key{day, i}]
+			if i == primary {
+				out += dailySpend
+			}
+			combined.Inflow += in
+			combined.Outflow += out
+			if i < 0 {
+				continue
+			}
+			balances[i] += in - out
+			af := &f.Accounts[i]
+			bal := round2(balances[i])
+			af.Days = append(af.Days, models.CashFlowDay{Date: date, Inflow: round2(in), Outflow: round2(out), Balance: bal})
+			if bal < af.LowestBalance {
+				af.LowestBalance, af.LowestBalanceDate = bal, date
+			}
+			if af.IsChecking && af.BelowThresholdOn == nil && bal < af.Threshold {
+				d := date
+				af.BelowThresholdOn, af.BelowThresholdBalance = &d, &bal
+			}
+		}
+		total += combined.Inflow - combined.Outflow
+		combined.Inflow, combined.Outflow = round2(combined.Inflow), round2(combined.Outflow)
+		combined.Balance = round2(total)
+		if combined.Balance < f.LowestBalance {
+			f.LowestBalance, f.LowestBalanceDate = combined.Balance, date
+		}
+		f.Days = append(f.Days, combined)
+	}
+
+	for i := range f.Accounts {
+		af := &f.Accounts[i]
+		af.EndingBalance = round2(balances[i])
+		if af.BelowThresholdOn != nil && (f.BelowThresholdOn == nil || *af.BelowThresholdOn < *f.BelowThresholdOn) {
+			f.BelowThresholdOn = af.BelowThresholdOn
+		}
+	}
+	f.EndingBalance = round2(total)
+	return f
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
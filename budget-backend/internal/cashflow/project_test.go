@@ -0,0 +1,70 @@
+package cashflow
+
+import (
+	"testing"
+	"time"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestProject_FlagsFirstLowDay(t *testing.T) {
+	accounts := []Account{
+		{ID: "sav", Name: "Savings", Balance: 5000},
+		{ID: "chk", Name: "Checking", Checking: true, Balance: 1000, Threshold: 100},
+	}
+	flows := []Flow{
+		{Date: day(2026, 5, 3), Name: "Rent", Source: "bill", Amount: -900},
+		{Date: day(2026, 5, 5), Name: "Paycheck", Source: "income", Amount: 2000},
+		{Date: day(2026, 5, 4), AccountID: "sav", Name: "Transfer", Source: "expense", Amount: -50},
+		{Date: day(2026, 5, 1), Name: "Already posted", Source: "bill", Amount: -500},
+	}
+
+	f := Project(accounts, flows, 10, day(2026, 5, 1), 5)
+
+	chk := f.Accounts[1]
+	// 1000 - 10 on May 2, then - 900 - 10 = 80 on May 3: below the threshold.
+	if chk.BelowThresholdOn == nil || *chk.BelowThresholdOn != "2026-05-03" || *chk.BelowThresholdBalance != 80 {
+		t.Fatalf("expected checking flagged on May 3 at 80, got %+v", chk)
+	}
+	if chk.LowestBalance != 70 || chk.LowestBalanceDate != "2026-05-04" {
+		t.Errorf("lowest = %v on %s", chk.LowestBalance, chk.LowestBalanceDate)
+	}
+	if chk.EndingBalance != 2050 {
+		t.Errorf("checking ending = %v", chk.EndingBalance)
+	}
+	if sav := f.Accounts[0]; sav.EndingBalance != 4950 || sav.BelowThresholdOn != nil {
+		t.Errorf("savings = %+v", sav)
+	}
+	if f.BelowThresholdOn == nil || *f.BelowThresholdOn != "2026-05-03" {
+		t.Errorf("forecast not flagged")
+	}
+	if f.StartingBalance != 6000 || f.EndingBalance != 7000 || len(f.Days) != 5 {
+		t.Errorf("combined = %v -> %v over %d days", f.StartingBalance, f.EndingBalance, len(f.Days))
+	}
+	if len(f.Events) != 3 || f.Events[0].AccountID != "chk" {
+		t.Errorf("events = %+v", f.Events)
+	}
+}
+
+func TestProject_NoAccounts(t *testing.T) {
+	f := Project(nil, []Flow{{Date: day(2026, 5, 2), Amount: 300}}, 20, day(2026, 5, 1), 3)
+	if f.EndingBalance != 240 || f.LowestBalance != 0 {
+		t.Errorf("ending %v, lowest %v", f.EndingBalance, f.LowestBalance)
+	}
+}
+
+func TestPrimaryAccount(t *testing.T) {
+	accounts := []Account{
+		{ID: "sav", Balance: 9000},
+		{ID: "chk1", Checking: true, Balance: 200},
+		{ID: "chk2", Checking: true, Balance: 800},
+	}
+	if got := PrimaryAccount(accounts); got != 2 {
+		t.Errorf("PrimaryAccount = %d, want 2", got)
+	}
+	if got := PrimaryAccount(nil); got != -1 {
+		t.Errorf("PrimaryAccount(nil) = %d", got)
+	}
+}
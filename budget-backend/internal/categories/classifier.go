@@ -0,0 +1,401 @@
+package categories
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// The classifier is a multinomial naive Bayes model per household (or per
+// user without a household), trained on user-verified transactions. Counts
+// live in category_model_features / category_model_classes and are adjusted
+// one transaction at a time as users correct categories.
+
+const (
+	// minTrainingDocs is how many verified transactions a scope needs before
+	// the model is consulted at all.
+	minTrainingDocs = 10
+	// calibrationPrior shrinks the posterior of classes with few examples,
+	// since naive Bayes is overconfident on small samples.
+	calibrationPrior = 2.0
+)
+
+// merchantStopwords are tokens bank descriptions add to every merchant.
+var merchantStopwords = map[string]bool{
+	"pos": true, "debit": true, "credit": true, "purchase": true, "card": true, "ach": true,
+	"payment": true, "the": true, "inc": true, "llc": true, "co": true, "www": true, "com": true,
+}
+
+// ModelStats is the slice of a scope's model needed to classify one transaction.
+type ModelStats struct {
+	Docs       map[string]int            // category -> training transactions
+	Features   map[string]int            // category -> total feature occurrences
+	Counts     map[string]map[string]int // feature -> category -> count
+	Vocabulary int                       // distinct features in the scope
+}
+
+// Prediction is the classifier's best guess and its calibrated probability.
+type Prediction struct {
+	CategoryID  string
+	Probability float64
+}
+
+// Features extracts the merchant entity, merchant tokens, an amount bucket and
+// Plaid categories. Amount features are only produced when the type is known.
+func Features(tx Transaction) []string {
+	seen := map[string]bool{}
+	var out []string
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+
+	if tx.MerchantID != "" {
+		add("e:" + tx.MerchantID)
+	}
+	tokens := strings.FieldsFunc(strings.ToLower(tx.Merchant), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, tok := range tokens {
+		if len(tok) < 2 || merchantStopwords[tok] || strings.IndexFunc(tok, unicode.IsLetter) < 0 {
+			continue
+		}
+		add("m:" + tok)
+	}
+	if tx.Type != "" {
+		add("t:" + tx.Type)
+		// Log2 buckets: 0-1, 1-3, 3-7, 7-15, ... dollars.
+		add(fmt.Sprintf("a:%d", int(math.Log2(math.Abs(tx.Amount)+1))))
+	}
+	for _, c := range tx.PlaidCategories {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			add("p:" + c)
+		}
+	}
+	return out
+}
+
+// Classify returns the most likely category for a set of features. It
+// declines when the scope has too little training data or none of the
+// features has been seen before.
+func (m ModelStats) Classify(features []string) (Prediction, bool) {
+	total := 0
+	for _, n := range m.Docs {
+		total += n
+	}
+	if total < minTrainingDocs || len(m.Docs) == 0 {
+		return Prediction{}, false
+	}
+	known := false
+	for _, f := range features {
+		if len(m.Counts[f]) > 0 {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return Prediction{}, false
+	}
+
+	vocab := float64(m.Vocabulary)
+	if vocab < 1 {
+		vocab = 1
+	}
+	scores := map[string]float64{}
+	best, bestScore := "", math.Inf(-1)
+	for cat, docs := range m.Docs {
+		if docs <= 0 {
+			continue
+		}
+		score := math.Log(float64(docs) / float64(total))
+		denom := float64(m.Features[cat]) + vocab
+		for _, f := range features {
+			score += math.Log((float64(m.Counts[f][cat]) + 1) / denom)
+		}
+		scores[cat] = score
+		if score > bestScore {
+			best, bestScore = cat, score
+		}
+	}
+	if best == "" {
+		return Prediction{}, false
+	}
+
+	// Softmax relative to the best score to avoid underflow.
+	sum := 0.0
+	for _, s := range scores {
+		sum += math.Exp(s - bestScore)
+	}
+	p := 1 / sum
+	n := float64(m.Docs[best])
+	return Prediction{CategoryID: best, Probability: p * n / (n + calibrationPrior)}, true
+}
+
+// ModelConfidence maps a calibrated probability onto match_confidence.
+// Predictions below "medium" aren't used.
+func ModelConfidence(p float64) string {
+	switch {
+	case p >= 0.9:
+		return "high"
+	case p >= 0.7:
+		return "medium"
+	}
+	return ""
+}
+
+// loadModelStats reads the counts for the given features in a scope.
+func loadModelStats(db *sql.DB, scopeKey string, features []string) (ModelStats, error) {
+	m := ModelStats{Docs: map[string]int{}, Features: map[string]int{}, Counts: map[string]map[string]int{}}
+
+	rows, err := db.Query(`
+		SELECT category_id, doc_count, feature_count
+		FROM category_model_classes
+		WHERE scope_key = $1 AND doc_count > 0
+	`, scopeKey)
+	if err != nil {
+		return m, err
+	}
+	for rows.Next() {
+		var cat string
+		var docs, feats int
+		if err := rows.Scan(&cat, &docs, &feats); err != nil {
+			rows.Close()
+			return m, err
+		}
+		m.Docs[cat] = docs
+		m.Features[cat] = feats
+	}
+	rows.Close()
+	if len(m.Docs) == 0 {
+		return m, nil
+	}
+
+	if err := db.QueryRow(`
+		SELECT COUNT(DISTINCT feature) FROM category_model_features
+		WHERE scope_key = $1 AND count > 0
+	`, scopeKey).Scan(&m.Vocabulary); err != nil {
+		return m, err
+	}
+
+	rows, err = db.Query(`
+		SELECT feature, category_id, count
+		FROM category_model_features
+		WHERE scope_key = $1 AND feature = ANY($2) AND count > 0
+	`, scopeKey, pq.Array(features))
+	if err != nil {
+		return m, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f, cat string
+		var n int
+		if err := rows.Scan(&f, &cat, &n); err != nil {
+			return m, err
+		}
+		if m.Counts[f] == nil {
+			m.Counts[f] = map[string]int{}
+		}
+		m.Counts[f][cat] = n
+	}
+	return m, rows.Err()
+}
+
+// classifyTransaction runs the scope's model over a transaction.
+func classifyTransaction(db *sql.DB, scopeKey string, tx Transaction) (categoryID, confidence string, found bool, err error) {
+	features := Features(tx)
+	if scopeKey == "" || len(features) == 0 {
+		return "", "", false, nil
+	}
+	m, err := loadModelStats(db, scopeKey, features)
+	if err != nil {
+		return "", "", false, err
+	}
+	pred, ok := m.Classify(features)
+	if !ok {
+		return "", "", false, nil
+	}
+	conf := ModelConfidence(pred.Probability)
+	if conf == "" {
+		return "", "", false, nil
+	}
+	return pred.CategoryID, conf, true, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// adjustModel adds (delta=1) or removes (delta=-1) one training example.
+func adjustModel(ex execer, scopeKey, categoryID string, features []string, delta int) error {
+	if _, err := ex.Exec(`
+		INSERT INTO category_model_features (scope_key, category_id, feature, count)
+		SELECT $1, $2, f, GREATEST($4, 0) FROM unnest($3::text[]) AS f
+		ON CONFLICT (scope_key, feature, category_id)
+		DO UPDATE SET count = GREATEST(category_model_features.count + $4, 0)
+	`, scopeKey, categoryID, pq.Array(features), delta); err != nil {
+		return err
+	}
+	_, err := ex.Exec(`
+		INSERT INTO category_model_classes (scope_key, category_id, doc_count, feature_count)
+		VALUES ($1, $2, GREATEST($3, 0), GREATEST($4, 0))
+		ON CONFLICT (scope_key, category_id)
+		DO UPDATE SET doc_count = GREATEST(category_model_classes.doc_count + $3, 0),
+		              feature_count = GREATEST(category_model_classes.feature_count + $4, 0),
+		              updated_at = NOW()
+	`, scopeKey, categoryID, delta, delta*len(features))
+	return err
+}
+
+// trainingTransaction builds classifier input from a stored transaction. The
+// stored category_name is only a Plaid category on bank rows; on manual rows
+// it's the user's own label.
+func trainingTransaction(note, merchantID, txType, categoryName, source string, amount float64) Transaction {
+	tx := Transaction{Merchant: note, MerchantID: merchantID, Amount: amount, Type: txType}
+	if source == "bank" && categoryName != "" {
+		tx.PlaidCategories = []string{categoryName}
+	}
+	return tx
+}
+
+// LearnTransaction brings the model in line with one transaction's current
+// category. transactions.model_category_id records what the model last
+// learned from the row, so a correction moves the example from the old
+// category to the new one. Unverified and split rows contribute nothing.
+func LearnTransaction(db *sql.DB, transactionID string) error {
+	var scopeKey, note, merchantID, txType, categoryName, source, categoryID, modelCategoryID string
+	var amount float64
+	var verified, split bool
+	err := db.QueryRow(`
+		SELECT COALESCE(household_id, user_id)::text, COALESCE(note, ''), COALESCE(merchant_id::text, ''),
+		       type, COALESCE(category_name, ''),
+		       COALESCE(source, 'manual'), amount, COALESCE(category_id::text, ''),
+		       COALESCE(model_category_id::text, ''), COALESCE(user_verified, false), COALESCE(is_split, false)
+		FROM transactions WHERE id = $1
+	`, transactionID).Scan(&scopeKey, &note, &merchantID, &txType, &categoryName, &source, &amount, &categoryID,
+		&modelCategoryID, &verified, &split)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	target := categoryID
+	if !verified || split {
+		target = ""
+	}
+	if target == modelCategoryID {
+		return nil
+	}
+	features := Features(trainingTransaction(note, merchantID, txType, categoryName, source, amount))
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if modelCategoryID != "" {
+		if err := adjustModel(dbTx, scopeKey, modelCategoryID, features, -1); err != nil {
+			return err
+		}
+	}
+	if target != "" {
+		if err := adjustModel(dbTx, scopeKey, target, features, 1); err != nil {
+			return err
+		}
+	}
+	if _, err := dbTx.Exec(`UPDATE transactions SET model_category_id = NULLIF($1, '')::uuid WHERE id = $2`,
+		target, transactionID); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// Retrain rebuilds a scope's model from scratch from its verified, unsplit
+// transactions and returns how many were used.
+func Retrain(db *sql.DB, scopeKey string) (int, error) {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer dbTx.Rollback()
+
+	for _, q := range []string{
+		`DELETE FROM category_model_features WHERE scope_key = $1`,
+		`DELETE FROM category_model_classes WHERE scope_key = $1`,
+		`UPDATE transactions SET model_category_id = NULL WHERE COALESCE(household_id, user_id) = $1`,
+	} {
+		if _, err := dbTx.Exec(q, scopeKey); err != nil {
+			return 0, err
+		}
+	}
+
+	rows, err := dbTx.Query(`
+		SELECT COALESCE(note, ''), COALESCE(merchant_id::text, ''), type, COALESCE(category_name, ''),
+		       COALESCE(source, 'manual'), amount, category_id
+		FROM transactions
+		WHERE COALESCE(household_id, user_id) = $1
+		  AND COALESCE(user_verified, false) AND NOT COALESCE(is_split, false)
+		  AND category_id IS NOT NULL
+	`, scopeKey)
+	if err != nil {
+		return 0, err
+	}
+	counts := map[string]map[string]int{} // category -> feature -> count
+	docs := map[string]int{}
+	for rows.Next() {
+		var note, merchantID, txType, categoryName, source, categoryID string
+		var amount float64
+		if err := rows.Scan(&note, &merchantID, &txType, &categoryName, &source, &amount, &categoryID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if counts[categoryID] == nil {
+			counts[categoryID] = map[string]int{}
+		}
+		for _, f := range Features(trainingTransaction(note, merchantID, txType, categoryName, source, amount)) {
+			counts[categoryID][f]++
+		}
+		docs[categoryID]++
+	}
+	rows.Close()
+
+	trained := 0
+	for cat, feats := range counts {
+		featureTotal := 0
+		for f, n := range feats {
+			featureTotal += n
+			if _, err := dbTx.Exec(`
+				INSERT INTO category_model_features (scope_key, category_id, feature, count)
+				VALUES ($1, $2, $3, $4)
+			`, scopeKey, cat, f, n); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := dbTx.Exec(`
+			INSERT INTO category_model_classes (scope_key, category_id, doc_count, feature_count)
+			VALUES ($1, $2, $3, $4)
+		`, scopeKey, cat, docs[cat], featureTotal); err != nil {
+			return 0, err
+		}
+		trained += docs[cat]
+	}
+
+	if _, err := dbTx.Exec(`
+		UPDATE transactions SET model_category_id = category_id
+		WHERE COALESCE(household_id, user_id) = $1
+		  AND COALESCE(user_verified, false) AND NOT COALESCE(is_split, false)
+		  AND category_id IS NOT NULL
+	`, scopeKey); err != nil {
+		return 0, err
+	}
+	return trained, dbTx.Commit()
+}
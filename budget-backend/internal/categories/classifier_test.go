@@ -0,0 +1,93 @@
+package categories
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	got := Features(Transaction{
+		Merchant:        "POS DEBIT Trader Joe's #552",
+		Amount:          84.10,
+		Type:            "expense",
+		PlaidCategories: []string{"Shops", "Supermarkets and Groceries"},
+	})
+	want := []string{"m:trader", "m:joe", "t:expense", "a:6", "p:shops", "p:supermarkets and groceries"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// Without a type the amount is unknown and produces no features.
+	if got := Features(Transaction{Merchant: "Shell"}); !reflect.DeepEqual(got, []string{"m:shell"}) {
+		t.Errorf("expected merchant only, got %v", got)
+	}
+}
+
+// trainedStats builds model stats from labelled feature sets, the way
+// Retrain aggregates them.
+func trainedStats(examples map[string][][]string) ModelStats {
+	m := ModelStats{Docs: map[string]int{}, Features: map[string]int{}, Counts: map[string]map[string]int{}}
+	vocab := map[string]bool{}
+	for cat, docs := range examples {
+		for _, feats := range docs {
+			m.Docs[cat]++
+			for _, f := range feats {
+				m.Features[cat]++
+				if m.Counts[f] == nil {
+					m.Counts[f] = map[string]int{}
+				}
+				m.Counts[f][cat]++
+				vocab[f] = true
+			}
+		}
+	}
+	m.Vocabulary = len(vocab)
+	return m
+}
+
+func repeat(feats []string, n int) [][]string {
+	out := make([][]string, n)
+	for i := range out {
+		out[i] = feats
+	}
+	return out
+}
+
+func TestClassify(t *testing.T) {
+	m := trainedStats(map[string][][]string{
+		"groceries": repeat([]string{"m:trader", "m:joe", "t:expense", "a:6"}, 12),
+		"coffee":    repeat([]string{"m:blue", "m:bottle", "t:expense", "a:3"}, 8),
+	})
+
+	pred, ok := m.Classify([]string{"m:trader", "m:joe", "t:expense", "a:5"})
+	if !ok || pred.CategoryID != "groceries" {
+		t.Fatalf("expected groceries, got %+v ok=%v", pred, ok)
+	}
+	if conf := ModelConfidence(pred.Probability); conf != "medium" && conf != "high" {
+		t.Errorf("expected a usable confidence, got %q (p=%.3f)", conf, pred.Probability)
+	}
+
+	if _, ok := m.Classify([]string{"m:unknown"}); ok {
+		t.Error("expected no prediction when no feature has been seen")
+	}
+
+	small := trainedStats(map[string][][]string{"coffee": repeat([]string{"m:blue"}, 3)})
+	if _, ok := small.Classify([]string{"m:blue"}); ok {
+		t.Error("expected no prediction with too little training data")
+	}
+}
+
+func TestClassify_CalibratesSmallClasses(t *testing.T) {
+	m := trainedStats(map[string][][]string{
+		"groceries": repeat([]string{"m:market"}, 20),
+		"gifts":     repeat([]string{"m:etsy"}, 3),
+	})
+	pred, ok := m.Classify([]string{"m:etsy"})
+	if !ok || pred.CategoryID != "gifts" {
+		t.Fatalf("expected gifts, got %+v ok=%v", pred, ok)
+	}
+	// Three examples are not enough to be confident.
+	if conf := ModelConfidence(pred.Probability); conf != "" {
+		t.Errorf("expected no usable confidence for three examples, got %q (p=%.3f)", conf, pred.Probability)
+	}
+}
@@ -7,8 +7,13 @@ import (
 
 // ResolveCategory determines the best category_id for a transaction using a
 // priority waterfall. It checks user-specific rules first, then household rules,
-// then system-level Plaid category mappings, and finally attempts a fuzzy match
-// against category names.
+// then the household's merchant defaults, then system-level Plaid category
+// mappings, then the household's learned model, and finally attempts a fuzzy
+// match against category names.
+//
+// Advanced rules are evaluated first; only those conditions that can be
+// checked from the merchant name apply here. Callers that know the amount,
+// account or date should use Resolve.
 //
 // Returns categoryID, confidence ("exact"|"high"|"medium"|"low"), ruleID (if matched), error.
 func ResolveCategory(
@@ -18,11 +23,27 @@ func ResolveCategory(
 	merchantName string,
 	plaidCategories []string,
 ) (categoryID string, confidence string, ruleID *string, err error) {
+	res, err := Resolve(db, userID, householdID, Transaction{Merchant: merchantName, PlaidCategories: plaidCategories})
+	if err != nil {
+		return "", "", nil, err
+	}
+	return res.CategoryID, res.Confidence, res.RuleID, nil
+}
+
+// resolveSimple runs the merchant/keyword/plaid_category waterfall, then the
+// household's learned model.
+func resolveSimple(
+	db *sql.DB,
+	userID string,
+	householdID string,
+	tx Transaction,
+) (categoryID string, confidence string, ruleID *string, err error) {
+	merchantName, plaidCategories := tx.Merchant, tx.PlaidCategories
 	lowerMerchant := strings.ToLower(strings.TrimSpace(merchantName))
 
-	// 1. User merchant rule — exact match on lowercased merchant name
-	if userID != "" && lowerMerchant != "" {
-		cid, rid, found, e := matchMerchantRule(db, &userID, nil, lowerMerchant)
+	// 1. User merchant rule — exact match on lowercased merchant name or merchant entity
+	if userID != "" && (lowerMerchant != "" || tx.MerchantID != "") {
+		cid, rid, found, e := matchMerchantRule(db, &userID, nil, lowerMerchant, tx.MerchantID)
 		if e != nil {
 			return "", "", nil, e
 		}
@@ -32,8 +53,8 @@ func ResolveCategory(
 	}
 
 	// 2. Household merchant rule — exact match
-	if householdID != "" && lowerMerchant != "" {
-		cid, rid, found, e := matchMerchantRule(db, nil, &householdID, lowerMerchant)
+	if householdID != "" && (lowerMerchant != "" || tx.MerchantID != "") {
+		cid, rid, found, e := matchMerchantRule(db, nil, &householdID, lowerMerchant, tx.MerchantID)
 		if e != nil {
 			return "", "", nil, e
 		}
@@ -64,7 +85,18 @@ func ResolveCategory(
 		}
 	}
 
-	// 5. System plaid_category rule — exact match on any element
+	// 5. Household (or user) default category for the merchant entity
+	if tx.MerchantID != "" && (userID != "" || householdID != "") {
+		cid, found, e := matchMerchantDefault(db, userID, householdID, tx.MerchantID)
+		if e != nil {
+			return "", "", nil, e
+		}
+		if found {
+			return cid, "high", nil, nil
+		}
+	}
+
+	// 6. System plaid_category rule — exact match on any element
 	if len(plaidCategories) > 0 {
 		cid, rid, found, e := matchPlaidCategoryRule(db, plaidCategories)
 		if e != nil {
@@ -75,7 +107,7 @@ func ResolveCategory(
 		}
 	}
 
-	// 6. System merchant rule — exact match (no user/household scope)
+	// 7. System merchant rule — exact match (no user/household scope)
 	if lowerMerchant != "" {
 		cid, rid, found, e := matchSystemMerchantRule(db, lowerMerchant)
 		if e != nil {
@@ -86,7 +118,18 @@ func ResolveCategory(
 		}
 	}
 
-	// 7. Fuzzy match: ILIKE against category names using plaid categories
+	// 8. Learned model trained on the household's verified transactions
+	scopeKey := householdID
+	if scopeKey == "" {
+		scopeKey = userID
+	}
+	if cid, conf, found, e := classifyTransaction(db, scopeKey, tx); e != nil {
+		return "", "", nil, e
+	} else if found {
+		return cid, conf, nil, nil
+	}
+
+	// 9. Fuzzy match: ILIKE against category names using plaid categories
 	if len(plaidCategories) > 0 {
 		cid, found, e := fuzzyMatchCategoryName(db, plaidCategories)
 		if e != nil {
@@ -97,28 +140,33 @@ func ResolveCategory(
 		}
 	}
 
-	// 8. Fallback: no match
+	// 10. Fallback: no match
 	return "", "low", nil, nil
 }
 
-// matchMerchantRule finds a merchant-type rule scoped to a user or household.
-func matchMerchantRule(db *sql.DB, userID *string, householdID *string, lowerMerchant string) (categoryID string, ruleID string, found bool, err error) {
+// matchMerchantRule finds a merchant-type rule scoped to a user or household,
+// matching either the raw merchant name or the normalized merchant entity.
+func matchMerchantRule(db *sql.DB, userID *string, householdID *string, lowerMerchant, merchantID string) (categoryID string, ruleID string, found bool, err error) {
 	var query string
 	var arg interface{}
 
 	if userID != nil {
 		query = `SELECT id, category_id FROM category_mapping_rules
-			WHERE rule_type = 'merchant' AND LOWER(match_value) = $1 AND user_id = $2
+			WHERE rule_type = 'merchant' AND (LOWER(match_value) = $1 OR merchant_id = $3::uuid) AND user_id = $2
 			ORDER BY priority DESC LIMIT 1`
 		arg = *userID
 	} else {
 		query = `SELECT id, category_id FROM category_mapping_rules
-			WHERE rule_type = 'merchant' AND LOWER(match_value) = $1 AND household_id = $2
+			WHERE rule_type = 'merchant' AND (LOWER(match_value) = $1 OR merchant_id = $3::uuid) AND household_id = $2
 			ORDER BY priority DESC LIMIT 1`
 		arg = *householdID
 	}
 
-	err = db.QueryRow(query, lowerMerchant, arg).Scan(&ruleID, &categoryID)
+	var mid interface{}
+	if merchantID != "" {
+		mid = merchantID
+	}
+	err = db.QueryRow(query, lowerMerchant, arg, mid).Scan(&ruleID, &categoryID)
 	if err == sql.ErrNoRows {
 		return "", "", false, nil
 	}
@@ -131,6 +179,26 @@ func matchMerchantRule(db *sql.DB, userID *string, householdID *string, lowerMer
 	return categoryID, ruleID, true, nil
 }
 
+// matchMerchantDefault returns the default category the household, or the
+// user outside one, set for a merchant entity. A household default wins over
+// one the user set before joining.
+func matchMerchantDefault(db *sql.DB, userID, householdID, merchantID string) (categoryID string, found bool, err error) {
+	err = db.QueryRow(`
+		SELECT category_id FROM merchant_category_defaults
+		WHERE merchant_id = $1
+		  AND (($2 <> '' AND household_id::text = $2) OR (household_id IS NULL AND user_id::text = $3))
+		ORDER BY household_id IS NULL
+		LIMIT 1
+	`, merchantID, householdID, userID).Scan(&categoryID)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return categoryID, true, nil
+}
+
 // matchKeywordRule finds a keyword-type rule where the merchant name contains the keyword.
 func matchKeywordRule(db *sql.DB, userID *string, householdID *string, lowerMerchant string) (categoryID string, ruleID string, found bool, err error) {
 	var query string
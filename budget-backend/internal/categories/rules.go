@@ -0,0 +1,285 @@
+package categories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// Transaction is what the resolver knows about a transaction. Simple rules
+// only look at Merchant and PlaidCategories. Advanced rule conditions on a
+// field the caller didn't supply (zero Date, empty Type or AccountBalanceID)
+// never match; an empty Type also means the amount is unknown.
+type Transaction struct {
+	Merchant         string
+	MerchantID       string // normalized merchant entity, if known
+	PlaidCategories  []string
+	Amount           float64
+	Type             string
+	AccountBalanceID string
+	Date             time.Time
+}
+
+// Resolution is the outcome of Resolve.
+type Resolution struct {
+	CategoryID string
+	Confidence string  // exact, high, medium, low
+	RuleID     *string // rule that supplied the category
+	// ActionRuleID and Actions come from the first matching advanced rule,
+	// which may differ from RuleID when that rule sets no category.
+	ActionRuleID *string
+	Actions      *models.RuleActions
+}
+
+// advancedRule is an advanced category_mapping_rules row ready for matching.
+type advancedRule struct {
+	ID         string
+	CategoryID string
+	Conditions models.RuleConditions
+	Actions    models.RuleActions
+}
+
+var transactionTypes = map[string]bool{"income": true, "expense": true}
+
+// ValidateConditions checks an advanced rule's conditions before they are saved.
+func ValidateConditions(c models.RuleConditions) error {
+	if noConditions(c) {
+		return fmt.Errorf("at least one condition is required")
+	}
+	if c.MerchantRegex != "" {
+		if _, err := regexp.Compile("(?i)" + c.MerchantRegex); err != nil {
+			return fmt.Errorf("merchant_regex is not a valid regular expression")
+		}
+	}
+	if c.AmountMin != nil && *c.AmountMin < 0 || c.AmountMax != nil && *c.AmountMax < 0 {
+		return fmt.Errorf("amount bounds must be positive")
+	}
+	if c.AmountMin != nil && c.AmountMax != nil && *c.AmountMin > *c.AmountMax {
+		return fmt.Errorf("amount_min must not exceed amount_max")
+	}
+	if c.TransactionType != "" && !transactionTypes[c.TransactionType] {
+		return fmt.Errorf("transaction_type must be income or expense")
+	}
+	for _, d := range []int{c.DayOfMonthMin, c.DayOfMonthMax} {
+		if d < 0 || d > 31 {
+			return fmt.Errorf("day of month must be between 1 and 31")
+		}
+	}
+	if c.DayOfMonthMin > 0 && c.DayOfMonthMax > 0 && c.DayOfMonthMin > c.DayOfMonthMax {
+		return fmt.Errorf("day_of_month_min must not exceed day_of_month_max")
+	}
+	return nil
+}
+
+// noConditions reports whether c sets no condition at all.
+func noConditions(c models.RuleConditions) bool {
+	return c.MerchantRegex == "" && c.AmountMin == nil && c.AmountMax == nil && c.AccountBalanceID == "" &&
+		c.TransactionType == "" && c.DayOfMonthMin == 0 && c.DayOfMonthMax == 0
+}
+
+// ValidateSplitTemplate checks that a split template covers exactly 100%.
+func ValidateSplitTemplate(entries []models.SplitTemplateEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if len(entries) < 2 {
+		return fmt.Errorf("split_template needs at least 2 entries")
+	}
+	total := 0.0
+	for _, e := range entries {
+		if e.CategoryID == "" || e.Percent <= 0 {
+			return fmt.Errorf("each split_template entry needs a category_id and a positive percent")
+		}
+		total += e.Percent
+	}
+	if math.Abs(total-100) > 0.01 {
+		return fmt.Errorf("split_template percents must add up to 100")
+	}
+	return nil
+}
+
+var regexCache sync.Map // pattern -> *regexp.Regexp
+
+func compiledRegex(pattern string) *regexp.Regexp {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil
+	}
+	regexCache.Store(pattern, re)
+	return re
+}
+
+// MatchConditions reports whether a transaction satisfies every set condition.
+// An empty condition set matches nothing rather than everything.
+func MatchConditions(c models.RuleConditions, tx Transaction) bool {
+	if noConditions(c) {
+		return false
+	}
+	if c.MerchantRegex != "" {
+		re := compiledRegex(c.MerchantRegex)
+		if re == nil || !re.MatchString(strings.TrimSpace(tx.Merchant)) {
+			return false
+		}
+	}
+	if c.AmountMin != nil || c.AmountMax != nil {
+		if tx.Type == "" {
+			return false
+		}
+		if c.AmountMin != nil && tx.Amount < *c.AmountMin {
+			return false
+		}
+		if c.AmountMax != nil && tx.Amount > *c.AmountMax {
+			return false
+		}
+	}
+	if c.TransactionType != "" && c.TransactionType != tx.Type {
+		return false
+	}
+	if c.AccountBalanceID != "" && c.AccountBalanceID != tx.AccountBalanceID {
+		return false
+	}
+	if c.DayOfMonthMin > 0 || c.DayOfMonthMax > 0 {
+		if tx.Date.IsZero() {
+			return false
+		}
+		day := tx.Date.Day()
+		if c.DayOfMonthMin > 0 && day < c.DayOfMonthMin {
+			return false
+		}
+		if c.DayOfMonthMax > 0 && day > c.DayOfMonthMax {
+			return false
+		}
+	}
+	return true
+}
+
+// loadAdvancedRules returns the user's and household's advanced rules in
+// evaluation order: highest priority first, the user's own rule winning ties.
+func loadAdvancedRules(db *sql.DB, userID, householdID string) ([]advancedRule, error) {
+	if userID == "" && householdID == "" {
+		return nil, nil
+	}
+	var uid, hid interface{}
+	if userID != "" {
+		uid = userID
+	}
+	if householdID != "" {
+		hid = householdID
+	}
+
+	rows, err := db.Query(`
+		SELECT id, COALESCE(category_id::text, ''), conditions, budget_id, split_template, tag, mark_transfer
+		FROM category_mapping_rules
+		WHERE rule_type = 'advanced' AND (user_id = $1 OR household_id = $2)
+		ORDER BY priority DESC, (user_id IS NOT DISTINCT FROM $1) DESC, created_at
+	`, uid, hid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []advancedRule
+	for rows.Next() {
+		var r advancedRule
+		var conds, split []byte
+		var budgetID, tag sql.NullString
+		if err := rows.Scan(&r.ID, &r.CategoryID, &conds, &budgetID, &split, &tag, &r.Actions.MarkTransfer); err != nil {
+			return nil, err
+		}
+		if len(conds) > 0 {
+			if err := json.Unmarshal(conds, &r.Conditions); err != nil {
+				continue
+			}
+		}
+		if len(split) > 0 {
+			_ = json.Unmarshal(split, &r.Actions.SplitTemplate)
+		}
+		if budgetID.Valid {
+			r.Actions.BudgetID = &budgetID.String
+		}
+		if tag.Valid {
+			r.Actions.Tag = &tag.String
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// firstMatchingRule returns the first rule whose conditions match, or nil.
+func firstMatchingRule(rules []advancedRule, tx Transaction) *advancedRule {
+	for i := range rules {
+		if MatchConditions(rules[i].Conditions, tx) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// Resolve evaluates advanced rules in priority order, then falls back to the
+// simple rule waterfall used by ResolveCategory. The first matching advanced
+// rule supplies actions; if it also sets a category that category wins with
+// "exact" confidence.
+func Resolve(db *sql.DB, userID, householdID string, tx Transaction) (Resolution, error) {
+	rules, err := loadAdvancedRules(db, userID, householdID)
+	if err != nil {
+		return Resolution{}, err
+	}
+
+	var res Resolution
+	if rule := firstMatchingRule(rules, tx); rule != nil {
+		ruleID := rule.ID
+		actions := rule.Actions
+		res.ActionRuleID = &ruleID
+		res.Actions = &actions
+		go incrementUsage(db, ruleID)
+		if rule.CategoryID != "" {
+			res.CategoryID = rule.CategoryID
+			res.Confidence = "exact"
+			res.RuleID = &ruleID
+			return res, nil
+		}
+	}
+
+	catID, conf, ruleID, err := resolveSimple(db, userID, householdID, tx)
+	if err != nil {
+		return res, err
+	}
+	res.CategoryID, res.Confidence, res.RuleID = catID, conf, ruleID
+	return res, nil
+}
+
+// MatchRule reports whether a single rule matches a transaction, using the
+// same comparisons as the resolver. It ignores other rules, so a match here
+// doesn't mean the rule would win the waterfall.
+func MatchRule(rule models.CategoryMappingRule, tx Transaction) bool {
+	merchant := strings.ToLower(strings.TrimSpace(tx.Merchant))
+	value := strings.ToLower(strings.TrimSpace(rule.MatchValue))
+	switch rule.RuleType {
+	case "merchant":
+		if rule.MerchantID != nil && *rule.MerchantID != "" && *rule.MerchantID == tx.MerchantID {
+			return true
+		}
+		return merchant != "" && merchant == value
+	case "keyword":
+		return merchant != "" && value != "" && strings.Contains(merchant, value)
+	case "plaid_category":
+		for _, c := range tx.PlaidCategories {
+			if value != "" && strings.EqualFold(strings.TrimSpace(c), value) {
+				return true
+			}
+		}
+	case "advanced":
+		return rule.Conditions != nil && MatchConditions(*rule.Conditions, tx)
+	}
+	return false
+}
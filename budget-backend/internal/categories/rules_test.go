@@ -0,0 +1,113 @@
+package categories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+func floatPtr(f float64) *float64 { return &f }
+
+func TestMatchConditions(t *testing.T) {
+	rent := models.RuleConditions{
+		MerchantRegex:   `^zelle .*landlord`,
+		AmountMin:       floatPtr(1500),
+		AmountMax:       floatPtr(2500),
+		TransactionType: "expense",
+		DayOfMonthMin:   1,
+		DayOfMonthMax:   5,
+	}
+	tx := Transaction{
+		Merchant: "Zelle to Landlord LLC",
+		Amount:   1800,
+		Type:     "expense",
+		Date:     time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	if !MatchConditions(rent, tx) {
+		t.Fatal("expected rent rule to match")
+	}
+
+	late := tx
+	late.Date = time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+	if MatchConditions(rent, late) {
+		t.Error("expected day-of-month condition to reject the 15th")
+	}
+
+	small := tx
+	small.Amount = 20
+	if MatchConditions(rent, small) {
+		t.Error("expected amount range to reject 20")
+	}
+
+	// Without amount and date the rule can't be evaluated, so it doesn't match.
+	if MatchConditions(rent, Transaction{Merchant: tx.Merchant}) {
+		t.Error("expected missing details to never match")
+	}
+
+	account := models.RuleConditions{AccountBalanceID: "acct-1"}
+	if !MatchConditions(account, Transaction{AccountBalanceID: "acct-1"}) {
+		t.Error("expected account condition to match")
+	}
+	if MatchConditions(account, Transaction{AccountBalanceID: "acct-2"}) {
+		t.Error("expected account condition to reject other accounts")
+	}
+
+	// A rule with no conditions must not become a catch-all.
+	if MatchConditions(models.RuleConditions{}, tx) {
+		t.Error("expected empty conditions to match nothing")
+	}
+}
+
+func TestValidateConditions(t *testing.T) {
+	cases := []struct {
+		name  string
+		conds models.RuleConditions
+		ok    bool
+	}{
+		{"empty", models.RuleConditions{}, false},
+		{"regex", models.RuleConditions{MerchantRegex: `amazon|amzn`}, true},
+		{"bad regex", models.RuleConditions{MerchantRegex: `(`}, false},
+		{"inverted amounts", models.RuleConditions{AmountMin: floatPtr(50), AmountMax: floatPtr(10)}, false},
+		{"bad type", models.RuleConditions{TransactionType: "transfer"}, false},
+		{"bad day", models.RuleConditions{DayOfMonthMax: 32}, false},
+		{"inverted days", models.RuleConditions{DayOfMonthMin: 20, DayOfMonthMax: 10}, false},
+	}
+	for _, tc := range cases {
+		if err := ValidateConditions(tc.conds); (err == nil) != tc.ok {
+			t.Errorf("%s: expected ok=%v, got %v", tc.name, tc.ok, err)
+		}
+	}
+}
+
+func TestValidateSplitTemplate(t *testing.T) {
+	ok := []models.SplitTemplateEntry{{CategoryID: "a", Percent: 60}, {CategoryID: "b", Percent: 40}}
+	if err := ValidateSplitTemplate(ok); err != nil {
+		t.Errorf("expected valid template, got %v", err)
+	}
+	short := []models.SplitTemplateEntry{{CategoryID: "a", Percent: 60}, {CategoryID: "b", Percent: 30}}
+	if err := ValidateSplitTemplate(short); err == nil {
+		t.Error("expected error when percents don't add up to 100")
+	}
+}
+
+func TestMatchRule(t *testing.T) {
+	tx := Transaction{Merchant: "Starbucks #1234", PlaidCategories: []string{"Food and Drink"}}
+	cases := []struct {
+		rule models.CategoryMappingRule
+		want bool
+	}{
+		{models.CategoryMappingRule{RuleType: "merchant", MatchValue: "starbucks #1234"}, true},
+		{models.CategoryMappingRule{RuleType: "merchant", MatchValue: "starbucks"}, false},
+		{models.CategoryMappingRule{RuleType: "keyword", MatchValue: "Starbucks"}, true},
+		{models.CategoryMappingRule{RuleType: "plaid_category", MatchValue: "food and drink"}, true},
+		{models.CategoryMappingRule{RuleType: "advanced", Conditions: &models.RuleConditions{MerchantRegex: `^starbucks`}}, true},
+		{models.CategoryMappingRule{RuleType: "advanced"}, false},
+	}
+	for _, tc := range cases {
+		if got := MatchRule(tc.rule, tx); got != tc.want {
+			t.Errorf("%s %q: expected %v, got %v", tc.rule.RuleType, tc.rule.MatchValue, tc.want, got)
+		}
+	}
+}
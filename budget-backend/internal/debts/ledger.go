@@ -0,0 +1,142 @@
+// Package debts tracks the running balance of a debt: monthly interest
+// accrual, how each payment splits between interest and principal, and
+// payoff progress over time.
+package debts
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// MonthlyInterest is one month of interest on balance at an annual
+// percentage rate.
+func MonthlyInterest(balance, apr float64) float64 {
+	if balance <= 0 || apr <= 0 {
+		return 0
+	}
+	return round2(balance * apr / 100 / 12)
+}
+
+// MonthEnd returns the last day of t's month.
+func MonthEnd(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+}
+
+// Accrual is a month of interest charged at a month end.
+type Accrual struct {
+	Date     time.Time
+	Interest float64
+	Balance  float64 // after the charge
+}
+
+// Accrue charges a month of interest at each month end after `after` up to
+// and including `through`. Interest compounds onto the balance, as it does
+// on a card statement.
+func Accrue(balance, apr float64, after, through time.Time) []Accrual {
+	var out []Accrual
+	for end := MonthEnd(after); !end.After(through); end = MonthEnd(end.AddDate(0, 0, 1)) {
+		if !end.After(after) {
+			continue
+		}
+		interest := MonthlyInterest(balance, apr)
+		if interest == 0 {
+			continue
+		}
+		balance = round2(balance + interest)
+		out = append(out, Accrual{Date: end, Interest: interest, Balance: balance})
+	}
+	return out
+}
+
+// Split divides a payment between the unpaid interest it covers first and
+// the principal it retires. Any amount beyond the balance is not applied.
+func Split(amount, balance, unpaidInterest float64) (interest, principal float64) {
+	applied := math.Max(math.Min(amount, balance), 0)
+	interest = math.Min(applied, math.Max(unpaidInterest, 0))
+	return round2(interest), round2(applied - interest)
+}
+
+// Entry is a ledger entry as far as progress is concerned. Opening and
+// interest entries raise the balance by Amount, adjustments move it by a
+// signed Amount, and payments lower it by their interest and principal.
+type Entry struct {
+	Date      time.Time
+	Kind      string // opening, payment, interest, adjustment
+	Amount    float64
+	Principal float64
+	Interest  float64
+}
+
+func (e Entry) effect() float64 {
+	if e.Kind == "payment" {
+		return -(e.Principal + e.Interest)
+	}
+	return e.Amount
+}
+
+// Progress sums up how far a debt has been paid down since its ledger was
+// opened.
+type Progress struct {
+	StartingBalance float64
+	PrincipalPaid   float64
+	InterestPaid    float64
+	InterestAccrued float64
+	PercentPaid     float64
+	Months          []models.DebtProgressPoint
+}
+
+// Summarize replays a ledger in date order and records the balance at the
+// end of every month from the first entry through the month containing
+// `through`. PercentPaid compares the current balance to the opening one.
+func Summarize(entries []Entry, current float64, through time.Time) Progress {
+	p := Progress{Months: []models.DebtProgressPoint{}}
+	if len(entries) == 0 {
+		return p
+	}
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	var balance float64
+	i := 0
+	for end := MonthEnd(sorted[0].Date); ; end = MonthEnd(end.AddDate(0, 0, 1)) {
+		for ; i < len(sorted) && !sorted[i].Date.After(end); i++ {
+			e := sorted[i]
+			balance += e.effect()
+			switch e.Kind {
+			case "opening":
+				p.StartingBalance += e.Amount
+			case "payment":
+				p.PrincipalPaid += e.Principal
+				p.InterestPaid += e.Interest
+			case "interest":
+				p.InterestAccrued += e.Amount
+			}
+		}
+		p.Months = append(p.Months, models.DebtProgressPoint{
+			Month:         end.Format("2006-01"),
+			Balance:       round2(math.Max(balance, 0)),
+			PrincipalPaid: round2(p.PrincipalPaid),
+			InterestPaid:  round2(p.InterestPaid),
+		})
+		if !end.Before(MonthEnd(through)) && i == len(sorted) {
+			break
+		}
+	}
+
+	p.StartingBalance = round2(p.StartingBalance)
+	p.PrincipalPaid = round2(p.PrincipalPaid)
+	p.InterestPaid = round2(p.InterestPaid)
+	p.InterestAccrued = round2(p.InterestAccrued)
+	if p.StartingBalance > 0 {
+		pct := (p.StartingBalance - current) / p.StartingBalance * 100
+		p.PercentPaid = round2(math.Min(math.Max(pct, 0), 100))
+	}
+	return p
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
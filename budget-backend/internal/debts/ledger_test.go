@@ -0,0 +1,80 @@
+package debts
+
+import (
+	"testing"
+	"time"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAccrue_CompoundsAtMonthEnds(t *testing.T) {
+	got := Accrue(1200, 24, day(2026, 1, 15), day(2026, 3, 30))
+	if len(got) != 2 {
+		t.Fatalf("expected Jan and Feb charges, got %+v", got)
+	}
+	if got[0].Date != day(2026, 1, 31) || got[0].Interest != 24 || got[0].Balance != 1224 {
+		t.Errorf("january = %+v", got[0])
+	}
+	if got[1].Date != day(2026, 2, 28) || got[1].Interest != 24.48 || got[1].Balance != 1248.48 {
+		t.Errorf("february = %+v", got[1])
+	}
+
+	if got := Accrue(1200, 24, day(2026, 1, 31), day(2026, 1, 31)); len(got) != 0 {
+		t.Errorf("already accrued through month end: %+v", got)
+	}
+	if got := Accrue(1200, 0, day(2026, 1, 1), day(2026, 6, 1)); len(got) != 0 {
+		t.Errorf("zero APR accrued %+v", got)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		amount, balance, unpaid float64
+		interest, principal     float64
+	}{
+		{200, 1000, 15, 15, 185},
+		{10, 1000, 15, 10, 0},
+		{500, 300, 20, 20, 280},
+		{100, 1000, 0, 0, 100},
+	}
+	for _, tt := range tests {
+		i, p := Split(tt.amount, tt.balance, tt.unpaid)
+		if i != tt.interest || p != tt.principal {
+			t.Errorf("Split(%v, %v, %v) = %v, %v; want %v, %v",
+				tt.amount, tt.balance, tt.unpaid, i, p, tt.interest, tt.principal)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	entries := []Entry{
+		{Date: day(2026, 1, 10), Kind: "opening", Amount: 1000},
+		{Date: day(2026, 1, 31), Kind: "interest", Amount: 20},
+		{Date: day(2026, 2, 5), Kind: "payment", Amount: 220, Interest: 20, Principal: 200},
+		// Posted out of order: still counts in March.
+		{Date: day(2026, 3, 5), Kind: "payment", Amount: 100, Principal: 100},
+		{Date: day(2026, 2, 28), Kind: "adjustment", Amount: -50},
+	}
+	p := Summarize(entries, 650, day(2026, 4, 2))
+
+	if p.StartingBalance != 1000 || p.PrincipalPaid != 300 || p.InterestPaid != 20 || p.InterestAccrued != 20 {
+		t.Errorf("totals = %+v", p)
+	}
+	if p.PercentPaid != 35 {
+		t.Errorf("percent paid = %v", p.PercentPaid)
+	}
+	want := []struct {
+		month   string
+		balance float64
+	}{{"2026-01", 1020}, {"2026-02", 750}, {"2026-03", 650}, {"2026-04", 650}}
+	if len(p.Months) != len(want) {
+		t.Fatalf("months = %+v", p.Months)
+	}
+	for i, w := range want {
+		if p.Months[i].Month != w.month || p.Months[i].Balance != w.balance {
+			t.Errorf("month %d = %+v, want %s at %v", i, p.Months[i], w.month, w.balance)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package equity follows a mortgage against the value of its property:
+// equity and loan-to-value over time, when PMI can come off, and what extra
+// principal payments change.
+package equity
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aboogie/budget-backend/internal/debts"
+	"github.com/aboogie/budget-backend/models"
+)
+
+// maxMonths caps projections at a 40-year mortgage.
+const maxMonths = 480
+
+// PMI thresholds as a share of the original value. Borrowers can ask for PMI
+// to be cancelled at 80%; lenders must end it at 78% on the scheduled
+// payments.
+const (
+	PMIRequestLTV = 0.80
+	PMIAutoLTV    = 0.78
+)
+
+// Mortgage is what a projection needs: the balance today, its APR
+// percentage, the monthly principal and interest payment and the home's
+// value when the loan was taken out.
+type Mortgage struct {
+	Balance       float64
+	APR           float64
+	Payment       float64
+	OriginalValue float64
+}
+
+// Projection is a mortgage paid down month by month. Balances[i] is the
+// balance after the payment in month i+1.
+type Projection struct {
+	Months        int
+	PaysOff       bool
+	TotalInterest float64
+	Balances      []float64
+}
+
+// Project pays the mortgage down with extra principal on top of the regular
+// payment each month. Interest is charged before each payment, so a payment
+// that only covers the interest never pays it off.
+func Project(m Mortgage, extra float64) Projection {
+	var p Projection
+	balance := m.Balance
+	payment := m.Payment + math.Max(extra, 0)
+	for month := 1; balance > 0 && month <= maxMonths; month++ {
+		interest := debts.MonthlyInterest(balance, m.APR)
+		if payment <= interest {
+			break
+		}
+		balance = round2(balance + interest - math.Min(payment, balance+interest))
+		p.TotalInterest += interest
+		p.Balances = append(p.Balances, balance)
+		p.Months = month
+	}
+	p.PaysOff = balance <= 0
+	p.TotalInterest = round2(p.TotalInterest)
+	return p
+}
+
+// MonthReaching is the first month the balance is at or below target: 0 if
+// it already is, -1 if the projection never gets there.
+func (p Projection) MonthReaching(start, target float64) int {
+	if start <= target {
+		return 0
+	}
+	for i, b := range p.Balances {
+		if b <= target {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// PMIDates returns the months PMI can be cancelled on request and ends
+// automatically on the regular payments. Either is empty when the mortgage
+// never reaches it or there is no original value to measure against.
+func PMIDates(m Mortgage, now time.Time) (request, auto string) {
+	if m.OriginalValue <= 0 {
+		return "", ""
+	}
+	p := Project(m, 0)
+	return monthDate(now, p.MonthReaching(m.Balance, m.OriginalValue*PMIRequestLTV)),
+		monthDate(now, p.MonthReaching(m.Balance, m.OriginalValue*PMIAutoLTV))
+}
+
+// Scenarios projects the mortgage with each extra principal amount and
+// compares it with the regular payments. The first scenario is always the
+// regular payments alone. Extra principal moves the date PMI can be
+// cancelled on request; the automatic date stays on the original schedule.
+func Scenarios(m Mortgage, extras []float64, now time.Time) []models.EquityScenario {
+	amounts := []float64{0}
+	for _, e := range extras {
+		if e > 0 {
+			amounts = append(amounts, round2(e))
+		}
+	}
+
+	base := Project(m, 0)
+	out := make([]models.EquityScenario, 0, len(amounts))
+	for _, extra := range amounts {
+		p := base
+		if extra > 0 {
+			p = Project(m, extra)
+		}
+		s := models.EquityScenario{
+			ExtraPrincipal: extra,
+			Months:         p.Months,
+			PaysOff:        p.PaysOff,
+			TotalInterest:  p.TotalInterest,
+		}
+		if p.PaysOff {
+			s.PayoffDate = monthDate(now, p.Months)
+		}
+		if base.PaysOff && p.PaysOff {
+			s.InterestSaved = round2(base.TotalInterest - p.TotalInterest)
+			s.MonthsSaved = base.Months - p.Months
+		}
+		if m.OriginalValue > 0 {
+			s.PMIRequestDate = monthDate(now, p.MonthReaching(m.Balance, m.OriginalValue*PMIRequestLTV))
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// Valuation is a property's value on a date.
+type Valuation struct {
+	Date  time.Time
+	Value float64
+}
+
+// History lines up the property's value with the mortgage balance at the
+// end of each month, carrying the last known figure forward. It starts in
+// the first month both are known and ends with today's value and balance.
+// Balances must be in month order, as debts.Summarize returns them.
+func History(values []Valuation, balances []models.DebtProgressPoint, currentValue, currentBalance float64, now time.Time) []models.EquityPoint {
+	sorted := append([]Valuation(nil), values...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+	byMonth := map[string]float64{}
+	for _, b := range balances {
+		byMonth[b.Month] = b.Balance
+	}
+
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	start := current
+	if len(sorted) > 0 {
+		start = firstOfMonth(sorted[0].Date)
+	}
+	switch {
+	case len(balances) > 0:
+		if first, err := time.Parse("2006-01", balances[0].Month); err == nil && first.After(start) {
+			start = first
+		}
+	case currentBalance > 0:
+		// A mortgage with no ledger only has today's balance.
+		start = current
+	}
+
+	points := []models.EquityPoint{}
+	var value, balance float64
+	i := 0
+	for month := start; !month.After(current); month = month.AddDate(0, 1, 0) {
+		end := debts.MonthEnd(month)
+		for ; i < len(sorted) && !sorted[i].Date.After(end); i++ {
+			value = sorted[i].Value
+		}
+		if b, ok := byMonth[month.Format("2006-01")]; ok {
+			balance = b
+		}
+		if month.Equal(current) {
+			if currentValue > 0 {
+				value = currentValue
+			}
+			balance = currentBalance
+		}
+		if value <= 0 {
+			continue
+		}
+		points = append(points, models.EquityPoint{
+			Month:           month.Format("2006-01"),
+			Value:           round2(value),
+			MortgageBalance: round2(balance),
+			Equity:          round2(value - balance),
+			LTV:             LTV(balance, value),
+		})
+	}
+	return points
+}
+
+// LTV is the loan-to-value ratio as a percentage.
+func LTV(balance, value float64) float64 {
+	if value <= 0 {
+		return 0
+	}
+	return round2(math.Max(balance, 0) / value * 100)
+}
+
+// monthDate labels month n of a projection starting after now; a negative
+// n (never reached) gives "".
+func monthDate(now time.Time, n int) string {
+	if n < 0 {
+		return ""
+	}
+	return time.Date(now.Year(), now.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,100 @@
+package equity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+var now = time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+
+// A $200,000 30-year mortgage at 6% on a $250,000 home.
+var mortgage = Mortgage{Balance: 200000, APR: 6, Payment: 1199.11, OriginalValue: 250000}
+
+func TestProject(t *testing.T) {
+	p := Project(mortgage, 0)
+	if !p.PaysOff || p.Months != 360 {
+		t.Fatalf("expected a 30-year payoff, got %d months (pays off %v)", p.Months, p.PaysOff)
+	}
+	if p.TotalInterest < 231000 || p.TotalInterest > 232000 {
+		t.Errorf("total interest = %v", p.TotalInterest)
+	}
+
+	if p := Project(Mortgage{Balance: 200000, APR: 6, Payment: 1000}, 0); p.PaysOff || p.Months != 0 {
+		t.Errorf("a payment below the interest should never pay off: %+v", p)
+	}
+}
+
+func TestPMIDates(t *testing.T) {
+	request, auto := PMIDates(mortgage, now)
+	if request != "2026-03" {
+		t.Errorf("already at 80%%, request = %q", request)
+	}
+	// 78% of $250,000 is $195,000, two years of payments away.
+	if auto != "2028-03" {
+		t.Errorf("auto = %q", auto)
+	}
+
+	m := mortgage
+	m.OriginalValue = 0
+	if request, auto := PMIDates(m, now); request != "" || auto != "" {
+		t.Errorf("no original value: %q, %q", request, auto)
+	}
+}
+
+func TestScenarios_ExtraPrincipal(t *testing.T) {
+	m := mortgage
+	m.OriginalValue = 240000 // 80% is $192,000
+	got := Scenarios(m, []float64{0, 500}, now)
+	if len(got) != 2 {
+		t.Fatalf("expected the regular payments and one extra, got %+v", got)
+	}
+	base, extra := got[0], got[1]
+	if base.ExtraPrincipal != 0 || base.InterestSaved != 0 || base.MonthsSaved != 0 {
+		t.Errorf("base = %+v", base)
+	}
+	if extra.ExtraPrincipal != 500 || extra.MonthsSaved < 100 || extra.InterestSaved < 90000 {
+		t.Errorf("extra = %+v", extra)
+	}
+	if extra.PMIRequestDate >= base.PMIRequestDate {
+		t.Errorf("extra principal should reach 80%% sooner: %s vs %s", extra.PMIRequestDate, base.PMIRequestDate)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	values := []Valuation{
+		{Date: time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC), Value: 260000},
+		{Date: time.Date(2025, 11, 5, 0, 0, 0, 0, time.UTC), Value: 250000},
+	}
+	balances := []models.DebtProgressPoint{
+		{Month: "2025-12", Balance: 201000},
+		{Month: "2026-01", Balance: 200500},
+		{Month: "2026-02", Balance: 200200},
+	}
+	got := History(values, balances, 265000, 200000, now)
+
+	want := []models.EquityPoint{
+		{Month: "2025-12", Value: 250000, MortgageBalance: 201000, Equity: 49000, LTV: 80.4},
+		{Month: "2026-01", Value: 260000, MortgageBalance: 200500, Equity: 59500, LTV: 77.12},
+		{Month: "2026-02", Value: 260000, MortgageBalance: 200200, Equity: 59800, LTV: 77},
+		{Month: "2026-03", Value: 265000, MortgageBalance: 200000, Equity: 65000, LTV: 75.47},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("month %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Without a ledger only today's balance is known.
+	if got := History(values, nil, 265000, 200000, now); len(got) != 1 || got[0].Month != "2026-03" {
+		t.Errorf("no ledger: %+v", got)
+	}
+	// Without a mortgage the value history stands on its own.
+	if got := History(values, nil, 265000, 0, now); len(got) != 5 || got[0].LTV != 0 {
+		t.Errorf("no mortgage: %+v", got)
+	}
+}
@@ -0,0 +1,112 @@
+// Package ical writes read-only iCalendar (RFC 5545) feeds of all-day events
+// that calendar apps can subscribe to.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Event is a single all-day entry. UID must stay the same across refreshes
+// so calendar apps update the event instead of duplicating it.
+type Event struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	Category    string
+}
+
+// Calendar is a named feed of events.
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// maxLineOctets is the longest a content line may be before it is folded.
+const maxLineOctets = 75
+
+// Write renders the calendar in iCalendar format. Events are ordered by date
+// and stamped with now.
+func Write(w io.Writer, cal Calendar, now time.Time) error {
+	events := append([]Event(nil), cal.Events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Date.Equal(events[j].Date) {
+			return events[i].Date.Before(events[j].Date)
+		}
+		return events[i].UID < events[j].UID
+	})
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//CoupleFlow//Budget Calendar//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + escape(cal.Name),
+		"REFRESH-INTERVAL;VALUE=DURATION:PT6H",
+		"X-PUBLISHED-TTL:PT6H",
+	}
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, e := range events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+escape(e.UID),
+			"DTSTAMP:"+stamp,
+			"DTSTART;VALUE=DATE:"+e.Date.Format("20060102"),
+			"DTEND;VALUE=DATE:"+e.Date.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+escape(e.Summary),
+		)
+		if e.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+escape(e.Description))
+		}
+		if e.Category != "" {
+			lines = append(lines, "CATEGORIES:"+escape(e.Category))
+		}
+		lines = append(lines, "TRANSP:TRANSPARENT", "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, fold(line)+"\r\n"); err != nil {
+			return fmt.Errorf("write calendar: %w", err)
+		}
+	}
+	return nil
+}
+
+// escape quotes the characters that are special in TEXT values.
+func escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// fold splits a content line into 75-octet chunks, continuing each with a
+// leading space. It never splits a multi-byte character.
+func fold(line string) string {
+	if len(line) <= maxLineOctets {
+		return line
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	n := 0
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 0
+			limit = maxLineOctets - 1 // the leading space counts
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite_AllDayEvents(t *testing.T) {
+	cal := Calendar{Name: "Bills", Events: []Event{
+		{UID: "b@x", Date: time.Date(2026, 5, 3, 0, 0, 0, 0, time.UTC), Summary: "Rent; due, now"},
+		{UID: "a@x", Date: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), Summary: "Power", Description: "line one\nline two"},
+	}}
+	var b strings.Builder
+	if err := Write(&b, cal, time.Date(2026, 4, 30, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Bills\r\n",
+		"DTSTAMP:20260430T120000Z\r\n",
+		"DTSTART;VALUE=DATE:20260501\r\nDTEND;VALUE=DATE:20260502\r\n",
+		`SUMMARY:Rent\; due\, now`,
+		`DESCRIPTION:line one\nline two`,
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "UID:a@x") > strings.Index(out, "UID:b@x") {
+		t.Error("events not ordered by date")
+	}
+}
+
+func TestFold(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("é", 60)
+	folded := fold(line)
+	for i, part := range strings.Split(folded, "\r\n") {
+		if len(part) > maxLineOctets {
+			t.Errorf("part %d is %d octets", i, len(part))
+		}
+		if i > 0 && !strings.HasPrefix(part, " ") {
+			t.Errorf("continuation %d lacks leading space", i)
+		}
+	}
+	if strings.ReplaceAll(folded, "\r\n ", "") != line {
+		t.Error("unfolding does not restore the line")
+	}
+}
@@ -0,0 +1,86 @@
+package investments
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// Analyze loads holdings and investment transactions for a user (and their
+// household, when householdID is set) and computes performance as of today.
+func Analyze(conn *sql.DB, userID, householdID string) (models.InvestmentPerformance, error) {
+	scope := "user_id = $1"
+	args := []interface{}{userID}
+	if householdID != "" {
+		scope = "(user_id = $1 OR household_id = $2)"
+		args = append(args, householdID)
+	}
+
+	holdings, err := loadHoldings(conn, scope, args)
+	if err != nil {
+		return models.InvestmentPerformance{}, fmt.Errorf("holdings query: %w", err)
+	}
+	txns, err := loadTransactions(conn, scope, args)
+	if err != nil {
+		return models.InvestmentPerformance{}, fmt.Errorf("investment transactions query: %w", err)
+	}
+
+	return Calculate(holdings, txns, time.Now().UTC()), nil
+}
+
+func loadHoldings(conn *sql.DB, scope string, args []interface{}) ([]models.InvestmentHolding, error) {
+	rows, err := conn.Query(`
+		SELECT id, user_id, plaid_account_id, plaid_security_id,
+		       security_name, ticker_symbol, security_type,
+		       quantity, institution_price, institution_value, cost_basis
+		FROM investment_holdings
+		WHERE `+scope, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holdings []models.InvestmentHolding
+	for rows.Next() {
+		var h models.InvestmentHolding
+		if err := rows.Scan(
+			&h.ID, &h.UserID, &h.PlaidAccountID, &h.PlaidSecurityID,
+			&h.SecurityName, &h.TickerSymbol, &h.SecurityType,
+			&h.Quantity, &h.InstitutionPrice, &h.InstitutionValue, &h.CostBasis,
+		); err != nil {
+			return nil, err
+		}
+		holdings = append(holdings, h)
+	}
+	return holdings, rows.Err()
+}
+
+func loadTransactions(conn *sql.DB, scope string, args []interface{}) ([]models.InvestmentTransaction, error) {
+	rows, err := conn.Query(`
+		SELECT id, user_id, plaid_account_id, plaid_investment_transaction_id, plaid_security_id,
+		       security_name, ticker_symbol, security_type,
+		       type, subtype, name, quantity, price, amount, fees, date
+		FROM investment_transactions
+		WHERE `+scope+`
+		ORDER BY date, created_at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var txns []models.InvestmentTransaction
+	for rows.Next() {
+		var t models.InvestmentTransaction
+		if err := rows.Scan(
+			&t.ID, &t.UserID, &t.PlaidAccountID, &t.PlaidInvestmentTransactionID, &t.PlaidSecurityID,
+			&t.SecurityName, &t.TickerSymbol, &t.SecurityType,
+			&t.Type, &t.Subtype, &t.Name, &t.Quantity, &t.Price, &t.Amount, &t.Fees, &t.Date,
+		); err != nil {
+			return nil, err
+		}
+		txns = append(txns, t)
+	}
+	return txns, rows.Err()
+}
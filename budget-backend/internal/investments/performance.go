@@ -0,0 +1,536 @@
+package investments
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// Returns are measured on invested (non-cash) positions: buys and transfers
+// in are contributions, sells and transfers out are withdrawals, dividends and
+// interest are income, and fees are a cost. Cash sweep holdings only count
+// toward allocation.
+
+const epsilon = 1e-9
+
+type cashFlow struct {
+	date   time.Time
+	amount float64 // investor perspective: negative = money put in
+}
+
+type position struct {
+	id       string
+	name     string
+	ticker   string
+	secType  string
+	qty      float64
+	cost     float64
+	realized float64
+	income   float64
+	holding  *models.InvestmentHolding
+	prices   []pricePoint
+	openQty  float64
+}
+
+type pricePoint struct {
+	date  time.Time
+	price float64
+}
+
+// Calculate computes performance for the given holdings (valued as of asOf)
+// and the transaction history that produced them. Transactions may be in any
+// order.
+func Calculate(holdings []models.InvestmentHolding, txns []models.InvestmentTransaction, asOf time.Time) models.InvestmentPerformance {
+	asOf = truncateDay(asOf)
+	perf := models.InvestmentPerformance{AsOf: asOf.Format("2006-01-02")}
+
+	perf.Allocation = allocation(holdings)
+	for _, h := range holdings {
+		perf.MarketValue += h.InstitutionValue
+	}
+
+	// Keep only transactions that move invested positions.
+	var history []models.InvestmentTransaction
+	for _, t := range txns {
+		if t.Type == "cancel" || isCashSecurity(t.SecurityType) {
+			continue
+		}
+		history = append(history, t)
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Date.Before(history[j].Date) })
+
+	positions := buildPositions(holdings, history)
+
+	// Average-cost walk for realized gains, contributions and income.
+	for _, t := range history {
+		p := positions[securityKey(t)]
+		switch t.Type {
+		case "buy":
+			perf.TotalContributed += t.Amount
+			if p != nil {
+				p.qty += math.Abs(t.Quantity)
+				p.cost += t.Amount
+			}
+		case "sell":
+			proceeds := -t.Amount
+			perf.TotalWithdrawn += proceeds
+			if p != nil {
+				p.realized += reducePosition(p, math.Abs(t.Quantity), proceeds)
+			}
+		case "transfer":
+			value := t.Quantity * t.Price
+			if value >= 0 {
+				perf.TotalContributed += value
+			} else {
+				perf.TotalWithdrawn += -value
+			}
+			if p != nil {
+				if t.Quantity >= 0 {
+					p.qty += t.Quantity
+					p.cost += value
+				} else {
+					reducePosition(p, -t.Quantity, -1)
+				}
+			}
+		case "fee":
+			perf.FeesPaid += t.Amount
+		case "cash":
+			if isIncomeSubtype(t.Subtype) {
+				perf.DividendIncome += -t.Amount
+				if p != nil {
+					p.income += -t.Amount
+				}
+			}
+		}
+	}
+
+	for _, p := range positions {
+		sp := models.SecurityPerformance{
+			SecurityID:     p.id,
+			Name:           p.name,
+			TickerSymbol:   p.ticker,
+			SecurityType:   p.secType,
+			CostBasis:      round2(p.cost),
+			RealizedGain:   round2(p.realized),
+			DividendIncome: round2(p.income),
+		}
+		if p.holding != nil {
+			sp.Quantity = p.holding.Quantity
+			sp.MarketValue = round2(p.holding.InstitutionValue)
+			// Prefer the institution's cost basis when it reports one.
+			if p.holding.CostBasis != nil && *p.holding.CostBasis > 0 {
+				sp.CostBasis = round2(*p.holding.CostBasis)
+			}
+			if !isCashSecurity(p.holding.SecurityType) {
+				sp.UnrealizedGain = round2(sp.MarketValue - sp.CostBasis)
+			}
+		}
+		if sp.Quantity == 0 && sp.RealizedGain == 0 && sp.DividendIncome == 0 {
+			continue
+		}
+		perf.CostBasis += sp.CostBasis
+		perf.RealizedGain += sp.RealizedGain
+		perf.UnrealizedGain += sp.UnrealizedGain
+		perf.Securities = append(perf.Securities, sp)
+	}
+	sort.Slice(perf.Securities, func(i, j int) bool {
+		return perf.Securities[i].MarketValue > perf.Securities[j].MarketValue
+	})
+	if perf.Securities == nil {
+		perf.Securities = []models.SecurityPerformance{}
+	}
+
+	if len(history) > 0 {
+		start := truncateDay(history[0].Date)
+		perf.PeriodStart = start.Format("2006-01-02")
+
+		twr := timeWeightedReturn(positions, history, investedValue(holdings), asOf)
+		perf.TimeWeightedReturnPct = round2(twr * 100)
+		perf.TWRAnnualizedPct = perf.TimeWeightedReturnPct
+		if days := asOf.Sub(start).Hours() / 24; days >= 365 {
+			perf.TWRAnnualizedPct = round2((math.Pow(1+twr, 365/days) - 1) * 100)
+		}
+
+		if irr, ok := xirr(moneyWeightedFlows(positions, history, investedValue(holdings), asOf)); ok {
+			pct := round2(irr * 100)
+			perf.MoneyWeightedReturnPct = &pct
+		}
+	}
+
+	perf.MarketValue = round2(perf.MarketValue)
+	perf.CostBasis = round2(perf.CostBasis)
+	perf.TotalContributed = round2(perf.TotalContributed)
+	perf.TotalWithdrawn = round2(perf.TotalWithdrawn)
+	perf.DividendIncome = round2(perf.DividendIncome)
+	perf.FeesPaid = round2(perf.FeesPaid)
+	perf.RealizedGain = round2(perf.RealizedGain)
+	perf.UnrealizedGain = round2(perf.UnrealizedGain)
+	return perf
+}
+
+// timeWeightedReturn chain-links sub-period returns between transaction
+// dates. Positions on each date are reconstructed backwards from current
+// quantities and valued at the most recent observed trade price. Returns a
+// fraction (0.05 = 5%).
+func timeWeightedReturn(positions map[string]*position, history []models.InvestmentTransaction, endValue float64, asOf time.Time) float64 {
+	dates := flowDates(history)
+	if len(dates) == 0 {
+		return 0
+	}
+
+	growth := 1.0
+	prevAfter := valueAt(positions, history, dates[0], true)
+	for _, d := range dates[1:] {
+		before := valueAt(positions, history, d, false)
+		if prevAfter > epsilon {
+			growth *= (before + incomeOn(history, d)) / prevAfter
+		}
+		prevAfter = valueAt(positions, history, d, true)
+	}
+	if prevAfter > epsilon && asOf.After(dates[len(dates)-1]) {
+		growth *= endValue / prevAfter
+	}
+	return growth - 1
+}
+
+// xirr solves for the annualized rate that makes the net present value of
+// the cash flows zero. ok is false when the flows have no sign change or the
+// solver fails to converge.
+func xirr(flows []cashFlow) (rate float64, ok bool) {
+	if len(flows) < 2 {
+		return 0, false
+	}
+	var hasPos, hasNeg bool
+	for _, f := range flows {
+		if f.amount > 0 {
+			hasPos = true
+		} else if f.amount < 0 {
+			hasNeg = true
+		}
+	}
+	if !hasPos || !hasNeg {
+		return 0, false
+	}
+
+	t0 := flows[0].date
+	npv := func(r float64) (v, dv float64) {
+		for _, f := range flows {
+			years := f.date.Sub(t0).Hours() / 24 / 365
+			denom := math.Pow(1+r, years)
+			v += f.amount / denom
+			dv -= years * f.amount / (denom * (1 + r))
+		}
+		return v, dv
+	}
+
+	// Newton-Raphson first; fall back to bisection if it wanders off.
+	r := 0.1
+	for i := 0; i < 100; i++ {
+		v, dv := npv(r)
+		if math.Abs(v) < 1e-7 {
+			return r, true
+		}
+		if dv == 0 {
+			break
+		}
+		next := r - v/dv
+		if next <= -0.9999 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-r) < 1e-10 {
+			return next, true
+		}
+		r = next
+	}
+
+	lo, hi := -0.9999, 10.0
+	vlo, _ := npv(lo)
+	vhi, _ := npv(hi)
+	if vlo*vhi > 0 {
+		return 0, false
+	}
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		vmid, _ := npv(mid)
+		if math.Abs(vmid) < 1e-7 || (hi-lo)/2 < 1e-10 {
+			return mid, true
+		}
+		if vmid*vlo < 0 {
+			hi = mid
+		} else {
+			lo, vlo = mid, vmid
+		}
+	}
+	return (lo + hi) / 2, true
+}
+
+// moneyWeightedFlows builds investor cash flows: the opening position value at
+// the start of the history, each trade/income/fee, and the current value as a
+// terminal inflow.
+func moneyWeightedFlows(positions map[string]*position, history []models.InvestmentTransaction, endValue float64, asOf time.Time) []cashFlow {
+	if len(history) == 0 {
+		return nil
+	}
+	start := truncateDay(history[0].Date)
+
+	var flows []cashFlow
+	if opening := valueAt(positions, history, start, false); opening > epsilon {
+		flows = append(flows, cashFlow{date: start, amount: -opening})
+	}
+	for _, t := range history {
+		d := truncateDay(t.Date)
+		switch t.Type {
+		case "buy", "sell", "fee":
+			flows = append(flows, cashFlow{date: d, amount: -t.Amount})
+		case "transfer":
+			flows = append(flows, cashFlow{date: d, amount: -t.Quantity * t.Price})
+		case "cash":
+			if isIncomeSubtype(t.Subtype) {
+				flows = append(flows, cashFlow{date: d, amount: -t.Amount})
+			}
+		}
+	}
+	flows = append(flows, cashFlow{date: asOf, amount: endValue})
+	return flows
+}
+
+// buildPositions seeds one position per security with its opening quantity
+// (current quantity minus everything the history added) and price history.
+// A security held in several accounts becomes one position over their total.
+func buildPositions(holdings []models.InvestmentHolding, history []models.InvestmentTransaction) map[string]*position {
+	positions := map[string]*position{}
+	for i := range holdings {
+		h := &holdings[i]
+		if p := positions[h.PlaidSecurityID]; p != nil {
+			p.holding = mergeHoldings(*p.holding, *h)
+			p.qty = p.holding.Quantity
+			continue
+		}
+		p := &position{
+			id:      h.PlaidSecurityID,
+			name:    deref(h.SecurityName),
+			ticker:  deref(h.TickerSymbol),
+			secType: securityType(h.SecurityType),
+			holding: h,
+			qty:     h.Quantity,
+		}
+		positions[h.PlaidSecurityID] = p
+	}
+
+	for _, t := range history {
+		key := securityKey(t)
+		if key == "" {
+			continue
+		}
+		p := positions[key]
+		if p == nil {
+			p = &position{
+				id:      key,
+				name:    deref(t.SecurityName),
+				ticker:  deref(t.TickerSymbol),
+				secType: securityType(t.SecurityType),
+			}
+			positions[key] = p
+		}
+		if p.name == "" {
+			p.name = deref(t.Name)
+		}
+		p.openQty -= t.Quantity
+		if t.Price > 0 {
+			p.prices = append(p.prices, pricePoint{date: truncateDay(t.Date), price: t.Price})
+		}
+	}
+
+	for _, p := range positions {
+		p.openQty += p.qty
+		if p.openQty < epsilon {
+			p.openQty = 0
+		}
+		unitCost := p.priceAt(time.Time{})
+		if h := p.holding; h != nil && h.CostBasis != nil && *h.CostBasis > 0 && h.Quantity > 0 {
+			unitCost = *h.CostBasis / h.Quantity
+		}
+		p.qty = p.openQty
+		p.cost = p.openQty * unitCost
+	}
+	return positions
+}
+
+// mergeHoldings combines two accounts' holdings of the same security. The
+// cost basis is only kept when both accounts report one.
+func mergeHoldings(a, b models.InvestmentHolding) *models.InvestmentHolding {
+	a.Quantity += b.Quantity
+	a.InstitutionValue += b.InstitutionValue
+	if a.CostBasis != nil && b.CostBasis != nil {
+		cost := *a.CostBasis + *b.CostBasis
+		a.CostBasis = &cost
+	} else {
+		a.CostBasis = nil
+	}
+	return &a
+}
+
+// reducePosition removes qty units at average cost and returns the realized
+// gain for proceeds. A negative proceeds value means no sale (e.g. transfer
+// out), so no gain is realized.
+func reducePosition(p *position, qty, proceeds float64) float64 {
+	if qty <= 0 || p.qty <= epsilon {
+		return 0
+	}
+	matched := math.Min(qty, p.qty)
+	costOut := p.cost / p.qty * matched
+	p.qty -= matched
+	p.cost -= costOut
+	if proceeds < 0 {
+		return 0
+	}
+	// Units sold beyond what history can account for have no known cost.
+	return proceeds*(matched/qty) - costOut
+}
+
+// priceAt returns the most recent trade price on or before d, falling back to
+// the earliest known trade price and finally the institution's current price.
+func (p *position) priceAt(d time.Time) float64 {
+	price := 0.0
+	for _, pp := range p.prices {
+		if pp.date.After(d) {
+			if price == 0 {
+				price = pp.price
+			}
+			break
+		}
+		price = pp.price
+	}
+	if price == 0 && p.holding != nil {
+		price = p.holding.InstitutionPrice
+	}
+	return price
+}
+
+// valueAt values all invested positions on day d, either before or after
+// that day's transactions are applied.
+func valueAt(positions map[string]*position, history []models.InvestmentTransaction, d time.Time, afterFlows bool) float64 {
+	total := 0.0
+	for key, p := range positions {
+		if p.holding != nil && isCashSecurity(p.holding.SecurityType) {
+			continue
+		}
+		qty := p.openQty
+		for _, t := range history {
+			if securityKey(t) != key {
+				continue
+			}
+			td := truncateDay(t.Date)
+			if td.Before(d) || (afterFlows && td.Equal(d)) {
+				qty += t.Quantity
+			}
+		}
+		if qty > epsilon {
+			total += qty * p.priceAt(d)
+		}
+	}
+	return total
+}
+
+func incomeOn(history []models.InvestmentTransaction, d time.Time) float64 {
+	income := 0.0
+	for _, t := range history {
+		if !truncateDay(t.Date).Equal(d) {
+			continue
+		}
+		switch {
+		case t.Type == "cash" && isIncomeSubtype(t.Subtype):
+			income += -t.Amount
+		case t.Type == "fee":
+			income -= t.Amount
+		}
+	}
+	return income
+}
+
+func flowDates(history []models.InvestmentTransaction) []time.Time {
+	var dates []time.Time
+	for _, t := range history {
+		d := truncateDay(t.Date)
+		if len(dates) == 0 || !dates[len(dates)-1].Equal(d) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+func allocation(holdings []models.InvestmentHolding) []models.AllocationSlice {
+	totals := map[string]float64{}
+	total := 0.0
+	for _, h := range holdings {
+		totals[securityType(h.SecurityType)] += h.InstitutionValue
+		total += h.InstitutionValue
+	}
+	slices := make([]models.AllocationSlice, 0, len(totals))
+	for t, v := range totals {
+		s := models.AllocationSlice{SecurityType: t, Value: round2(v)}
+		if total > 0 {
+			s.Percent = round2(v / total * 100)
+		}
+		slices = append(slices, s)
+	}
+	sort.Slice(slices, func(i, j int) bool {
+		if slices[i].Value == slices[j].Value {
+			return slices[i].SecurityType < slices[j].SecurityType
+		}
+		return slices[i].Value > slices[j].Value
+	})
+	return slices
+}
+
+func investedValue(holdings []models.InvestmentHolding) float64 {
+	total := 0.0
+	for _, h := range holdings {
+		if !isCashSecurity(h.SecurityType) {
+			total += h.InstitutionValue
+		}
+	}
+	return total
+}
+
+func isIncomeSubtype(subtype *string) bool {
+	if subtype == nil {
+		return false
+	}
+	s := strings.ToLower(*subtype)
+	return strings.Contains(s, "dividend") || strings.Contains(s, "interest") ||
+		strings.Contains(s, "capital gain")
+}
+
+func isCashSecurity(t *string) bool {
+	return t != nil && strings.EqualFold(*t, "cash")
+}
+
+func securityKey(t models.InvestmentTransaction) string {
+	return deref(t.PlaidSecurityID)
+}
+
+func securityType(t *string) string {
+	if t == nil || *t == "" {
+		return "other"
+	}
+	return strings.ToLower(*t)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,187 @@
+package investments
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+func strPtr(s string) *string     { return &s }
+func floatPtr(f float64) *float64 { return &f }
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func holding(secID, secType string, qty, price float64, costBasis *float64) models.InvestmentHolding {
+	return models.InvestmentHolding{
+		PlaidSecurityID:  secID,
+		SecurityName:     strPtr(secID),
+		SecurityType:     strPtr(secType),
+		Quantity:         qty,
+		InstitutionPrice: price,
+		InstitutionValue: qty * price,
+		CostBasis:        costBasis,
+	}
+}
+
+func trade(secID, txType string, date time.Time, qty, price, amount float64) models.InvestmentTransaction {
+	return models.InvestmentTransaction{
+		PlaidSecurityID: strPtr(secID),
+		SecurityType:    strPtr("equity"),
+		Type:            txType,
+		Date:            date,
+		Quantity:        qty,
+		Price:           price,
+		Amount:          amount,
+	}
+}
+
+func approx(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+func TestCalculate_SingleBuyOneYear(t *testing.T) {
+	holdings := []models.InvestmentHolding{holding("AAA", "equity", 10, 110, floatPtr(1000))}
+	txns := []models.InvestmentTransaction{trade("AAA", "buy", day(2025, 1, 1), 10, 100, 1000)}
+
+	perf := Calculate(holdings, txns, day(2026, 1, 1))
+
+	if perf.TimeWeightedReturnPct != 10 {
+		t.Errorf("expected TWR 10%%, got %v", perf.TimeWeightedReturnPct)
+	}
+	if perf.MoneyWeightedReturnPct == nil || !approx(*perf.MoneyWeightedReturnPct, 10, 0.01) {
+		t.Errorf("expected MWR ~10%%, got %v", perf.MoneyWeightedReturnPct)
+	}
+	if perf.UnrealizedGain != 100 {
+		t.Errorf("expected unrealized gain 100, got %v", perf.UnrealizedGain)
+	}
+	if perf.TotalContributed != 1000 {
+		t.Errorf("expected contributions 1000, got %v", perf.TotalContributed)
+	}
+}
+
+func TestCalculate_RealizedGainAverageCost(t *testing.T) {
+	holdings := []models.InvestmentHolding{holding("AAA", "equity", 6, 150, nil)}
+	txns := []models.InvestmentTransaction{
+		trade("AAA", "sell", day(2025, 6, 1), -4, 150, -600),
+		trade("AAA", "buy", day(2025, 1, 1), 10, 100, 1000),
+	}
+
+	perf := Calculate(holdings, txns, day(2025, 12, 1))
+
+	if perf.RealizedGain != 200 {
+		t.Errorf("expected realized gain 200, got %v", perf.RealizedGain)
+	}
+	if perf.CostBasis != 600 {
+		t.Errorf("expected remaining cost basis 600, got %v", perf.CostBasis)
+	}
+	if perf.UnrealizedGain != 300 {
+		t.Errorf("expected unrealized gain 300, got %v", perf.UnrealizedGain)
+	}
+	if perf.TotalWithdrawn != 600 {
+		t.Errorf("expected withdrawals 600, got %v", perf.TotalWithdrawn)
+	}
+}
+
+func TestCalculate_TWRIgnoresContributionTiming(t *testing.T) {
+	// Price doubles, then a large buy lands right before the price stops
+	// moving. TWR reflects the 100% gain; MWR is dragged down by the late
+	// contribution.
+	holdings := []models.InvestmentHolding{holding("AAA", "equity", 20, 200, nil)}
+	txns := []models.InvestmentTransaction{
+		trade("AAA", "buy", day(2025, 1, 1), 10, 100, 1000),
+		trade("AAA", "buy", day(2025, 7, 1), 10, 200, 2000),
+	}
+
+	perf := Calculate(holdings, txns, day(2026, 1, 1))
+
+	if perf.TimeWeightedReturnPct != 100 {
+		t.Errorf("expected TWR 100%%, got %v", perf.TimeWeightedReturnPct)
+	}
+	if perf.MoneyWeightedReturnPct == nil || *perf.MoneyWeightedReturnPct >= perf.TimeWeightedReturnPct {
+		t.Errorf("expected MWR below TWR, got %v", perf.MoneyWeightedReturnPct)
+	}
+}
+
+func TestCalculate_DividendsCountAsReturn(t *testing.T) {
+	holdings := []models.InvestmentHolding{holding("AAA", "equity", 10, 100, floatPtr(1000))}
+	div := trade("AAA", "cash", day(2025, 6, 1), 0, 0, -50)
+	div.Subtype = strPtr("dividend")
+	txns := []models.InvestmentTransaction{
+		trade("AAA", "buy", day(2025, 1, 1), 10, 100, 1000),
+		div,
+	}
+
+	perf := Calculate(holdings, txns, day(2025, 12, 1))
+
+	if perf.DividendIncome != 50 {
+		t.Errorf("expected dividend income 50, got %v", perf.DividendIncome)
+	}
+	if perf.TimeWeightedReturnPct != 5 {
+		t.Errorf("expected TWR 5%%, got %v", perf.TimeWeightedReturnPct)
+	}
+}
+
+func TestCalculate_AllocationBySecurityType(t *testing.T) {
+	holdings := []models.InvestmentHolding{
+		holding("AAA", "equity", 5, 150, nil),
+		holding("BBB", "etf", 1, 200, nil),
+		holding("CUR", "cash", 50, 1, nil),
+	}
+
+	perf := Calculate(holdings, nil, day(2025, 12, 1))
+
+	if perf.MarketValue != 1000 {
+		t.Fatalf("expected market value 1000, got %v", perf.MarketValue)
+	}
+	if len(perf.Allocation) != 3 {
+		t.Fatalf("expected 3 allocation slices, got %d", len(perf.Allocation))
+	}
+	if perf.Allocation[0].SecurityType != "equity" || perf.Allocation[0].Percent != 75 {
+		t.Errorf("expected equity at 75%%, got %+v", perf.Allocation[0])
+	}
+	if perf.MoneyWeightedReturnPct != nil {
+		t.Errorf("expected no MWR without history, got %v", *perf.MoneyWeightedReturnPct)
+	}
+}
+
+func TestCalculate_SameSecurityInTwoAccounts(t *testing.T) {
+	holdings := []models.InvestmentHolding{
+		holding("VTI", "etf", 10, 100, floatPtr(800)),
+		holding("VTI", "etf", 5, 100, floatPtr(450)),
+	}
+
+	perf := Calculate(holdings, nil, day(2025, 12, 1))
+
+	if perf.MarketValue != 1500 {
+		t.Fatalf("expected market value 1500, got %v", perf.MarketValue)
+	}
+	if len(perf.Securities) != 1 {
+		t.Fatalf("expected one position, got %+v", perf.Securities)
+	}
+	sp := perf.Securities[0]
+	if sp.Quantity != 15 || sp.MarketValue != 1500 || sp.CostBasis != 1250 || sp.UnrealizedGain != 250 {
+		t.Errorf("expected the two accounts summed, got %+v", sp)
+	}
+	if perf.CostBasis != 1250 || perf.UnrealizedGain != 250 {
+		t.Errorf("expected totals over both accounts, got cost %v gain %v", perf.CostBasis, perf.UnrealizedGain)
+	}
+}
+
+func TestXIRR(t *testing.T) {
+	flows := []cashFlow{
+		{date: day(2025, 1, 1), amount: -100},
+		{date: day(2026, 1, 1), amount: 110},
+	}
+	rate, ok := xirr(flows)
+	if !ok || !approx(rate, 0.10, 1e-6) {
+		t.Errorf("expected 10%%, got %v (ok=%v)", rate, ok)
+	}
+
+	if _, ok := xirr([]cashFlow{{date: day(2025, 1, 1), amount: 100}}); ok {
+		t.Error("expected no solution for a single flow")
+	}
+}
@@ -0,0 +1,149 @@
+// Package merchants turns raw bank descriptions into canonical merchants so
+// "SQ *BLUE BOTTLE 1234 SF" and "BLUE BOTTLE COFFEE #88" land on one row.
+package merchants
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// processorPrefixes are payment processors and wallets that prepend their own
+// name to the merchant's. Matched case-insensitively at the start.
+var processorPrefixes = []string{
+	"sq *", "sq*", "tst* ", "tst*", "sp * ", "sp *", "sp*", "pp*", "paypal *", "paypal*",
+	"py *", "pos debit ", "pos ", "debit card purchase ", "purchase authorized on ", "checkcard ",
+	"ach debit ", "recurring payment ", "google *", "apple.com/bill ", "in *", "sumup *",
+	"zel*", "dd *", "doordash*", "clover* ",
+}
+
+// aliases map the normalized form of well-known fragments to one name.
+// Keys are compared against the start of the cleaned, lowercased name.
+var aliases = []struct{ prefix, name string }{
+	{"amzn mktp", "Amazon"},
+	{"amzn", "Amazon"},
+	{"amazon.com", "Amazon"},
+	{"amazon mktpl", "Amazon"},
+	{"amazon prime", "Amazon Prime"},
+	{"prime video", "Amazon Prime"},
+	{"wm supercenter", "Walmart"},
+	{"wal-mart", "Walmart"},
+	{"walmart", "Walmart"},
+	{"uber eats", "Uber Eats"},
+	{"ubereats", "Uber Eats"},
+	{"uber", "Uber"},
+	{"lyft", "Lyft"},
+	{"netflix", "Netflix"},
+	{"spotify", "Spotify"},
+	{"starbucks", "Starbucks"},
+	{"target", "Target"},
+	{"costco", "Costco"},
+	{"apple.com", "Apple"},
+	{"google", "Google"},
+}
+
+// usStates are trailing location tokens to drop.
+var usStates = map[string]bool{
+	"al": true, "ak": true, "az": true, "ar": true, "ca": true, "co": true, "ct": true, "de": true,
+	"fl": true, "ga": true, "hi": true, "id": true, "il": true, "in": true, "ia": true, "ks": true,
+	"ky": true, "la": true, "me": true, "md": true, "ma": true, "mi": true, "mn": true, "ms": true,
+	"mo": true, "mt": true, "ne": true, "nv": true, "nh": true, "nj": true, "nm": true, "ny": true,
+	"nc": true, "nd": true, "oh": true, "ok": true, "or": true, "pa": true, "ri": true, "sc": true,
+	"sd": true, "tn": true, "tx": true, "ut": true, "vt": true, "va": true, "wa": true, "wv": true,
+	"wi": true, "wy": true, "dc": true, "us": true, "usa": true,
+}
+
+var (
+	whitespace = regexp.MustCompile(`\s+`)
+	// Phone numbers and URLs often trail the name on card statements.
+	trailingNoise = regexp.MustCompile(`(?i)\s+(\d{3}[-.]?\d{3}[-.]?\d{4}|[a-z0-9-]+\.(com|net|org|co))\b.*$`)
+)
+
+// Normalize returns a display name for a raw description: processor prefixes,
+// store numbers, reference codes and trailing locations removed, known
+// aliases collapsed, and the result title-cased. Returns "" when nothing
+// meaningful is left.
+func Normalize(raw string) string {
+	s := strings.TrimSpace(whitespace.ReplaceAllString(raw, " "))
+	lower := strings.ToLower(s)
+	for stripped := true; stripped; {
+		stripped = false
+		for _, p := range processorPrefixes {
+			if strings.HasPrefix(lower, p) {
+				s = strings.TrimSpace(s[len(p):])
+				lower = strings.ToLower(s)
+				stripped = true
+			}
+		}
+	}
+
+	for _, a := range aliases {
+		if hasWordPrefix(lower, a.prefix) {
+			return a.name
+		}
+	}
+
+	// Everything after a '*' is a processor reference ("AMZN Mktp US*2K3").
+	if i := strings.Index(s, "*"); i > 0 {
+		s = s[:i]
+	}
+	s = trailingNoise.ReplaceAllString(s, "")
+
+	// Stop at the first token carrying a digit: store numbers come first and
+	// locations follow them ("BLUE BOTTLE 1234 SF").
+	var kept []string
+	for _, tok := range strings.Fields(s) {
+		tok = strings.Trim(tok, "#-.,:;/")
+		if tok == "" {
+			continue
+		}
+		if strings.IndexFunc(tok, unicode.IsDigit) >= 0 {
+			break
+		}
+		kept = append(kept, tok)
+	}
+	for len(kept) > 1 && usStates[strings.ToLower(kept[len(kept)-1])] {
+		kept = kept[:len(kept)-1]
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+
+	for i, tok := range kept {
+		kept[i] = titleCase(tok)
+	}
+	return strings.Join(kept, " ")
+}
+
+// Key is the dedupe key for a raw description: its normalized name lowercased
+// with punctuation dropped.
+func Key(raw string) string {
+	name := strings.ToLower(Normalize(raw))
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(whitespace.ReplaceAllString(b.String(), " "))
+}
+
+// titleCase upper-cases the first letter of a token and lower-cases the rest,
+// leaving mixed-case brand names ("eBay", "McDonald's") alone.
+func titleCase(tok string) string {
+	if tok != strings.ToUpper(tok) && tok != strings.ToLower(tok) {
+		return tok
+	}
+	runes := []rune(strings.ToLower(tok))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// hasWordPrefix reports whether s starts with prefix followed by a word break.
+func hasWordPrefix(s, prefix string) bool {
+	if !strings.HasPrefix(s, prefix) {
+		return false
+	}
+	rest := s[len(prefix):]
+	return rest == "" || !unicode.IsLetter([]rune(rest)[0])
+}
@@ -0,0 +1,34 @@
+package merchants
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"SQ *BLUE BOTTLE 1234 SF":        "Blue Bottle",
+		"BLUE BOTTLE COFFEE #88":         "Blue Bottle Coffee",
+		"AMZN Mktp US*2K3":               "Amazon",
+		"Amazon.com*RT4YU1":              "Amazon",
+		"TST* JOE'S PIZZA NEW YORK NY":   "Joe's Pizza New York",
+		"PAYPAL *SPOTIFY":                "Spotify",
+		"WHOLEFDS MKT 10234 AUSTIN TX":   "Wholefds Mkt",
+		"SHELL OIL 57444 HOUSTON TX":     "Shell Oil",
+		"COMCAST CABLE 800-266-2278 PA":  "Comcast Cable",
+		"Targeted Ads LLC":               "Targeted Ads Llc",
+		"POS DEBIT TRADER JOE'S #552 CA": "Trader Joe's",
+		"eBay O*12-34567":                "eBay O",
+		"12345":                          "",
+	}
+	for raw, want := range cases {
+		if got := Normalize(raw); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestKey_CollapsesVariants(t *testing.T) {
+	a := Key("SQ *BLUE BOTTLE 1234 SF")
+	b := Key("Blue Bottle 0042 Oakland CA")
+	if a != "blue bottle" || a != b {
+		t.Fatalf("expected both to key to %q, got %q and %q", "blue bottle", a, b)
+	}
+}
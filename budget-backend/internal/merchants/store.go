@@ -0,0 +1,77 @@
+package merchants
+
+import (
+	"database/sql"
+	"hash/fnv"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// Columns is the column list ScanMerchant expects.
+const Columns = `id, name, normalized_key, logo_url, color, created_at`
+
+// ScanMerchant scans a row selected with Columns followed by any extra columns.
+func ScanMerchant(row interface{ Scan(...any) error }, extra ...any) (models.Merchant, error) {
+	var m models.Merchant
+	dest := []any{&m.ID, &m.Name, &m.NormalizedKey, &m.LogoURL, &m.Color, &m.CreatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return m, err
+}
+
+// palette holds the colors merchants are drawn from.
+var palette = []string{
+	"#E4572E", "#29335C", "#F3A712", "#669BBC", "#A8C686",
+	"#8E5572", "#2E86AB", "#C73E1D", "#3B8EA5", "#6A994E",
+}
+
+// Color returns a stable color for a normalized merchant key, so a merchant
+// keeps its color everywhere it appears.
+func Color(key string) string {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return palette[h.Sum32()%uint32(len(palette))]
+}
+
+// Resolve finds or creates the merchant for a raw description. A logo URL
+// from the provider and a derived color fill in a merchant that doesn't have
+// them yet. Returns nil when the description doesn't normalize to anything.
+func Resolve(db *sql.DB, raw, logoURL string) (*models.Merchant, error) {
+	key := Key(raw)
+	if key == "" {
+		return nil, nil
+	}
+	var logo any
+	if logoURL != "" {
+		logo = logoURL
+	}
+	m, err := ScanMerchant(db.QueryRow(`
+		INSERT INTO merchants (name, normalized_key, logo_url, color)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (normalized_key) DO UPDATE
+		SET logo_url = COALESCE(merchants.logo_url, EXCLUDED.logo_url),
+		    color = COALESCE(merchants.color, EXCLUDED.color)
+		RETURNING `+Columns,
+		Normalize(raw), key, logo, Color(key),
+	))
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Lookup returns the existing merchant for a raw description or name without
+// creating one. Returns nil when there is no such merchant.
+func Lookup(db *sql.DB, raw string) (*models.Merchant, error) {
+	key := Key(raw)
+	if key == "" {
+		return nil, nil
+	}
+	m, err := ScanMerchant(db.QueryRow(`SELECT `+Columns+` FROM merchants WHERE normalized_key = $1`, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,13 @@
+package merchants
+
+import "testing"
+
+func TestColor_StablePerKey(t *testing.T) {
+	key := Key("SQ *BLUE BOTTLE 1234 SF")
+	if Color(key) != Color(Key("Blue Bottle 0042 Oakland CA")) {
+		t.Error("expected variants of a merchant to share a color")
+	}
+	if c := Color(key); len(c) != 7 || c[0] != '#' {
+		t.Errorf("expected a hex color, got %q", c)
+	}
+}
@@ -0,0 +1,65 @@
+package receipts
+
+import (
+	"math"
+	"strings"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// maxNoteLength keeps split notes readable when many items share a category.
+const maxNoteLength = 120
+
+// Allocate groups categorized items into one split per category and scales
+// them so the splits add up to total exactly. Tax, tip, discounts and any
+// gap between the receipt and the bank amount are spread in proportion to
+// each item's amount. Categories keep the order they first appear in.
+func Allocate(items []models.SuggestedLineItem, total float64) []models.SplitEntry {
+	sum := 0.0
+	for _, it := range items {
+		sum += it.Amount
+	}
+	if sum <= 0 || total <= 0 {
+		return []models.SplitEntry{}
+	}
+
+	var order []string
+	amounts := map[string]float64{}
+	names := map[string][]string{}
+	for _, it := range items {
+		if _, ok := amounts[it.CategoryID]; !ok {
+			order = append(order, it.CategoryID)
+		}
+		amounts[it.CategoryID] += it.Amount * total / sum
+		names[it.CategoryID] = append(names[it.CategoryID], it.Description)
+	}
+
+	entries := make([]models.SplitEntry, len(order))
+	allocated, largest := 0.0, 0
+	for i, cat := range order {
+		entries[i] = models.SplitEntry{CategoryID: cat, Amount: round2(amounts[cat]), Note: splitNote(names[cat])}
+		allocated += entries[i].Amount
+		if entries[i].Amount > entries[largest].Amount {
+			largest = i
+		}
+	}
+	// Rounding leaves at most a few cents over or under; the largest split
+	// absorbs it.
+	if diff := round2(total - allocated); math.Abs(diff) > 0 {
+		entries[largest].Amount = round2(entries[largest].Amount + diff)
+	}
+	return entries
+}
+
+// splitNote lists the items in a split, trimmed to maxNoteLength.
+func splitNote(items []string) string {
+	note := strings.Join(items, ", ")
+	if len(note) <= maxNoteLength {
+		return note
+	}
+	cut := strings.LastIndex(note[:maxNoteLength], ", ")
+	if cut <= 0 {
+		cut = maxNoteLength
+	}
+	return note[:cut] + "…"
+}
@@ -0,0 +1,171 @@
+// Package receipts reads line items out of receipt text and turns them into
+// suggested transaction splits.
+package receipts
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+var (
+	// priceLine matches a line ending in an amount, optionally with a dollar
+	// sign, a trailing minus for credits and a tax flag ("4.99 F", "1.00-").
+	priceLine = regexp.MustCompile(`^(.*?)[\s.:]+(-)?\$?\s?(\d{1,6}(?:,\d{3})*\.\d{2})(-)?(?:\s+[A-Za-z]{1,2}|\s*\*)?$`)
+	// quantity matches "2 @ 1.99", "2 x 1.99" or "2 @" inside a description.
+	quantity = regexp.MustCompile(`(?i)\b(\d{1,3})\s*(?:@|x)\s*(?:\$?\d+\.\d{2}(?:\s*(?:/\s*)?ea)?)?`)
+	spaces   = regexp.MustCompile(`\s+`)
+)
+
+// Keyword lists are matched against the lowercased description.
+var (
+	paymentWords = []string{
+		"visa", "mastercard", "master card", "amex", "american express", "discover", "debit", "credit",
+		"cash", "change", "tender", "balance", "amount due", "payment", "card #", "approved", "auth", "authorization",
+		"you saved", "total savings", "savings today", "points",
+	}
+	taxWords      = []string{"tax", "hst", "gst", "pst", "qst", "vat"}
+	tipWords      = []string{"tip", "gratuity"}
+	discountWords = []string{"discount", "coupon", "promo", "savings", "rebate"}
+)
+
+func containsAny(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWord reports whether s contains w as a whole word or phrase: not
+// preceded or followed by a letter, so "tender" doesn't match "tenderloin".
+func hasWord(s, w string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], w)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(w)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if !unicode.IsLetter(before) && !unicode.IsLetter(after) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func hasAnyWord(s string, words []string) bool {
+	for _, w := range words {
+		if hasWord(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanDescription tidies an item description and pulls out a quantity.
+func cleanDescription(desc string) (string, int) {
+	qty := 0
+	if m := quantity.FindStringSubmatch(desc); m != nil {
+		qty, _ = strconv.Atoi(m[1])
+		desc = strings.Replace(desc, m[0], " ", 1)
+	}
+	desc = strings.Trim(spaces.ReplaceAllString(desc, " "), " .:-*#")
+	return desc, qty
+}
+
+// Parse reads a receipt line by line. Lines ending in an amount become
+// items unless they are subtotal, tax, tip, total or payment lines; credits
+// (negative or with a trailing minus) count as discounts. A price line with
+// no description of its own ("2 @ 3.99  7.98") takes the line above it.
+func Parse(text string) models.ParsedReceipt {
+	receipt := models.ParsedReceipt{Items: []models.ReceiptItem{}}
+	pending := ""
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		line := strings.TrimSpace(spaces.ReplaceAllString(raw, " "))
+		if line == "" {
+			continue
+		}
+		m := priceLine.FindStringSubmatch(line)
+		if m == nil {
+			if receipt.Merchant == "" && len(receipt.Items) == 0 && strings.IndexFunc(line, unicode.IsLetter) >= 0 {
+				receipt.Merchant = line
+			} else {
+				pending = line
+			}
+			continue
+		}
+
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(m[3], ",", ""), 64)
+		if err != nil || amount == 0 {
+			continue
+		}
+		credit := m[2] != "" || m[4] != ""
+		desc, qty := cleanDescription(m[1])
+		if desc == "" {
+			desc, pending = pending, ""
+			if d, q := cleanDescription(desc); d != "" {
+				desc = d
+				if qty == 0 {
+					qty = q
+				}
+			}
+		} else {
+			pending = ""
+		}
+		lower := strings.ToLower(desc)
+
+		switch {
+		case hasAnyWord(lower, paymentWords):
+			// Payment and informational lines don't describe purchases.
+		case strings.Contains(lower, "subtotal") || strings.Contains(lower, "sub total") || strings.Contains(lower, "sub-total"):
+			receipt.Subtotal = amount
+		case hasAnyWord(lower, taxWords):
+			receipt.Tax = round2(receipt.Tax + amount)
+		case hasAnyWord(lower, tipWords):
+			receipt.Tip = round2(receipt.Tip + amount)
+		case strings.Contains(lower, "total"):
+			receipt.Total = amount
+		case credit || containsAny(lower, discountWords):
+			receipt.Discount = round2(receipt.Discount + amount)
+		case desc == "":
+			// An amount with nothing to name it.
+		default:
+			receipt.Items = append(receipt.Items, models.ReceiptItem{Description: desc, Quantity: qty, Amount: amount})
+		}
+	}
+	return receipt
+}
+
+// ItemsTotal sums the line items.
+func ItemsTotal(r models.ParsedReceipt) float64 {
+	sum := 0.0
+	for _, it := range r.Items {
+		sum += it.Amount
+	}
+	return round2(sum)
+}
+
+// GrandTotal is the receipt's total: the printed total when there is one,
+// otherwise the items adjusted by tax, tip and discounts.
+func GrandTotal(r models.ParsedReceipt) float64 {
+	if r.Total > 0 {
+		return r.Total
+	}
+	base := r.Subtotal
+	if base == 0 {
+		base = ItemsTotal(r)
+	}
+	return round2(base + r.Tax + r.Tip - r.Discount)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
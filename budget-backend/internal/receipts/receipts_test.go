@@ -0,0 +1,104 @@
+package receipts
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+const groceryReceipt = `
+TRADER JOE'S #552
+1234 MARKET ST
+SAN FRANCISCO CA
+
+BANANAS                  1.18
+ORGANIC MILK
+  2 @ 3.99               7.98 F
+DISH SOAP               $4.49 T
+COUPON                   1.00-
+SUBTOTAL                12.65
+TAX                      0.41
+TOTAL                   13.06
+VISA ************1234   13.06
+CHANGE                   0.00
+`
+
+func TestParse(t *testing.T) {
+	r := Parse(groceryReceipt)
+
+	if r.Merchant != "TRADER JOE'S #552" {
+		t.Errorf("merchant = %q", r.Merchant)
+	}
+	want := []models.ReceiptItem{
+		{Description: "BANANAS", Amount: 1.18},
+		{Description: "ORGANIC MILK", Quantity: 2, Amount: 7.98},
+		{Description: "DISH SOAP", Amount: 4.49},
+	}
+	if len(r.Items) != len(want) {
+		t.Fatalf("items = %+v", r.Items)
+	}
+	for i := range want {
+		if r.Items[i] != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, r.Items[i], want[i])
+		}
+	}
+	if r.Subtotal != 12.65 || r.Tax != 0.41 || r.Total != 13.06 || r.Discount != 1.00 {
+		t.Errorf("summary = %+v", r)
+	}
+	if GrandTotal(r) != 13.06 {
+		t.Errorf("GrandTotal = %v", GrandTotal(r))
+	}
+}
+
+func TestParse_EmailReceiptWithoutTotal(t *testing.T) {
+	r := Parse("Your order from Sweetgreen\nHarvest Bowl: $14.95\nKombucha: $4.50\nSales Tax: $1.70\nTip: $3.00\n")
+
+	if len(r.Items) != 2 || r.Items[0].Description != "Harvest Bowl" {
+		t.Fatalf("items = %+v", r.Items)
+	}
+	if got := GrandTotal(r); got != 24.15 {
+		t.Errorf("GrandTotal = %v, want 24.15", got)
+	}
+}
+
+func TestParse_ItemsNamedLikePaymentLines(t *testing.T) {
+	// "tender" and "cash" are payment words, but only as whole words.
+	r := Parse("BEEF TENDERLOIN         24.99\nCASHEWS                  6.49\nTOTAL                   31.48\nCASH                    40.00\nCHANGE                   8.52\n")
+
+	if len(r.Items) != 2 || r.Items[0].Description != "BEEF TENDERLOIN" || r.Items[1].Description != "CASHEWS" {
+		t.Fatalf("items = %+v", r.Items)
+	}
+	if r.Total != 31.48 {
+		t.Errorf("total = %v", r.Total)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	items := []models.SuggestedLineItem{
+		{ReceiptItem: models.ReceiptItem{Description: "BANANAS", Amount: 1.18}, CategoryID: "groceries"},
+		{ReceiptItem: models.ReceiptItem{Description: "ORGANIC MILK", Amount: 7.98}, CategoryID: "groceries"},
+		{ReceiptItem: models.ReceiptItem{Description: "DISH SOAP", Amount: 4.49}, CategoryID: "household"},
+	}
+
+	splits := Allocate(items, 13.06)
+
+	if len(splits) != 2 || splits[0].CategoryID != "groceries" || splits[1].CategoryID != "household" {
+		t.Fatalf("splits = %+v", splits)
+	}
+	sum := splits[0].Amount + splits[1].Amount
+	if math.Abs(sum-13.06) > 0.001 {
+		t.Fatalf("splits sum to %v, want 13.06", sum)
+	}
+	// 9.16 of 13.65 in items is groceries, so groceries gets that share.
+	if splits[0].Amount != 8.76 {
+		t.Errorf("groceries = %v, want 8.76", splits[0].Amount)
+	}
+	if splits[0].Note != "BANANAS, ORGANIC MILK" {
+		t.Errorf("note = %q", splits[0].Note)
+	}
+
+	if got := Allocate(nil, 10); len(got) != 0 {
+		t.Errorf("expected no splits without items, got %+v", got)
+	}
+}
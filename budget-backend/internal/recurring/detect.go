@@ -0,0 +1,257 @@
+// Package recurring finds periodic charges (bills and subscriptions) in a
+// merchant's transaction history and watches subscription bills for price
+// changes, missed charges, trial conversions and renewals.
+package recurring
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Charge is a single expense from one merchant.
+type Charge struct {
+	Date   time.Time
+	Amount float64
+}
+
+// Pattern is a recurring charge detected in a series.
+type Pattern struct {
+	Frequency      string // weekly, biweekly, monthly, quarterly, yearly
+	DueDay         int    // day of month, or weekday 1=Mon..7=Sun for weekly
+	TypicalAmount  float64
+	MinAmount      float64
+	MaxAmount      float64
+	AmountVariance float64 // coefficient of variation of the amounts
+	Confidence     float64 // 0..1
+	Occurrences    int
+	LastDate       time.Time
+	NextDate       time.Time
+}
+
+type cadence struct {
+	frequency  string
+	days       float64
+	tolerance  float64
+	minCharges int
+}
+
+var cadences = []cadence{
+	{"weekly", 7, 1, 4},
+	{"biweekly", 14, 2, 3},
+	{"monthly", 30.44, 4, 3},
+	{"quarterly", 91.31, 8, 3},
+	{"yearly", 365.25, 12, 2},
+}
+
+const (
+	// minRegularity is the share of gaps that must land on the cadence.
+	minRegularity = 0.6
+	// maxVariance rejects series whose amounts swing too much to be one bill.
+	maxVariance = 0.5
+	// MinConfidence is the lowest confidence worth suggesting.
+	MinConfidence = 0.5
+)
+
+// Find returns the recurring pattern in a merchant's charges, if any. The
+// whole series is tried first so a variable utility bill stays one pattern;
+// when that fails the charges are split into amount bands, which separates a
+// subscription from one-off purchases at the same merchant. The most
+// confident pattern wins.
+func Find(charges []Charge, now time.Time) (Pattern, bool) {
+	if p, ok := Detect(charges, now); ok {
+		return p, true
+	}
+	var best Pattern
+	found := false
+	for _, band := range amountBands(charges) {
+		if len(band) == len(charges) {
+			continue
+		}
+		if p, ok := Detect(band, now); ok && (!found || p.Confidence > best.Confidence) {
+			best, found = p, true
+		}
+	}
+	return best, found
+}
+
+// Detect checks whether the charges recur on a known cadence and are still
+// active as of now.
+func Detect(charges []Charge, now time.Time) (Pattern, bool) {
+	if len(charges) < 2 {
+		return Pattern{}, false
+	}
+	sorted := append([]Charge(nil), charges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	gaps := make([]float64, 0, len(sorted)-1)
+	for i := 1; i < len(sorted); i++ {
+		gaps = append(gaps, sorted[i].Date.Sub(sorted[i-1].Date).Hours()/24)
+	}
+	typicalGap := median(gaps)
+
+	var c *cadence
+	for i := range cadences {
+		if math.Abs(typicalGap-cadences[i].days) <= cadences[i].tolerance {
+			c = &cadences[i]
+			break
+		}
+	}
+	if c == nil || len(sorted) < c.minCharges {
+		return Pattern{}, false
+	}
+
+	onCadence := 0
+	for _, g := range gaps {
+		if math.Abs(g-c.days) <= c.tolerance {
+			onCadence++
+		}
+	}
+	regularity := float64(onCadence) / float64(len(gaps))
+	if regularity < minRegularity {
+		return Pattern{}, false
+	}
+
+	last := sorted[len(sorted)-1].Date
+	// A charge that has missed more than one cycle has likely been cancelled.
+	if now.Sub(last).Hours()/24 > c.days*1.5+c.tolerance {
+		return Pattern{}, false
+	}
+
+	amounts := make([]float64, len(sorted))
+	for i, ch := range sorted {
+		amounts[i] = ch.Amount
+	}
+	variance := coefficientOfVariation(amounts)
+	if variance > maxVariance {
+		return Pattern{}, false
+	}
+
+	amountScore := 1 - variance/maxVariance
+	countScore := math.Min(1, float64(len(sorted))/float64(c.minCharges*2))
+	confidence := 0.5*regularity + 0.3*amountScore + 0.2*countScore
+	if confidence < MinConfidence {
+		return Pattern{}, false
+	}
+
+	minAmt, maxAmt := amounts[0], amounts[0]
+	for _, a := range amounts {
+		minAmt = math.Min(minAmt, a)
+		maxAmt = math.Max(maxAmt, a)
+	}
+
+	return Pattern{
+		Frequency:      c.frequency,
+		DueDay:         dueDay(c.frequency, sorted),
+		TypicalAmount:  round2(median(amounts)),
+		MinAmount:      round2(minAmt),
+		MaxAmount:      round2(maxAmt),
+		AmountVariance: math.Round(variance*10000) / 10000,
+		Confidence:     round2(confidence),
+		Occurrences:    len(sorted),
+		LastDate:       last,
+		NextDate:       NextDate(c.frequency, last),
+	}, true
+}
+
+// NextDate is the charge expected one cycle after last.
+func NextDate(frequency string, last time.Time) time.Time {
+	switch frequency {
+	case "weekly":
+		return last.AddDate(0, 0, 7)
+	case "biweekly":
+		return last.AddDate(0, 0, 14)
+	case "1st-15th":
+		return last.AddDate(0, 0, 15)
+	case "quarterly":
+		return last.AddDate(0, 3, 0)
+	case "yearly":
+		return last.AddDate(1, 0, 0)
+	default:
+		return last.AddDate(0, 1, 0)
+	}
+}
+
+// dueDay picks the day bills use for the frequency: the most common weekday
+// for weekly charges, the day of the latest charge for biweekly ones and the
+// median day of month otherwise.
+func dueDay(frequency string, sorted []Charge) int {
+	switch frequency {
+	case "weekly":
+		counts := map[int]int{}
+		best := 0
+		for _, ch := range sorted {
+			wd := int(ch.Date.Weekday())
+			if wd == 0 {
+				wd = 7
+			}
+			counts[wd]++
+			if best == 0 || counts[wd] > counts[best] {
+				best = wd
+			}
+		}
+		return best
+	case "biweekly":
+		return sorted[len(sorted)-1].Date.Day()
+	default:
+		days := make([]float64, len(sorted))
+		for i, ch := range sorted {
+			days[i] = float64(ch.Date.Day())
+		}
+		return int(math.Round(median(days)))
+	}
+}
+
+// amountBands groups charges whose amounts sit within 20% of the smallest
+// amount in the band.
+func amountBands(charges []Charge) [][]Charge {
+	sorted := append([]Charge(nil), charges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Amount < sorted[j].Amount })
+
+	var bands [][]Charge
+	var current []Charge
+	for _, ch := range sorted {
+		if len(current) > 0 && ch.Amount > current[0].Amount*1.2 {
+			bands = append(bands, current)
+			current = nil
+		}
+		current = append(current, ch)
+	}
+	if len(current) > 0 {
+		bands = append(bands, current)
+	}
+	return bands
+}
+
+func median(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	s := append([]float64(nil), vals...)
+	sort.Float64s(s)
+	mid := len(s) / 2
+	if len(s)%2 == 0 {
+		return (s[mid-1] + s[mid]) / 2
+	}
+	return s[mid]
+}
+
+func coefficientOfVariation(vals []float64) float64 {
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	mean := sum / float64(len(vals))
+	if mean == 0 {
+		return 0
+	}
+	var sq float64
+	for _, v := range vals {
+		sq += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sq/float64(len(vals))) / mean
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
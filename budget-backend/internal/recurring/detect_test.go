@@ -0,0 +1,112 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDetect_MonthlySubscription(t *testing.T) {
+	var charges []Charge
+	for m := time.January; m <= time.June; m++ {
+		charges = append(charges, Charge{Date: day(2026, m, 15), Amount: 15.49})
+	}
+
+	p, ok := Detect(charges, day(2026, 6, 20))
+	if !ok {
+		t.Fatal("expected a monthly pattern")
+	}
+	if p.Frequency != "monthly" || p.DueDay != 15 || p.TypicalAmount != 15.49 {
+		t.Errorf("unexpected pattern: %+v", p)
+	}
+	if p.AmountVariance != 0 || p.Confidence != 1 {
+		t.Errorf("expected a perfect match, got variance=%v confidence=%v", p.AmountVariance, p.Confidence)
+	}
+	if !p.NextDate.Equal(day(2026, 7, 15)) {
+		t.Errorf("next date = %v", p.NextDate)
+	}
+}
+
+func TestDetect_VariableUtilityStaysOnePattern(t *testing.T) {
+	amounts := []float64{92.10, 118.40, 131.75, 104.30, 88.00}
+	dates := []time.Time{day(2026, 1, 3), day(2026, 2, 4), day(2026, 3, 3), day(2026, 4, 2), day(2026, 5, 4)}
+	var charges []Charge
+	for i := range amounts {
+		charges = append(charges, Charge{Date: dates[i], Amount: amounts[i]})
+	}
+
+	p, ok := Find(charges, day(2026, 5, 10))
+	if !ok {
+		t.Fatal("expected a monthly pattern")
+	}
+	if p.Occurrences != 5 || p.MinAmount != 88 || p.MaxAmount != 131.75 {
+		t.Errorf("unexpected pattern: %+v", p)
+	}
+	if p.AmountVariance <= 0 || p.Confidence >= 1 {
+		t.Errorf("variable amounts should lower confidence: %+v", p)
+	}
+}
+
+func TestDetect_WeeklyUsesWeekday(t *testing.T) {
+	var charges []Charge
+	for i := 0; i < 5; i++ {
+		charges = append(charges, Charge{Date: day(2026, 3, 6).AddDate(0, 0, 7*i), Amount: 12}) // Fridays
+	}
+
+	p, ok := Detect(charges, day(2026, 4, 5))
+	if !ok || p.Frequency != "weekly" || p.DueDay != 5 {
+		t.Fatalf("expected weekly on Friday, got %+v ok=%v", p, ok)
+	}
+}
+
+func TestDetect_Yearly(t *testing.T) {
+	charges := []Charge{{Date: day(2025, 2, 11), Amount: 139}, {Date: day(2026, 2, 11), Amount: 149}}
+
+	p, ok := Detect(charges, day(2026, 9, 1))
+	if !ok || p.Frequency != "yearly" || p.DueDay != 11 {
+		t.Fatalf("expected a yearly pattern, got %+v ok=%v", p, ok)
+	}
+}
+
+func TestDetect_RejectsIrregularAndStale(t *testing.T) {
+	irregular := []Charge{
+		{Date: day(2026, 1, 2), Amount: 40},
+		{Date: day(2026, 1, 19), Amount: 40},
+		{Date: day(2026, 3, 1), Amount: 40},
+		{Date: day(2026, 3, 8), Amount: 40},
+	}
+	if p, ok := Detect(irregular, day(2026, 3, 10)); ok {
+		t.Errorf("irregular charges detected as %+v", p)
+	}
+
+	var cancelled []Charge
+	for m := time.January; m <= time.April; m++ {
+		cancelled = append(cancelled, Charge{Date: day(2026, m, 1), Amount: 9.99})
+	}
+	if p, ok := Detect(cancelled, day(2026, 7, 1)); ok {
+		t.Errorf("cancelled subscription detected as %+v", p)
+	}
+}
+
+func TestFind_SeparatesSubscriptionFromPurchases(t *testing.T) {
+	var charges []Charge
+	for m := time.January; m <= time.May; m++ {
+		charges = append(charges, Charge{Date: day(2026, m, 8), Amount: 14.99})
+	}
+	charges = append(charges,
+		Charge{Date: day(2026, 1, 20), Amount: 62.30},
+		Charge{Date: day(2026, 3, 2), Amount: 241.99},
+		Charge{Date: day(2026, 4, 27), Amount: 35.10},
+	)
+
+	p, ok := Find(charges, day(2026, 5, 12))
+	if !ok {
+		t.Fatal("expected the subscription to be found")
+	}
+	if p.Frequency != "monthly" || p.TypicalAmount != 14.99 || p.Occurrences != 5 {
+		t.Errorf("unexpected pattern: %+v", p)
+	}
+}
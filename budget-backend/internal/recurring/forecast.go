@@ -0,0 +1,90 @@
+package recurring
+
+import (
+	"math"
+	"time"
+)
+
+// Forecast is the expected amount of a variable bill for a month and how it
+// was worked out.
+type Forecast struct {
+	Amount float64
+	Method string // seasonal_average, same_month_last_year, trailing_average, fixed
+}
+
+// forecastYears limits how far back seasonal history is used.
+const forecastYears = 3
+
+// ForecastAmount estimates a variable bill's charge for the month containing
+// on from its payments, oldest first. Charges from the same calendar month in
+// earlier years are the best guide: two or more are averaged, a single one
+// from last year is used as is. Without those it falls back to the
+// neighbouring months in earlier years, then the last three charges, then
+// the bill's fixed amount.
+func ForecastAmount(amountDue float64, payments []Charge, on time.Time) Forecast {
+	var sameMonth, lastYear, season, recent []float64
+	cutoff := time.Date(on.Year()-forecastYears, on.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(on.Year(), on.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for _, p := range payments {
+		if !p.Date.Before(monthStart) || p.Date.Before(cutoff) {
+			continue
+		}
+		recent = append(recent, p.Amount)
+		if p.Date.Month() == on.Month() {
+			sameMonth = append(sameMonth, p.Amount)
+			if p.Date.Year() == on.Year()-1 {
+				lastYear = append(lastYear, p.Amount)
+			}
+		}
+		if monthsApart(p.Date.Month(), on.Month()) <= 1 && p.Date.Before(monthStart.AddDate(0, -6, 0)) {
+			season = append(season, p.Amount)
+		}
+	}
+
+	switch {
+	case len(sameMonth) >= 2:
+		return Forecast{Amount: mean(sameMonth), Method: "seasonal_average"}
+	case len(lastYear) > 0:
+		return Forecast{Amount: mean(lastYear), Method: "same_month_last_year"}
+	case len(season) > 0:
+		return Forecast{Amount: mean(season), Method: "seasonal_average"}
+	case len(recent) > 0:
+		if len(recent) > 3 {
+			recent = recent[len(recent)-3:]
+		}
+		return Forecast{Amount: mean(recent), Method: "trailing_average"}
+	default:
+		return Forecast{Amount: round2(amountDue), Method: "fixed"}
+	}
+}
+
+// Deviation is how far actual is from forecast, as a percentage of the
+// forecast. Positive when the charge came in higher.
+func Deviation(actual, forecast float64) float64 {
+	if forecast <= 0 {
+		return 0
+	}
+	return math.Round((actual-forecast)/forecast*1000) / 10
+}
+
+// monthsApart is the distance between two calendar months, wrapping around
+// the year.
+func monthsApart(a, b time.Month) int {
+	d := int(a) - int(b)
+	if d < 0 {
+		d = -d
+	}
+	if d > 6 {
+		d = 12 - d
+	}
+	return d
+}
+
+func mean(vals []float64) float64 {
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	return round2(sum / float64(len(vals)))
+}
@@ -0,0 +1,51 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForecastAmount(t *testing.T) {
+	twoWinters := []Charge{
+		{Date: day(2024, 1, 12), Amount: 180},
+		{Date: day(2025, 1, 12), Amount: 200},
+		{Date: day(2025, 7, 12), Amount: 60},
+		{Date: day(2025, 12, 12), Amount: 150},
+	}
+	cases := []struct {
+		name     string
+		payments []Charge
+		on       time.Time
+		amount   float64
+		method   string
+	}{
+		{"same month in several years", twoWinters, day(2026, 1, 3), 190, "seasonal_average"},
+		{"same month last year", twoWinters[1:], day(2026, 1, 3), 200, "same_month_last_year"},
+		{"neighbouring months", twoWinters[1:], day(2026, 2, 3), 200, "seasonal_average"},
+		{"recent charges only", []Charge{
+			{Date: day(2026, 1, 5), Amount: 90},
+			{Date: day(2026, 2, 5), Amount: 100},
+			{Date: day(2026, 3, 5), Amount: 110},
+			{Date: day(2026, 4, 5), Amount: 120},
+		}, day(2026, 5, 1), 110, "trailing_average"},
+		{"no history", nil, day(2026, 5, 1), 95, "fixed"},
+	}
+	for _, tc := range cases {
+		f := ForecastAmount(95, tc.payments, tc.on)
+		if f.Amount != tc.amount || f.Method != tc.method {
+			t.Errorf("%s: got %v (%s), want %v (%s)", tc.name, f.Amount, f.Method, tc.amount, tc.method)
+		}
+	}
+}
+
+func TestDeviation(t *testing.T) {
+	if d := Deviation(150, 120); d != 25 {
+		t.Errorf("Deviation(150, 120) = %v, want 25", d)
+	}
+	if d := Deviation(90, 120); d != -25 {
+		t.Errorf("Deviation(90, 120) = %v, want -25", d)
+	}
+	if d := Deviation(50, 0); d != 0 {
+		t.Errorf("Deviation with no forecast = %v, want 0", d)
+	}
+}
@@ -0,0 +1,80 @@
+package recurring
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// LoadSubscriptions returns the user's subscription bills with their payments
+// since the given date.
+func LoadSubscriptions(db *sql.DB, userID string, since time.Time) ([]Subscription, error) {
+	rows, err := db.Query(`
+		SELECT id, name, frequency, amount_due, COALESCE(is_autopay, false), trial_ends_at
+		FROM bills
+		WHERE user_id = $1 AND is_subscription
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []Subscription
+	var ids []string
+	for rows.Next() {
+		var s Subscription
+		var trialEndsAt sql.NullTime
+		if err := rows.Scan(&s.BillID, &s.Name, &s.Frequency, &s.AmountDue, &s.IsAutopay, &trialEndsAt); err != nil {
+			return nil, err
+		}
+		if trialEndsAt.Valid {
+			s.TrialEndsAt = &trialEndsAt.Time
+		}
+		ids = append(ids, s.BillID)
+		subs = append(subs, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(subs) == 0 {
+		return subs, nil
+	}
+
+	payments, err := LoadPayments(db, ids, since)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		subs[i].Payments = payments[subs[i].BillID]
+	}
+	return subs, nil
+}
+
+// LoadPayments returns each bill's payments since the given date, oldest
+// first. Payments recorded at an estimated amount are left out.
+func LoadPayments(db *sql.DB, billIDs []string, since time.Time) (map[string][]Charge, error) {
+	rows, err := db.Query(`
+		SELECT bill_id, amount_paid, paid_date
+		FROM bill_payments
+		WHERE bill_id = ANY($1) AND paid_date >= $2
+		  AND COALESCE(source, '') <> 'estimated'
+		ORDER BY paid_date
+	`, pq.Array(billIDs), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := map[string][]Charge{}
+	for rows.Next() {
+		var billID string
+		var p Charge
+		if err := rows.Scan(&billID, &p.Amount, &p.Date); err != nil {
+			return nil, err
+		}
+		payments[billID] = append(payments[billID], p)
+	}
+	return payments, rows.Err()
+}
@@ -0,0 +1,162 @@
+package recurring
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// Subscription is a subscription bill and its recent payments, oldest first.
+type Subscription struct {
+	BillID      string
+	Name        string
+	Frequency   string
+	AmountDue   float64
+	IsAutopay   bool
+	TrialEndsAt *time.Time
+	Payments    []Charge
+}
+
+const (
+	// priceIncreaseThreshold ignores rounding-sized changes such as a few
+	// cents of sales tax.
+	priceIncreaseThreshold = 0.02
+	priceHistoryLength     = 6
+	trialNoticeDays        = 7
+	trialConvertedDays     = 30
+	renewalNoticeDays      = 30
+)
+
+// MonthlyCost normalizes a bill amount to a month.
+func MonthlyCost(amount float64, frequency string) float64 {
+	switch frequency {
+	case "weekly":
+		return round2(amount * 52 / 12)
+	case "biweekly":
+		return round2(amount * 26 / 12)
+	case "1st-15th":
+		return round2(amount * 2)
+	case "quarterly":
+		return round2(amount / 3)
+	case "yearly":
+		return round2(amount / 12)
+	default:
+		return round2(amount)
+	}
+}
+
+// graceDays is how long after the expected date a charge may land before it
+// counts as late.
+func graceDays(frequency string) int {
+	switch frequency {
+	case "weekly", "biweekly", "1st-15th":
+		return 2
+	default:
+		return 5
+	}
+}
+
+// NextCharge is when the subscription should next be charged: one cycle after
+// the last payment, or the trial end if it's still running.
+func (s Subscription) NextCharge(now time.Time) (time.Time, bool) {
+	if s.TrialEndsAt != nil && !s.TrialEndsAt.Before(dayOf(now)) {
+		return *s.TrialEndsAt, true
+	}
+	if len(s.Payments) == 0 {
+		return time.Time{}, false
+	}
+	return NextDate(s.Frequency, dayOf(s.Payments[len(s.Payments)-1].Date)), true
+}
+
+// Alerts checks a subscription against its payment history.
+func Alerts(s Subscription, now time.Time) []models.SubscriptionAlert {
+	today := dayOf(now)
+	var alerts []models.SubscriptionAlert
+	alert := func(kind, msg string, amount float64, previous *float64, date time.Time) {
+		alerts = append(alerts, models.SubscriptionAlert{
+			Type:           kind,
+			BillID:         s.BillID,
+			Name:           s.Name,
+			Message:        msg,
+			Amount:         amount,
+			PreviousAmount: previous,
+			Date:           date.Format("2006-01-02"),
+		})
+	}
+
+	if s.TrialEndsAt != nil {
+		end := dayOf(*s.TrialEndsAt)
+		left := daysBetween(today, end)
+		switch {
+		case left >= 0 && left <= trialNoticeDays:
+			alert("trial_ending", fmt.Sprintf("Your %s trial ends %s and converts to $%.2f %s.",
+				s.Name, end.Format("Jan 2"), s.AmountDue, s.Frequency), s.AmountDue, nil, end)
+		case left < 0 && -left <= trialConvertedDays:
+			for _, p := range s.Payments {
+				if !dayOf(p.Date).Before(end.AddDate(0, 0, -1)) {
+					alert("trial_converted", fmt.Sprintf("Your %s trial converted to a paid plan: charged $%.2f on %s.",
+						s.Name, p.Amount, p.Date.Format("Jan 2")), p.Amount, nil, dayOf(p.Date))
+					break
+				}
+			}
+		}
+		if left >= 0 {
+			// Nothing is due until the trial ends.
+			return alerts
+		}
+	}
+
+	if n := len(s.Payments); n >= 2 {
+		latest := s.Payments[n-1]
+		history := make([]float64, 0, priceHistoryLength)
+		for i := n - 2; i >= 0 && len(history) < priceHistoryLength; i-- {
+			history = append(history, s.Payments[i].Amount)
+		}
+		previous := round2(median(history))
+		recent := !today.After(NextDate(s.Frequency, dayOf(latest.Date)))
+		if recent && latest.Amount > previous*(1+priceIncreaseThreshold) && latest.Amount-previous >= 0.5 {
+			pct := math.Round((latest.Amount/previous - 1) * 100)
+			alert("price_increase", fmt.Sprintf("%s went up from $%.2f to $%.2f (+%.0f%%).",
+				s.Name, previous, latest.Amount, pct), latest.Amount, &previous, dayOf(latest.Date))
+		}
+	}
+
+	if len(s.Payments) > 0 {
+		last := dayOf(s.Payments[len(s.Payments)-1].Date)
+		expected := NextDate(s.Frequency, last)
+		overdue := daysBetween(expected, today)
+		switch {
+		case today.After(NextDate(s.Frequency, expected)):
+			alert("missing_charge", fmt.Sprintf("No %s charge since %s. It may have been cancelled or the payment failed.",
+				s.Name, last.Format("Jan 2")), s.AmountDue, nil, expected)
+		case overdue > graceDays(s.Frequency):
+			alert("late_charge", fmt.Sprintf("%s was expected around %s and hasn't been charged yet.",
+				s.Name, expected.Format("Jan 2")), s.AmountDue, nil, expected)
+		}
+	}
+
+	if s.Frequency == "yearly" {
+		if renewal, ok := s.NextCharge(now); ok {
+			if left := daysBetween(today, renewal); left >= 0 && left <= renewalNoticeDays {
+				amount := s.AmountDue
+				if n := len(s.Payments); n > 0 {
+					amount = s.Payments[n-1].Amount
+				}
+				alert("annual_renewal", fmt.Sprintf("%s renews %s for about $%.2f.",
+					s.Name, renewal.Format("Jan 2"), amount), amount, nil, renewal)
+			}
+		}
+	}
+
+	return alerts
+}
+
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(dayOf(to).Sub(dayOf(from)).Hours() / 24))
+}
@@ -0,0 +1,95 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+)
+
+func monthlyPayments(amounts ...float64) []Charge {
+	var out []Charge
+	for i, a := range amounts {
+		out = append(out, Charge{Date: day(2026, time.January+time.Month(i), 5), Amount: a})
+	}
+	return out
+}
+
+func alertTypes(s Subscription, now time.Time) map[string]bool {
+	types := map[string]bool{}
+	for _, a := range Alerts(s, now) {
+		types[a.Type] = true
+	}
+	return types
+}
+
+func TestAlerts_PriceIncrease(t *testing.T) {
+	s := Subscription{BillID: "b1", Name: "Netflix", Frequency: "monthly", AmountDue: 15.49,
+		Payments: monthlyPayments(15.49, 15.49, 15.49, 17.99)}
+
+	alerts := Alerts(s, day(2026, 4, 10))
+	if len(alerts) != 1 || alerts[0].Type != "price_increase" {
+		t.Fatalf("expected one price increase, got %+v", alerts)
+	}
+	if alerts[0].Amount != 17.99 || *alerts[0].PreviousAmount != 15.49 {
+		t.Errorf("unexpected amounts: %+v", alerts[0])
+	}
+
+	// A few cents of tax drift isn't a price increase.
+	s.Payments = monthlyPayments(15.49, 15.49, 15.52)
+	if types := alertTypes(s, day(2026, 3, 10)); types["price_increase"] {
+		t.Error("rounding-sized change flagged as a price increase")
+	}
+}
+
+func TestAlerts_LateAndMissing(t *testing.T) {
+	s := Subscription{BillID: "b1", Name: "Spotify", Frequency: "monthly", AmountDue: 11.99,
+		Payments: monthlyPayments(11.99, 11.99, 11.99)} // last charged Mar 5
+
+	if types := alertTypes(s, day(2026, 4, 8)); len(types) != 0 {
+		t.Errorf("within the grace period, got %v", types)
+	}
+	if types := alertTypes(s, day(2026, 4, 14)); !types["late_charge"] {
+		t.Errorf("expected a late charge, got %v", types)
+	}
+	if types := alertTypes(s, day(2026, 5, 20)); !types["missing_charge"] || types["late_charge"] {
+		t.Errorf("expected only a missing charge, got %v", types)
+	}
+}
+
+func TestAlerts_Trial(t *testing.T) {
+	end := day(2026, 6, 10)
+	s := Subscription{BillID: "b1", Name: "Max", Frequency: "monthly", AmountDue: 16.99, TrialEndsAt: &end}
+
+	if types := alertTypes(s, day(2026, 6, 5)); !types["trial_ending"] || len(types) != 1 {
+		t.Errorf("expected only trial ending, got %v", types)
+	}
+	if types := alertTypes(s, day(2026, 5, 1)); len(types) != 0 {
+		t.Errorf("trial end is weeks away, got %v", types)
+	}
+
+	s.Payments = []Charge{{Date: day(2026, 6, 10), Amount: 16.99}}
+	if types := alertTypes(s, day(2026, 6, 12)); !types["trial_converted"] {
+		t.Errorf("expected trial converted, got %v", types)
+	}
+}
+
+func TestAlerts_AnnualRenewal(t *testing.T) {
+	s := Subscription{BillID: "b1", Name: "Prime", Frequency: "yearly", AmountDue: 139,
+		Payments: []Charge{{Date: day(2025, 7, 1), Amount: 139}}}
+
+	alerts := Alerts(s, day(2026, 6, 15))
+	if len(alerts) != 1 || alerts[0].Type != "annual_renewal" || alerts[0].Date != "2026-07-01" {
+		t.Fatalf("expected a renewal on Jul 1, got %+v", alerts)
+	}
+	if types := alertTypes(s, day(2026, 3, 1)); len(types) != 0 {
+		t.Errorf("renewal is months away, got %v", types)
+	}
+}
+
+func TestMonthlyCost(t *testing.T) {
+	cases := map[string]float64{"weekly": 43.33, "biweekly": 21.67, "monthly": 10, "quarterly": 3.33, "yearly": 0.83}
+	for freq, want := range cases {
+		if got := MonthlyCost(10, freq); got != want {
+			t.Errorf("MonthlyCost(10, %s) = %v, want %v", freq, got, want)
+		}
+	}
+}
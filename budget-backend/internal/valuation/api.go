@@ -0,0 +1,79 @@
+package valuation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// APIValuer asks a licensed automated valuation API for the property's
+// value. The API is called as
+//
+//	GET <base URL>?address=<street>&city=<city>&state=<state>&zip=<zip>
+//	Authorization: Bearer <key>
+//
+// and answers {"value": 412000, "as_of": "2026-03-01"}. A 404 means it has
+// no value for the address.
+type APIValuer struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewAPIValuer creates an APIValuer for the API at baseURL.
+func NewAPIValuer(baseURL, apiKey string) *APIValuer {
+	return &APIValuer{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 20 * time.Second},
+	}
+}
+
+func (a *APIValuer) Name() string { return "api" }
+
+func (a *APIValuer) Value(ctx context.Context, s Subject) (*Estimate, error) {
+	q := url.Values{}
+	q.Set("address", s.StreetAddress)
+	q.Set("city", s.City)
+	q.Set("state", s.State)
+	q.Set("zip", s.ZipCode)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("valuation API request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNoValue
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("valuation API returned %d: %s", resp.StatusCode, body)
+	}
+
+	var out struct {
+		Value float64 `json:"value"`
+		AsOf  string  `json:"as_of"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("valuation API response: %w", err)
+	}
+	if out.Value <= 0 {
+		return nil, ErrNoValue
+	}
+	asOf, ok := parseDay(out.AsOf)
+	if !ok {
+		asOf = time.Now().UTC()
+	}
+	return &Estimate{Value: out.Value, Source: a.Name(), AsOf: asOf}, nil
+}
@@ -0,0 +1,155 @@
+package valuation
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HPIValuer moves the last known value along a house price index. The index
+// is a CSV with a header row and the columns region, period and index:
+//
+//	region,period,index
+//	62701,2025-09,312.4
+//	627,2025Q3,305.1
+//	IL,2025-09,298.7
+//	US,2025-09,301.2
+//
+// A region is a 5-digit ZIP code, a 3-digit ZIP prefix, a state code or US;
+// the most specific one with data for the property is used. Periods are
+// months (YYYY-MM) or quarters (YYYYQn).
+type HPIValuer struct {
+	series map[string][]hpiPoint
+}
+
+type hpiPoint struct {
+	period time.Time // first day of the month or quarter
+	index  float64
+}
+
+// NewHPIValuer loads the index from a CSV file.
+func NewHPIValuer(path string) (*HPIValuer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open house price index: %w", err)
+	}
+	defer f.Close()
+	return ParseHPI(f)
+}
+
+// ParseHPI reads an index CSV.
+func ParseHPI(r io.Reader) (*HPIValuer, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read house price index: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("house price index is empty")
+	}
+
+	cols := map[string]int{}
+	for i, name := range rows[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"region", "period", "index"} {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("house price index is missing the %q column", name)
+		}
+	}
+
+	h := &HPIValuer{series: map[string][]hpiPoint{}}
+	for n, row := range rows[1:] {
+		line := n + 2
+		if len(row) < len(rows[0]) {
+			return nil, fmt.Errorf("house price index line %d: expected %d columns", line, len(rows[0]))
+		}
+		period, err := parsePeriod(strings.TrimSpace(row[cols["period"]]))
+		if err != nil {
+			return nil, fmt.Errorf("house price index line %d: %w", line, err)
+		}
+		index, err := strconv.ParseFloat(strings.TrimSpace(row[cols["index"]]), 64)
+		if err != nil || index <= 0 {
+			return nil, fmt.Errorf("house price index line %d: invalid index %q", line, row[cols["index"]])
+		}
+		region := strings.ToUpper(strings.TrimSpace(row[cols["region"]]))
+		h.series[region] = append(h.series[region], hpiPoint{period: period, index: index})
+	}
+	for _, points := range h.series {
+		sort.Slice(points, func(i, j int) bool { return points[i].period.Before(points[j].period) })
+	}
+	return h, nil
+}
+
+func (h *HPIValuer) Name() string { return "hpi" }
+
+// Value applies the change in the index since the most recent value that
+// didn't itself come from the index, so adjustments never compound.
+func (h *HPIValuer) Value(_ context.Context, s Subject) (*Estimate, error) {
+	var base float64
+	var baseOn time.Time
+	for i := len(s.History) - 1; i >= 0; i-- {
+		v := s.History[i]
+		on, ok := parseDay(v.ValuedOn)
+		if v.Source == h.Name() || !ok || v.Value <= 0 {
+			continue
+		}
+		base, baseOn = v.Value, on
+		break
+	}
+	if base == 0 {
+		return nil, fmt.Errorf("%w: no earlier value to adjust", ErrNoValue)
+	}
+
+	zip := strings.TrimSpace(s.ZipCode)
+	regions := []string{zip}
+	if len(zip) >= 3 {
+		regions = append(regions, zip[:3])
+	}
+	regions = append(regions, strings.ToUpper(strings.TrimSpace(s.State)), "US")
+	for _, region := range regions {
+		points := h.series[region]
+		from, ok := indexAt(points, baseOn)
+		if !ok {
+			continue
+		}
+		latest := points[len(points)-1]
+		if !latest.period.After(from.period) {
+			return nil, fmt.Errorf("%w: index has nothing newer than the last value", ErrNoValue)
+		}
+		value := math.Round(base*latest.index/from.index*100) / 100
+		return &Estimate{Value: value, Source: h.Name(), AsOf: latest.period}, nil
+	}
+	return nil, fmt.Errorf("%w: no index for %s %s", ErrNoValue, zip, s.State)
+}
+
+// indexAt is the index for the period containing t: the last point that
+// starts on or before it.
+func indexAt(points []hpiPoint, t time.Time) (hpiPoint, bool) {
+	i := sort.Search(len(points), func(i int) bool { return points[i].period.After(t) })
+	if i == 0 {
+		return hpiPoint{}, false
+	}
+	return points[i-1], true
+}
+
+// parsePeriod reads a month (2025-09) or a quarter (2025Q3).
+func parsePeriod(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01", s); err == nil {
+		return t, nil
+	}
+	if year, quarter, ok := strings.Cut(strings.ToUpper(s), "Q"); ok {
+		y, yerr := strconv.Atoi(year)
+		q, qerr := strconv.Atoi(quarter)
+		if yerr == nil && qerr == nil && q >= 1 && q <= 4 {
+			return time.Date(y, time.Month(3*q-2), 1, 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid period %q", s)
+}
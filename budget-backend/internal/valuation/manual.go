@@ -0,0 +1,38 @@
+package valuation
+
+import (
+	"context"
+	"time"
+)
+
+// manualMaxAge is how long a manual value or appraisal stays current. After
+// that the chain falls through to the house price index.
+const manualMaxAge = 365 * 24 * time.Hour
+
+// ManualValuer uses the most recent value the owner entered or an appraisal
+// they recorded, as long as it is under a year old.
+type ManualValuer struct {
+	now func() time.Time
+}
+
+// NewManualValuer creates a ManualValuer.
+func NewManualValuer() *ManualValuer {
+	return &ManualValuer{now: time.Now}
+}
+
+func (m *ManualValuer) Name() string { return "manual" }
+
+func (m *ManualValuer) Value(_ context.Context, s Subject) (*Estimate, error) {
+	for i := len(s.History) - 1; i >= 0; i-- {
+		v := s.History[i]
+		if v.Source != "manual" && v.Source != "appraisal" {
+			continue
+		}
+		on, ok := parseDay(v.ValuedOn)
+		if !ok || m.now().Sub(on) > manualMaxAge {
+			return nil, ErrNoValue
+		}
+		return &Estimate{Value: v.Value, Source: v.Source, AsOf: on}, nil
+	}
+	return nil, ErrNoValue
+}
@@ -0,0 +1,177 @@
+package valuation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+var home = Subject{
+	PropertyID:    "prop1",
+	StreetAddress: "1 Main St",
+	City:          "Springfield",
+	State:         "IL",
+	ZipCode:       "62701",
+}
+
+func withHistory(vals ...models.PropertyValuation) Subject {
+	s := home
+	s.History = vals
+	return s
+}
+
+type stubValuer struct {
+	name string
+	est  *Estimate
+	err  error
+}
+
+func (s stubValuer) Name() string { return s.name }
+
+func (s stubValuer) Value(context.Context, Subject) (*Estimate, error) { return s.est, s.err }
+
+func TestChain_FirstValueWins(t *testing.T) {
+	chain := Chain{
+		stubValuer{name: "api", err: ErrNoValue},
+		stubValuer{name: "manual", est: &Estimate{Value: 300000, Source: "appraisal"}},
+		stubValuer{name: "hpi", est: &Estimate{Value: 310000, Source: "hpi"}},
+	}
+	est, err := chain.Value(context.Background(), home)
+	if err != nil || est.Source != "appraisal" {
+		t.Fatalf("got %+v, %v", est, err)
+	}
+
+	_, err = Chain{stubValuer{name: "api", err: ErrNoValue}, stubValuer{name: "hpi"}}.Value(context.Background(), home)
+	if !errors.Is(err, ErrNoValue) {
+		t.Errorf("every provider empty should be ErrNoValue, got %v", err)
+	}
+	_, err = Chain{stubValuer{name: "api", err: errors.New("timeout")}, stubValuer{name: "hpi", err: ErrNoValue}}.Value(context.Background(), home)
+	if err == nil || errors.Is(err, ErrNoValue) || !strings.Contains(err.Error(), "api: timeout") {
+		t.Errorf("a failing provider should be reported, got %v", err)
+	}
+}
+
+func TestNewChainFromEnv(t *testing.T) {
+	t.Setenv("PROPERTY_VALUERS", "")
+	t.Setenv("VALUATION_API_URL", "")
+	t.Setenv("VALUATION_API_KEY", "")
+	t.Setenv("HPI_CSV_PATH", "")
+	chain, err := NewChainFromEnv()
+	if err != nil || len(chain) != 1 || chain[0].Name() != "manual" {
+		t.Fatalf("unconfigured default chain = %v, %v", chain, err)
+	}
+
+	t.Setenv("VALUATION_API_URL", "https://avm.example.com/v1/value")
+	t.Setenv("VALUATION_API_KEY", "key")
+	t.Setenv("PROPERTY_VALUERS", "manual, api")
+	chain, err = NewChainFromEnv()
+	if err != nil || len(chain) != 2 || chain[0].Name() != "manual" || chain[1].Name() != "api" {
+		t.Fatalf("configured chain = %v, %v", chain, err)
+	}
+
+	t.Setenv("PROPERTY_VALUERS", "hpi")
+	if _, err := NewChainFromEnv(); err == nil {
+		t.Error("hpi without HPI_CSV_PATH should fail")
+	}
+	t.Setenv("PROPERTY_VALUERS", "zillow")
+	if _, err := NewChainFromEnv(); err == nil {
+		t.Error("unknown provider should fail")
+	}
+}
+
+func TestAPIValuer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("zip") != "62701" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"value": 412000, "as_of": "2026-03-01"}`))
+	}))
+	defer srv.Close()
+
+	est, err := NewAPIValuer(srv.URL, "key").Value(context.Background(), home)
+	if err != nil || est.Value != 412000 || est.Source != "api" || est.AsOf.Format("2006-01-02") != "2026-03-01" {
+		t.Fatalf("got %+v, %v", est, err)
+	}
+
+	other := home
+	other.ZipCode = "10001"
+	if _, err := NewAPIValuer(srv.URL, "key").Value(context.Background(), other); !errors.Is(err, ErrNoValue) {
+		t.Errorf("404 should be ErrNoValue, got %v", err)
+	}
+	if _, err := NewAPIValuer(srv.URL, "wrong").Value(context.Background(), home); err == nil || errors.Is(err, ErrNoValue) {
+		t.Errorf("401 should be a failure, got %v", err)
+	}
+}
+
+func TestManualValuer(t *testing.T) {
+	m := &ManualValuer{now: func() time.Time { return time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC) }}
+
+	est, err := m.Value(context.Background(), withHistory(
+		models.PropertyValuation{Value: 280000, Source: "manual", ValuedOn: "2025-06-01"},
+		models.PropertyValuation{Value: 295000, Source: "appraisal", ValuedOn: "2025-11-20"},
+		models.PropertyValuation{Value: 305000, Source: "api", ValuedOn: "2026-02-01"},
+	))
+	if err != nil || est.Value != 295000 || est.Source != "appraisal" {
+		t.Fatalf("got %+v, %v", est, err)
+	}
+
+	_, err = m.Value(context.Background(), withHistory(
+		models.PropertyValuation{Value: 280000, Source: "manual", ValuedOn: "2024-12-01"},
+	))
+	if !errors.Is(err, ErrNoValue) {
+		t.Errorf("a value over a year old should be skipped, got %v", err)
+	}
+}
+
+const testIndex = `region,period,index
+62701,2025-06,200
+62701,2026-01,210
+IL,2025Q1,300
+IL,2025Q4,330
+US,2026-01,400
+`
+
+func TestHPIValuer(t *testing.T) {
+	h, err := ParseHPI(strings.NewReader(testIndex))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ZIP index up 5% since the appraisal; the earlier hpi value is ignored.
+	est, err := h.Value(context.Background(), withHistory(
+		models.PropertyValuation{Value: 300000, Source: "appraisal", ValuedOn: "2025-07-10"},
+		models.PropertyValuation{Value: 301000, Source: "hpi", ValuedOn: "2025-09-01"},
+	))
+	if err != nil || est.Value != 315000 || est.AsOf.Format("2006-01") != "2026-01" {
+		t.Fatalf("zip index: got %+v, %v", est, err)
+	}
+
+	// No ZIP data before the value's date falls back to the state's quarters.
+	s := withHistory(models.PropertyValuation{Value: 300000, Source: "manual", ValuedOn: "2025-02-14"})
+	est, err = h.Value(context.Background(), s)
+	if err != nil || est.Value != 330000 {
+		t.Fatalf("state index: got %+v, %v", est, err)
+	}
+
+	if _, err := h.Value(context.Background(), home); !errors.Is(err, ErrNoValue) {
+		t.Errorf("no history should be ErrNoValue, got %v", err)
+	}
+
+	if _, err := ParseHPI(strings.NewReader("region,index\nUS,1\n")); err == nil {
+		t.Error("missing period column should fail")
+	}
+	if _, err := ParseHPI(strings.NewReader("region,period,index\nUS,March,1\n")); err == nil {
+		t.Error("bad period should fail")
+	}
+}
@@ -0,0 +1,135 @@
+// Package valuation estimates what a property is worth from a chain of
+// providers: a licensed valuation API, the owner's own manual or appraisal
+// values, and a house price index applied to the last known value.
+package valuation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aboogie/budget-backend/models"
+)
+
+// ErrNoValue means a provider has no value for the property. The chain moves
+// on to the next provider.
+var ErrNoValue = errors.New("no value available")
+
+// Subject is the property being valued, with its valuation history oldest
+// first.
+type Subject struct {
+	PropertyID    string
+	StreetAddress string
+	City          string
+	State         string
+	ZipCode       string
+	History       []models.PropertyValuation
+}
+
+// Estimate is a value from one provider. Source is recorded with the value
+// in the property's valuation history.
+type Estimate struct {
+	Value  float64
+	Source string
+	AsOf   time.Time
+}
+
+// PropertyValuer estimates a property's current value.
+type PropertyValuer interface {
+	// Name identifies the provider in PROPERTY_VALUERS.
+	Name() string
+
+	// Value returns the provider's estimate, or an error wrapping
+	// ErrNoValue when it has nothing for this property.
+	Value(ctx context.Context, s Subject) (*Estimate, error)
+}
+
+// Chain asks each provider in turn and returns the first value.
+type Chain []PropertyValuer
+
+// Value returns the first provider's estimate. If none has one, the error
+// says why each provider came up empty, and wraps ErrNoValue only when none
+// of them failed outright.
+func (c Chain) Value(ctx context.Context, s Subject) (*Estimate, error) {
+	if len(c) == 0 {
+		return nil, fmt.Errorf("%w: no valuation providers configured", ErrNoValue)
+	}
+	var reasons []string
+	failed := false
+	for _, v := range c {
+		est, err := v.Value(ctx, s)
+		if err == nil && est != nil && est.Value > 0 {
+			return est, nil
+		}
+		if err == nil {
+			err = ErrNoValue
+		}
+		if !errors.Is(err, ErrNoValue) {
+			failed = true
+		}
+		reasons = append(reasons, v.Name()+": "+err.Error())
+	}
+	if failed {
+		return nil, fmt.Errorf("valuation failed (%s)", strings.Join(reasons, "; "))
+	}
+	return nil, fmt.Errorf("%w (%s)", ErrNoValue, strings.Join(reasons, "; "))
+}
+
+// NewChainFromEnv builds the provider chain selected by the environment.
+// Environment variables:
+//   - PROPERTY_VALUERS: comma-separated providers in the order to try them,
+//     from "api", "manual" and "hpi" (default: all that are configured, in
+//     that order)
+//   - VALUATION_API_URL, VALUATION_API_KEY: the licensed valuation API
+//   - HPI_CSV_PATH: house price index CSV for the "hpi" provider
+func NewChainFromEnv() (Chain, error) {
+	apiURL, apiKey := os.Getenv("VALUATION_API_URL"), os.Getenv("VALUATION_API_KEY")
+	hpiPath := os.Getenv("HPI_CSV_PATH")
+
+	names := strings.Split(os.Getenv("PROPERTY_VALUERS"), ",")
+	explicit := os.Getenv("PROPERTY_VALUERS") != ""
+	if !explicit {
+		names = []string{"api", "manual", "hpi"}
+	}
+
+	var chain Chain
+	for _, name := range names {
+		switch name = strings.TrimSpace(name); name {
+		case "api":
+			if apiURL == "" || apiKey == "" {
+				if explicit {
+					return nil, fmt.Errorf("valuation provider %q needs VALUATION_API_URL and VALUATION_API_KEY", name)
+				}
+				continue
+			}
+			chain = append(chain, NewAPIValuer(apiURL, apiKey))
+		case "manual":
+			chain = append(chain, NewManualValuer())
+		case "hpi":
+			if hpiPath == "" {
+				if explicit {
+					return nil, fmt.Errorf("valuation provider %q needs HPI_CSV_PATH", name)
+				}
+				continue
+			}
+			v, err := NewHPIValuer(hpiPath)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, v)
+		case "":
+		default:
+			return nil, fmt.Errorf("unsupported valuation provider %q", name)
+		}
+	}
+	return chain, nil
+}
+
+// parseDay reads a YYYY-MM-DD valuation date.
+func parseDay(s string) (time.Time, bool) {
+	t, err := time.Parse("2006-01-02", s)
+	return t, err == nil
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_investment_transactions_linked;
+DROP INDEX IF EXISTS idx_investment_transactions_household;
+DROP INDEX IF EXISTS idx_investment_transactions_user;
+
+DROP TABLE IF EXISTS investment_transactions;
@@ -0,0 +1,31 @@
+-- Investment transactions from Plaid (buys, sells, dividends, fees)
+CREATE TABLE IF NOT EXISTS investment_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id TEXT NOT NULL,
+    household_id UUID REFERENCES households(id),
+    linked_account_id UUID REFERENCES linked_accounts(id) ON DELETE CASCADE,
+    plaid_account_id TEXT NOT NULL,
+    plaid_investment_transaction_id TEXT NOT NULL UNIQUE,
+    plaid_security_id TEXT,
+    -- Security info (denormalized, same as investment_holdings)
+    security_name TEXT,
+    ticker_symbol TEXT,
+    security_type TEXT,
+    -- Transaction data. Plaid sign convention: amount > 0 is cash leaving the
+    -- account (buy, fee), amount < 0 is cash arriving (sell, dividend).
+    type TEXT NOT NULL,       -- buy, sell, cash, fee, transfer, cancel
+    subtype TEXT,             -- dividend, interest, contribution, ...
+    name TEXT,
+    quantity NUMERIC NOT NULL DEFAULT 0,
+    price NUMERIC NOT NULL DEFAULT 0,
+    amount NUMERIC NOT NULL DEFAULT 0,
+    fees NUMERIC NOT NULL DEFAULT 0,
+    iso_currency_code TEXT DEFAULT 'USD',
+    date DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_investment_transactions_user ON investment_transactions(user_id, date);
+CREATE INDEX idx_investment_transactions_household ON investment_transactions(household_id);
+CREATE INDEX idx_investment_transactions_linked ON investment_transactions(linked_account_id);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_transactions_account_balance;
+ALTER TABLE transactions DROP COLUMN IF EXISTS account_balance_id;
+
+DROP INDEX IF EXISTS idx_account_balance_history_account;
+DROP TABLE IF EXISTS account_balance_history;
+
+DELETE FROM account_balances WHERE is_manual = true;
+
+ALTER TABLE account_balances
+    DROP COLUMN IF EXISTS notes,
+    DROP COLUMN IF EXISTS is_liability,
+    DROP COLUMN IF EXISTS is_manual;
@@ -0,0 +1,30 @@
+-- Manual (offline) accounts live alongside linked ones in account_balances.
+-- They have no linked_account_id and use a synthetic 'manual:<id>'
+-- plaid_account_id so the (user_id, plaid_account_id) unique index still holds.
+ALTER TABLE account_balances
+    ADD COLUMN IF NOT EXISTS is_manual BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS is_liability BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS notes TEXT;
+
+-- Linked credit cards and loans are liabilities.
+UPDATE account_balances SET is_liability = true WHERE type IN ('credit', 'loan');
+
+-- Value history for any account (manual value updates, manual transactions).
+CREATE TABLE IF NOT EXISTS account_balance_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_balance_id UUID NOT NULL REFERENCES account_balances(id) ON DELETE CASCADE,
+    balance NUMERIC NOT NULL,
+    recorded_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    source TEXT NOT NULL DEFAULT 'manual', -- manual, transaction, sync
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_balance_history_account
+    ON account_balance_history(account_balance_id, recorded_on);
+
+-- Manual transactions posted against a manual account.
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS account_balance_id UUID REFERENCES account_balances(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_account_balance ON transactions(account_balance_id);
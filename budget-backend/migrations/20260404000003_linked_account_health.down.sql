@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS linked_account_sync_status;
+ALTER TABLE linked_accounts DROP COLUMN IF EXISTS reauth_notified_at;
+ALTER TABLE linked_accounts DROP COLUMN IF EXISTS consent_expiration_time;
@@ -0,0 +1,16 @@
+-- Consent expiry and re-auth notification tracking per Plaid item
+ALTER TABLE linked_accounts ADD COLUMN IF NOT EXISTS consent_expiration_time TIMESTAMPTZ;
+ALTER TABLE linked_accounts ADD COLUMN IF NOT EXISTS reauth_notified_at TIMESTAMPTZ;
+
+-- Last sync outcome per linked account and product (transactions, balances,
+-- investments, liabilities)
+CREATE TABLE IF NOT EXISTS linked_account_sync_status (
+    linked_account_id UUID NOT NULL REFERENCES linked_accounts(id) ON DELETE CASCADE,
+    product TEXT NOT NULL,
+    last_attempt_at TIMESTAMPTZ,
+    last_success_at TIMESTAMPTZ,
+    last_error_code TEXT,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (linked_account_id, product)
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_account_balances_excluded;
+ALTER TABLE account_balances
+    DROP COLUMN IF EXISTS scope,
+    DROP COLUMN IF EXISTS include_in_budgets,
+    DROP COLUMN IF EXISTS sync_enabled;
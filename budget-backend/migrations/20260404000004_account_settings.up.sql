@@ -0,0 +1,12 @@
+-- Per-account settings for linked accounts, independent of the Plaid item:
+--   sync_enabled        false = skip balances and transactions for this account
+--   include_in_budgets  false = keep syncing but leave out of budgets and insights
+--   scope               inherit (follow the item), personal, or shared
+ALTER TABLE account_balances
+    ADD COLUMN IF NOT EXISTS sync_enabled BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN IF NOT EXISTS include_in_budgets BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN IF NOT EXISTS scope TEXT NOT NULL DEFAULT 'inherit'
+        CHECK (scope IN ('inherit', 'personal', 'shared'));
+
+CREATE INDEX IF NOT EXISTS idx_account_balances_excluded
+    ON account_balances(id) WHERE include_in_budgets = false;
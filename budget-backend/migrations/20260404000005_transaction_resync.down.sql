@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS plaid_resync_jobs;
+DROP INDEX IF EXISTS idx_transactions_plaid_transaction_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS plaid_transaction_id;
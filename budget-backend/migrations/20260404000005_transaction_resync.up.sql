@@ -0,0 +1,25 @@
+-- Provider transaction IDs let re-imports dedupe against rows we already have.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS plaid_transaction_id TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_plaid_transaction_id
+    ON transactions(plaid_transaction_id) WHERE plaid_transaction_id IS NOT NULL;
+
+-- Background re-imports of a linked item's transaction history.
+CREATE TABLE IF NOT EXISTS plaid_resync_jobs (
+    id UUID PRIMARY KEY,
+    linked_account_id UUID NOT NULL REFERENCES linked_accounts(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mode TEXT NOT NULL CHECK (mode IN ('backfill', 'rebuild')),
+    status TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'completed', 'failed')),
+    pages_fetched INTEGER NOT NULL DEFAULT 0,
+    added INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    removed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_plaid_resync_jobs_account
+    ON plaid_resync_jobs(linked_account_id, created_at DESC);
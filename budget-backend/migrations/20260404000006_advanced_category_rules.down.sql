@@ -0,0 +1,15 @@
+DELETE FROM category_mapping_rules WHERE rule_type = 'advanced' OR category_id IS NULL;
+ALTER TABLE category_mapping_rules DROP CONSTRAINT IF EXISTS category_mapping_rules_advanced_conditions;
+ALTER TABLE category_mapping_rules DROP CONSTRAINT IF EXISTS category_mapping_rules_has_action;
+ALTER TABLE category_mapping_rules
+    DROP COLUMN IF EXISTS mark_transfer,
+    DROP COLUMN IF EXISTS tag,
+    DROP COLUMN IF EXISTS split_template,
+    DROP COLUMN IF EXISTS budget_id,
+    DROP COLUMN IF EXISTS conditions,
+    DROP COLUMN IF EXISTS name;
+ALTER TABLE category_mapping_rules ALTER COLUMN category_id SET NOT NULL;
+
+ALTER TABLE category_mapping_rules DROP CONSTRAINT IF EXISTS category_mapping_rules_rule_type_check;
+ALTER TABLE category_mapping_rules ADD CONSTRAINT category_mapping_rules_rule_type_check
+    CHECK (rule_type IN ('merchant', 'plaid_category', 'keyword'));
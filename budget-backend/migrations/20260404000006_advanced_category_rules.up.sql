@@ -0,0 +1,23 @@
+-- Multi-condition ("advanced") rules. Conditions are ANDed together; actions
+-- can set a category, budget, split template, tag, or mark a transfer.
+ALTER TABLE category_mapping_rules DROP CONSTRAINT IF EXISTS category_mapping_rules_rule_type_check;
+ALTER TABLE category_mapping_rules ADD CONSTRAINT category_mapping_rules_rule_type_check
+    CHECK (rule_type IN ('merchant', 'plaid_category', 'keyword', 'advanced'));
+
+ALTER TABLE category_mapping_rules ALTER COLUMN category_id DROP NOT NULL;
+ALTER TABLE category_mapping_rules
+    ADD COLUMN IF NOT EXISTS name TEXT,
+    -- {"merchant_regex", "amount_min", "amount_max", "account_balance_id",
+    --  "transaction_type", "day_of_month_min", "day_of_month_max"}
+    ADD COLUMN IF NOT EXISTS conditions JSONB,
+    ADD COLUMN IF NOT EXISTS budget_id UUID REFERENCES budgets(id) ON DELETE SET NULL,
+    -- [{"category_id", "percent"}], percents sum to 100
+    ADD COLUMN IF NOT EXISTS split_template JSONB,
+    ADD COLUMN IF NOT EXISTS tag TEXT,
+    ADD COLUMN IF NOT EXISTS mark_transfer BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE category_mapping_rules ADD CONSTRAINT category_mapping_rules_has_action
+    CHECK (category_id IS NOT NULL OR budget_id IS NOT NULL OR split_template IS NOT NULL
+           OR tag IS NOT NULL OR mark_transfer);
+ALTER TABLE category_mapping_rules ADD CONSTRAINT category_mapping_rules_advanced_conditions
+    CHECK (rule_type <> 'advanced' OR (conditions IS NOT NULL AND conditions <> '{}'::jsonb));
@@ -0,0 +1,13 @@
+-- Audit trail for rules applied retroactively to existing transactions.
+CREATE TABLE IF NOT EXISTS category_rule_applications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_id UUID NOT NULL REFERENCES category_mapping_rules(id) ON DELETE CASCADE,
+    transaction_id UUID NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    previous_category_id UUID REFERENCES categories(id),
+    new_category_id UUID NOT NULL REFERENCES categories(id),
+    applied_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rule_applications_rule ON category_rule_applications(rule_id, applied_at DESC);
+CREATE INDEX IF NOT EXISTS idx_rule_applications_transaction ON category_rule_applications(transaction_id);
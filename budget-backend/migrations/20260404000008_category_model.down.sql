@@ -0,0 +1,3 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS model_category_id;
+DROP TABLE IF EXISTS category_model_features;
+DROP TABLE IF EXISTS category_model_classes;
@@ -0,0 +1,23 @@
+-- Per-household naive Bayes categorization model. scope_key is the household
+-- id, or the user id for users without a household.
+CREATE TABLE IF NOT EXISTS category_model_classes (
+    scope_key UUID NOT NULL,
+    category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    doc_count INTEGER NOT NULL DEFAULT 0,
+    feature_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (scope_key, category_id)
+);
+
+CREATE TABLE IF NOT EXISTS category_model_features (
+    scope_key UUID NOT NULL,
+    category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    feature TEXT NOT NULL, -- m:<merchant token>, a:<amount bucket>, t:<type>, p:<plaid category>
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (scope_key, feature, category_id)
+);
+
+-- The category this row last contributed to the model, so corrections can
+-- move the example instead of counting it twice.
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS model_category_id UUID REFERENCES categories(id) ON DELETE SET NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE bills DROP COLUMN IF EXISTS merchant_id;
+DROP INDEX IF EXISTS idx_mapping_rules_merchant;
+ALTER TABLE category_mapping_rules DROP COLUMN IF EXISTS merchant_id;
+DROP INDEX IF EXISTS idx_transactions_merchant;
+ALTER TABLE transactions DROP COLUMN IF EXISTS merchant_id;
+DROP TABLE IF EXISTS merchant_category_defaults;
+DROP TABLE IF EXISTS merchants;
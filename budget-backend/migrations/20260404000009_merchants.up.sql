@@ -0,0 +1,35 @@
+-- Canonical merchants shared across users. normalized_key is the output of
+-- the merchant normalizer (lowercase, no store numbers or locations); color
+-- is derived from it so every merchant has one even without a logo.
+CREATE TABLE IF NOT EXISTS merchants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    normalized_key TEXT NOT NULL UNIQUE,
+    logo_url TEXT,
+    color TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- A merchant's default category. Merchants are shared, so defaults belong
+-- to a household, or to a user who isn't in one, like tags do.
+CREATE TABLE IF NOT EXISTS merchant_category_defaults (
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    household_id UUID REFERENCES households(id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_merchant_category_defaults_scope
+    ON merchant_category_defaults (merchant_id, COALESCE(household_id::text, user_id::text));
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS merchant_id UUID REFERENCES merchants(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_merchant ON transactions(merchant_id) WHERE merchant_id IS NOT NULL;
+
+ALTER TABLE category_mapping_rules
+    ADD COLUMN IF NOT EXISTS merchant_id UUID REFERENCES merchants(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_mapping_rules_merchant ON category_mapping_rules(merchant_id) WHERE merchant_id IS NOT NULL;
+
+ALTER TABLE bills
+    ADD COLUMN IF NOT EXISTS merchant_id UUID REFERENCES merchants(id) ON DELETE SET NULL;
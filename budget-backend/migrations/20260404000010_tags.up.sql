@@ -0,0 +1,30 @@
+-- Cross-cutting labels ("reimbursable", "vacation-2026") that sit alongside
+-- the category hierarchy. Tags belong to a household, or to a user who isn't
+-- in one; names are unique per scope, case-insensitively.
+CREATE TABLE IF NOT EXISTS tags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    household_id UUID REFERENCES households(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    color TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_scope_name
+    ON tags (COALESCE(household_id::text, user_id::text), LOWER(name));
+
+CREATE TABLE IF NOT EXISTS transaction_tags (
+    transaction_id UUID NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (transaction_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_transaction_tags_tag ON transaction_tags(tag_id);
+
+CREATE TABLE IF NOT EXISTS split_tags (
+    split_id UUID NOT NULL REFERENCES transaction_splits(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (split_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_split_tags_tag ON split_tags(tag_id);
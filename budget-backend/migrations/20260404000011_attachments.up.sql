@@ -0,0 +1,20 @@
+-- Receipts and documents attached to a transaction or a bill payment. The
+-- bytes live in the blob store under storage_key; household_id is copied
+-- from the parent so access follows it.
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    household_id UUID REFERENCES households(id) ON DELETE SET NULL,
+    transaction_id UUID REFERENCES transactions(id) ON DELETE CASCADE,
+    bill_payment_id UUID REFERENCES bill_payments(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    thumbnail_key TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT attachments_one_parent CHECK (num_nonnulls(transaction_id, bill_payment_id) = 1)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_transaction ON attachments(transaction_id) WHERE transaction_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_attachments_bill_payment ON attachments(bill_payment_id) WHERE bill_payment_id IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS settlements;
+DROP TABLE IF EXISTS transaction_shares;
+ALTER TABLE household_members DROP COLUMN IF EXISTS split_ratio;
+ALTER TABLE households DROP COLUMN IF EXISTS split_method;
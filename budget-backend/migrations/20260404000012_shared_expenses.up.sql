@@ -0,0 +1,40 @@
+-- How a household divides shared expenses by default. 'equal' splits evenly,
+-- 'income' in proportion to each member's income over the last 90 days, and
+-- 'custom' uses household_members.split_ratio.
+ALTER TABLE households ADD COLUMN IF NOT EXISTS split_method TEXT NOT NULL DEFAULT 'equal'
+    CHECK (split_method IN ('equal', 'income', 'custom'));
+ALTER TABLE household_members ADD COLUMN IF NOT EXISTS split_ratio NUMERIC(5,4)
+    CHECK (split_ratio >= 0 AND split_ratio <= 1);
+
+-- Each member's share of a transaction, or of one split of it. The member
+-- who owns the transaction paid it; every other member's share is owed to
+-- them.
+CREATE TABLE IF NOT EXISTS transaction_shares (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    split_id UUID REFERENCES transaction_splits(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(12,2) NOT NULL CHECK (amount >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transaction_shares_member
+    ON transaction_shares(transaction_id, COALESCE(split_id, '00000000-0000-0000-0000-000000000000'::uuid), user_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_shares_user ON transaction_shares(user_id);
+
+-- Payments between members that clear what one owes the other. The
+-- matching transfer transaction is kept so it shows in the payer's history.
+CREATE TABLE IF NOT EXISTS settlements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    household_id UUID NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    from_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+    transaction_id UUID REFERENCES transactions(id) ON DELETE SET NULL,
+    note TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_settlements_household ON settlements(household_id, created_at DESC);
@@ -0,0 +1,22 @@
+-- Expenses fronted for someone else (an employer, a friend) and expected
+-- back. matched_transaction_id is the incoming payment once it arrives.
+CREATE TABLE IF NOT EXISTS reimbursements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL UNIQUE REFERENCES transactions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    household_id UUID REFERENCES households(id) ON DELETE SET NULL,
+    expected_amount NUMERIC(12,2) NOT NULL CHECK (expected_amount > 0),
+    source TEXT NOT NULL,
+    payer_keywords TEXT[] NOT NULL DEFAULT '{}',
+    window_days INTEGER NOT NULL DEFAULT 60 CHECK (window_days BETWEEN 1 AND 365),
+    status TEXT NOT NULL DEFAULT 'outstanding' CHECK (status IN ('outstanding', 'received', 'written_off')),
+    received_amount NUMERIC(12,2),
+    matched_transaction_id UUID UNIQUE REFERENCES transactions(id) ON DELETE SET NULL,
+    received_at DATE,
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reimbursements_outstanding ON reimbursements(user_id) WHERE status = 'outstanding';
+CREATE INDEX IF NOT EXISTS idx_reimbursements_household ON reimbursements(household_id) WHERE household_id IS NOT NULL;
@@ -0,0 +1,26 @@
+-- Recurring charges found in transaction history and proposed as bills.
+-- One row per user and merchant; accepted and ignored rows are kept so the
+-- same merchant is never suggested again.
+CREATE TABLE IF NOT EXISTS bill_suggestions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    merchant_key TEXT NOT NULL,
+    merchant_id UUID REFERENCES merchants(id) ON DELETE SET NULL,
+    category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    name TEXT NOT NULL,
+    frequency TEXT NOT NULL,
+    due_day INTEGER NOT NULL CHECK (due_day BETWEEN 1 AND 31),
+    typical_amount NUMERIC(12,2) NOT NULL,
+    min_amount NUMERIC(12,2) NOT NULL,
+    max_amount NUMERIC(12,2) NOT NULL,
+    amount_variance NUMERIC(6,4) NOT NULL DEFAULT 0,
+    confidence NUMERIC(4,2) NOT NULL,
+    occurrences INTEGER NOT NULL,
+    last_charged DATE NOT NULL,
+    next_expected DATE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'suggested' CHECK (status IN ('suggested', 'accepted', 'ignored')),
+    bill_id UUID REFERENCES bills(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, merchant_key)
+);
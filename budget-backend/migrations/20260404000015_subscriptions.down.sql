@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_bill_payments_bill_paid;
+DROP INDEX IF EXISTS idx_bills_subscriptions;
+ALTER TABLE bills
+    DROP COLUMN IF EXISTS trial_ends_at,
+    DROP COLUMN IF EXISTS is_subscription;
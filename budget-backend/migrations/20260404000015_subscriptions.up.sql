@@ -0,0 +1,8 @@
+-- Subscriptions are bills flagged by the user. trial_ends_at marks a free
+-- trial that turns into a paid charge on that date.
+ALTER TABLE bills
+    ADD COLUMN IF NOT EXISTS is_subscription BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS trial_ends_at DATE;
+
+CREATE INDEX IF NOT EXISTS idx_bills_subscriptions ON bills(user_id) WHERE is_subscription;
+CREATE INDEX IF NOT EXISTS idx_bill_payments_bill_paid ON bill_payments(bill_id, paid_date);
@@ -0,0 +1,3 @@
+ALTER TABLE bills
+    DROP COLUMN IF EXISTS forecast_alert_pct,
+    DROP COLUMN IF EXISTS is_variable;
@@ -0,0 +1,6 @@
+-- Variable bills (utilities) are forecast from their payment history instead
+-- of using amount_due. forecast_alert_pct is how far a charge may stray from
+-- the forecast before the user is told; NULL uses the default.
+ALTER TABLE bills
+    ADD COLUMN IF NOT EXISTS is_variable BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS forecast_alert_pct NUMERIC(5,2) CHECK (forecast_alert_pct > 0);
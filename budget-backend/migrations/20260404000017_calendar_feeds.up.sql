@@ -0,0 +1,10 @@
+-- Each user can publish one read-only iCalendar feed of bill due dates,
+-- recurring income, debt payments and plan milestones. The token is the only
+-- credential the calendar app holds, so rotating or deleting the row revokes
+-- every subscriber at once.
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_accessed_at TIMESTAMPTZ
+);
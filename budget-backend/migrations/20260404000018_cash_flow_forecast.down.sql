@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cash_flow_alerts;
+ALTER TABLE account_balances DROP COLUMN IF EXISTS low_balance_threshold;
@@ -0,0 +1,14 @@
+-- Checking accounts warn when the cash-flow forecast dips below this balance.
+-- NULL warns only before an overdraft (below zero).
+ALTER TABLE account_balances
+    ADD COLUMN IF NOT EXISTS low_balance_threshold NUMERIC CHECK (low_balance_threshold >= 0);
+
+-- One push per account and predicted low date, so the daily run doesn't
+-- repeat the same warning.
+CREATE TABLE IF NOT EXISTS cash_flow_alerts (
+    account_balance_id UUID NOT NULL REFERENCES account_balances(id) ON DELETE CASCADE,
+    predicted_date DATE NOT NULL,
+    projected_balance NUMERIC NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (account_balance_id, predicted_date)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE debt_accounts
+    DROP COLUMN IF EXISTS unpaid_interest,
+    DROP COLUMN IF EXISTS interest_accrued_through;
+
+DROP TABLE IF EXISTS debt_payments;
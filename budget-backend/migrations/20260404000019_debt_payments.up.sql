@@ -0,0 +1,26 @@
+-- Ledger of payments, monthly interest and balance adjustments per debt.
+CREATE TABLE IF NOT EXISTS debt_payments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    debt_account_id UUID NOT NULL REFERENCES debt_accounts(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('opening', 'payment', 'interest', 'adjustment')),
+    amount NUMERIC(12,2) NOT NULL,
+    principal NUMERIC(12,2) NOT NULL DEFAULT 0,
+    interest NUMERIC(12,2) NOT NULL DEFAULT 0,
+    balance_after NUMERIC(12,2) NOT NULL,
+    paid_on DATE NOT NULL,
+    transaction_id UUID REFERENCES transactions(id) ON DELETE SET NULL,
+    bill_payment_id UUID REFERENCES bill_payments(id) ON DELETE SET NULL,
+    source TEXT NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'bill', 'plaid', 'accrual')),
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_debt_payments_debt ON debt_payments(debt_account_id, paid_on);
+
+-- Interest is charged at each month end; interest_accrued_through is the
+-- last date charged and unpaid_interest is what the next payment covers
+-- before principal.
+ALTER TABLE debt_accounts
+    ADD COLUMN IF NOT EXISTS interest_accrued_through DATE,
+    ADD COLUMN IF NOT EXISTS unpaid_interest NUMERIC(12,2) NOT NULL DEFAULT 0;
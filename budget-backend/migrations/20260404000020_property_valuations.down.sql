@@ -0,0 +1,3 @@
+ALTER TABLE properties DROP COLUMN IF EXISTS original_value;
+
+DROP TABLE IF EXISTS property_valuations;
@@ -0,0 +1,25 @@
+-- Valuation history per property. Each refresh, manual value or appraisal
+-- adds a row so equity and LTV can be charted over time.
+CREATE TABLE IF NOT EXISTS property_valuations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    property_id UUID NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    value NUMERIC(14,2) NOT NULL CHECK (value > 0),
+    source TEXT NOT NULL,
+    valued_on DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_property_valuations_property ON property_valuations(property_id, valued_on);
+
+-- Seed the history with the values already on file.
+INSERT INTO property_valuations (property_id, value, source, valued_on)
+SELECT id, zestimate, 'zillow', COALESCE(last_fetched_at, updated_at, created_at)::date
+FROM properties WHERE zestimate > 0;
+
+INSERT INTO property_valuations (property_id, value, source, valued_on)
+SELECT id, manual_value, 'manual', COALESCE(updated_at, created_at)::date
+FROM properties WHERE manual_value > 0;
+
+-- The home's value when the mortgage was taken out (purchase price or
+-- original appraisal). PMI cancellation is measured against it.
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS original_value NUMERIC;
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_cash_flow_alerts_open;
+DELETE FROM cash_flow_alerts a
+WHERE EXISTS (
+    SELECT 1 FROM cash_flow_alerts b
+    WHERE b.account_balance_id = a.account_balance_id
+      AND b.predicted_date = a.predicted_date
+      AND (b.sent_at, b.id) > (a.sent_at, a.id)
+);
+ALTER TABLE cash_flow_alerts DROP COLUMN IF EXISTS cleared_at;
+ALTER TABLE cash_flow_alerts DROP CONSTRAINT IF EXISTS cash_flow_alerts_pkey;
+ALTER TABLE cash_flow_alerts DROP COLUMN IF EXISTS id;
+ALTER TABLE cash_flow_alerts ADD PRIMARY KEY (account_balance_id, predicted_date);
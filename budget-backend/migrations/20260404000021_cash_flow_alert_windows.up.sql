@@ -0,0 +1,18 @@
+-- A low-balance warning stays open until the account's forecast recovers
+-- above its threshold, so a low date that moves from day to day doesn't
+-- warn again each time. At most one open warning per account.
+ALTER TABLE cash_flow_alerts DROP CONSTRAINT IF EXISTS cash_flow_alerts_pkey;
+ALTER TABLE cash_flow_alerts ADD COLUMN IF NOT EXISTS id UUID NOT NULL DEFAULT gen_random_uuid() PRIMARY KEY;
+ALTER TABLE cash_flow_alerts ADD COLUMN IF NOT EXISTS cleared_at TIMESTAMPTZ;
+
+-- Keep the latest warning per account open.
+UPDATE cash_flow_alerts a SET cleared_at = NOW()
+WHERE cleared_at IS NULL
+  AND EXISTS (
+      SELECT 1 FROM cash_flow_alerts b
+      WHERE b.account_balance_id = a.account_balance_id
+        AND (b.sent_at, b.id) > (a.sent_at, a.id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cash_flow_alerts_open
+    ON cash_flow_alerts (account_balance_id) WHERE cleared_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_plaid_account;
+ALTER TABLE transactions DROP COLUMN IF EXISTS plaid_account_id;
@@ -0,0 +1,7 @@
+-- The Plaid account a bank transaction was posted to. Rows synced before
+-- their account_balances row existed have no account_balance_id; the budget
+-- account filter falls back to this, and sync fills both in when Plaid sends
+-- the transaction again (for example during a resync).
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS plaid_account_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_transactions_plaid_account
+    ON transactions(user_id, plaid_account_id) WHERE plaid_account_id IS NOT NULL;
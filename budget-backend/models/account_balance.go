@@ -3,20 +3,83 @@ package models
 import "time"
 
 type AccountBalance struct {
+	ID               string   `json:"id"`
+	UserID           string   `json:"user_id"`
+	HouseholdID      *string  `json:"household_id,omitempty"`
+	LinkedAccountID  *string  `json:"linked_account_id,omitempty"`
+	PlaidAccountID   string   `json:"plaid_account_id"`
+	Name             string   `json:"name"`
+	OfficialName     *string  `json:"official_name,omitempty"`
+	Type             string   `json:"type"`
+	Subtype          *string  `json:"subtype,omitempty"`
+	CurrentBalance   float64  `json:"current_balance"`
+	AvailableBalance *float64 `json:"available_balance,omitempty"`
+	IsoCurrencyCode  string   `json:"iso_currency_code"`
+	InstitutionName  *string  `json:"institution_name,omitempty"`
+	Mask             *string  `json:"mask,omitempty"`
+	IsManual         bool     `json:"is_manual"`
+	IsLiability      bool     `json:"is_liability"`
+	Notes            *string  `json:"notes,omitempty"`
+	SyncEnabled      bool     `json:"sync_enabled"`
+	IncludeInBudgets bool     `json:"include_in_budgets"`
+	Scope            string   `json:"scope"`
+	// LowBalanceThreshold is the balance below which the cash-flow forecast
+	// warns; nil means below zero.
+	LowBalanceThreshold *float64  `json:"low_balance_threshold,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// AccountBalanceHistory is one recorded value of an account over time.
+type AccountBalanceHistory struct {
 	ID               string    `json:"id"`
-	UserID           string    `json:"user_id"`
-	HouseholdID      *string   `json:"household_id,omitempty"`
-	LinkedAccountID  *string   `json:"linked_account_id,omitempty"`
-	PlaidAccountID   string    `json:"plaid_account_id"`
-	Name             string    `json:"name"`
-	OfficialName     *string   `json:"official_name,omitempty"`
-	Type             string    `json:"type"`
-	Subtype          *string   `json:"subtype,omitempty"`
-	CurrentBalance   float64   `json:"current_balance"`
-	AvailableBalance *float64  `json:"available_balance,omitempty"`
-	IsoCurrencyCode  string    `json:"iso_currency_code"`
-	InstitutionName  *string   `json:"institution_name,omitempty"`
-	Mask             *string   `json:"mask,omitempty"`
+	AccountBalanceID string    `json:"account_balance_id"`
+	Balance          float64   `json:"balance"`
+	RecordedOn       string    `json:"recorded_on"`
+	Source           string    `json:"source"`
+	Note             *string   `json:"note,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ManualAccountRequest creates or updates an offline account.
+// IsLiability defaults from the account type when omitted.
+type ManualAccountRequest struct {
+	UserID          string  `json:"user_id"`
+	Name            string  `json:"name"`
+	Type            string  `json:"type"`
+	Subtype         *string `json:"subtype,omitempty"`
+	IsLiability     *bool   `json:"is_liability,omitempty"`
+	CurrentBalance  float64 `json:"current_balance"`
+	IsoCurrencyCode string  `json:"iso_currency_code"`
+	InstitutionName *string `json:"institution_name,omitempty"`
+	Notes           *string `json:"notes,omitempty"`
+}
+
+// AccountValueUpdate records a new value for a manual account.
+type AccountValueUpdate struct {
+	UserID     string  `json:"user_id"`
+	Balance    float64 `json:"balance"`
+	RecordedOn string  `json:"recorded_on,omitempty"` // YYYY-MM-DD, defaults to today
+	Note       *string `json:"note,omitempty"`
+}
+
+// NetWorthSummary rolls linked and manual accounts together with debts
+// that are not already tracked as a linked account.
+type NetWorthSummary struct {
+	TotalAssets       float64 `json:"total_assets"`
+	TotalLiabilities  float64 `json:"total_liabilities"`
+	UntrackedDebt     float64 `json:"untracked_debt"`
+	ManualAssets      float64 `json:"manual_assets"`
+	ManualLiabilities float64 `json:"manual_liabilities"`
+	NetWorth          float64 `json:"net_worth"`
+}
+
+// AccountSettingsRequest updates per-account sync and visibility settings.
+// Omitted fields are left unchanged.
+type AccountSettingsRequest struct {
+	UserID              string   `json:"user_id"`
+	SyncEnabled         *bool    `json:"sync_enabled"`
+	IncludeInBudgets    *bool    `json:"include_in_budgets"`
+	Scope               *string  `json:"scope"`
+	LowBalanceThreshold *float64 `json:"low_balance_threshold"`
 }
@@ -0,0 +1,18 @@
+package models
+
+// Attachment is a receipt photo or document attached to a transaction or a
+// bill payment. Exactly one of TransactionID and BillPaymentID is set.
+type Attachment struct {
+	ID            string  `json:"id"`
+	UserID        string  `json:"user_id"`
+	HouseholdID   *string `json:"household_id,omitempty"`
+	TransactionID *string `json:"transaction_id,omitempty"`
+	BillPaymentID *string `json:"bill_payment_id,omitempty"`
+	Filename      string  `json:"filename"`
+	ContentType   string  `json:"content_type"`
+	SizeBytes     int64   `json:"size_bytes"`
+	HasThumbnail  bool    `json:"has_thumbnail"`
+	CreatedAt     string  `json:"created_at"`
+	StorageKey    string  `json:"-"`
+	ThumbnailKey  *string `json:"-"`
+}
@@ -4,22 +4,31 @@ import "time"
 
 // Bill represents a recurring expense obligation (rent, utilities, subscriptions, loan payments).
 type Bill struct {
-	ID            string  `json:"id"`
-	UserID        string  `json:"user_id"`
-	HouseholdID   string  `json:"household_id,omitempty"`
-	Name          string  `json:"name"`
-	AmountDue     float64 `json:"amount_due"`
-	DueDay        int     `json:"due_day"`
-	Frequency     string  `json:"frequency"`
-	Payee         *string `json:"payee,omitempty"`
-	CategoryID    *string `json:"category_id,omitempty"`
-	DebtAccountID *string `json:"debt_account_id,omitempty"`
-	IsAutopay     bool    `json:"is_autopay"`
-	IsShared      bool    `json:"is_shared"`
+	ID             string  `json:"id"`
+	UserID         string  `json:"user_id"`
+	HouseholdID    string  `json:"household_id,omitempty"`
+	Name           string  `json:"name"`
+	AmountDue      float64 `json:"amount_due"`
+	DueDay         int     `json:"due_day"`
+	Frequency      string  `json:"frequency"`
+	Payee          *string `json:"payee,omitempty"`
+	CategoryID     *string `json:"category_id,omitempty"`
+	DebtAccountID  *string `json:"debt_account_id,omitempty"`
+	IsAutopay      bool    `json:"is_autopay"`
+	IsShared       bool    `json:"is_shared"`
+	MerchantID     *string `json:"merchant_id,omitempty"` // normalized payee, used to match payments
+	IsSubscription bool    `json:"is_subscription"`
+	TrialEndsAt    *string `json:"trial_ends_at,omitempty"` // YYYY-MM-DD, free trial converts to paid on this date
+	IsVariable     bool    `json:"is_variable"`
+	// ForecastAlertPct is how far a variable bill's charge may stray from
+	// its forecast before alerting; nil uses the default.
+	ForecastAlertPct *float64 `json:"forecast_alert_pct,omitempty"`
 	// Computed fields (populated by handler, not stored)
-	Status       string  `json:"status,omitempty"`
-	CategoryName *string `json:"category_name,omitempty"`
-	DebtName     *string `json:"debt_name,omitempty"`
+	Status         string   `json:"status,omitempty"`
+	CategoryName   *string  `json:"category_name,omitempty"`
+	DebtName       *string  `json:"debt_name,omitempty"`
+	ExpectedAmount *float64 `json:"expected_amount,omitempty"` // forecast for variable bills
+	ForecastMethod string   `json:"forecast_method,omitempty"`
 }
 
 // BillPayment tracks an individual payment event for a billing period.
@@ -35,3 +44,39 @@ type BillPayment struct {
 	PeriodStart   string    `json:"period_start"`
 	PeriodEnd     string    `json:"period_end"`
 }
+
+// BillSuggestion is a recurring charge found in transaction history that the
+// user can accept as a bill or ignore.
+type BillSuggestion struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"user_id"`
+	MerchantID     *string   `json:"merchant_id,omitempty"`
+	CategoryID     *string   `json:"category_id,omitempty"`
+	Name           string    `json:"name"`
+	Frequency      string    `json:"frequency"`
+	DueDay         int       `json:"due_day"`
+	TypicalAmount  float64   `json:"typical_amount"`
+	MinAmount      float64   `json:"min_amount"`
+	MaxAmount      float64   `json:"max_amount"`
+	AmountVariance float64   `json:"amount_variance"` // coefficient of variation, 0 = always the same
+	Confidence     float64   `json:"confidence"`
+	Occurrences    int       `json:"occurrences"`
+	LastCharged    string    `json:"last_charged"`
+	NextExpected   string    `json:"next_expected"`
+	Status         string    `json:"status"` // suggested, accepted, ignored
+	BillID         *string   `json:"bill_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// AcceptBillSuggestionRequest turns a suggestion into a bill. Every field is
+// optional and overrides what was detected.
+type AcceptBillSuggestionRequest struct {
+	Name           *string  `json:"name,omitempty"`
+	AmountDue      *float64 `json:"amount_due,omitempty"`
+	DueDay         *int     `json:"due_day,omitempty"`
+	Frequency      *string  `json:"frequency,omitempty"`
+	CategoryID     *string  `json:"category_id,omitempty"`
+	IsAutopay      bool     `json:"is_autopay"`
+	IsShared       bool     `json:"is_shared"`
+	IsSubscription bool     `json:"is_subscription"`
+}
@@ -0,0 +1,53 @@
+package models
+
+// CashFlowDay is one day of a projected balance.
+type CashFlowDay struct {
+	Date    string  `json:"date"`
+	Inflow  float64 `json:"inflow"`
+	Outflow float64 `json:"outflow"`
+	Balance float64 `json:"balance"`
+}
+
+// CashFlowEvent is a scheduled inflow or outflow used by a forecast.
+type CashFlowEvent struct {
+	Date      string  `json:"date"`
+	Name      string  `json:"name"`
+	Source    string  `json:"source"` // bill, income, expense
+	Amount    float64 `json:"amount"` // positive money in, negative money out
+	AccountID string  `json:"account_id"`
+}
+
+// AccountCashFlowForecast is the projected daily balance of one account.
+// BelowThresholdOn is the first day a checking account is expected to fall
+// under its low-balance threshold.
+type AccountCashFlowForecast struct {
+	AccountID             string        `json:"account_id"`
+	Name                  string        `json:"name"`
+	IsChecking            bool          `json:"is_checking"`
+	Threshold             float64       `json:"threshold"`
+	StartingBalance       float64       `json:"starting_balance"`
+	EndingBalance         float64       `json:"ending_balance"`
+	LowestBalance         float64       `json:"lowest_balance"`
+	LowestBalanceDate     string        `json:"lowest_balance_date"`
+	BelowThresholdOn      *string       `json:"below_threshold_on,omitempty"`
+	BelowThresholdBalance *float64      `json:"below_threshold_balance,omitempty"`
+	Days                  []CashFlowDay `json:"days"`
+}
+
+// CashFlowForecast projects balances forward from today across a user's or
+// household's cash accounts. Days is the combined balance of all accounts.
+type CashFlowForecast struct {
+	Scope              string                    `json:"scope"` // personal, household
+	HorizonDays        int                       `json:"horizon_days"`
+	StartDate          string                    `json:"start_date"`
+	EndDate            string                    `json:"end_date"`
+	DailyDiscretionary float64                   `json:"daily_discretionary"`
+	StartingBalance    float64                   `json:"starting_balance"`
+	EndingBalance      float64                   `json:"ending_balance"`
+	LowestBalance      float64                   `json:"lowest_balance"`
+	LowestBalanceDate  string                    `json:"lowest_balance_date"`
+	BelowThresholdOn   *string                   `json:"below_threshold_on,omitempty"`
+	Days               []CashFlowDay             `json:"days"`
+	Accounts           []AccountCashFlowForecast `json:"accounts"`
+	Events             []CashFlowEvent           `json:"events"`
+}
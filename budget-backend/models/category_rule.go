@@ -1,27 +1,61 @@
 package models
 
 // CategoryMappingRule represents a rule that maps merchants, Plaid categories,
-// or keywords to a specific budget category.
+// or keywords to a specific budget category. Advanced rules match on a set of
+// Conditions instead of MatchValue and may carry extra actions.
 type CategoryMappingRule struct {
-	ID           string  `json:"id"`
-	UserID       *string `json:"user_id,omitempty"`
-	HouseholdID  *string `json:"household_id,omitempty"`
-	RuleType     string  `json:"rule_type"`     // merchant, plaid_category, keyword
-	MatchValue   string  `json:"match_value"`
-	CategoryID   string  `json:"category_id"`
-	CategoryName string  `json:"category_name,omitempty"` // joined from categories table
-	Priority     int     `json:"priority"`
-	AutoCreated  bool    `json:"auto_created"`
-	UsageCount   int     `json:"usage_count"`
-	CreatedAt    string  `json:"created_at"`
+	ID           string          `json:"id"`
+	UserID       *string         `json:"user_id,omitempty"`
+	HouseholdID  *string         `json:"household_id,omitempty"`
+	RuleType     string          `json:"rule_type"` // merchant, plaid_category, keyword, advanced
+	Name         *string         `json:"name,omitempty"`
+	MatchValue   string          `json:"match_value"`
+	MerchantID   *string         `json:"merchant_id,omitempty"` // merchant rules keyed on a merchant entity
+	Conditions   *RuleConditions `json:"conditions,omitempty"`  // advanced only
+	CategoryID   string          `json:"category_id"`
+	CategoryName string          `json:"category_name,omitempty"` // joined from categories table
+	RuleActions
+	Priority    int    `json:"priority"`
+	AutoCreated bool   `json:"auto_created"`
+	UsageCount  int    `json:"usage_count"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// RuleConditions are ANDed together; unset fields are ignored. Amounts are
+// compared against the positive transaction amount.
+type RuleConditions struct {
+	MerchantRegex    string   `json:"merchant_regex,omitempty"` // case-insensitive
+	AmountMin        *float64 `json:"amount_min,omitempty"`
+	AmountMax        *float64 `json:"amount_max,omitempty"`
+	AccountBalanceID string   `json:"account_balance_id,omitempty"`
+	TransactionType  string   `json:"transaction_type,omitempty"` // income, expense
+	DayOfMonthMin    int      `json:"day_of_month_min,omitempty"`
+	DayOfMonthMax    int      `json:"day_of_month_max,omitempty"`
+}
+
+// RuleActions are the side effects of an advanced rule beyond its category.
+type RuleActions struct {
+	BudgetID      *string              `json:"budget_id,omitempty"`
+	SplitTemplate []SplitTemplateEntry `json:"split_template,omitempty"`
+	Tag           *string              `json:"tag,omitempty"` // tag name, created in scope if missing
+	MarkTransfer  bool                 `json:"mark_transfer"`
+}
+
+// SplitTemplateEntry splits a matched transaction by percentage.
+type SplitTemplateEntry struct {
+	CategoryID string  `json:"category_id"`
+	Percent    float64 `json:"percent"`
 }
 
 // CreateRuleRequest is the payload for creating a new mapping rule.
 type CreateRuleRequest struct {
-	RuleType   string `json:"rule_type"`
-	MatchValue string `json:"match_value"`
-	CategoryID string `json:"category_id"`
-	Priority   int    `json:"priority"`
+	RuleType   string          `json:"rule_type"`
+	Name       *string         `json:"name,omitempty"`
+	MatchValue string          `json:"match_value"`
+	Conditions *RuleConditions `json:"conditions,omitempty"`
+	CategoryID string          `json:"category_id"`
+	RuleActions
+	Priority int `json:"priority"`
 }
 
 // CreateRuleFromEditRequest is the payload for auto-creating a rule
@@ -37,3 +71,54 @@ type ResolveResult struct {
 	Confidence string  `json:"confidence"` // exact, high, medium, low
 	RuleID     *string `json:"rule_id,omitempty"`
 }
+
+// RuleCategoryRef is a category as shown in a rule preview.
+type RuleCategoryRef struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// RuleChange is one existing transaction a rule would recategorize.
+type RuleChange struct {
+	TransactionID string          `json:"transaction_id"`
+	Date          string          `json:"date"`
+	Description   string          `json:"description"`
+	Amount        float64         `json:"amount"`
+	Type          string          `json:"type"`
+	Before        RuleCategoryRef `json:"before"`
+	After         RuleCategoryRef `json:"after"`
+}
+
+// RulePreview is the dry-run result of applying a rule to existing
+// transactions. Verified transactions are counted but never changed.
+type RulePreview struct {
+	RuleID          string       `json:"rule_id"`
+	Matched         int          `json:"matched"`
+	SkippedVerified int          `json:"skipped_verified"`
+	Unchanged       int          `json:"unchanged"`
+	Changes         []RuleChange `json:"changes"`
+}
+
+// ApplyRuleRequest optionally limits an apply to transactions picked from a
+// preview. An empty list applies to every change the preview would show.
+type ApplyRuleRequest struct {
+	TransactionIDs []string `json:"transaction_ids,omitempty"`
+}
+
+// ApplyRuleResult reports what an apply changed.
+type ApplyRuleResult struct {
+	RuleID  string       `json:"rule_id"`
+	Applied int          `json:"applied"`
+	Changes []RuleChange `json:"changes"`
+}
+
+// RuleApplication records one category change made by applying a rule.
+type RuleApplication struct {
+	ID                 string  `json:"id"`
+	RuleID             string  `json:"rule_id"`
+	TransactionID      string  `json:"transaction_id"`
+	PreviousCategoryID *string `json:"previous_category_id,omitempty"`
+	NewCategoryID      string  `json:"new_category_id"`
+	AppliedBy          string  `json:"applied_by"`
+	AppliedAt          string  `json:"applied_at"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// DebtPayment is one entry in a debt's ledger. Payments split into the
+// interest they cover and the principal they retire; interest entries are
+// monthly accruals, and adjustments bring the ledger in line with an edited
+// or lender-reported balance.
+type DebtPayment struct {
+	ID            string    `json:"id"`
+	DebtAccountID string    `json:"debt_account_id"`
+	UserID        string    `json:"user_id"`
+	Kind          string    `json:"kind"` // opening, payment, interest, adjustment
+	Amount        float64   `json:"amount"`
+	Principal     float64   `json:"principal"`
+	Interest      float64   `json:"interest"`
+	BalanceAfter  float64   `json:"balance_after"`
+	PaidOn        string    `json:"paid_on"`
+	TransactionID *string   `json:"transaction_id,omitempty"`
+	BillPaymentID *string   `json:"bill_payment_id,omitempty"`
+	Source        string    `json:"source"` // manual, bill, plaid, accrual
+	Note          string    `json:"note,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// DebtProgressPoint is a debt's balance at the end of a month, with the
+// principal and interest paid so far.
+type DebtProgressPoint struct {
+	Month         string  `json:"month"` // YYYY-MM
+	Balance       float64 `json:"balance"`
+	PrincipalPaid float64 `json:"principal_paid"`
+	InterestPaid  float64 `json:"interest_paid"`
+}
+
+// DebtPaymentHistory is a debt's ledger with payoff progress since the
+// ledger was opened.
+type DebtPaymentHistory struct {
+	Debt            DebtAccount         `json:"debt"`
+	UnpaidInterest  float64             `json:"unpaid_interest"`
+	StartingBalance float64             `json:"starting_balance"`
+	PrincipalPaid   float64             `json:"principal_paid"`
+	InterestPaid    float64             `json:"interest_paid"`
+	InterestAccrued float64             `json:"interest_accrued"`
+	PercentPaid     float64             `json:"percent_paid"`
+	Progress        []DebtProgressPoint `json:"progress"`
+	Payments        []DebtPayment       `json:"payments"`
+}
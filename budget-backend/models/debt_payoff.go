@@ -0,0 +1,71 @@
+package models
+
+// ExtraPaymentChange sets the monthly extra payment from a month onward.
+type ExtraPaymentChange struct {
+	FromMonth int     `json:"from_month"`
+	Amount    float64 `json:"amount"`
+}
+
+// LumpSumPayment is a one-time payment in a given month. Without a DebtID it
+// goes to whichever debt the strategy is targeting that month.
+type LumpSumPayment struct {
+	Month  int     `json:"month"`
+	Amount float64 `json:"amount"`
+	DebtID string  `json:"debt_id,omitempty"`
+}
+
+// PromoRate is a promotional APR on a debt for its first Months months, after
+// which the debt's regular APR applies again.
+type PromoRate struct {
+	DebtID string  `json:"debt_id"`
+	APR    float64 `json:"apr"`
+	Months int     `json:"months"`
+}
+
+// PayoffOptions shapes a payoff simulation beyond a flat monthly extra
+// payment. CustomOrder lists debt IDs in the order the "custom" strategy pays
+// them down.
+type PayoffOptions struct {
+	ExtraPayment float64              `json:"extra_payment"`
+	ExtraChanges []ExtraPaymentChange `json:"extra_changes,omitempty"`
+	LumpSums     []LumpSumPayment     `json:"lump_sums,omitempty"`
+	CustomOrder  []string             `json:"custom_order,omitempty"`
+	PromoRates   []PromoRate          `json:"promo_rates,omitempty"`
+}
+
+// PayoffComparisonRequest is the payload for comparing payoff strategies.
+type PayoffComparisonRequest struct {
+	PayoffOptions
+	DebtCategory string `json:"debt_category"` // attack (default), structured, all
+}
+
+// PayoffDebtResult is when one debt is paid off under a strategy.
+type PayoffDebtResult struct {
+	DebtID        string  `json:"debt_id"`
+	DebtName      string  `json:"debt_name"`
+	Order         int     `json:"order"`
+	PayoffMonth   int     `json:"payoff_month"`
+	PayoffDate    string  `json:"payoff_date"`
+	TotalInterest float64 `json:"total_interest"`
+}
+
+// PayoffStrategyResult summarizes one strategy's simulation. MonthsSaved and
+// InterestSaved compare it to paying only the minimums.
+type PayoffStrategyResult struct {
+	Strategy      string             `json:"strategy"`
+	Months        int                `json:"months"`
+	DebtFree      bool               `json:"debt_free"`
+	DebtFreeDate  string             `json:"debt_free_date"`
+	TotalInterest float64            `json:"total_interest"`
+	TotalPaid     float64            `json:"total_paid"`
+	MonthsSaved   int                `json:"months_saved"`
+	InterestSaved float64            `json:"interest_saved"`
+	Debts         []PayoffDebtResult `json:"debts"`
+}
+
+// PayoffComparison runs every strategy over the same debts and options.
+type PayoffComparison struct {
+	MinimumOnly PayoffStrategyResult   `json:"minimum_only"`
+	Strategies  []PayoffStrategyResult `json:"strategies"`
+	Recommended string                 `json:"recommended"`
+}
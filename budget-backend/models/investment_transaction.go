@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// InvestmentTransaction is a single buy/sell/dividend/fee synced from Plaid.
+// Amount follows Plaid's sign convention: positive when cash leaves the
+// account (buys, fees), negative when cash arrives (sells, dividends).
+type InvestmentTransaction struct {
+	ID                           string    `json:"id"`
+	UserID                       string    `json:"user_id"`
+	HouseholdID                  *string   `json:"household_id,omitempty"`
+	LinkedAccountID              *string   `json:"linked_account_id,omitempty"`
+	PlaidAccountID               string    `json:"plaid_account_id"`
+	PlaidInvestmentTransactionID string    `json:"plaid_investment_transaction_id"`
+	PlaidSecurityID              *string   `json:"plaid_security_id,omitempty"`
+	SecurityName                 *string   `json:"security_name,omitempty"`
+	TickerSymbol                 *string   `json:"ticker_symbol,omitempty"`
+	SecurityType                 *string   `json:"security_type,omitempty"`
+	Type                         string    `json:"type"`
+	Subtype                      *string   `json:"subtype,omitempty"`
+	Name                         *string   `json:"name,omitempty"`
+	Quantity                     float64   `json:"quantity"`
+	Price                        float64   `json:"price"`
+	Amount                       float64   `json:"amount"`
+	Fees                         float64   `json:"fees"`
+	IsoCurrencyCode              string    `json:"iso_currency_code"`
+	Date                         time.Time `json:"date"`
+	CreatedAt                    time.Time `json:"created_at"`
+	UpdatedAt                    time.Time `json:"updated_at"`
+}
+
+// InvestmentPerformance summarizes returns and allocation across a user's
+// (or household's) investment accounts.
+type InvestmentPerformance struct {
+	AsOf             string  `json:"as_of"`
+	PeriodStart      string  `json:"period_start,omitempty"`
+	MarketValue      float64 `json:"market_value"`
+	CostBasis        float64 `json:"cost_basis"`
+	TotalContributed float64 `json:"total_contributed"`
+	TotalWithdrawn   float64 `json:"total_withdrawn"`
+	DividendIncome   float64 `json:"dividend_income"`
+	FeesPaid         float64 `json:"fees_paid"`
+	RealizedGain     float64 `json:"realized_gain"`
+	UnrealizedGain   float64 `json:"unrealized_gain"`
+	// Returns are percentages. MWR is annualized (IRR); it is nil when there
+	// is no transaction history to solve against.
+	TimeWeightedReturnPct  float64               `json:"time_weighted_return_pct"`
+	TWRAnnualizedPct       float64               `json:"twr_annualized_pct"`
+	MoneyWeightedReturnPct *float64              `json:"money_weighted_return_pct"`
+	Allocation             []AllocationSlice     `json:"allocation"`
+	Securities             []SecurityPerformance `json:"securities"`
+}
+
+// AllocationSlice is the share of market value held in one security type.
+type AllocationSlice struct {
+	SecurityType string  `json:"security_type"`
+	Value        float64 `json:"value"`
+	Percent      float64 `json:"percent"`
+}
+
+// SecurityPerformance breaks gains down for a single security.
+type SecurityPerformance struct {
+	SecurityID     string  `json:"security_id"`
+	Name           string  `json:"name"`
+	TickerSymbol   string  `json:"ticker_symbol,omitempty"`
+	SecurityType   string  `json:"security_type"`
+	Quantity       float64 `json:"quantity"`
+	MarketValue    float64 `json:"market_value"`
+	CostBasis      float64 `json:"cost_basis"`
+	RealizedGain   float64 `json:"realized_gain"`
+	UnrealizedGain float64 `json:"unrealized_gain"`
+	DividendIncome float64 `json:"dividend_income"`
+}
@@ -12,3 +12,50 @@ type LinkedAccount struct {
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
+
+// ProductSyncStatus is the last sync outcome for one Plaid product on an item.
+type ProductSyncStatus struct {
+	Product             string     `json:"product"`
+	LastAttemptAt       *time.Time `json:"last_attempt_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastErrorCode       *string    `json:"last_error_code"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+// LinkedAccountHealth is the per-item view returned by the linked account
+// status endpoint.
+type LinkedAccountHealth struct {
+	ID                    string              `json:"id"`
+	InstitutionName       string              `json:"institution_name"`
+	ItemStatus            string              `json:"item_status"`
+	ErrorCode             *string             `json:"error_code"`
+	ErrorReason           string              `json:"error_reason,omitempty"`
+	NeedsReauth           bool                `json:"needs_reauth"`
+	ConsentExpirationTime *time.Time          `json:"consent_expiration_time"`
+	LastWebhookAt         *time.Time          `json:"last_webhook_at"`
+	Products              []ProductSyncStatus `json:"products"`
+	UpdateLinkToken       string              `json:"update_link_token,omitempty"`
+	CreatedAt             time.Time           `json:"created_at"`
+	UpdatedAt             time.Time           `json:"updated_at"`
+	Provider              string              `json:"provider"`
+}
+
+// PlaidResyncJob tracks a background re-import of an item's transactions.
+// Mode "backfill" only adds missing rows; "rebuild" also refreshes amounts and
+// categories on rows the user hasn't touched.
+type PlaidResyncJob struct {
+	ID              string     `json:"id"`
+	LinkedAccountID string     `json:"linked_account_id"`
+	UserID          string     `json:"user_id"`
+	Mode            string     `json:"mode"`
+	Status          string     `json:"status"`
+	PagesFetched    int        `json:"pages_fetched"`
+	Added           int        `json:"added"`
+	Updated         int        `json:"updated"`
+	Skipped         int        `json:"skipped"`
+	Removed         int        `json:"removed"`
+	Error           *string    `json:"error,omitempty"`
+	StartedAt       *time.Time `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// Merchant is a canonical merchant shared by every user. Raw bank
+// descriptions are normalized onto it so rules and insights see one row per
+// merchant instead of one per store number.
+type Merchant struct {
+	ID                string  `json:"id"`
+	Name              string  `json:"name"`
+	NormalizedKey     string  `json:"normalized_key"`
+	LogoURL           *string `json:"logo_url,omitempty"`
+	Color             *string `json:"color,omitempty"`
+	DefaultCategoryID *string `json:"default_category_id,omitempty"` // the caller's household default, if set
+	CreatedAt         string  `json:"created_at"`
+}
@@ -10,8 +10,9 @@ type Property struct {
 	City          string   `json:"city"`
 	State         string   `json:"state"`
 	ZipCode       string   `json:"zip_code"`
-	Zestimate     *float64 `json:"zestimate,omitempty"`
+	Zestimate     *float64 `json:"zestimate,omitempty"` // legacy; new values go to property_valuations
 	ManualValue   *float64 `json:"manual_value,omitempty"`
+	OriginalValue *float64 `json:"original_value,omitempty"`
 	ZillowURL     *string  `json:"zillow_url,omitempty"`
 	ZPID          *string  `json:"zpid,omitempty"`
 	DebtAccountID *string  `json:"debt_account_id,omitempty"`
@@ -22,10 +23,19 @@ type Property struct {
 	// Joined fields (not stored in properties table)
 	DebtName    *string  `json:"debt_name,omitempty"`
 	DebtBalance *float64 `json:"debt_balance,omitempty"`
+	// Most recent entry in property_valuations
+	LatestValue       *float64 `json:"latest_value,omitempty"`
+	LatestValueSource *string  `json:"latest_value_source,omitempty"`
+	LatestValuedOn    *string  `json:"latest_valued_on,omitempty"`
 }
 
-// EffectiveValue returns manual_value if set, else zestimate, else 0.
+// EffectiveValue returns the latest valuation. Properties without any
+// valuation history fall back to the legacy manual_value, then zestimate,
+// else 0.
 func (p Property) EffectiveValue() float64 {
+	if p.LatestValue != nil && *p.LatestValue > 0 {
+		return *p.LatestValue
+	}
 	if p.ManualValue != nil && *p.ManualValue > 0 {
 		return *p.ManualValue
 	}
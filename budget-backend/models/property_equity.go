@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// PropertyValuation is a property's value on a date from one source: a
+// valuation provider ("api", "hpi"), a manual value or an appraisal. Values
+// from the old Zillow lookups have the source "zillow".
+type PropertyValuation struct {
+	ID         string    `json:"id"`
+	PropertyID string    `json:"property_id"`
+	Value      float64   `json:"value"`
+	Source     string    `json:"source"`
+	ValuedOn   string    `json:"valued_on"` // YYYY-MM-DD
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// EquityPoint is a property's value, mortgage balance, equity and
+// loan-to-value ratio at the end of a month.
+type EquityPoint struct {
+	Month           string  `json:"month"` // YYYY-MM
+	Value           float64 `json:"value"`
+	MortgageBalance float64 `json:"mortgage_balance"`
+	Equity          float64 `json:"equity"`
+	LTV             float64 `json:"ltv"` // percent
+}
+
+// EquityScenario projects the mortgage with an extra principal payment each
+// month. PMIRequestDate is when the balance reaches 80% of the original
+// value and PMI can be cancelled on request.
+type EquityScenario struct {
+	ExtraPrincipal float64 `json:"extra_principal"`
+	Months         int     `json:"months"`
+	PaysOff        bool    `json:"pays_off"`
+	PayoffDate     string  `json:"payoff_date,omitempty"`
+	TotalInterest  float64 `json:"total_interest"`
+	InterestSaved  float64 `json:"interest_saved"`
+	MonthsSaved    int     `json:"months_saved"`
+	PMIRequestDate string  `json:"pmi_request_date,omitempty"`
+}
+
+// PropertyEquity is a property's equity position now and over time. The
+// PMI dates follow the Homeowners Protection Act: PMI can be cancelled on
+// request at 80% of the original value and ends automatically at 78% on the
+// scheduled payments.
+type PropertyEquity struct {
+	Property             Property            `json:"property"`
+	CurrentValue         float64             `json:"current_value"`
+	MortgageBalance      float64             `json:"mortgage_balance"`
+	Equity               float64             `json:"equity"`
+	LTV                  float64             `json:"ltv"`
+	APR                  float64             `json:"apr"`
+	MonthlyPayment       float64             `json:"monthly_payment"`
+	EscrowBalance        *float64            `json:"escrow_balance,omitempty"`
+	OriginationPrincipal *float64            `json:"origination_principal,omitempty"`
+	OriginalValue        float64             `json:"original_value"`
+	OriginalValueSource  string              `json:"original_value_source"` // property, valuation_history, current_value
+	HasPMI               *bool               `json:"has_pmi,omitempty"`
+	PMIRequestDate       string              `json:"pmi_request_date,omitempty"`
+	PMIAutoDate          string              `json:"pmi_auto_date,omitempty"`
+	Valuations           []PropertyValuation `json:"valuations"`
+	History              []EquityPoint       `json:"history"`
+	Scenarios            []EquityScenario    `json:"scenarios"`
+}
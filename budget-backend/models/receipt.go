@@ -0,0 +1,48 @@
+package models
+
+// ReceiptItem is one purchased line on a receipt. Amount is the line total
+// after any quantity multiplier.
+type ReceiptItem struct {
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity,omitempty"`
+	Amount      float64 `json:"amount"`
+}
+
+// ParsedReceipt is what could be read from receipt text. Summary amounts are
+// zero when the receipt didn't show them.
+type ParsedReceipt struct {
+	Merchant string        `json:"merchant,omitempty"`
+	Items    []ReceiptItem `json:"items"`
+	Subtotal float64       `json:"subtotal,omitempty"`
+	Tax      float64       `json:"tax,omitempty"`
+	Tip      float64       `json:"tip,omitempty"`
+	Discount float64       `json:"discount,omitempty"` // positive
+	Total    float64       `json:"total,omitempty"`
+}
+
+// ReceiptSplitRequest carries receipt text (OCR output or a pasted
+// e-receipt) to turn into suggested splits.
+type ReceiptSplitRequest struct {
+	Text  string `json:"text"`
+	UseAI bool   `json:"use_ai,omitempty"`
+}
+
+// SuggestedLineItem is a receipt line with the category the rules picked.
+type SuggestedLineItem struct {
+	ReceiptItem
+	CategoryID   string `json:"category_id,omitempty"`
+	CategoryName string `json:"category_name,omitempty"`
+	Confidence   string `json:"confidence,omitempty"`
+}
+
+// ReceiptSplitSuggestion is a draft the user reviews before saving it with
+// SplitTransaction. Ready is false when the draft can't be saved as is.
+type ReceiptSplitSuggestion struct {
+	TransactionID string              `json:"transaction_id"`
+	Source        string              `json:"source"` // parser, ai
+	Receipt       ParsedReceipt       `json:"receipt"`
+	Items         []SuggestedLineItem `json:"items"`
+	Draft         SplitRequest        `json:"draft"`
+	Ready         bool                `json:"ready"`
+	Warnings      []string            `json:"warnings"`
+}
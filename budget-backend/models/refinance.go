@@ -0,0 +1,57 @@
+package models
+
+// RefinanceRequest describes a hypothetical loan that pays off some debts.
+// Rate is an APR percentage. Fees are paid up front unless FeesFinanced adds
+// them to the loan. DebtCategory is the new loan's category; it defaults to
+// "structured" when it replaces a structured debt and "attack" otherwise.
+type RefinanceRequest struct {
+	DebtIDs      []string `json:"debt_ids"`
+	Rate         float64  `json:"rate"`
+	TermMonths   int      `json:"term_months"`
+	Fees         float64  `json:"fees"`
+	FeesFinanced bool     `json:"fees_financed"`
+	DebtCategory string   `json:"debt_category,omitempty"`
+}
+
+// RefinanceScenario is the cost of paying off a set of debts one way.
+type RefinanceScenario struct {
+	MonthlyPayment float64 `json:"monthly_payment"`
+	TotalInterest  float64 `json:"total_interest"`
+	Fees           float64 `json:"fees"`
+	TotalCost      float64 `json:"total_cost"` // interest plus fees
+	Months         int     `json:"months"`
+	PaysOff        bool    `json:"pays_off"`
+	PayoffDate     string  `json:"payoff_date"`
+}
+
+// RefinanceFrameworkImpact is how a refinance changes progress through the
+// framework's Attack Debt level.
+type RefinanceFrameworkImpact struct {
+	CurrentLevel          int    `json:"current_level"`
+	CurrentLevelName      string `json:"current_level_name"`
+	ProjectedLevel        int    `json:"projected_level"`
+	ProjectedLevelName    string `json:"projected_level_name"`
+	AttackDebtsBefore     int    `json:"attack_debts_before"`
+	AttackDebtsAfter      int    `json:"attack_debts_after"`
+	AttackFreeMonths      int    `json:"attack_free_months"`
+	AttackFreeMonthsAfter int    `json:"attack_free_months_after"`
+	Summary               string `json:"summary"`
+}
+
+// RefinanceAnalysis compares keeping the replaced debts on their current
+// minimum payments with consolidating them into the new loan. BreakEvenMonth
+// is the first month the interest saved covers the fees, or 0 if it never
+// does.
+type RefinanceAnalysis struct {
+	Replaced             []DebtInfo               `json:"replaced"`
+	Principal            float64                  `json:"principal"`
+	Current              RefinanceScenario        `json:"current"`
+	Refinanced           RefinanceScenario        `json:"refinanced"`
+	MonthlyPaymentChange float64                  `json:"monthly_payment_change"`
+	InterestSaved        float64                  `json:"interest_saved"`
+	NetSavings           float64                  `json:"net_savings"` // interest saved less fees
+	BreakEvenMonth       int                      `json:"break_even_month"`
+	Framework            RefinanceFrameworkImpact `json:"framework"`
+	CurrentSchedules     []DebtPayoffSchedule     `json:"current_schedules"`
+	RefinancedSchedule   DebtPayoffSchedule       `json:"refinanced_schedule"`
+}
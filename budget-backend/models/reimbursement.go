@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Reimbursement is an expense the user fronted and expects to get back.
+type Reimbursement struct {
+	ID                   string     `json:"id"`
+	TransactionID        string     `json:"transaction_id"`
+	UserID               string     `json:"user_id"`
+	HouseholdID          *string    `json:"household_id,omitempty"`
+	ExpectedAmount       float64    `json:"expected_amount"`
+	Source               string     `json:"source"`
+	PayerKeywords        []string   `json:"payer_keywords"`
+	WindowDays           int        `json:"window_days"`
+	Status               string     `json:"status"` // outstanding, received, written_off
+	ReceivedAmount       *float64   `json:"received_amount,omitempty"`
+	MatchedTransactionID *string    `json:"matched_transaction_id,omitempty"`
+	ReceivedAt           *time.Time `json:"received_at,omitempty"`
+	Note                 *string    `json:"note,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+
+	// Joined from the expense transaction.
+	Description string    `json:"description"`
+	SpentAmount float64   `json:"spent_amount"`
+	SpentOn     time.Time `json:"spent_on"`
+	AgeDays     int       `json:"age_days"`
+}
+
+// ReimbursementRequest marks a transaction as reimbursable. ExpectedAmount
+// defaults to the transaction amount and WindowDays to 60.
+type ReimbursementRequest struct {
+	ExpectedAmount *float64 `json:"expected_amount,omitempty"`
+	Source         string   `json:"source"`
+	PayerKeywords  []string `json:"payer_keywords,omitempty"`
+	WindowDays     int      `json:"window_days,omitempty"`
+	Note           string   `json:"note,omitempty"`
+}
+
+// ReimbursementStatusRequest settles a reimbursement by hand: received with
+// the incoming transaction, written off, or back to outstanding.
+type ReimbursementStatusRequest struct {
+	Status               string  `json:"status"`
+	MatchedTransactionID *string `json:"matched_transaction_id,omitempty"`
+}
+
+// ReimbursementAgingBucket groups outstanding reimbursements by age.
+type ReimbursementAgingBucket struct {
+	Label  string  `json:"label"`
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+}
+
+// ReimbursementList is the reimbursements view with outstanding aging.
+type ReimbursementList struct {
+	Reimbursements   []Reimbursement            `json:"reimbursements"`
+	TotalOutstanding float64                    `json:"total_outstanding"`
+	Aging            []ReimbursementAgingBucket `json:"aging"`
+}
@@ -0,0 +1,97 @@
+package models
+
+// MemberSplitRatio is one member's default fraction of shared expenses.
+type MemberSplitRatio struct {
+	UserID string  `json:"user_id"`
+	Name   string  `json:"name"`
+	Ratio  float64 `json:"ratio"`
+}
+
+// HouseholdSplitSettings is how a household divides shared expenses by
+// default. Members carries the ratios the method currently produces.
+type HouseholdSplitSettings struct {
+	HouseholdID string             `json:"household_id"`
+	Method      string             `json:"method"` // equal, income, custom
+	Members     []MemberSplitRatio `json:"members"`
+}
+
+// SplitSettingsRequest updates a household's split method. Ratios, keyed by
+// user ID, are required for the custom method and must add up to 1.
+type SplitSettingsRequest struct {
+	Method string             `json:"method"`
+	Ratios map[string]float64 `json:"ratios,omitempty"`
+}
+
+// TransactionShare is a member's portion of a transaction or of one split.
+type TransactionShare struct {
+	ID            string  `json:"id"`
+	TransactionID string  `json:"transaction_id"`
+	SplitID       *string `json:"split_id,omitempty"`
+	UserID        string  `json:"user_id"`
+	UserName      string  `json:"user_name,omitempty"`
+	Amount        float64 `json:"amount"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// ShareEntry is one member's amount in a ShareRequest.
+type ShareEntry struct {
+	UserID string  `json:"user_id"`
+	Amount float64 `json:"amount"`
+}
+
+// ShareRequest sets who shares a transaction, or one split when SplitID is
+// given. With UseDefault the household's split ratios are applied and
+// Shares is ignored.
+type ShareRequest struct {
+	SplitID    *string      `json:"split_id,omitempty"`
+	Shares     []ShareEntry `json:"shares"`
+	UseDefault bool         `json:"use_default,omitempty"`
+}
+
+// MemberDebt is what one member owes another after netting.
+type MemberDebt struct {
+	FromUserID string  `json:"from_user_id"`
+	FromName   string  `json:"from_name"`
+	ToUserID   string  `json:"to_user_id"`
+	ToName     string  `json:"to_name"`
+	Amount     float64 `json:"amount"`
+}
+
+// MemberNet is a member's overall position: positive when others owe them.
+type MemberNet struct {
+	UserID string  `json:"user_id"`
+	Name   string  `json:"name"`
+	Net    float64 `json:"net"`
+}
+
+// HouseholdBalance is the running who-owes-whom for a household.
+type HouseholdBalance struct {
+	HouseholdID string       `json:"household_id"`
+	Debts       []MemberDebt `json:"debts"`
+	Members     []MemberNet  `json:"members"`
+}
+
+// SettleUpRequest records a payment from one member to another. FromUserID
+// defaults to the caller; ToUserID may be omitted in a two-member
+// household. A zero Amount settles everything FromUserID owes ToUserID.
+type SettleUpRequest struct {
+	FromUserID string  `json:"from_user_id,omitempty"`
+	ToUserID   string  `json:"to_user_id,omitempty"`
+	Amount     float64 `json:"amount,omitempty"`
+	Note       string  `json:"note,omitempty"`
+}
+
+// Settlement is a recorded settle-up payment.
+type Settlement struct {
+	ID            string  `json:"id"`
+	HouseholdID   string  `json:"household_id"`
+	FromUserID    string  `json:"from_user_id"`
+	FromName      string  `json:"from_name,omitempty"`
+	ToUserID      string  `json:"to_user_id"`
+	ToName        string  `json:"to_name,omitempty"`
+	Amount        float64 `json:"amount"`
+	TransactionID *string `json:"transaction_id,omitempty"`
+	Note          *string `json:"note,omitempty"`
+	CreatedBy     *string `json:"created_by,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+// SubscriptionAlert flags something worth a look on a subscription: a price
+// increase, a late or missing charge, a trial about to convert or one that
+// just did, or an upcoming annual renewal.
+type SubscriptionAlert struct {
+	Type           string   `json:"type"` // price_increase, late_charge, missing_charge, trial_ending, trial_converted, annual_renewal
+	BillID         string   `json:"bill_id"`
+	Name           string   `json:"name"`
+	Message        string   `json:"message"`
+	Amount         float64  `json:"amount"`
+	PreviousAmount *float64 `json:"previous_amount,omitempty"`
+	Date           string   `json:"date"` // the charge, trial end or renewal the alert is about
+}
+
+// Subscription is a subscription bill with its cost normalized to a month
+// and a year.
+type Subscription struct {
+	BillID      string              `json:"bill_id"`
+	Name        string              `json:"name"`
+	Frequency   string              `json:"frequency"`
+	AmountDue   float64             `json:"amount_due"`
+	MonthlyCost float64             `json:"monthly_cost"`
+	AnnualCost  float64             `json:"annual_cost"`
+	IsAutopay   bool                `json:"is_autopay"`
+	TrialEndsAt *string             `json:"trial_ends_at,omitempty"`
+	LastCharged *string             `json:"last_charged,omitempty"`
+	LastAmount  *float64            `json:"last_amount,omitempty"`
+	NextCharge  *string             `json:"next_charge,omitempty"`
+	Alerts      []SubscriptionAlert `json:"alerts"`
+}
+
+// SubscriptionSummary is the subscriptions view.
+type SubscriptionSummary struct {
+	Subscriptions []Subscription      `json:"subscriptions"`
+	MonthlyTotal  float64             `json:"monthly_total"`
+	AnnualTotal   float64             `json:"annual_total"`
+	Alerts        []SubscriptionAlert `json:"alerts"`
+}
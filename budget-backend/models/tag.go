@@ -0,0 +1,50 @@
+package models
+
+// Tag is a cross-cutting label on transactions and splits. Tags belong to
+// the user's household, or to the user when they aren't in one.
+type Tag struct {
+	ID          string  `json:"id"`
+	UserID      string  `json:"user_id"`
+	HouseholdID *string `json:"household_id,omitempty"`
+	Name        string  `json:"name"`
+	Color       *string `json:"color,omitempty"`
+	UsageCount  int     `json:"usage_count"` // tagged transactions and splits
+	CreatedAt   string  `json:"created_at"`
+}
+
+// TagRef is a tag as embedded in a transaction or split.
+type TagRef struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Color *string `json:"color,omitempty"`
+}
+
+// TagRequest is the payload for creating or renaming a tag.
+type TagRequest struct {
+	Name  string  `json:"name"`
+	Color *string `json:"color,omitempty"`
+}
+
+// BulkTagRequest adds and removes tags on many transactions and splits at
+// once. Tags in AddTagNames are created if they don't exist yet.
+type BulkTagRequest struct {
+	TransactionIDs []string `json:"transaction_ids,omitempty"`
+	SplitIDs       []string `json:"split_ids,omitempty"`
+	AddTagIDs      []string `json:"add_tag_ids,omitempty"`
+	AddTagNames    []string `json:"add_tag_names,omitempty"`
+	RemoveTagIDs   []string `json:"remove_tag_ids,omitempty"`
+}
+
+// BulkTagResult reports how many assignments a bulk tag request changed.
+type BulkTagResult struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// TagSpending is the total tagged income and spending for one tag.
+type TagSpending struct {
+	TagRef
+	Expenses     float64 `json:"expenses"`
+	Income       float64 `json:"income"`
+	Transactions int     `json:"transactions"`
+}
@@ -21,4 +21,7 @@ type Transaction struct {
 	MatchConfidence *string `json:"match_confidence,omitempty"`
 	MatchedRuleID   *string `json:"matched_rule_id,omitempty"`
 	UserVerified    bool    `json:"user_verified"`
+	AccountBalanceID *string `json:"account_balance_id,omitempty"` // manual account this was posted against
+	Tags             []TagRef `json:"tags"`
+	Shares           []TransactionShare `json:"shares,omitempty"` // per-member shares, split-level ones included
 }
@@ -0,0 +1,48 @@
+package models
+
+// BulkTransactionFilter selects transactions for a bulk operation. At least
+// one field must be set; fields are ANDed together.
+type BulkTransactionFilter struct {
+	StartDate        string   `json:"start_date,omitempty"` // YYYY-MM-DD, inclusive
+	EndDate          string   `json:"end_date,omitempty"`   // YYYY-MM-DD, inclusive
+	Type             string   `json:"type,omitempty"`       // income, expense, transfer
+	CategoryID       string   `json:"category_id,omitempty"`
+	Uncategorized    bool     `json:"uncategorized,omitempty"`
+	AccountBalanceID string   `json:"account_balance_id,omitempty"`
+	MerchantID       string   `json:"merchant_id,omitempty"`
+	Source           string   `json:"source,omitempty"` // manual, bank
+	Search           string   `json:"search,omitempty"` // matched against the note
+	TagIDs           []string `json:"tag_ids,omitempty"`
+	UnverifiedOnly   bool     `json:"unverified_only,omitempty"`
+}
+
+// BulkTransactionRequest applies one operation to many transactions, picked
+// by TransactionIDs or by Filter.
+type BulkTransactionRequest struct {
+	TransactionIDs []string               `json:"transaction_ids,omitempty"`
+	Filter         *BulkTransactionFilter `json:"filter,omitempty"`
+	// Operation is one of recategorize, assign_budget, mark_verified, tag,
+	// delete, convert_to_transfer.
+	Operation  string   `json:"operation"`
+	CategoryID *string  `json:"category_id,omitempty"` // recategorize
+	BudgetID   *string  `json:"budget_id,omitempty"`   // assign_budget; null clears
+	TagIDs     []string `json:"tag_ids,omitempty"`     // tag
+	TagNames   []string `json:"tag_names,omitempty"`   // tag; created if missing
+}
+
+// BulkItemResult is the outcome for one transaction. Status is updated,
+// unchanged, deleted, skipped, not_found, forbidden or failed.
+type BulkItemResult struct {
+	TransactionID string `json:"transaction_id"`
+	Status        string `json:"status"`
+	Message       string `json:"message,omitempty"`
+}
+
+// BulkTransactionResult summarizes a bulk operation.
+type BulkTransactionResult struct {
+	Operation string           `json:"operation"`
+	Requested int              `json:"requested"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
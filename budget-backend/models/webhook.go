@@ -20,7 +20,9 @@ type PlaidWebhookRequest struct {
 	ItemID              string `json:"item_id"`
 	NewTransactions     int    `json:"new_transactions,omitempty"`
 	RemovedTransactions int    `json:"removed_transactions,omitempty"`
-	Error               *struct {
+	// ConsentExpirationTime is set on ITEM PENDING_EXPIRATION webhooks (RFC 3339).
+	ConsentExpirationTime *string `json:"consent_expiration_time,omitempty"`
+	Error                 *struct {
 		ErrorType    string `json:"error_type"`
 		ErrorCode    string `json:"error_code"`
 		ErrorMessage string `json:"error_message"`
@@ -31,12 +31,25 @@ func SetupRoutes(r *mux.Router) {
 
 	// Transactions
 	authRoutes.HandleFunc("/transactions/backfill-categories", handlers.BackfillTransactionCategories).Methods("POST")
+	authRoutes.HandleFunc("/transactions/backfill-merchants", handlers.BackfillTransactionMerchants).Methods("POST")
+	authRoutes.HandleFunc("/transactions/bulk", handlers.BulkUpdateTransactions).Methods("POST")
 	authRoutes.HandleFunc("/transactions", handlers.CreateTransaction).Methods("POST")
 	authRoutes.HandleFunc("/transactions", handlers.GetTransactions).Methods("GET")
 	authRoutes.HandleFunc("/transactions/{id}/split", handlers.SplitTransaction).Methods("POST")
 	authRoutes.HandleFunc("/transactions/{id}/split", handlers.GetTransactionSplits).Methods("GET")
 	authRoutes.HandleFunc("/transactions/{id}/split", handlers.UpdateTransactionSplits).Methods("PUT")
 	authRoutes.HandleFunc("/transactions/{id}/split", handlers.DeleteTransactionSplits).Methods("DELETE")
+	authRoutes.HandleFunc("/transactions/{id}/split/suggest", handlers.SuggestReceiptSplits).Methods("POST")
+	authRoutes.HandleFunc("/transactions/{id}/shares", handlers.GetTransactionShares).Methods("GET")
+	authRoutes.HandleFunc("/transactions/{id}/shares", handlers.SetTransactionShares).Methods("PUT")
+	authRoutes.HandleFunc("/transactions/{id}/shares", handlers.DeleteTransactionShares).Methods("DELETE")
+	authRoutes.HandleFunc("/transactions/{id}/reimbursement", handlers.MarkReimbursable).Methods("PUT")
+	authRoutes.HandleFunc("/transactions/{id}/reimbursement", handlers.UnmarkReimbursable).Methods("DELETE")
+	authRoutes.HandleFunc("/reimbursements", handlers.ListReimbursements).Methods("GET")
+	authRoutes.HandleFunc("/reimbursements/match", handlers.MatchReimbursements).Methods("POST")
+	authRoutes.HandleFunc("/reimbursements/{id}", handlers.UpdateReimbursementStatus).Methods("PATCH")
+	authRoutes.HandleFunc("/transactions/{id}/attachments", handlers.UploadTransactionAttachment).Methods("POST")
+	authRoutes.HandleFunc("/transactions/{id}/attachments", handlers.ListTransactionAttachments).Methods("GET")
 	authRoutes.HandleFunc("/transactions/{id}", handlers.UpdateTransaction).Methods("PUT")
 	authRoutes.HandleFunc("/transactions/{id}", handlers.DeleteTransaction).Methods("Delete")
 	authRoutes.HandleFunc("/savings-goals", handlers.ListSavingsGoals).Methods("GET")
@@ -49,8 +62,11 @@ func SetupRoutes(r *mux.Router) {
 	authRoutes.HandleFunc("/debts", handlers.CreateDebt).Methods("POST")
 	authRoutes.HandleFunc("/debts/{id}", handlers.UpdateDebt).Methods("PUT")
 	authRoutes.HandleFunc("/debts/{id}/payment", handlers.ApplyDebtPayment).Methods("PATCH")
+	authRoutes.HandleFunc("/debts/{id}/payments", handlers.ListDebtPayments).Methods("GET")
 	authRoutes.HandleFunc("/debts/{id}/category", handlers.UpdateDebtCategory).Methods("PUT")
 	authRoutes.HandleFunc("/debts/grouped", handlers.ListDebtsByCategory).Methods("GET")
+	authRoutes.HandleFunc("/debts/payoff/compare", handlers.ComparePayoffStrategies).Methods("POST")
+	authRoutes.HandleFunc("/debts/refinance/analyze", handlers.AnalyzeRefinance).Methods("POST")
 	authRoutes.HandleFunc("/priorities", handlers.ListFinancialPriorities).Methods("GET")
 	authRoutes.HandleFunc("/priorities", handlers.CreateFinancialPriority).Methods("POST")
 	authRoutes.HandleFunc("/priorities/{id}", handlers.UpdateFinancialPriority).Methods("PUT")
@@ -69,13 +85,37 @@ func SetupRoutes(r *mux.Router) {
 	authRoutes.HandleFunc("/plaid/sync", handlers.SyncTransactions(plaid)).Methods("POST")
 	authRoutes.HandleFunc("/plaid/investments", handlers.SyncInvestments(plaid)).Methods("POST")
 	authRoutes.HandleFunc("/plaid/investments", handlers.GetInvestmentHoldings).Methods("GET")
+	authRoutes.HandleFunc("/plaid/investments/performance", handlers.GetInvestmentPerformance).Methods("GET")
 	authRoutes.HandleFunc("/plaid/liabilities", handlers.SyncLiabilities(plaid)).Methods("POST")
 	authRoutes.HandleFunc("/plaid/liabilities", handlers.GetLiabilities).Methods("GET")
 	authRoutes.HandleFunc("/plaid/balances", handlers.SyncAccountBalances(plaid)).Methods("POST")
 	authRoutes.HandleFunc("/plaid/balances", handlers.GetAccountBalances).Methods("GET")
+
+	// Manual (offline) accounts
+	authRoutes.HandleFunc("/accounts/manual", handlers.ListManualAccounts).Methods("GET")
+	authRoutes.HandleFunc("/accounts/manual", handlers.CreateManualAccount).Methods("POST")
+	authRoutes.HandleFunc("/accounts/manual/{id}", handlers.UpdateManualAccount).Methods("PUT")
+	authRoutes.HandleFunc("/accounts/manual/{id}", handlers.DeleteManualAccount).Methods("DELETE")
+	authRoutes.HandleFunc("/accounts/manual/{id}/value", handlers.RecordManualAccountValue).Methods("POST")
+	authRoutes.HandleFunc("/accounts/manual/{id}/history", handlers.GetAccountBalanceHistory).Methods("GET")
+	authRoutes.HandleFunc("/accounts/manual/{id}/transactions", handlers.CreateManualAccountTransaction).Methods("POST")
+
+	// Per-account sync, budget visibility, sharing scope and low-balance threshold
+	authRoutes.HandleFunc("/accounts/{id}/settings", handlers.UpdateAccountSettings).Methods("PUT")
+
 	authRoutes.HandleFunc("/recurring/process", handlers.ProcessRecurring).Methods("POST")
 	authRoutes.HandleFunc("/insights", handlers.GetSpendingInsights).Methods("GET")
-	authRoutes.HandleFunc("/top-categories", handlers.GetTopMerchants).Methods("GET")
+	authRoutes.HandleFunc("/cash-flow/forecast", handlers.GetCashFlowForecast).Methods("GET")
+	authRoutes.HandleFunc("/top-categories", handlers.GetTopCategories).Methods("GET")
+	authRoutes.HandleFunc("/top-merchants", handlers.GetTopMerchants).Methods("GET")
+	authRoutes.HandleFunc("/merchants", handlers.ListMerchants).Methods("GET")
+	authRoutes.HandleFunc("/merchants/{id}/default-category", handlers.SetMerchantDefaultCategory).Methods("PUT")
+	authRoutes.HandleFunc("/insights/tags", handlers.GetTagSpending).Methods("GET")
+	authRoutes.HandleFunc("/tags", handlers.ListTags).Methods("GET")
+	authRoutes.HandleFunc("/tags", handlers.CreateTag).Methods("POST")
+	authRoutes.HandleFunc("/tags/bulk", handlers.BulkTagTransactions).Methods("POST")
+	authRoutes.HandleFunc("/tags/{id}", handlers.UpdateTag).Methods("PUT")
+	authRoutes.HandleFunc("/tags/{id}", handlers.DeleteTag).Methods("DELETE")
 	authRoutes.HandleFunc("/refresh", handlers.RefreshTokenHandler).Methods("POST")
 	authRoutes.HandleFunc("/onboarding/complete", handlers.CompleteOnboarding).Methods("POST")
 
@@ -83,10 +123,19 @@ func SetupRoutes(r *mux.Router) {
 	authRoutes.HandleFunc("/bills", handlers.ListBills).Methods("GET")
 	authRoutes.HandleFunc("/bills", handlers.CreateBill).Methods("POST")
 	authRoutes.HandleFunc("/bills/auto-detect", handlers.AutoDetectBillPayments).Methods("POST")
+	authRoutes.HandleFunc("/bills/suggestions", handlers.ListBillSuggestions).Methods("GET")
+	authRoutes.HandleFunc("/bills/suggestions/{id}/accept", handlers.AcceptBillSuggestion).Methods("POST")
+	authRoutes.HandleFunc("/bills/suggestions/{id}/ignore", handlers.IgnoreBillSuggestion).Methods("POST")
 	authRoutes.HandleFunc("/bills/{id}", handlers.UpdateBill).Methods("PUT")
 	authRoutes.HandleFunc("/bills/{id}", handlers.DeleteBill).Methods("DELETE")
 	authRoutes.HandleFunc("/bills/{id}/pay", handlers.MarkBillPaid).Methods("POST")
 	authRoutes.HandleFunc("/bills/{id}/payments", handlers.ListBillPayments).Methods("GET")
+	authRoutes.HandleFunc("/subscriptions", handlers.ListSubscriptions).Methods("GET")
+	authRoutes.HandleFunc("/bill-payments/{id}/attachments", handlers.UploadBillPaymentAttachment).Methods("POST")
+	authRoutes.HandleFunc("/bill-payments/{id}/attachments", handlers.ListBillPaymentAttachments).Methods("GET")
+	authRoutes.HandleFunc("/attachments/{id}", handlers.DownloadAttachment).Methods("GET")
+	authRoutes.HandleFunc("/attachments/{id}/thumbnail", handlers.DownloadAttachmentThumbnail).Methods("GET")
+	authRoutes.HandleFunc("/attachments/{id}", handlers.DeleteAttachment).Methods("DELETE")
 
 	// Auth (Login, Register, OAuth)
 	r.HandleFunc("/users/register", handlers.RegisterUser).Methods("POST")
@@ -109,6 +158,10 @@ func SetupRoutes(r *mux.Router) {
 	authRoutes.HandleFunc("/category-rules", handlers.CreateCategoryRule).Methods("POST")
 	authRoutes.HandleFunc("/category-rules/from-edit", handlers.CreateRuleFromEdit).Methods("POST")
 	authRoutes.HandleFunc("/category-rules/{id}", handlers.UpdateCategoryRule).Methods("PUT")
+	authRoutes.HandleFunc("/category-rules/{id}/preview", handlers.PreviewCategoryRule).Methods("POST")
+	authRoutes.HandleFunc("/category-rules/{id}/apply", handlers.ApplyCategoryRule).Methods("POST")
+	authRoutes.HandleFunc("/category-rules/{id}/applications", handlers.ListRuleApplications).Methods("GET")
+	authRoutes.HandleFunc("/category-model/retrain", handlers.RetrainCategoryModel).Methods("POST")
 	authRoutes.HandleFunc("/category-rules/{id}", handlers.DeleteCategoryRule).Methods("DELETE")
 
 	// Budgets (behind auth)
@@ -122,9 +175,11 @@ func SetupRoutes(r *mux.Router) {
 	// Plaid (behind auth)
 	authRoutes.HandleFunc("/link_token", handlers.CreateLinkToken(plaid)).Methods("GET")
 	authRoutes.HandleFunc("/exchange_token", handlers.ExchangeToken(plaid)).Methods("POST")
-	authRoutes.HandleFunc("/linked-accounts/status", handlers.GetLinkedAccountStatus).Methods("GET")
+	authRoutes.HandleFunc("/linked-accounts/status", handlers.GetLinkedAccountStatus(plaid)).Methods("GET")
 	authRoutes.HandleFunc("/plaid/update-link-token", handlers.CreateUpdateLinkToken(plaid)).Methods("POST")
 	authRoutes.HandleFunc("/linked-accounts/{id}/reset", handlers.ResetItemError).Methods("PUT")
+	authRoutes.HandleFunc("/linked-accounts/{id}/resync", handlers.ResyncLinkedAccount(plaid)).Methods("POST")
+	authRoutes.HandleFunc("/linked-accounts/{id}/resync", handlers.GetResyncStatus).Methods("GET")
 
 	// Plaid link page (public — serves HTML for WebView)
 	r.HandleFunc("/plaid/link-page", handlers.PlaidLinkPage).Methods("GET")
@@ -141,12 +196,21 @@ func SetupRoutes(r *mux.Router) {
 	authRoutes.HandleFunc("/push-preference", handlers.UpdatePushPreference).Methods("PUT")
 	authRoutes.HandleFunc("/push-preference", handlers.GetPushPreference).Methods("GET")
 
+	// Calendar feed (behind auth; the feed itself is public, keyed by token)
+	authRoutes.HandleFunc("/calendar-feed", handlers.GetCalendarFeed).Methods("GET")
+	authRoutes.HandleFunc("/calendar-feed", handlers.CreateCalendarFeed).Methods("POST")
+	authRoutes.HandleFunc("/calendar-feed", handlers.RevokeCalendarFeed).Methods("DELETE")
+	r.HandleFunc("/calendar/{token:[0-9a-f]+}.ics", handlers.ServeCalendarFeed).Methods("GET")
+
 	// Properties (behind auth)
 	authRoutes.HandleFunc("/properties", handlers.ListProperties).Methods("GET")
 	authRoutes.HandleFunc("/properties", handlers.CreateProperty).Methods("POST")
 	authRoutes.HandleFunc("/properties/{id}", handlers.UpdateProperty).Methods("PUT")
 	authRoutes.HandleFunc("/properties/{id}", handlers.DeleteProperty).Methods("DELETE")
 	authRoutes.HandleFunc("/properties/{id}/refresh", handlers.RefreshPropertyValue).Methods("POST")
+	authRoutes.HandleFunc("/properties/{id}/equity", handlers.GetPropertyEquity).Methods("GET")
+	authRoutes.HandleFunc("/properties/{id}/valuations", handlers.ListPropertyValuations).Methods("GET")
+	authRoutes.HandleFunc("/properties/{id}/valuations", handlers.AddPropertyValuation).Methods("POST")
 
 	// Households (behind auth)
 	authRoutes.HandleFunc("/households", handlers.CreateHousehold).Methods("POST")
@@ -155,6 +219,11 @@ func SetupRoutes(r *mux.Router) {
 	authRoutes.HandleFunc("/households/invites", handlers.ListHouseholdInvites).Methods("GET")
 	authRoutes.HandleFunc("/households/me", handlers.GetHouseholdForUser).Methods("GET")
 	authRoutes.HandleFunc("/households/summary", handlers.GetHouseholdSummary).Methods("GET")
+	authRoutes.HandleFunc("/households/split-settings", handlers.GetSplitSettings).Methods("GET")
+	authRoutes.HandleFunc("/households/split-settings", handlers.UpdateSplitSettings).Methods("PUT")
+	authRoutes.HandleFunc("/households/balance", handlers.GetHouseholdBalance).Methods("GET")
+	authRoutes.HandleFunc("/households/settle-up", handlers.SettleUp).Methods("POST")
+	authRoutes.HandleFunc("/households/settlements", handlers.ListSettlements).Methods("GET")
 
 	// Activity Feed (behind auth)
 	authRoutes.HandleFunc("/activity-feed", handlers.GetActivityFeed).Methods("GET")